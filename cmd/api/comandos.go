@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// executarComando roda um subcomando administrativo e devolve o código de saída do processo.
+func executarComando(ctx context.Context, db *sql.DB, args []string) int {
+	switch args[0] {
+	case "criar-api-key":
+		return criarAPIKey(ctx, db, args[1:])
+	case "definir-papel":
+		return definirPapel(ctx, db, args[1:])
+	case "sincronizar-cupons":
+		return sincronizarCupons(ctx, db)
+	default:
+		fmt.Fprintf(os.Stderr, "comando desconhecido: %s\ncomandos disponíveis: criar-api-key, definir-papel, sincronizar-cupons\n", args[0])
+		return 2
+	}
+}
+
+// criarAPIKey cria uma API key pela linha de comando. É o caminho para a primeira chave
+// com o escopo apikeys:admin, que depois cria as demais pela API:
+//
+//	api criar-api-key -nome "admin" -escopos apikeys:admin
+func criarAPIKey(ctx context.Context, db *sql.DB, args []string) int {
+	fs := flag.NewFlagSet("criar-api-key", flag.ContinueOnError)
+	nome := fs.String("nome", "", "nome que identifica a chave")
+	escopos := fs.String("escopos", "", "escopos separados por vírgula (ex: usuarios:read,usuarios:write)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var lista []string
+	for _, escopo := range strings.Split(*escopos, ",") {
+		if escopo = strings.TrimSpace(escopo); escopo != "" {
+			lista = append(lista, escopo)
+		}
+	}
+
+	repo, err := repository.NewAPIKeyRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "erro ao preparar o repositório:", err)
+		return 1
+	}
+	defer repo.Close()
+
+	chave, key, err := service.NewAPIKeyService(repo).CreateAPIKey(ctx, *nome, lista)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "erro ao criar a API key:", err)
+		var de *domain.Error
+		if errors.As(err, &de) {
+			for _, fe := range de.Fields {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", fe.Field, fe.Message)
+			}
+		}
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "API key %d (%s) criada com os escopos %s. Guarde-a agora: ela não será exibida de novo.\n",
+		key.ID, key.Prefixo, strings.Join(key.Escopos, ", "))
+	fmt.Println(chave)
+	return 0
+}
+
+// definirPapel altera o papel de um usuário (ex: para promovê-lo a admin). A alteração fica
+// registrada na trilha de auditoria com o ator "cli":
+//
+//	api definir-papel -id 7 -papel admin
+func definirPapel(ctx context.Context, db *sql.DB, args []string) int {
+	fs := flag.NewFlagSet("definir-papel", flag.ContinueOnError)
+	id := fs.Int64("id", 0, "ID do usuário")
+	papel := fs.String("papel", "", "novo papel ("+strings.Join(domain.Papeis, ", ")+")")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	repo, err := repository.NewSQLiteRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "erro ao preparar o repositório:", err)
+		return 1
+	}
+	defer repo.Close()
+	auditoria, err := repository.NewAuditoriaRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "erro ao preparar o repositório:", err)
+		return 1
+	}
+	defer auditoria.Close()
+
+	auth := service.NewAuthService(repo, nil, nil, "").WithAuditoria(auditoria)
+	if err := auth.SetRole(ctx, *id, *papel, "cli"); err != nil {
+		fmt.Fprintln(os.Stderr, "erro ao definir o papel:", err)
+		var de *domain.Error
+		if errors.As(err, &de) {
+			for _, fe := range de.Fields {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", fe.Field, fe.Message)
+			}
+		}
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Usuário %d agora tem o papel %s.\n", *id, *papel)
+	return 0
+}
+
+// sincronizarCupons copia os códigos promocionais da Stripe para o banco. Roda na carga
+// inicial, antes de configurar os webhooks, ou para corrigir webhooks perdidos:
+//
+//	STRIPE_SECRET_KEY=sk_... api sincronizar-cupons
+func sincronizarCupons(ctx context.Context, db *sql.DB) int {
+	orgs, err := repository.NewOrganizacaoRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "erro ao preparar o repositório:", err)
+		return 1
+	}
+	defer orgs.Close()
+	cupons, err := repository.NewCupomRepository(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "erro ao preparar o repositório:", err)
+		return 1
+	}
+	defer cupons.Close()
+
+	organizacoes := service.NewOrganizacaoService(orgs, nil, nil, "").WithCupons(cupons)
+	n, err := organizacoes.SincronizarCupons(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "erro ao sincronizar os códigos promocionais (%d gravados): %v\n", n, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%d códigos promocionais sincronizados com a Stripe.\n", n)
+	return 0
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/mailer"
+	"github.com/willjrcristo/go-sqlite-db/internal/oidc"
+	"github.com/willjrcristo/go-sqlite-db/internal/ratelimit"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// durationFromEnv lê uma duração no formato do Go (ex: "5m", "30s"), ou o padrão se a variável estiver vazia.
+func durationFromEnv(nome string, padrao time.Duration) (time.Duration, error) {
+	valor := os.Getenv(nome)
+	if valor == "" {
+		return padrao, nil
+	}
+	d, err := time.ParseDuration(valor)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s=%q: use uma duração como 30s ou 5m", nome, valor)
+	}
+	return d, nil
+}
+
+// intFromEnv lê um inteiro positivo, ou o padrão se a variável estiver vazia.
+func intFromEnv(nome string, padrao int) (int, error) {
+	valor := os.Getenv(nome)
+	if valor == "" {
+		return padrao, nil
+	}
+	n, err := strconv.Atoi(valor)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s=%q: use um número inteiro positivo", nome, valor)
+	}
+	return n, nil
+}
+
+// regrasLimitePadrao são os limites usados quando RATE_LIMITS não é definida: mais rígidos
+// para criação de usuários e para o checkout, a troca de assentos das organizações e os dados
+// de cobrança (que chamam a Stripe) e folgados no restante.
+// Os pedidos de /auth que enviam e-mail também são limitados, para não virarem spam, assim
+// como o login, a renovação de sessão e as rotas que conferem códigos de MFA, contra
+// tentativa e erro. Respostas 401 também são limitadas por IP (falhas-autenticacao), contra
+// a adivinhação de chaves de API e tokens.
+const regrasLimitePadrao = "POST /usuarios=10/m; POST /organizations/{id}/checkout=5/m; PUT /organizations/{id}/seats=5/m; PUT /auth/me/billing=5/m; " +
+	"POST /auth/verify/resend=5/m; POST /auth/password-reset=5/m; " +
+	"POST /auth/login=10/m; POST /auth/login/mfa=5/m; POST /auth/refresh=30/m; " +
+	"POST /auth/mfa/enroll/confirm=5/m; POST /auth/mfa/disable=5/m; falhas-autenticacao=20/m; *=120/m"
+
+// rateLimitsFromEnv lê as regras de rate limit por rota de RATE_LIMITS (veja ratelimit.ParseRegras).
+// "off" desliga o rate limit.
+func rateLimitsFromEnv() (map[string]ratelimit.Limite, error) {
+	valor := os.Getenv("RATE_LIMITS")
+	switch valor {
+	case "":
+		valor = regrasLimitePadrao
+	case "off":
+		return nil, nil
+	}
+	regras, err := ratelimit.ParseRegras(valor)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMITS: %w", err)
+	}
+	return regras, nil
+}
+
+// segredoTokensFromEnv lê o segredo que assina os tokens enviados por e-mail. Sem
+// AUTH_TOKEN_SECRET, gera um aleatório: os links enviados deixam de valer quando o
+// servidor reinicia, o que só é aceitável em desenvolvimento.
+func segredoTokensFromEnv() ([]byte, error) {
+	if segredo := os.Getenv("AUTH_TOKEN_SECRET"); segredo != "" {
+		if len(segredo) < 32 {
+			return nil, fmt.Errorf("AUTH_TOKEN_SECRET: use pelo menos 32 caracteres")
+		}
+		return []byte(segredo), nil
+	}
+	slog.Warn("AUTH_TOKEN_SECRET não definida; usando um segredo aleatório (os links enviados expiram ao reiniciar)")
+	segredo := make([]byte, 32)
+	if _, err := rand.Read(segredo); err != nil {
+		return nil, err
+	}
+	return segredo, nil
+}
+
+// papeisMFAFromEnv lê de MFA_REQUIRED_ROLES (ex: "admin") os papéis que exigem o segundo
+// fator. O padrão é "admin"; "none" não exige de nenhum papel.
+func papeisMFAFromEnv() ([]string, error) {
+	valor := os.Getenv("MFA_REQUIRED_ROLES")
+	switch valor {
+	case "":
+		return []string{domain.PapelAdmin}, nil
+	case "none":
+		return nil, nil
+	}
+	var papeis []string
+	for _, papel := range strings.Split(valor, ",") {
+		papel = strings.TrimSpace(papel)
+		if !slices.Contains(domain.Papeis, papel) {
+			return nil, fmt.Errorf("MFA_REQUIRED_ROLES: papel %q desconhecido (use %s)", papel, strings.Join(domain.Papeis, ", "))
+		}
+		papeis = append(papeis, papel)
+	}
+	return papeis, nil
+}
+
+// mailerFromEnv escolhe o envio de e-mails por MAILER: "log" (padrão, só registra no log),
+// "file" (grava arquivos .eml em MAILER_DIR) ou "smtp" (SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASSWORD).
+func mailerFromEnv() (mailer.Mailer, error) {
+	remetente := os.Getenv("MAIL_FROM")
+	if remetente == "" {
+		remetente = "nao-responda@localhost"
+	}
+	switch tipo := os.Getenv("MAILER"); tipo {
+	case "", "log":
+		return mailer.LogMailer{}, nil
+	case "file":
+		dir := os.Getenv("MAILER_DIR")
+		if dir == "" {
+			dir = "emails"
+		}
+		return mailer.NewFileMailer(dir, remetente)
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("MAILER=smtp exige SMTP_HOST")
+		}
+		porta, err := intFromEnv("SMTP_PORT", 587)
+		if err != nil {
+			return nil, err
+		}
+		return mailer.NewSMTPMailer(host, porta, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"), remetente), nil
+	default:
+		return nil, fmt.Errorf("MAILER=%q: use log, file ou smtp", tipo)
+	}
+}
+
+// billingProviderFromEnv escolhe para onde vai o consumo medido: BILLING_PROVIDER=stripe
+// (padrão com STRIPE_SECRET_KEY definida) ou log.
+func billingProviderFromEnv() (billing.Provider, error) {
+	tipo := os.Getenv("BILLING_PROVIDER")
+	if tipo == "" {
+		tipo = "log"
+		if os.Getenv("STRIPE_SECRET_KEY") != "" {
+			tipo = "stripe"
+		}
+	}
+	switch tipo {
+	case "log":
+		return billing.LogProvider{}, nil
+	case "stripe":
+		chave := os.Getenv("STRIPE_SECRET_KEY")
+		if chave == "" {
+			return nil, fmt.Errorf("BILLING_PROVIDER=stripe exige STRIPE_SECRET_KEY")
+		}
+		return billing.NewStripeProvider(chave), nil
+	default:
+		return nil, fmt.Errorf("BILLING_PROVIDER=%q: use stripe ou log", tipo)
+	}
+}
+
+// politicaClientesFromEnv lê de CUSTOMER_SYNC_POLICY quem vence quando o contato do cliente é
+// alterado na Stripe: "local" (padrão, o perfil do usuário), "stripe" ou "newest" (o lado
+// alterado por último). O e-mail do perfil sempre vence.
+func politicaClientesFromEnv() (string, error) {
+	politica := os.Getenv("CUSTOMER_SYNC_POLICY")
+	if politica == "" {
+		return service.PoliticaClienteLocal, nil
+	}
+	if !slices.Contains(service.PoliticasCliente, politica) {
+		return "", fmt.Errorf("CUSTOMER_SYNC_POLICY=%q: use %s", politica, strings.Join(service.PoliticasCliente, ", "))
+	}
+	return politica, nil
+}
+
+// moedaPreco é o formato das moedas em STRIPE_PRICES: o código ISO 4217 em minúsculas.
+var moedaPreco = regexp.MustCompile(`^[a-z]{3}$`)
+
+// planoCheckoutFromEnv lê os preços e os impostos do checkout. STRIPE_PRICES traz um price
+// ID por moeda (ex: "brl:price_123,usd:price_456"); STRIPE_DEFAULT_CURRENCY é a moeda usada
+// quando nem o endereço nem o idioma do cliente indicam outra (padrão: a primeira listada).
+// STRIPE_AUTOMATIC_TAX=true liga o Stripe Tax; sem ele, STRIPE_TAX_RATES lista as tax rates
+// (ex: "txr_br,txr_pt") que a Stripe aplica conforme o país do cliente.
+func planoCheckoutFromEnv() (service.PlanoCheckout, error) {
+	var plano service.PlanoCheckout
+	for _, item := range strings.Split(os.Getenv("STRIPE_PRICES"), ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		moeda, preco, ok := strings.Cut(item, ":")
+		moeda, preco = strings.ToLower(strings.TrimSpace(moeda)), strings.TrimSpace(preco)
+		if !ok || !moedaPreco.MatchString(moeda) || preco == "" {
+			return plano, fmt.Errorf("STRIPE_PRICES: %q inválido (use moeda:price_id, ex: brl:price_123)", item)
+		}
+		if plano.Precos == nil {
+			plano.Precos = map[string]string{}
+			plano.MoedaPadrao = moeda
+		}
+		plano.Precos[moeda] = preco
+	}
+	if moeda := strings.ToLower(os.Getenv("STRIPE_DEFAULT_CURRENCY")); moeda != "" {
+		if _, ok := plano.Precos[moeda]; !ok {
+			return plano, fmt.Errorf("STRIPE_DEFAULT_CURRENCY=%q: a moeda não tem preço em STRIPE_PRICES", moeda)
+		}
+		plano.MoedaPadrao = moeda
+	}
+
+	if valor := os.Getenv("STRIPE_AUTOMATIC_TAX"); valor != "" {
+		automatico, err := strconv.ParseBool(valor)
+		if err != nil {
+			return plano, fmt.Errorf("STRIPE_AUTOMATIC_TAX=%q: use true ou false", valor)
+		}
+		plano.ImpostoAutomatico = automatico
+	}
+	for _, taxa := range strings.Split(os.Getenv("STRIPE_TAX_RATES"), ",") {
+		if taxa = strings.TrimSpace(taxa); taxa != "" {
+			plano.TaxasImposto = append(plano.TaxasImposto, taxa)
+		}
+	}
+	if plano.ImpostoAutomatico && len(plano.TaxasImposto) > 0 {
+		return plano, fmt.Errorf("STRIPE_TAX_RATES não pode ser usada com STRIPE_AUTOMATIC_TAX=true")
+	}
+	return plano, nil
+}
+
+// nomeProvedorOIDC é o formato dos nomes em OIDC_PROVIDERS, usados nas rotas e nas variáveis.
+var nomeProvedorOIDC = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// provedoresOIDCFromEnv lê os provedores de login de OIDC_PROVIDERS (ex: "google,local").
+// Cada um é configurado por OIDC_<NOME>_ISSUER, OIDC_<NOME>_CLIENT_ID, OIDC_<NOME>_CLIENT_SECRET
+// e, opcionalmente, OIDC_<NOME>_SCOPES; o callback registrado no provedor deve ser
+// <urlAPI>/auth/oidc/<nome>/callback.
+func provedoresOIDCFromEnv(urlAPI string, cliente *http.Client) (map[string]service.ProvedorOIDC, error) {
+	provedores := map[string]service.ProvedorOIDC{}
+	for _, nome := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		nome = strings.TrimSpace(nome)
+		if nome == "" {
+			continue
+		}
+		if !nomeProvedorOIDC.MatchString(nome) {
+			return nil, fmt.Errorf("OIDC_PROVIDERS: nome %q inválido (use letras minúsculas, números e _)", nome)
+		}
+		prefixo := "OIDC_" + strings.ToUpper(nome) + "_"
+		cfg := oidc.Config{
+			Issuer:       os.Getenv(prefixo + "ISSUER"),
+			ClientID:     os.Getenv(prefixo + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefixo + "CLIENT_SECRET"),
+			RedirectURL:  strings.TrimRight(urlAPI, "/") + "/auth/oidc/" + nome + "/callback",
+			Escopos:      strings.Fields(os.Getenv(prefixo + "SCOPES")),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("provedor OIDC %q: defina %sISSUER e %sCLIENT_ID", nome, prefixo, prefixo)
+		}
+		provedores[nome] = oidc.NewProvedor(cfg, cliente)
+	}
+	return provedores, nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -16,15 +19,24 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/joho/godotenv"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stripe/stripe-go/v78"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	// --- Pacotes Internos ---
 	_ "github.com/willjrcristo/go-sqlite-db/docs" // Efeito colateral para o Swagger
+	"github.com/willjrcristo/go-sqlite-db/internal/cache"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
 	httphandler "github.com/willjrcristo/go-sqlite-db/internal/handler/http"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/ratelimit"
 	"github.com/willjrcristo/go-sqlite-db/internal/repository"
 	"github.com/willjrcristo/go-sqlite-db/internal/service"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+	"github.com/willjrcristo/go-sqlite-db/internal/tokens"
 )
 
 // @title           API de Usuários
@@ -41,6 +53,11 @@ import (
 //
 // @host      localhost:8080
 // @BasePath  /
+//
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 API key criada em /api-keys (ou com o comando criar-api-key). Também é aceita em Authorization: Bearer.
 func main() {
 
 	// --- CARREGAR VARIÁVEIS DE AMBIENTE ---
@@ -51,12 +68,35 @@ func main() {
 
     // --- CONFIGURAÇÃO DA STRIPE E DO LOGGER ---
     stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
+	// As chamadas à Stripe passam por um cliente instrumentado para gerar spans de cliente.
+	stripe.SetHTTPClient(telemetry.NewHTTPClient("stripe"))
 
 	// --- CONFIGURAÇÃO DO LOGGER ---
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	// O nível inicial vem de LOG_LEVEL e pode ser alterado em tempo de execução via /admin/log-level.
+	// Os handlers de logging e telemetria adicionam rota, trace_id e span_id aos logs emitidos com contexto.
+	if err := logging.Level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		logging.Level.Set(slog.LevelInfo)
+	}
+	jsonHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logging.Level})
+	logger := slog.New(telemetry.NewLogHandler(logging.NewHandler(jsonHandler)))
 	slog.SetDefault(logger)
 	slog.Info("🚀 Iniciando a API de Usuários...")
 
+	// --- CONFIGURAÇÃO DO TRACING (OPENTELEMETRY) ---
+	shutdownTracing, err := telemetry.Setup(context.Background())
+	if err != nil {
+		slog.Error("Erro ao configurar o tracing", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Erro ao finalizar o tracing", "error", err)
+		}
+	}()
+	slog.Info("🔭 Tracing configurado", "exporter", os.Getenv("OTEL_TRACES_EXPORTER"))
+
 	// --- CONEXÃO COM O BANCO DE DADOS ---
 	db, err := sql.Open("sqlite3", "./sqlite-database.db")
 	if err != nil {
@@ -72,34 +112,204 @@ func main() {
 	}
 	slog.Info("💾 Conexão com o banco de dados estabelecida com sucesso.")
 
+	// A busca de usuários usa FTS5, que o go-sqlite3 só inclui com a build tag sqlite_fts5.
+	// Sem ela, a API sobe sem o índice de busca e GET /usuarios/search responde 503.
+	comBusca, err := checarBusca(context.Background(), db)
+	if err != nil {
+		slog.Error("Erro ao verificar o suporte a FTS5", "error", err)
+		os.Exit(1)
+	}
+
 	// --- EXECUÇÃO DAS MIGRATIONS ---
 	slog.Info("⏳ Executando migrations do banco de dados...")
-	if err := runMigrations(db); err != nil {
+	if err := runMigrations(db, comBusca); err != nil {
 		slog.Error("Erro ao executar as migrations", "error", err)
 		os.Exit(1)
 	}
+	if comBusca {
+		if err := criarIndiceBusca(context.Background(), db); err != nil {
+			slog.Error("Erro ao criar o índice de busca", "error", err)
+			os.Exit(1)
+		}
+	}
 	slog.Info("✅ Migrations executadas com sucesso.")
 
+	// Subcomandos administrativos (ex: criar-api-key) rodam sobre o banco já migrado e encerram.
+	if len(os.Args) > 1 {
+		os.Exit(executarComando(context.Background(), db, os.Args[1:]))
+	}
+
 	// --- INJEÇÃO DE DEPENDÊNCIAS (WIRING) ---
-	usuarioRepo := repository.NewSQLiteRepository(db)
+	usuarioRepo, err := repository.NewSQLiteRepository(db)
+	if err != nil {
+		slog.Error("Erro ao preparar as consultas do repositório", "error", err)
+		os.Exit(1)
+	}
+	defer usuarioRepo.Close() // Roda antes do db.Close, registrado antes.
+	defer fecharRepositorios()
+
+	// Cache de leitura para GetUserByID (checkout, leitura de perfil). TTL zero desliga o cache.
+	cacheTTL, err := durationFromEnv("CACHE_USUARIOS_TTL", 5*time.Minute)
+	if err != nil {
+		slog.Error("Configuração de cache inválida", "error", err)
+		os.Exit(1)
+	}
+	cacheTamanho, err := intFromEnv("CACHE_USUARIOS_TAMANHO", 10000)
+	if err != nil {
+		slog.Error("Configuração de cache inválida", "error", err)
+		os.Exit(1)
+	}
+	if cacheTTL > 0 {
+		usuarioRepo = repository.NewCachedRepository(usuarioRepo, cache.NewLRU(cacheTamanho), cacheTTL)
+		slog.Info("Cache de usuários habilitado", "ttl", cacheTTL, "tamanho", cacheTamanho)
+	}
 	slog.Info("Camada de repositório inicializada")
 
-	usuarioService := service.NewUsuarioService(usuarioRepo)
+	// Verificação de e-mail e redefinição de senha: links assinados enviados pelo Mailer.
+	segredoTokens, err := segredoTokensFromEnv()
+	if err != nil {
+		slog.Error("Configuração de tokens inválida", "error", err)
+		os.Exit(1)
+	}
+	m, err := mailerFromEnv()
+	if err != nil {
+		slog.Error("Configuração de e-mail inválida", "error", err)
+		os.Exit(1)
+	}
+	urlApp := os.Getenv("APP_URL")
+	if urlApp == "" {
+		urlApp = "http://localhost:3000"
+	}
+	// Segundo fator (TOTP), exigido dos papéis de MFA_REQUIRED_ROLES nas rotas protegidas.
+	papeisMFA, err := papeisMFAFromEnv()
+	if err != nil {
+		slog.Error("Configuração de MFA inválida", "error", err)
+		os.Exit(1)
+	}
+	emissorMFA := os.Getenv("MFA_ISSUER")
+	if emissorMFA == "" {
+		emissorMFA = "API de Usuários"
+	}
+	// Cada login abre uma sessão, renovável com refresh tokens em /auth/refresh e revogável
+	// em /usuarios/{id}/sessions.
+	sessaoRepo := preparado(repository.NewSessaoRepository(db))
+	authService := service.NewAuthService(usuarioRepo, m, tokens.NewAssinador(segredoTokens), urlApp).
+		WithMFA(preparado(repository.NewMFARepository(db)), emissorMFA, papeisMFA).
+		WithAuditoria(preparado(repository.NewAuditoriaRepository(db))).
+		WithSessoes(sessaoRepo)
+
+	usuarioService := service.NewUsuarioService(usuarioRepo).WithVerificacaoEmail(authService).WithSessoes(sessaoRepo)
+
+	// As assinaturas pertencem às organizações, que convidam membros por e-mail.
+	// O checkout cobra na moeda do cliente, com os impostos configurados.
+	plano, err := planoCheckoutFromEnv()
+	if err != nil {
+		slog.Error("Configuração de preços inválida", "error", err)
+		os.Exit(1)
+	}
+	provedorCobranca, err := billingProviderFromEnv()
+	if err != nil {
+		slog.Error("Configuração de cobrança inválida", "error", err)
+		os.Exit(1)
+	}
+	// O nome e o e-mail do dono vão para o cliente da Stripe pela caixa de saída; as alterações
+	// feitas na Stripe voltam pelo webhook customer.updated.
+	politicaClientes, err := politicaClientesFromEnv()
+	if err != nil {
+		slog.Error("Configuração da sincronização de clientes inválida", "error", err)
+		os.Exit(1)
+	}
+	intervaloClientes, err := durationFromEnv("CUSTOMER_SYNC_INTERVAL", time.Minute)
+	if err != nil || intervaloClientes == 0 {
+		slog.Error("Configuração da sincronização de clientes inválida", "error", err, "intervalo", intervaloClientes)
+		os.Exit(1)
+	}
+	orgRepo := preparado(repository.NewOrganizacaoRepository(db))
+	organizacaoService := service.NewOrganizacaoService(orgRepo, usuarioRepo, m, urlApp).
+		WithCupons(preparado(repository.NewCupomRepository(db))).
+		WithPlano(plano).
+		WithDadosCobranca(preparado(repository.NewCobrancaRepository(db)), provedorCobranca).
+		WithSincronizacaoClientes(preparado(repository.NewOutboxClienteRepository(db)), provedorCobranca, politicaClientes)
+	go sincronizarClientes(context.Background(), organizacaoService, intervaloClientes)
+
+	// Consumo medido por usuário, cobrado da organização e reportado em lotes ao provedor.
+	intervaloReporte, err := durationFromEnv("USAGE_REPORT_INTERVAL", time.Minute)
+	if err != nil || intervaloReporte == 0 {
+		slog.Error("Configuração do reporte de consumo inválida", "error", err, "intervalo", intervaloReporte)
+		os.Exit(1)
+	}
+	usoService := service.NewUsoService(preparado(repository.NewUsoRepository(db)), orgRepo, usuarioRepo, provedorCobranca)
+	go reportarUso(context.Background(), usoService, intervaloReporte)
+
+	// Login social pelos provedores OIDC de OIDC_PROVIDERS (nenhum por padrão).
+	urlAPI := os.Getenv("API_URL")
+	if urlAPI == "" {
+		urlAPI = "http://localhost:8080"
+	}
+	provedoresOIDC, err := provedoresOIDCFromEnv(urlAPI, telemetry.NewHTTPClient("oidc"))
+	if err != nil {
+		slog.Error("Configuração de OIDC inválida", "error", err)
+		os.Exit(1)
+	}
+	oidcService := service.NewOIDCService(usuarioRepo, preparado(repository.NewIdentidadeRepository(db)), authService, provedoresOIDC)
 	slog.Info("Camada de serviço inicializada")
 
-	usuarioHandler := httphandler.NewUsuarioHandler(usuarioService)
+	// Todas as rotas de /usuarios exigem uma API key com o escopo da operação ou o token de
+	// acesso de um usuário cujo papel tenha o escopo.
+	apiKeyService := service.NewAPIKeyService(preparado(repository.NewAPIKeyRepository(db)))
+	usuarioHandler := httphandler.NewUsuarioHandler(usuarioService).WithAutenticacao(apiKeyService, authService).WithUso(usoService)
+	apiKeyHandler := httphandler.NewAPIKeyHandler(apiKeyService)
+	authHandler := httphandler.NewAuthHandler(authService).WithCobranca(organizacaoService)
+	oidcHandler := httphandler.NewOIDCHandler(oidcService)
+	organizacaoHandler := httphandler.NewOrganizacaoHandler(organizacaoService, apiKeyService, authService).WithMedicao(usoService)
+	regrasLimite, err := rateLimitsFromEnv()
+	if err != nil {
+		slog.Error("Configuração de rate limit inválida", "error", err)
+		os.Exit(1)
+	}
+	var limiter *httphandler.RateLimiter
+	if len(regrasLimite) > 0 {
+		limiter = httphandler.NewRateLimiter(ratelimit.NewMemoryStore(), regrasLimite)
+		usuarioHandler.WithRateLimiter(limiter)
+		authHandler.WithRateLimiter(limiter)
+		oidcHandler.WithRateLimiter(limiter)
+		organizacaoHandler.WithRateLimiter(limiter)
+		slog.Info("Rate limit habilitado", "regras", fmt.Sprint(regrasLimite))
+	}
+	// Idempotency-Key nos POST que criam recursos; as chaves vencidas são apagadas periodicamente.
+	idempotenciaTTL, err := durationFromEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil || idempotenciaTTL == 0 {
+		slog.Error("Configuração de idempotência inválida", "error", err, "ttl", idempotenciaTTL)
+		os.Exit(1)
+	}
+	idempotenciaRepo := preparado(repository.NewIdempotenciaRepository(db))
+	usuarioHandler.WithIdempotencia(idempotenciaRepo, idempotenciaTTL)
+	organizacaoHandler.WithIdempotencia(idempotenciaRepo, idempotenciaTTL)
+	go limparIdempotencyKeys(context.Background(), idempotenciaRepo, time.Hour)
+
+	webhookHandler := httphandler.NewStripeWebhookHandler(organizacaoService)
 	slog.Info("Camada de handler inicializada")
 
+	// --- MÉTRICAS DE BANCO E DE NEGÓCIO ---
+	// Estatísticas do pool de conexões (go_sql_*) e gauge de assinaturas calculado a partir do banco.
+	prometheus.MustRegister(
+		collectors.NewDBStatsCollector(db, "sqlite"),
+		metrics.NewSubscriptionCollector(orgRepo.CountBySubscriptionStatus),
+	)
+
 	// --- CONFIGURAÇÃO DO ROTEADOR E ROTAS ---
 	r := chi.NewRouter()
 
 	// Middlewares
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(telemetry.Middleware)
+	r.Use(logging.Middleware)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 	r.Use(prometheusMiddleware)
+	// Antes de qualquer autenticação, para que credenciais não possam ser adivinhadas.
+	r.Use(limiter.LimitarFalhasAutenticacao())
 
 	// Rotas Principais
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -108,6 +318,10 @@ func main() {
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
 	slog.Info("📖 Documentação Swagger disponível em http://localhost:8080/swagger/index.html")
 
+	// Só API keys com o escopo logs:admin podem trocar o nível de log.
+	r.With(httphandler.Autenticar(apiKeyService, nil), httphandler.RequerEscopo(domain.EscopoLogsAdmin)).
+		Handle("/admin/log-level", logging.LevelHandler())
+
 	r.Handle("/metrics", promhttp.Handler())
 	slog.Info("📊 Métricas Prometheus disponíveis em http://localhost:8080/metrics")
 
@@ -115,6 +329,21 @@ func main() {
 	r.Mount("/usuarios", usuarioHandler.Routes())
 	slog.Info("🛰️  Rotas de /usuarios registradas")
 
+	r.Mount("/api-keys", apiKeyHandler.Routes())
+	slog.Info("🔐 Rotas de /api-keys registradas")
+
+	r.Mount("/auth", authHandler.Routes())
+	slog.Info("✉️  Rotas de /auth registradas")
+
+	r.Mount("/auth/oidc", oidcHandler.Routes())
+	slog.Info("🔑 Login OIDC registrado em /auth/oidc", "provedores", len(provedoresOIDC))
+
+	r.Mount("/organizations", organizacaoHandler.Routes())
+	slog.Info("🏢 Rotas de /organizations registradas")
+
+	r.Post("/webhooks/stripe", webhookHandler.HandleStripeWebhook)
+	slog.Info("💳 Webhook da Stripe registrado em /webhooks/stripe")
+
 	// --- INICIALIZAÇÃO DO SERVIDOR HTTP ---
 	slog.Info("✅ Servidor pronto para receber requisições na porta :8080")
 	if err := http.ListenAndServe(":8080", r); err != nil {
@@ -123,8 +352,85 @@ func main() {
 	}
 }
 
+// repositorios guarda os repositórios criados por preparado, fechados por fecharRepositorios.
+var repositorios []io.Closer
+
+// preparado devolve o repositório recém-criado ou encerra a API se as suas consultas não
+// puderem ser preparadas.
+func preparado[R io.Closer](repo R, err error) R {
+	if err != nil {
+		slog.Error("Erro ao preparar as consultas do repositório", "error", err)
+		os.Exit(1)
+	}
+	repositorios = append(repositorios, repo)
+	return repo
+}
+
+// fecharRepositorios fecha as instruções preparadas dos repositórios, antes do db.Close.
+func fecharRepositorios() {
+	for _, repo := range repositorios {
+		repo.Close()
+	}
+}
+
+// limparIdempotencyKeys apaga as Idempotency-Keys vencidas a cada intervalo. Elas já são
+// ignoradas depois de vencer; a limpeza só evita que a tabela cresça indefinidamente.
+func limparIdempotencyKeys(ctx context.Context, repo repository.IdempotenciaRepository, intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+	for {
+		if n, err := repo.RemoverExpiradas(ctx); err != nil {
+			slog.Error("Erro ao remover Idempotency-Keys vencidas", "error", err)
+		} else if n > 0 {
+			slog.Info("Idempotency-Keys vencidas removidas", "quantidade", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reportarUso envia o consumo medido ao provedor de cobrança a cada intervalo, até ctx ser cancelado.
+func reportarUso(ctx context.Context, s *service.UsoService, intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.ReportUsage(ctx); err != nil {
+			slog.Error("Erro ao reportar o consumo", "error", err)
+		} else if n > 0 {
+			slog.Info("Consumo reportado", "relatorios", n)
+		}
+	}
+}
+
+// sincronizarClientes envia as alterações de perfil pendentes aos clientes do provedor de
+// cobrança a cada intervalo, até ctx ser cancelado.
+func sincronizarClientes(ctx context.Context, s *service.OrganizacaoService, intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if n, err := s.SincronizarClientes(ctx); err != nil {
+			slog.Error("Erro ao sincronizar os clientes", "error", err)
+		} else if n > 0 {
+			slog.Info("Clientes sincronizados", "usuarios", n)
+		}
+	}
+}
+
 // runMigrations executa as migrations do banco de dados na inicialização.
-func runMigrations(db *sql.DB) error {
+func runMigrations(db *sql.DB, comBusca bool) error {
 	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
 	if err != nil {
 		return err
@@ -139,9 +445,77 @@ func runMigrations(db *sql.DB) error {
 		return err
 	}
 
+	// Sem FTS5, a migration do índice de busca é dada como aplicada sem rodar; o índice é
+	// criado por criarIndiceBusca na primeira vez que a API subir com FTS5.
+	if !comBusca {
+		versao, _, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			versao, err = 0, nil
+		}
+		if err != nil {
+			return err
+		}
+		if versao < versaoIndiceBusca {
+			if err := m.Migrate(versaoIndiceBusca - 1); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+				return err
+			}
+			if err := m.Force(versaoIndiceBusca); err != nil {
+				return err
+			}
+		}
+	}
+
 	err = m.Up()
 	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return err
 	}
 	return nil
 }
+
+// Migration que cria o índice de busca (a tabela virtual usuarios_fts, que exige FTS5).
+const (
+	versaoIndiceBusca    = 4
+	migrationIndiceBusca = "migrations/000004_add_usuarios_fts.up.sql"
+)
+
+// checarBusca informa se a busca de usuários pode ser habilitada. Sem FTS5, ela fica desligada,
+// a menos que o banco já tenha o índice: os triggers dele tornariam toda escrita em usuarios um
+// erro, então a API não sobe.
+func checarBusca(ctx context.Context, db *sql.DB) (bool, error) {
+	err := repository.ChecarFTS5(ctx, db)
+	if !errors.Is(err, repository.ErrFTS5Indisponivel) {
+		return err == nil, err
+	}
+	existe, errIndice := repository.IndiceBuscaExiste(ctx, db)
+	if errIndice != nil {
+		return false, errIndice
+	}
+	if existe {
+		return false, fmt.Errorf("o banco tem o índice de busca usuarios_fts: %w", err)
+	}
+	slog.Warn("SQLite sem FTS5: a busca de usuários fica desligada (GET /usuarios/search responde 503)", "error", err)
+	return false, nil
+}
+
+// criarIndiceBusca cria o índice de busca em um banco migrado antes por uma API sem FTS5,
+// rodando a migration dele, que também indexa os usuários existentes.
+func criarIndiceBusca(ctx context.Context, db *sql.DB) error {
+	existe, err := repository.IndiceBuscaExiste(ctx, db)
+	if err != nil || existe {
+		return err
+	}
+	migration, err := os.ReadFile(migrationIndiceBusca)
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, string(migration)); err != nil {
+		return err
+	}
+	slog.Info("Índice de busca criado para os usuários existentes")
+	return tx.Commit()
+}
@@ -0,0 +1,44 @@
+// Package auth guarda no contexto da requisição quem a fez (o Principal), depois que as
+// credenciais foram verificadas pelos middlewares de autenticação.
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Tipos de Principal.
+const (
+	TipoAPIKey  = "api_key"
+	TipoUsuario = "usuario" // Token de acesso emitido no login de um usuário.
+)
+
+// Principal é o cliente autenticado de uma requisição.
+type Principal struct {
+	Tipo    string   // Como o cliente se autenticou (ex: TipoAPIKey).
+	ID      string   // Identificador da credencial, único dentro do tipo.
+	Escopos []string // Permissões concedidas (ex: "usuarios:read").
+}
+
+// String identifica o Principal de forma única (ex: "api_key:42").
+func (p Principal) String() string {
+	return p.Tipo + ":" + p.ID
+}
+
+// Possui informa se o Principal tem o escopo.
+func (p Principal) Possui(escopo string) bool {
+	return slices.Contains(p.Escopos, escopo)
+}
+
+type chaveContexto struct{}
+
+// WithPrincipal devolve um contexto com o Principal autenticado.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, chaveContexto{}, p)
+}
+
+// FromContext devolve o Principal da requisição, se ela foi autenticada.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(chaveContexto{}).(Principal)
+	return p, ok
+}
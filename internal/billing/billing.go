@@ -0,0 +1,304 @@
+// Package billing reporta o consumo medido e os dados de contato e de cobrança dos clientes ao
+// provedor de cobrança. StripeProvider fala com a Stripe; LogProvider e StubProvider permitem desenvolver
+// e testar sem a Stripe.
+package billing
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/billing/meterevent"
+	"github.com/stripe/stripe-go/v78/customer"
+	"github.com/stripe/stripe-go/v78/taxid"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+)
+
+// Consumo é o uso agregado de uma métrica por um cliente, reportado de uma vez.
+type Consumo struct {
+	// Identificador torna o envio idempotente: o provedor descarta repetições com o mesmo
+	// identificador, então um lote pode ser reenviado inteiro depois de uma falha parcial.
+	Identificador string
+	ClienteID     string // Cliente no provedor (na Stripe, o stripe_customer_id).
+	Metrica       string
+	Quantidade    int64
+	Momento       time.Time
+}
+
+// MetadadoContato é o metadado do cliente na Stripe com o nome de quem responde por ele.
+const MetadadoContato = "contato_nome"
+
+// Cliente são os dados de contato de um cliente no provedor: o e-mail que recebe as faturas
+// e o nome de quem responde pelo cliente (o dono da organização).
+type Cliente struct {
+	ID      string // Cliente no provedor (na Stripe, o stripe_customer_id).
+	Email   string
+	Contato string
+}
+
+// Endereco é o endereço de cobrança de um cliente. Pais é o código ISO 3166-1 de duas letras.
+type Endereco struct {
+	Linha1, Linha2 string
+	Cidade, Estado string
+	CEP, Pais      string
+}
+
+// DadosCobranca são o endereço de cobrança e o documento fiscal de um cliente no provedor.
+type DadosCobranca struct {
+	ClienteID string // Cliente no provedor (na Stripe, o stripe_customer_id).
+	Endereco  Endereco
+	// TipoDocumento é o tipo do tax ID na Stripe (br_cpf ou br_cnpj), vazio sem documento.
+	TipoDocumento string
+	Documento     string // Só os dígitos.
+}
+
+// Provider recebe os lotes de consumo e as alterações dos clientes. ReportarUso só devolve
+// nil se todo o lote foi aceito; AtualizarCliente e AtualizarDadosCobranca gravam os valores
+// informados e podem ser repetidos sem efeito colateral.
+type Provider interface {
+	ReportarUso(ctx context.Context, lote []Consumo) error
+	AtualizarCliente(ctx context.Context, c Cliente) error
+	AtualizarDadosCobranca(ctx context.Context, d DadosCobranca) error
+}
+
+// --- Stripe ---
+
+// StripeProvider envia cada consumo como um meter event da Stripe, cujo event_name é a
+// métrica: cada métrica precisa de um meter com esse event_name, que agrega por soma o campo
+// "value" do cliente em "stripe_customer_id" (o padrão da Stripe).
+type StripeProvider struct {
+	eventos  *meterevent.Client
+	clientes *customer.Client
+	taxIDs   *taxid.Client
+}
+
+// NewStripeProvider cria um StripeProvider com a chave de API informada.
+func NewStripeProvider(chave string) *StripeProvider {
+	backend := stripe.GetBackend(stripe.APIBackend)
+	return &StripeProvider{
+		eventos:  &meterevent.Client{B: backend, Key: chave},
+		clientes: &customer.Client{B: backend, Key: chave},
+		taxIDs:   &taxid.Client{B: backend, Key: chave},
+	}
+}
+
+// ReportarUso envia o lote em ordem e para no primeiro erro. O identificador vai como
+// identifier do evento e como Idempotency-Key da requisição.
+func (p *StripeProvider) ReportarUso(ctx context.Context, lote []Consumo) error {
+	for _, c := range lote {
+		params := &stripe.BillingMeterEventParams{
+			EventName:  stripe.String(c.Metrica),
+			Identifier: stripe.String(c.Identificador),
+			Payload: map[string]string{
+				"stripe_customer_id": c.ClienteID,
+				"value":              strconv.FormatInt(c.Quantidade, 10),
+			},
+			Timestamp: stripe.Int64(c.Momento.Unix()),
+		}
+		params.Context = ctx
+		params.SetIdempotencyKey(c.Identificador)
+
+		start := time.Now()
+		_, err := p.eventos.New(params)
+		metrics.ObservarStripe("meter_event.create", start, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AtualizarCliente grava o e-mail do cliente e o contato no metadado MetadadoContato. O nome
+// do cliente (o da organização) não é alterado.
+func (p *StripeProvider) AtualizarCliente(ctx context.Context, c Cliente) error {
+	params := &stripe.CustomerParams{Email: stripe.String(c.Email)}
+	params.AddMetadata(MetadadoContato, c.Contato)
+	params.Context = ctx
+
+	start := time.Now()
+	_, err := p.clientes.Update(c.ID, params)
+	metrics.ObservarStripe("customer.update", start, err)
+	return err
+}
+
+// AtualizarDadosCobranca grava o endereço no cliente e troca o CPF ou CNPJ dele pelo documento
+// informado. Outros tax IDs do cliente (ex: coletados no checkout de um cliente estrangeiro)
+// não são tocados.
+func (p *StripeProvider) AtualizarDadosCobranca(ctx context.Context, d DadosCobranca) error {
+	params := &stripe.CustomerParams{Address: &stripe.AddressParams{
+		Line1:      stripe.String(d.Endereco.Linha1),
+		Line2:      stripe.String(d.Endereco.Linha2),
+		City:       stripe.String(d.Endereco.Cidade),
+		State:      stripe.String(d.Endereco.Estado),
+		PostalCode: stripe.String(d.Endereco.CEP),
+		Country:    stripe.String(d.Endereco.Pais),
+	}}
+	params.Context = ctx
+	start := time.Now()
+	_, err := p.clientes.Update(d.ClienteID, params)
+	metrics.ObservarStripe("customer.update", start, err)
+	if err != nil {
+		return err
+	}
+
+	listParams := &stripe.TaxIDListParams{Customer: stripe.String(d.ClienteID)}
+	listParams.Context = ctx
+	start = time.Now()
+	it := p.taxIDs.List(listParams)
+	var obsoletos []string
+	presente := false
+	for it.Next() {
+		t := it.TaxID()
+		if t.Type != stripe.TaxIDTypeBRCPF && t.Type != stripe.TaxIDTypeBRCNPJ {
+			continue
+		}
+		if string(t.Type) == d.TipoDocumento && apenasDigitos(t.Value) == d.Documento {
+			presente = true
+			continue
+		}
+		obsoletos = append(obsoletos, t.ID)
+	}
+	metrics.ObservarStripe("tax_id.list", start, it.Err())
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range obsoletos {
+		delParams := &stripe.TaxIDParams{Customer: stripe.String(d.ClienteID)}
+		delParams.Context = ctx
+		start = time.Now()
+		_, err := p.taxIDs.Del(id, delParams)
+		metrics.ObservarStripe("tax_id.delete", start, err)
+		if err != nil {
+			return err
+		}
+	}
+	if d.Documento == "" || presente {
+		return nil
+	}
+	newParams := &stripe.TaxIDParams{
+		Customer: stripe.String(d.ClienteID),
+		Type:     stripe.String(d.TipoDocumento),
+		Value:    stripe.String(d.Documento),
+	}
+	newParams.Context = ctx
+	start = time.Now()
+	_, err = p.taxIDs.New(newParams)
+	metrics.ObservarStripe("tax_id.create", start, err)
+	return err
+}
+
+func apenasDigitos(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// --- Log ---
+
+// LogProvider só registra o consumo no log, para desenvolvimento.
+type LogProvider struct{}
+
+// ReportarUso registra cada consumo no log e aceita o lote.
+func (LogProvider) ReportarUso(ctx context.Context, lote []Consumo) error {
+	for _, c := range lote {
+		logging.FromContext(ctx).InfoContext(ctx, "Consumo não reportado (provedor de log)",
+			"identificador", c.Identificador, "cliente", c.ClienteID, "metrica", c.Metrica, "quantidade", c.Quantidade)
+	}
+	return nil
+}
+
+// AtualizarCliente registra a alteração no log e a aceita.
+func (LogProvider) AtualizarCliente(ctx context.Context, c Cliente) error {
+	logging.FromContext(ctx).InfoContext(ctx, "Cliente não atualizado (provedor de log)", "cliente", c.ID, "email", c.Email, "contato", c.Contato)
+	return nil
+}
+
+// AtualizarDadosCobranca registra a alteração no log, sem o documento, e a aceita.
+func (LogProvider) AtualizarDadosCobranca(ctx context.Context, d DadosCobranca) error {
+	logging.FromContext(ctx).InfoContext(ctx, "Dados de cobrança não atualizados (provedor de log)",
+		"cliente", d.ClienteID, "pais", d.Endereco.Pais, "tipo_documento", d.TipoDocumento)
+	return nil
+}
+
+// --- Stub ---
+
+// StubProvider guarda os lotes recebidos em memória, descartando identificadores repetidos
+// como a Stripe faz, e os clientes e dados de cobrança atualizados. Com Err definido, recusa
+// os lotes e as alterações; usado nos testes.
+type StubProvider struct {
+	mu            sync.Mutex
+	Err           error
+	Lotes         [][]Consumo
+	Clientes      map[string]Cliente
+	DadosCobranca map[string]DadosCobranca
+	aceitos       map[string]Consumo
+}
+
+// ReportarUso guarda o lote, ou devolve Err.
+func (p *StubProvider) ReportarUso(ctx context.Context, lote []Consumo) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Err != nil {
+		return p.Err
+	}
+	if p.aceitos == nil {
+		p.aceitos = make(map[string]Consumo)
+	}
+	p.Lotes = append(p.Lotes, lote)
+	for _, c := range lote {
+		if _, ok := p.aceitos[c.Identificador]; !ok {
+			p.aceitos[c.Identificador] = c
+		}
+	}
+	return nil
+}
+
+// AtualizarCliente guarda o cliente, ou devolve Err.
+func (p *StubProvider) AtualizarCliente(ctx context.Context, c Cliente) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Err != nil {
+		return p.Err
+	}
+	if p.Clientes == nil {
+		p.Clientes = make(map[string]Cliente)
+	}
+	p.Clientes[c.ID] = c
+	return nil
+}
+
+// AtualizarDadosCobranca guarda os dados de cobrança do cliente, ou devolve Err.
+func (p *StubProvider) AtualizarDadosCobranca(ctx context.Context, d DadosCobranca) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Err != nil {
+		return p.Err
+	}
+	if p.DadosCobranca == nil {
+		p.DadosCobranca = make(map[string]DadosCobranca)
+	}
+	p.DadosCobranca[d.ClienteID] = d
+	return nil
+}
+
+// Total soma as quantidades aceitas do cliente na métrica, sem contar as repetições.
+func (p *StubProvider) Total(clienteID, metrica string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var total int64
+	for _, c := range p.aceitos {
+		if c.ClienteID == clienteID && c.Metrica == metrica {
+			total += c.Quantidade
+		}
+	}
+	return total
+}
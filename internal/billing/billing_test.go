@@ -0,0 +1,172 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/billing/meterevent"
+	"github.com/stripe/stripe-go/v78/customer"
+	"github.com/stripe/stripe-go/v78/taxid"
+)
+
+// stripeFalso responde aos meter events como a API da Stripe e guarda as requisições recebidas.
+func stripeFalso(t *testing.T, status int) (*StripeProvider, *[]*http.Request) {
+	return stripeFalsoCom(t, func(r *http.Request) (int, string) {
+		if status != http.StatusOK {
+			return status, `{"error":{"type":"invalid_request_error","code":"resource_missing","message":"meter inexistente"}}`
+		}
+		return status, `{"object":"billing.meter_event","event_name":"chamadas_api"}`
+	})
+}
+
+// stripeFalsoCom responde a cada requisição com o status e o corpo de resposta e guarda as
+// requisições recebidas.
+func stripeFalsoCom(t *testing.T, resposta func(r *http.Request) (int, string)) (*StripeProvider, *[]*http.Request) {
+	var recebidas []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		recebidas = append(recebidas, r)
+		status, corpo := resposta(r)
+		w.WriteHeader(status)
+		w.Write([]byte(corpo))
+	}))
+	t.Cleanup(srv.Close)
+
+	backend := stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		URL:               stripe.String(srv.URL),
+		MaxNetworkRetries: stripe.Int64(0),
+		LeveledLogger:     &stripe.LeveledLogger{Level: stripe.LevelNull},
+	})
+	return &StripeProvider{
+		eventos:  &meterevent.Client{B: backend, Key: "sk_test_x"},
+		clientes: &customer.Client{B: backend, Key: "sk_test_x"},
+		taxIDs:   &taxid.Client{B: backend, Key: "sk_test_x"},
+	}, &recebidas
+}
+
+func TestStripeProvider(t *testing.T) {
+	momento := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	lote := []Consumo{
+		{Identificador: "uso_1", ClienteID: "cus_1", Metrica: "chamadas_api", Quantidade: 42, Momento: momento},
+		{Identificador: "uso_2", ClienteID: "cus_2", Metrica: "chamadas_api", Quantidade: 7, Momento: momento},
+	}
+
+	t.Run("sucesso - envia um meter event por consumo, com o identificador como chave de idempotência", func(t *testing.T) {
+		p, recebidas := stripeFalso(t, http.StatusOK)
+
+		require.NoError(t, p.ReportarUso(context.Background(), lote))
+
+		require.Len(t, *recebidas, 2)
+		r := (*recebidas)[0]
+		assert.Equal(t, "/v1/billing/meter_events", r.URL.Path)
+		assert.Equal(t, "uso_1", r.Header.Get("Idempotency-Key"))
+		assert.Equal(t, "chamadas_api", r.PostForm.Get("event_name"))
+		assert.Equal(t, "uso_1", r.PostForm.Get("identifier"))
+		assert.Equal(t, "cus_1", r.PostForm.Get("payload[stripe_customer_id]"))
+		assert.Equal(t, "42", r.PostForm.Get("payload[value]"))
+		assert.Equal(t, "1773144000", r.PostForm.Get("timestamp"))
+	})
+
+	t.Run("erro - para no primeiro consumo recusado", func(t *testing.T) {
+		p, recebidas := stripeFalso(t, http.StatusBadRequest)
+
+		err := p.ReportarUso(context.Background(), lote)
+
+		var stripeErr *stripe.Error
+		require.ErrorAs(t, err, &stripeErr)
+		assert.Equal(t, stripe.ErrorCodeResourceMissing, stripeErr.Code)
+		assert.Len(t, *recebidas, 1)
+	})
+
+	t.Run("sucesso - atualiza o e-mail e o contato do cliente", func(t *testing.T) {
+		p, recebidas := stripeFalso(t, http.StatusOK)
+
+		require.NoError(t, p.AtualizarCliente(context.Background(), Cliente{ID: "cus_1", Email: "ana@email.com", Contato: "Ana Souza"}))
+
+		require.Len(t, *recebidas, 1)
+		r := (*recebidas)[0]
+		assert.Equal(t, "/v1/customers/cus_1", r.URL.Path)
+		assert.Equal(t, "ana@email.com", r.PostForm.Get("email"))
+		assert.Equal(t, "Ana Souza", r.PostForm.Get("metadata[contato_nome]"))
+		assert.Empty(t, r.PostForm.Get("name"), "o nome do cliente é o da organização")
+	})
+
+	t.Run("sucesso - grava o endereço e troca só o CPF ou CNPJ do cliente", func(t *testing.T) {
+		p, recebidas := stripeFalsoCom(t, func(r *http.Request) (int, string) {
+			if r.Method == http.MethodGet {
+				return http.StatusOK, `{"object":"list","has_more":false,"data":[
+					{"id":"txi_cpf","object":"tax_id","type":"br_cpf","value":"111.444.777-35"},
+					{"id":"txi_vat","object":"tax_id","type":"eu_vat","value":"PT123456789"}]}`
+			}
+			return http.StatusOK, `{"object":"customer","id":"cus_1"}`
+		})
+		dados := DadosCobranca{
+			ClienteID:     "cus_1",
+			Endereco:      Endereco{Linha1: "Av. Paulista, 1000", Cidade: "São Paulo", Estado: "SP", CEP: "01310-100", Pais: "BR"},
+			TipoDocumento: "br_cnpj",
+			Documento:     "11222333000181",
+		}
+
+		require.NoError(t, p.AtualizarDadosCobranca(context.Background(), dados))
+
+		var chamadas []string
+		for _, r := range *recebidas {
+			chamadas = append(chamadas, r.Method+" "+r.URL.Path)
+		}
+		assert.Equal(t, []string{
+			"POST /v1/customers/cus_1",
+			"GET /v1/customers/cus_1/tax_ids",
+			"DELETE /v1/customers/cus_1/tax_ids/txi_cpf",
+			"POST /v1/customers/cus_1/tax_ids",
+		}, chamadas, "o tax ID europeu fica no cliente")
+		atualizacao, novo := (*recebidas)[0], (*recebidas)[3]
+		assert.Equal(t, "Av. Paulista, 1000", atualizacao.PostForm.Get("address[line1]"))
+		assert.Equal(t, "BR", atualizacao.PostForm.Get("address[country]"))
+		assert.Equal(t, "br_cnpj", novo.PostForm.Get("type"))
+		assert.Equal(t, "11222333000181", novo.PostForm.Get("value"))
+	})
+
+	t.Run("sucesso - documento já cadastrado não é recriado", func(t *testing.T) {
+		p, recebidas := stripeFalsoCom(t, func(r *http.Request) (int, string) {
+			if r.Method == http.MethodGet {
+				return http.StatusOK, `{"object":"list","has_more":false,"data":[{"id":"txi_cpf","object":"tax_id","type":"br_cpf","value":"111.444.777-35"}]}`
+			}
+			return http.StatusOK, `{"object":"customer","id":"cus_1"}`
+		})
+
+		require.NoError(t, p.AtualizarDadosCobranca(context.Background(), DadosCobranca{ClienteID: "cus_1", TipoDocumento: "br_cpf", Documento: "11144477735"}))
+
+		assert.Len(t, *recebidas, 2)
+	})
+}
+
+func TestStubProvider(t *testing.T) {
+	t.Run("sucesso - descarta identificadores repetidos", func(t *testing.T) {
+		p := &StubProvider{}
+		c := Consumo{Identificador: "uso_1", ClienteID: "cus_1", Metrica: "chamadas_api", Quantidade: 5}
+
+		require.NoError(t, p.ReportarUso(context.Background(), []Consumo{c}))
+		require.NoError(t, p.ReportarUso(context.Background(), []Consumo{c}))
+
+		assert.Len(t, p.Lotes, 2)
+		assert.Equal(t, int64(5), p.Total("cus_1", "chamadas_api"))
+	})
+
+	t.Run("erro - recusa os lotes com Err definido", func(t *testing.T) {
+		p := &StubProvider{Err: errors.New("indisponível")}
+
+		assert.Error(t, p.ReportarUso(context.Background(), []Consumo{{Identificador: "uso_1"}}))
+		assert.Error(t, p.AtualizarCliente(context.Background(), Cliente{ID: "cus_1"}))
+		assert.Error(t, p.AtualizarDadosCobranca(context.Background(), DadosCobranca{ClienteID: "cus_1"}))
+		assert.Empty(t, p.Lotes)
+		assert.Empty(t, p.Clientes)
+		assert.Empty(t, p.DadosCobranca)
+	})
+}
@@ -0,0 +1,108 @@
+// Package cache define o armazenamento usado pelos caches da aplicação e uma
+// implementação em memória (LRU com expiração). Outras implementações, como Redis,
+// só precisam satisfazer Store.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store guarda valores já serializados, por chave, com tempo de expiração.
+// Trabalhar com bytes permite trocar o LRU em memória por um armazenamento remoto
+// sem mudar quem usa o cache, e impede que um valor em cache seja alterado por engano.
+type Store interface {
+	// Get devolve o valor e true, ou false se a chave não existir ou tiver expirado.
+	Get(ctx context.Context, chave string) ([]byte, bool, error)
+	Set(ctx context.Context, chave string, valor []byte, ttl time.Duration) error
+	Delete(ctx context.Context, chave string) error
+}
+
+// LRU é um Store em memória com capacidade fixa: ao atingi-la, descarta a entrada usada
+// há mais tempo. Entradas expiradas são descartadas quando lidas ou quando chegam ao fim da fila.
+type LRU struct {
+	mu         sync.Mutex
+	capacidade int
+	itens      map[string]*list.Element
+	ordem      *list.List // Da usada mais recentemente (frente) para a menos recente (fundo).
+	agora      func() time.Time
+}
+
+type entrada struct {
+	chave    string
+	valor    []byte
+	expiraEm time.Time
+}
+
+// NewLRU cria um LRU que guarda no máximo capacidade entradas.
+func NewLRU(capacidade int) *LRU {
+	if capacidade < 1 {
+		capacidade = 1
+	}
+	return &LRU{
+		capacidade: capacidade,
+		itens:      make(map[string]*list.Element, capacidade),
+		ordem:      list.New(),
+		agora:      time.Now,
+	}
+}
+
+func (c *LRU) Get(_ context.Context, chave string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.itens[chave]
+	if !ok {
+		return nil, false, nil
+	}
+	e := elem.Value.(*entrada)
+	if c.agora().After(e.expiraEm) {
+		c.remover(elem)
+		return nil, false, nil
+	}
+	c.ordem.MoveToFront(elem)
+	return e.valor, true, nil
+}
+
+func (c *LRU) Set(_ context.Context, chave string, valor []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiraEm := c.agora().Add(ttl)
+	if elem, ok := c.itens[chave]; ok {
+		e := elem.Value.(*entrada)
+		e.valor, e.expiraEm = valor, expiraEm
+		c.ordem.MoveToFront(elem)
+		return nil
+	}
+
+	c.itens[chave] = c.ordem.PushFront(&entrada{chave: chave, valor: valor, expiraEm: expiraEm})
+	for c.ordem.Len() > c.capacidade {
+		c.remover(c.ordem.Back())
+	}
+	return nil
+}
+
+func (c *LRU) Delete(_ context.Context, chave string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.itens[chave]; ok {
+		c.remover(elem)
+	}
+	return nil
+}
+
+// Len devolve a quantidade de entradas guardadas, inclusive as expiradas ainda não descartadas.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ordem.Len()
+}
+
+func (c *LRU) remover(elem *list.Element) {
+	c.ordem.Remove(elem)
+	delete(c.itens, elem.Value.(*entrada).chave)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sucesso - descarta a entrada usada há mais tempo", func(t *testing.T) {
+		c := NewLRU(2)
+		c.Set(ctx, "a", []byte("1"), time.Minute)
+		c.Set(ctx, "b", []byte("2"), time.Minute)
+		c.Get(ctx, "a") // "a" passa a ser a mais recente.
+		c.Set(ctx, "c", []byte("3"), time.Minute)
+
+		_, ok, _ := c.Get(ctx, "b")
+		assert.False(t, ok)
+		valor, ok, _ := c.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("1"), valor)
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("sucesso - entrada expirada não é devolvida", func(t *testing.T) {
+		c := NewLRU(10)
+		agora := time.Now()
+		c.agora = func() time.Time { return agora }
+		c.Set(ctx, "a", []byte("1"), time.Second)
+
+		agora = agora.Add(2 * time.Second)
+		_, ok, _ := c.Get(ctx, "a")
+
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("sucesso - set sobrescreve e delete remove", func(t *testing.T) {
+		c := NewLRU(10)
+		c.Set(ctx, "a", []byte("1"), time.Minute)
+		c.Set(ctx, "a", []byte("2"), time.Minute)
+
+		valor, _, _ := c.Get(ctx, "a")
+		assert.Equal(t, []byte("2"), valor)
+
+		c.Delete(ctx, "a")
+		_, ok, _ := c.Get(ctx, "a")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// Escopos que podem ser concedidos a uma API key.
+const (
+	EscopoUsuariosLeitura = "usuarios:read"
+	EscopoUsuariosEscrita = "usuarios:write"
+	EscopoBillingEscrita  = "billing:write"
+	EscopoAPIKeysAdmin    = "apikeys:admin" // Criar, listar e revogar API keys.
+	EscopoLogsAdmin       = "logs:admin"    // Alterar o nível de log em /admin/log-level.
+)
+
+// Escopos lista todos os escopos válidos.
+var Escopos = []string{EscopoUsuariosLeitura, EscopoUsuariosEscrita, EscopoBillingEscrita, EscopoAPIKeysAdmin, EscopoLogsAdmin}
+
+// APIKey é uma credencial de acesso para clientes máquina a máquina. O segredo nunca é
+// guardado: apenas o seu hash e o Prefixo, que identifica a chave em listagens e logs.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Nome       string     `json:"nome"`
+	Prefixo    string     `json:"prefixo"`
+	Escopos    []string   `json:"escopos"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// Tipos de documento fiscal aceitos, com os nomes usados nos tax IDs da Stripe.
+const (
+	DocumentoCPF  = "br_cpf"
+	DocumentoCNPJ = "br_cnpj"
+)
+
+// Endereco é um endereço de cobrança. Pais é o código ISO 3166-1 de duas letras.
+type Endereco struct {
+	Linha1 string `json:"linha1" example:"Av. Paulista, 1000"`
+	Linha2 string `json:"linha2,omitempty" example:"Conjunto 42"`
+	Cidade string `json:"cidade" example:"São Paulo"`
+	Estado string `json:"estado,omitempty" example:"SP"`
+	CEP    string `json:"cep" example:"01310-100"`
+	Pais   string `json:"pais" example:"BR"`
+}
+
+// DadosCobranca são o endereço de cobrança e o documento fiscal (CPF ou CNPJ, só dígitos) de
+// um usuário. Eles vão para o cliente da Stripe das organizações de que ele é dono.
+type DadosCobranca struct {
+	Endereco  Endereco  `json:"endereco"`
+	Documento string    `json:"documento,omitempty" example:"11144477735"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TipoDocumento devolve o tipo do documento pelo tamanho (DocumentoCPF ou DocumentoCNPJ), ou
+// "" se não houver documento.
+func (d DadosCobranca) TipoDocumento() string {
+	switch len(d.Documento) {
+	case 11:
+		return DocumentoCPF
+	case 14:
+		return DocumentoCNPJ
+	}
+	return ""
+}
+
+// PedidoCheckout é o que o checkout de uma organização recebe de quem o pede.
+type PedidoCheckout struct {
+	Assentos          int64
+	CodigoPromocional string
+	// Idioma é o Accept-Language da requisição, usado na escolha da moeda quando o dono da
+	// organização não tem endereço de cobrança.
+	Idioma string
+}
+
+// PendenciaCliente é uma alteração do nome ou do e-mail de um usuário ainda não enviada ao
+// cliente da Stripe das organizações de que ele é dono.
+type PendenciaCliente struct {
+	ID         int64
+	UsuarioID  int64
+	Tentativas int
+	CreatedAt  time.Time
+}
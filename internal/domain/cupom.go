@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Cupom é um código promocional (promotion code) da Stripe espelhado no banco. O desconto é o
+// do cupom da Stripe a que o código pertence: PercentOff ou AmountOff (em centavos de Moeda),
+// aplicado uma vez, por DuracaoMeses ou para sempre, conforme Duracao.
+type Cupom struct {
+	Codigo                string
+	StripePromotionCodeID string
+	StripeCouponID        string
+	Nome                  string
+	PercentOff            float64
+	AmountOff             int64
+	Moeda                 string
+	Duracao               string // once, repeating ou forever.
+	DuracaoMeses          int64
+	Ativo                 bool
+	ExpiresAt             *time.Time
+	MaxResgates           int64 // Zero é sem limite.
+	Resgates              int64
+	UpdatedAt             time.Time
+}
+
+// Valido indica se o código ainda pode ser resgatado no instante agora.
+func (c *Cupom) Valido(agora time.Time) bool {
+	if c == nil || !c.Ativo {
+		return false
+	}
+	if c.ExpiresAt != nil && !agora.Before(*c.ExpiresAt) {
+		return false
+	}
+	return c.MaxResgates == 0 || c.Resgates < c.MaxResgates
+}
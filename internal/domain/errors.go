@@ -0,0 +1,81 @@
+package domain
+
+import "net/http"
+
+// Error é o erro tipado da aplicação. Carrega um código estável, que os clientes usam para
+// tomar decisões, e o status HTTP correspondente, usado pelos handlers ao montar a resposta.
+//
+// Os erros de negócio são declarados como variáveis (ex: service.ErrUsuarioNaoEncontrado)
+// e comparados com errors.Is, que compara apenas o Code. Assim, cópias enriquecidas com
+// WithDetail/WithFields ou erros embrulhados com fmt.Errorf("...: %w", err) continuam casando.
+type Error struct {
+	Code    string         // Código estável e legível por máquina (ex: "usuario_nao_encontrado").
+	Status  int            // Status HTTP associado ao erro.
+	Title   string         // Resumo legível do tipo de erro.
+	Detail  string         // Explicação específica desta ocorrência (opcional).
+	Details map[string]any // Informações adicionais para o cliente (opcional).
+	Fields  []FieldError   // Erros de validação por campo (opcional).
+	Err     error          // Causa original, nunca exposta ao cliente.
+}
+
+// FieldError descreve um problema de validação em um campo específico do payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Erros genéricos, usados quando não há um erro de negócio mais específico.
+var (
+	ErrInterno            = &Error{Code: "erro_interno", Status: http.StatusInternalServerError, Title: "erro interno do servidor"}
+	ErrRequisicaoInvalida = &Error{Code: "requisicao_invalida", Status: http.StatusBadRequest, Title: "requisição inválida"}
+)
+
+func (e *Error) Error() string {
+	msg := e.Title
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is faz com que errors.Is compare erros de domínio pelo código.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail devolve uma cópia do erro com a explicação específica da ocorrência.
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+	return &c
+}
+
+// WithDetails devolve uma cópia do erro com informações adicionais para o cliente.
+func (e *Error) WithDetails(details map[string]any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// WithFields devolve uma cópia do erro com os erros de validação por campo.
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := *e
+	c.Fields = append([]FieldError(nil), fields...)
+	return &c
+}
+
+// Wrap devolve uma cópia do erro que guarda a causa original (acessível via errors.Unwrap).
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
@@ -0,0 +1,10 @@
+package domain
+
+// RespostaIdempotente é o que fica guardado para uma Idempotency-Key: o fingerprint da
+// requisição original e, depois que ela termina, a resposta a ser repetida nas novas tentativas.
+type RespostaIdempotente struct {
+	Fingerprint string
+	Status      int // Zero enquanto a requisição original ainda está em andamento.
+	Headers     map[string]string
+	Corpo       []byte
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Identidade vincula uma conta de um provedor OIDC (provedor + subject) a um usuário.
+type Identidade struct {
+	ID          int64      `json:"id"`
+	UsuarioID   int64      `json:"usuario_id"`
+	Provedor    string     `json:"provedor"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// EstadoLogin guarda, entre o redirecionamento ao provedor e o callback, o que é preciso
+// para concluir o login: o nonce esperado no ID token e o verificador PKCE.
+type EstadoLogin struct {
+	State       string
+	Provedor    string
+	Nonce       string
+	Verificador string
+	ExpiresAt   time.Time
+}
+
+// Login é o resultado de um login bem-sucedido (ou de uma renovação): o token de acesso e o
+// refresh token da sessão ou, se o usuário tiver MFA ativo, o desafio a ser concluído em
+// POST /auth/login/mfa.
+type Login struct {
+	UsuarioID   int64  `json:"usuario_id"`
+	AccessToken string `json:"access_token,omitempty"`
+	TokenType   string `json:"token_type,omitempty" example:"Bearer"`
+	ExpiresIn   int    `json:"expires_in" example:"3600"` // Em segundos (do token de acesso ou do desafio).
+	NovoUsuario bool   `json:"novo_usuario"`              // O login criou o usuário.
+
+	// Refresh token de uso único da sessão, trocado por um novo token de acesso (e um novo
+	// refresh token) em POST /auth/refresh.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	SessaoID     int64  `json:"sessao_id,omitempty"`
+
+	MFANecessario bool   `json:"mfa_necessario,omitempty"` // Falta o segundo fator; use MFAToken.
+	MFAToken      string `json:"mfa_token,omitempty"`
+	// O papel do usuário exige MFA e ele ainda não o cadastrou: até cadastrar, o token só
+	// serve para /auth/me e /auth/mfa.
+	MFACadastroObrigatorio bool `json:"mfa_cadastro_obrigatorio,omitempty"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+// RelatorioImportacao resume o resultado de uma importação em lote de usuários.
+type RelatorioImportacao struct {
+	Total      int `json:"total"`      // Linhas de dados lidas (sem contar o cabeçalho do CSV).
+	Importados int `json:"importados"` // Usuários gravados.
+	Rejeitados int `json:"rejeitados"` // Linhas recusadas por erro de formato ou de validação.
+
+	// Erros por linha, na ordem do arquivo. A lista é limitada; as ocorrências
+	// que não couberem são apenas contadas em ErrosOmitidos.
+	Erros         []ErroLinha `json:"erros"`
+	ErrosOmitidos int         `json:"erros_omitidos,omitempty"`
+}
+
+// ErroLinha descreve por que uma linha do arquivo importado foi rejeitada.
+type ErroLinha struct {
+	Linha  int          `json:"linha"` // Linha no arquivo, começando em 1.
+	Detail string       `json:"detail,omitempty"`
+	Erros  []FieldError `json:"erros,omitempty"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+// Tipos de operação aceitos em um lote.
+const (
+	OpCriar     = "create"
+	OpAtualizar = "update"
+	OpRemover   = "delete"
+)
+
+// Modos de execução de um lote.
+const (
+	// ModoAtomico aplica todas as operações ou nenhuma: a primeira falha desfaz o lote inteiro.
+	ModoAtomico = "atomico"
+	// ModoParcial aplica cada operação de forma independente; as que falham não afetam as demais.
+	ModoParcial = "parcial"
+)
+
+// OperacaoLote é uma das escritas de um lote (POST /usuarios/batch).
+type OperacaoLote struct {
+	Op      string   // create, update ou delete.
+	ID      int64    // Obrigatório em update e delete.
+	Versao  int64    // Versão esperada (a do If-Match); zero para não verificar.
+	Usuario *Usuario // Dados do usuário, em create e update.
+}
+
+// ResultadoOperacao é o desfecho de uma operação do lote, na mesma posição da requisição.
+type ResultadoOperacao struct {
+	Status int   // Status HTTP equivalente ao da operação feita individualmente.
+	ID     int64 // ID do usuário criado, alterado ou removido.
+	Versao int64 // Nova versão do usuário, em create e update.
+	Err    error // Motivo da falha; nil quando a operação foi aplicada.
+}
+
+// ResultadoLote reúne os resultados de todas as operações de um lote.
+type ResultadoLote struct {
+	Modo       string
+	Aplicado   bool // No modo atômico, false indica que nada foi gravado.
+	Resultados []ResultadoOperacao
+}
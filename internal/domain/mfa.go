@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// Papéis dos usuários.
+const (
+	PapelUsuario = "usuario"
+	PapelAdmin   = "admin"
+)
+
+// Papeis lista todos os papéis válidos.
+var Papeis = []string{PapelUsuario, PapelAdmin}
+
+// EscoposPorPapel são os escopos que o token de acesso de um usuário recebe nas rotas
+// protegidas, conforme o papel. Usuários comuns não recebem nenhum.
+var EscoposPorPapel = map[string][]string{
+	PapelAdmin: {EscopoUsuariosLeitura, EscopoUsuariosEscrita, EscopoBillingEscrita},
+}
+
+// MFA é o segundo fator (TOTP) de um usuário. Fica pendente até o primeiro código ser confirmado.
+type MFA struct {
+	UsuarioID    int64
+	Segredo      string
+	ConfirmadoEm *time.Time
+	UltimoPasso  int64 // Intervalo do último código aceito (zero se nenhum).
+	CreatedAt    time.Time
+}
+
+// Ativo indica se o cadastro foi confirmado e o segundo fator já é exigido no login.
+func (m *MFA) Ativo() bool {
+	return m != nil && m.ConfirmadoEm != nil
+}
+
+// CadastroMFA traz o que o aplicativo autenticador precisa para gerar os códigos.
+type CadastroMFA struct {
+	Segredo string `json:"segredo" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	// URI otpauth:// a ser exibida como QR code.
+	URI string `json:"uri" example:"otpauth://totp/API%20de%20Usu%C3%A1rios:ana@email.com?algorithm=SHA1&digits=6&issuer=API+de+Usu%C3%A1rios&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+// Ações registradas na trilha de auditoria.
+const (
+	AcaoMFAAtivado        = "mfa_ativado"
+	AcaoMFADesativado     = "mfa_desativado"
+	AcaoCodigoRecuperacao = "codigo_recuperacao_usado"
+	AcaoPapelAlterado     = "papel_alterado"
+	AcaoReusoRefreshToken = "refresh_token_reusado"
+	AcaoContaAssumida     = "conta_assumida"
+	AcaoSenhaRedefinida   = "senha_redefinida"
+)
+
+// EventoAuditoria registra uma ação sensível: quem fez (Ator, ex: "usuario:7" ou "cli"),
+// o que fez e sobre qual usuário.
+type EventoAuditoria struct {
+	ID        int64          `json:"id"`
+	Ator      string         `json:"ator"`
+	Acao      string         `json:"acao"`
+	UsuarioID int64          `json:"usuario_id"`
+	Detalhes  map[string]any `json:"detalhes,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
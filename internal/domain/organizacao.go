@@ -0,0 +1,82 @@
+package domain
+
+import "time"
+
+// Papéis de um membro dentro de uma organização. O dono e os admins gerenciam membros,
+// convites e a assinatura; os membros só consultam.
+const (
+	PapelOrgDono   = "dono"
+	PapelOrgAdmin  = "admin"
+	PapelOrgMembro = "membro"
+)
+
+// PapeisConvite lista os papéis que podem ser dados por convite. Uma organização tem um só
+// dono, quem a criou.
+var PapeisConvite = []string{PapelOrgAdmin, PapelOrgMembro}
+
+// Organizacao é o tenant dono de uma assinatura. Cada membro ou convite pendente ocupa um dos
+// Assentos contratados; com Assentos zero (a organização nunca assinou) não há limite.
+type Organizacao struct {
+	ID   int64  `json:"id"`
+	Nome string `json:"nome" example:"Acme Ltda"`
+
+	StripeCustomerID             string     `json:"-"`
+	StripeSubscriptionID         string     `json:"-"`
+	SubscriptionStatus           string     `json:"subscription_status" example:"active"`
+	SubscriptionCurrentPeriodEnd *time.Time `json:"subscription_current_period_end,omitempty"`
+	// Moeda da assinatura (ex: "brl"), fixa depois da primeira; vazia se nunca assinou.
+	Moeda string `json:"moeda,omitempty" example:"brl"`
+
+	// Quantidade da assinatura na Stripe e assentos ocupados por membros e convites pendentes.
+	Assentos      int64 `json:"assentos"`
+	AssentosEmUso int64 `json:"assentos_em_uso"`
+
+	// Código promocional do desconto em vigor na assinatura, se houver (veja Cupom).
+	Cupom string `json:"cupom,omitempty" example:"BEMVINDO10"`
+
+	// Papel de quem fez a requisição na organização (vazio para operadores, veja Solicitante).
+	Papel string `json:"papel,omitempty" example:"dono"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AssinaturaAtiva indica se a organização tem uma assinatura que já está sendo cobrada.
+func (o *Organizacao) AssinaturaAtiva() bool {
+	return o.SubscriptionStatus == "active" || o.SubscriptionStatus == "trialing"
+}
+
+// Membro é um usuário de uma organização.
+type Membro struct {
+	UsuarioID int64     `json:"usuario_id"`
+	Nome      string    `json:"nome"`
+	Email     string    `json:"email"`
+	Papel     string    `json:"papel" example:"membro"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Convite chama alguém, pelo e-mail, para entrar na organização com o papel indicado. O token
+// enviado por e-mail nunca é guardado, só o seu hash.
+type Convite struct {
+	ID            int64      `json:"id"`
+	OrganizacaoID int64      `json:"organizacao_id"`
+	Email         string     `json:"email" example:"bia@email.com"`
+	Papel         string     `json:"papel" example:"membro"`
+	ConvidadoPor  int64      `json:"convidado_por"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	AcceptedAt    *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Pendente indica se o convite ainda pode ser aceito no instante agora.
+func (c *Convite) Pendente(agora time.Time) bool {
+	return c != nil && c.AcceptedAt == nil && c.RevokedAt == nil && agora.Before(c.ExpiresAt)
+}
+
+// Solicitante é quem pede uma operação sobre organizações. Um usuário age conforme o seu papel
+// em cada organização; um operador (credencial com o escopo billing:write) age como dono em
+// qualquer uma, mas não pertence a nenhuma.
+type Solicitante struct {
+	UsuarioID int64 // Zero para API keys.
+	Operador  bool
+}
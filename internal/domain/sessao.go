@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Motivos de revogação de uma sessão.
+const (
+	MotivoLogout          = "logout"
+	MotivoRevogada        = "revogada"         // Pela API, em DELETE /usuarios/{id}/sessions.
+	MotivoReusoDetectado  = "reuso_detectado"  // Um refresh token já trocado foi reapresentado.
+	MotivoUsuarioRemovido = "usuario_removido" // O usuário foi removido em DeleteUser.
+	MotivoContaAssumida   = "conta_assumida"   // O dono do e-mail assumiu o cadastro pelo login OIDC.
+	MotivoSenhaRedefinida = "senha_redefinida" // A senha foi redefinida pelo link enviado por e-mail.
+)
+
+// Sessao é um login ativo de um usuário em um dispositivo. Ela é renovada com refresh tokens
+// de uso único e dura até ExpiresAt, a menos que seja revogada antes.
+type Sessao struct {
+	ID              int64      `json:"id"`
+	UsuarioID       int64      `json:"usuario_id"`
+	Dispositivo     string     `json:"dispositivo,omitempty" example:"iPhone da Ana"`
+	IP              string     `json:"ip" example:"203.0.113.7"`
+	UserAgent       string     `json:"user_agent"`
+	ComMFA          bool       `json:"com_mfa"` // O login passou pelo segundo fator.
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      time.Time  `json:"last_used_at"` // Login ou última renovação.
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	MotivoRevogacao string     `json:"motivo_revogacao,omitempty"`
+}
+
+// Ativa indica se a sessão ainda pode ser usada no instante agora.
+func (s *Sessao) Ativa(agora time.Time) bool {
+	return s != nil && s.RevokedAt == nil && agora.Before(s.ExpiresAt)
+}
+
+// OrigemSessao descreve de onde vem o login ou a renovação: o nome do dispositivo, informado
+// pelo cliente, e o IP e o User-Agent da requisição.
+type OrigemSessao struct {
+	Dispositivo string
+	IP          string
+	UserAgent   string
+}
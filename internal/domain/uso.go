@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// MetricaChamadasAPI conta as requisições bem-sucedidas feitas com o token de acesso de um usuário.
+const MetricaChamadasAPI = "chamadas_api"
+
+// EventoUso é um consumo medido de um usuário. Chave identifica o evento na origem (gerada pelo
+// servidor, nunca vinda do cliente), para que ele não seja contado duas vezes.
+type EventoUso struct {
+	ID            int64
+	UsuarioID     int64
+	OrganizacaoID int64 // Organização cobrada pelo consumo; preenchido só nos eventos pendentes.
+	Metrica       string
+	Quantidade    int64
+	Chave         string
+	OcorridoEm    time.Time
+}
+
+// RelatorioUso é o consumo de uma métrica de uma organização em um período de cobrança,
+// agregado a partir dos eventos e reportado ao provedor de cobrança de uma só vez.
+type RelatorioUso struct {
+	ID               int64
+	OrganizacaoID    int64
+	StripeCustomerID string
+	Metrica          string
+	Quantidade       int64
+	PeriodoInicio    time.Time
+	// Momento é o instante informado ao provedor: o do último evento agregado, que cai no
+	// mesmo período de cobrança dos demais.
+	Momento time.Time
+	// Identificador é a chave de idempotência junto ao provedor: reenviar o relatório depois
+	// de uma falha não duplica a cobrança.
+	Identificador string
+	Tentativas    int
+	EventoIDs     []int64 // Eventos agregados; usado só na criação.
+}
+
+// ConsumoMetrica é o total de uma métrica no período e quanto dele já foi reportado para cobrança.
+type ConsumoMetrica struct {
+	Metrica    string `json:"metrica" example:"chamadas_api"`
+	Quantidade int64  `json:"quantidade" example:"1520"`
+	Reportado  int64  `json:"reportado" example:"1500"`
+}
+
+// Uso é o consumo de um usuário no período de cobrança atual. O período é o da assinatura da
+// organização cobrada pelo consumo dele ou, sem ela, o mês corrente (UTC).
+type Uso struct {
+	UsuarioID     int64            `json:"usuario_id"`
+	OrganizacaoID int64            `json:"organizacao_id,omitempty"`
+	PeriodoInicio time.Time        `json:"periodo_inicio"`
+	PeriodoFim    time.Time        `json:"periodo_fim"`
+	Metricas      []ConsumoMetrica `json:"metricas"`
+}
+
+// PeriodoCobranca devolve o período de cobrança mensal que contém t. Com fimAtual (o fim do
+// período corrente da assinatura), os períodos são ancorados nele; sem ele, são os meses do
+// calendário em UTC.
+func PeriodoCobranca(fimAtual *time.Time, t time.Time) (inicio, fim time.Time) {
+	t = t.UTC()
+	if fimAtual == nil || fimAtual.IsZero() {
+		inicio = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return inicio, inicio.AddDate(0, 1, 0)
+	}
+	// O período k vai de fimAtual+(k-1) meses a fimAtual+k meses; k = 0 é o corrente.
+	ancora, k := fimAtual.UTC(), 0
+	for !t.Before(ancora.AddDate(0, k, 0)) {
+		k++
+	}
+	for t.Before(ancora.AddDate(0, k-1, 0)) {
+		k--
+	}
+	return ancora.AddDate(0, k-1, 0), ancora.AddDate(0, k, 0)
+}
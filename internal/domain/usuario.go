@@ -7,21 +7,41 @@ type Usuario struct {
 	Nome  string `json:"nome"`
 	Email string `json:"email"`
 
-	// --- NOVOS CAMPOS PARA ASSINATURA ---
+	// Papel do usuário (PapelUsuario ou PapelAdmin). Só é alterado pelo comando definir-papel.
+	Papel string `json:"papel"`
 
-	// ID do cliente no Stripe (ex: "cus_...")
-	// Essencial para ligar nosso usuário ao cliente na Stripe.
-	StripeCustomerID string `json:"-"` // O "-" significa que este campo não será exposto na nossa API JSON.
+	// Quando o usuário confirmou ser dono do e-mail (nil se ainda não confirmou).
+	// Volta a ser nil quando o e-mail é alterado.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 
-	// ID da assinatura na Stripe (ex: "sub_...")
-	// Para identificar a assinatura específica do usuário.
-	StripeSubscriptionID string `json:"-"`
+	// Datas de cadastro e da última alteração, mantidas pelo repositório (sempre em UTC).
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
-	// Status da assinatura (ex: "active", "canceled", "past_due").
-	// Este campo será nossa "fonte da verdade" interna.
-	SubscriptionStatus string `json:"subscription_status"`
+	// Versão do registro, incrementada a cada alteração. Usada no controle de concorrência
+	// otimista e exposta ao cliente apenas pelo cabeçalho ETag.
+	Version int64 `json:"-"`
+}
 
-	// Data de expiração do período atual da assinatura.
-	// É a "vigência" que você mencionou.
-	SubscriptionCurrentPeriodEnd time.Time `json:"subscription_current_period_end"`
-}
\ No newline at end of file
+// UsuarioPatch descreve uma alteração parcial de um usuário.
+// Campos nil não são alterados; apenas as colunas presentes são gravadas no banco.
+type UsuarioPatch struct {
+	Nome  *string
+	Email *string
+
+	// Versão esperada do registro (zero para não verificar).
+	Version int64
+}
+
+// Vazio indica se o patch não altera nenhum campo.
+func (p UsuarioPatch) Vazio() bool {
+	return p.Nome == nil && p.Email == nil
+}
+
+// FiltroUsuarios restringe a listagem de usuários. Campos zerados não filtram.
+// Pensado para sincronização incremental: o cliente guarda o maior updated_at
+// recebido e o envia como UpdatedSince na próxima chamada.
+type FiltroUsuarios struct {
+	CreatedAfter time.Time // Cadastrados estritamente depois deste instante.
+	UpdatedSince time.Time // Alterados neste instante ou depois.
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+// APIKeyService é a interface do serviço de API keys usada pelo APIKeyHandler.
+type APIKeyService interface {
+	Autenticador
+	CreateAPIKey(ctx context.Context, nome string, escopos []string) (string, *domain.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+// CreateAPIKeyRequest é o corpo do POST /api-keys.
+type CreateAPIKeyRequest struct {
+	Nome    string   `json:"nome" example:"job de conciliação"`
+	Escopos []string `json:"escopos" example:"usuarios:read,billing:write"`
+}
+
+// CreateAPIKeyResponse traz os dados da chave criada e a chave completa, exibida só desta vez.
+type CreateAPIKeyResponse struct {
+	domain.APIKey
+	Chave string `json:"chave"`
+}
+
+// APIKeyHandler gerencia as rotas de /api-keys. Todas exigem uma API key com o escopo apikeys:admin.
+type APIKeyHandler struct {
+	service APIKeyService
+}
+
+// NewAPIKeyHandler cria uma nova instância do APIKeyHandler.
+func NewAPIKeyHandler(s APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: s}
+}
+
+// Routes define as rotas de /api-keys.
+func (h *APIKeyHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(Autenticar(h.service, nil), RequerEscopo(domain.EscopoAPIKeysAdmin))
+
+	r.Post("/", h.CreateAPIKey)       // POST /api-keys
+	r.Get("/", h.ListAPIKeys)         // GET /api-keys
+	r.Delete("/{id}", h.RevokeAPIKey) // DELETE /api-keys/{id}
+	return r
+}
+
+// @Summary      Cria uma API key
+// @Description  Gera uma chave para clientes máquina a máquina com os escopos informados
+// @Description  (usuarios:read, usuarios:write, billing:write, apikeys:admin, logs:admin). A chave completa só
+// @Description  aparece nesta resposta; depois, apenas o prefixo identifica a chave.
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        apikey  body      CreateAPIKeyRequest  true  "Nome e escopos da chave"
+// @Success      201     {object}  CreateAPIKeyResponse
+// @Failure      400     {object}  Problem
+// @Failure      401     {object}  Problem
+// @Failure      403     {object}  Problem
+// @Failure      500     {object}  Problem
+// @Router       /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	chave, key, err := h.service.CreateAPIKey(r.Context(), req.Nome, req.Escopos)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "API key criada", "api_key_criada_id", key.ID, "prefixo", key.Prefixo, "escopos", key.Escopos)
+	respondWithJSON(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: *key, Chave: chave})
+}
+
+// @Summary      Lista as API keys
+// @Description  Retorna todas as chaves, inclusive as revogadas, sem o segredo
+// @Tags         api-keys
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   domain.APIKey
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.ListAPIKeys(r.Context())
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// @Summary      Revoga uma API key
+// @Description  A chave deixa de ser aceita imediatamente. Revogar uma chave já revogada não é erro.
+// @Tags         api-keys
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "ID da API key"
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), id); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "API key revogada", "api_key_revogada_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/auth"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/ratelimit"
+)
+
+var (
+	errNaoAutenticado     = &domain.Error{Code: "nao_autenticado", Status: http.StatusUnauthorized, Title: "autenticação necessária"}
+	errEscopoInsuficiente = &domain.Error{Code: "escopo_insuficiente", Status: http.StatusForbidden, Title: "a credencial não tem permissão para esta operação"}
+)
+
+// Autenticador verifica API keys. É satisfeito por service.APIKeyService.
+type Autenticador interface {
+	AuthenticateAPIKey(ctx context.Context, chave string) (*domain.APIKey, error)
+}
+
+// AutenticadorUsuarios verifica os tokens de acesso emitidos no login dos usuários, aplicando
+// a política de MFA do papel. É satisfeito por service.AuthService.
+type AutenticadorUsuarios interface {
+	AuthenticateUser(ctx context.Context, token string) (*domain.Usuario, error)
+}
+
+// prefixoAPIKey distingue as API keys (usk_<id>_<segredo>) dos tokens de acesso dos usuários.
+const prefixoAPIKey = "usk_"
+
+// Autenticar devolve o middleware que exige uma API key, enviada em Authorization: Bearer
+// ou em X-API-Key. A chave válida vira o auth.Principal da requisição, também usado como
+// identidade do cliente no rate limit.
+//
+// Com u, o Bearer também aceita o token de acesso de um usuário, que recebe os escopos do seu
+// papel (domain.EscoposPorPapel).
+func Autenticar(a Autenticador, u AutenticadorUsuarios) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			chave := credencial(r)
+			if chave == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				respondWithProblem(w, r, errNaoAutenticado.WithDetail("envie a API key em Authorization: Bearer <chave> ou X-API-Key"))
+				return
+			}
+
+			var p auth.Principal
+			ctx := r.Context()
+			if u != nil && r.Header.Get("X-API-Key") == "" && !strings.HasPrefix(chave, prefixoAPIKey) {
+				usuario, err := u.AuthenticateUser(ctx, chave)
+				if err != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+					respondWithProblem(w, r, err)
+					return
+				}
+				p = auth.Principal{Tipo: auth.TipoUsuario, ID: strconv.FormatInt(usuario.ID, 10), Escopos: domain.EscoposPorPapel[usuario.Papel]}
+				logging.Add(ctx, "principal", p.String())
+			} else {
+				key, err := a.AuthenticateAPIKey(ctx, chave)
+				if err != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+					respondWithProblem(w, r, err)
+					return
+				}
+				p = auth.Principal{Tipo: auth.TipoAPIKey, ID: strconv.FormatInt(key.ID, 10), Escopos: key.Escopos}
+				logging.Add(ctx, "api_key_id", key.ID)
+			}
+
+			ctx = auth.WithPrincipal(ctx, p)
+			ctx = ratelimit.WithCliente(ctx, p.String())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// credencial lê a API key (ou o token de acesso) dos cabeçalhos aceitos.
+func credencial(r *http.Request) string {
+	if chave := r.Header.Get("X-API-Key"); chave != "" {
+		return strings.TrimSpace(chave)
+	}
+	esquema, chave, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(esquema, "Bearer") {
+		return strings.TrimSpace(chave)
+	}
+	return ""
+}
+
+// RequerEscopo devolve o middleware que só deixa passar requisições autenticadas com o escopo.
+func RequerEscopo(escopo string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.FromContext(r.Context())
+			if !ok {
+				respondWithProblem(w, r, errNaoAutenticado)
+				return
+			}
+			if !p.Possui(escopo) {
+				respondWithProblem(w, r, errEscopoInsuficiente.
+					WithDetail("esta operação exige o escopo "+escopo).
+					WithDetails(map[string]any{"escopo_necessario": escopo}))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
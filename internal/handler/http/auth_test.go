@@ -0,0 +1,202 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// MockAPIKeyService aceita as chaves do mapa e registra as revogações.
+type MockAPIKeyService struct {
+	keys      map[string]domain.APIKey
+	revogadas []int64
+}
+
+func (m *MockAPIKeyService) AuthenticateAPIKey(ctx context.Context, chave string) (*domain.APIKey, error) {
+	key, ok := m.keys[chave]
+	if !ok {
+		return nil, service.ErrAPIKeyInvalida
+	}
+	return &key, nil
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, nome string, escopos []string) (string, *domain.APIKey, error) {
+	return "usk_novachave_segredo", &domain.APIKey{ID: 9, Nome: nome, Prefixo: "usk_novachave", Escopos: escopos}, nil
+}
+
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	return []domain.APIKey{m.keys["usk_admin_x"]}, nil
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id int64) error {
+	if id != 1 {
+		return service.ErrAPIKeyNaoEncontrada
+	}
+	m.revogadas = append(m.revogadas, id)
+	return nil
+}
+
+func novoMockAPIKeys() *MockAPIKeyService {
+	return &MockAPIKeyService{keys: map[string]domain.APIKey{
+		"usk_leitura_x": {ID: 2, Escopos: []string{domain.EscopoUsuariosLeitura}},
+		"usk_admin_x":   {ID: 1, Escopos: []string{domain.EscopoAPIKeysAdmin}},
+	}}
+}
+
+// mockUsuariosAutenticados aceita os tokens de acesso do mapa; os demais recebem o erro
+// configurado (token inválido, por padrão).
+type mockUsuariosAutenticados map[string]domain.Usuario
+
+func (m mockUsuariosAutenticados) AuthenticateUser(ctx context.Context, token string) (*domain.Usuario, error) {
+	if token == "admin-sem-mfa" {
+		return nil, service.ErrMFAObrigatorio
+	}
+	u, ok := m[token]
+	if !ok {
+		return nil, service.ErrTokenAcessoInvalido
+	}
+	return &u, nil
+}
+
+func TestUsuarioHandler_Autenticacao(t *testing.T) {
+	mockService := &MockUsuarioService{
+		GetUserByIDFn: func(ctx context.Context, id int64) (*domain.Usuario, error) {
+			return &domain.Usuario{ID: id, Nome: "Ana", Version: 1}, nil
+		},
+	}
+	router := NewUsuarioHandler(mockService).WithAutenticacao(novoMockAPIKeys(), nil).Routes()
+	requisicao := func(metodo, alvo string, cabecalhos map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(metodo, alvo, nil)
+		for k, v := range cabecalhos {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - aceita a chave em X-API-Key e em Authorization: Bearer", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, requisicao("GET", "/1", map[string]string{"X-API-Key": "usk_leitura_x"}).Code)
+		assert.Equal(t, http.StatusOK, requisicao("GET", "/1", map[string]string{"Authorization": "Bearer usk_leitura_x"}).Code)
+	})
+
+	t.Run("erro - sem chave ou com chave inválida retorna 401", func(t *testing.T) {
+		rr := requisicao("GET", "/1", nil)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, `Bearer realm="api"`, rr.Header().Get("WWW-Authenticate"))
+
+		rr = requisicao("GET", "/1", map[string]string{"Authorization": "Bearer usk_errada_x"})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		assert.Equal(t, "api_key_invalida", problem.Code)
+	})
+
+	t.Run("erro - chave sem o escopo da rota retorna 403", func(t *testing.T) {
+		for _, rota := range []struct{ metodo, alvo, escopo string }{
+			{"DELETE", "/1", domain.EscopoUsuariosEscrita},
+			{"DELETE", "/1/sessions", domain.EscopoUsuariosEscrita},
+		} {
+			rr := requisicao(rota.metodo, rota.alvo, map[string]string{"X-API-Key": "usk_leitura_x"})
+
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			var problem Problem
+			json.NewDecoder(rr.Body).Decode(&problem)
+			assert.Equal(t, "escopo_insuficiente", problem.Code)
+			assert.Equal(t, rota.escopo, problem.Details["escopo_necessario"])
+		}
+	})
+}
+
+func TestUsuarioHandler_AutenticacaoDeUsuarios(t *testing.T) {
+	mockService := &MockUsuarioService{
+		GetUserByIDFn: func(ctx context.Context, id int64) (*domain.Usuario, error) {
+			return &domain.Usuario{ID: id, Nome: "Ana", Version: 1}, nil
+		},
+	}
+	usuarios := mockUsuariosAutenticados{
+		"admin-com-mfa": {ID: 3, Papel: domain.PapelAdmin},
+		"usuario-comum": {ID: 4, Papel: domain.PapelUsuario},
+	}
+	router := NewUsuarioHandler(mockService).WithAutenticacao(novoMockAPIKeys(), usuarios).Routes()
+	requisicao := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	codigo := func(rr *httptest.ResponseRecorder) string {
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		return problem.Code
+	}
+
+	t.Run("sucesso - admin que passou pelo MFA recebe os escopos do papel", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, requisicao("admin-com-mfa").Code)
+	})
+
+	t.Run("erro - admin sem MFA é barrado pela política", func(t *testing.T) {
+		rr := requisicao("admin-sem-mfa")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, "mfa_obrigatorio", codigo(rr))
+	})
+
+	t.Run("erro - usuário comum não tem escopos e token inválido retorna 401", func(t *testing.T) {
+		rr := requisicao("usuario-comum")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, "escopo_insuficiente", codigo(rr))
+
+		rr = requisicao("inventado")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "token_acesso_invalido", codigo(rr))
+	})
+
+	t.Run("sucesso - chaves usk_ continuam indo para as API keys", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, requisicao("usk_leitura_x").Code)
+	})
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	requisicao := func(mock *MockAPIKeyService, metodo, alvo, chave, corpo string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(metodo, alvo, strings.NewReader(corpo))
+		req.Header.Set("X-API-Key", chave)
+		rr := httptest.NewRecorder()
+		NewAPIKeyHandler(mock).Routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - cria a chave e devolve o segredo uma única vez", func(t *testing.T) {
+		rr := requisicao(novoMockAPIKeys(), "POST", "/", "usk_admin_x", `{"nome":"job","escopos":["usuarios:read"]}`)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		var resp CreateAPIKeyResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		assert.Equal(t, "usk_novachave_segredo", resp.Chave)
+		assert.Equal(t, "usk_novachave", resp.Prefixo)
+		assert.Equal(t, []string{"usuarios:read"}, resp.Escopos)
+	})
+
+	t.Run("sucesso - revoga a chave", func(t *testing.T) {
+		mock := novoMockAPIKeys()
+
+		rr := requisicao(mock, "DELETE", "/1", "usk_admin_x", "")
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, []int64{1}, mock.revogadas)
+		assert.Equal(t, http.StatusNotFound, requisicao(mock, "DELETE", "/5", "usk_admin_x", "").Code)
+	})
+
+	t.Run("erro - exige o escopo apikeys:admin", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, requisicao(novoMockAPIKeys(), "GET", "/", "usk_leitura_x", "").Code)
+	})
+}
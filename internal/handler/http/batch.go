@@ -0,0 +1,117 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+// BatchRequest é o corpo do POST /usuarios/batch.
+type BatchRequest struct {
+	Modo      string                 `json:"modo" enums:"atomico,parcial" default:"atomico"`
+	Operacoes []BatchOperacaoRequest `json:"operacoes"`
+}
+
+// BatchOperacaoRequest é uma operação do lote. O if_match segue as regras do cabeçalho
+// If-Match das rotas individuais (ETag obtido no GET).
+type BatchOperacaoRequest struct {
+	Op      string          `json:"op" enums:"create,update,delete"`
+	ID      int64           `json:"id,omitempty"`
+	IfMatch string          `json:"if_match,omitempty"`
+	Usuario *domain.Usuario `json:"usuario,omitempty"`
+}
+
+// BatchResponse é o corpo da resposta 207 do lote, com um resultado por operação, na mesma ordem.
+type BatchResponse struct {
+	Modo       string                   `json:"modo"`
+	Aplicado   bool                     `json:"aplicado"`
+	Resultados []BatchResultadoResponse `json:"resultados"`
+}
+
+// BatchResultadoResponse traz o status que a operação teria se fosse feita individualmente
+// e, em caso de falha, o erro no mesmo formato problem+json das demais rotas.
+type BatchResultadoResponse struct {
+	Indice int      `json:"indice"`
+	Op     string   `json:"op"`
+	Status int      `json:"status"`
+	ID     int64    `json:"id,omitempty"`
+	ETag   string   `json:"etag,omitempty"`
+	Erro   *Problem `json:"erro,omitempty"`
+}
+
+// @Summary      Executa um lote de operações
+// @Description  Cria, atualiza e remove usuários em uma única transação. No modo atomico (padrão), qualquer
+// @Description  falha desfaz o lote inteiro; no modo parcial, cada operação é aplicada de forma independente.
+// @Description  A resposta é sempre 207, com o status de cada operação na mesma ordem da requisição.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        lote  body      BatchRequest  true  "Operações do lote"
+// @Success      207   {object}  BatchResponse
+// @Failure      400   {object}  Problem
+// @Failure      413   {object}  Problem
+// @Failure      401   {object}  Problem
+// @Failure      403   {object}  Problem
+// @Failure      429   {object}  Problem
+// @Failure      500   {object}  Problem
+// @Router       /usuarios/batch [post]
+func (h *UsuarioHandler) BatchUsers(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	operacoes := make([]domain.OperacaoLote, len(req.Operacoes))
+	var erros []domain.FieldError
+	for i, op := range req.Operacoes {
+		versao, err := parseETag(op.IfMatch)
+		if err != nil {
+			erros = append(erros, domain.FieldError{
+				Field:   fmt.Sprintf("operacoes[%d].if_match", i),
+				Code:    "etag_invalido",
+				Message: "use o ETag obtido no GET do usuário",
+			})
+			continue
+		}
+		operacoes[i] = domain.OperacaoLote{Op: op.Op, ID: op.ID, Versao: versao, Usuario: op.Usuario}
+	}
+	if len(erros) > 0 {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("lote de operações inválido").WithFields(erros...))
+		return
+	}
+
+	resultado, err := h.service.BatchUsers(r.Context(), req.Modo, operacoes)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	resp := BatchResponse{Modo: resultado.Modo, Aplicado: resultado.Aplicado, Resultados: make([]BatchResultadoResponse, len(resultado.Resultados))}
+	falhas := 0
+	for i, res := range resultado.Resultados {
+		item := BatchResultadoResponse{Indice: i, Op: operacoes[i].Op, Status: res.Status, ID: res.ID}
+		if res.Versao != 0 {
+			item.ETag = formatETag(res.Versao)
+		}
+		if res.Err != nil {
+			falhas++
+			var de *domain.Error
+			if !errors.As(res.Err, &de) {
+				de = domain.ErrInterno.Wrap(res.Err)
+			}
+			if de.Status >= http.StatusInternalServerError {
+				logging.FromContext(r.Context()).ErrorContext(r.Context(), "Erro em operação do lote", "indice", i, "error", res.Err)
+			}
+			p := newProblem(de)
+			item.Erro = &p
+		}
+		resp.Resultados[i] = item
+	}
+	logging.Add(r.Context(), "batch_operacoes", len(operacoes), "batch_falhas", falhas, "batch_aplicado", resultado.Aplicado)
+	respondWithJSON(w, http.StatusMultiStatus, resp)
+}
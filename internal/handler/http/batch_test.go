@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+func TestUsuarioHandler_BatchUsers(t *testing.T) {
+	t.Run("sucesso - responde 207 com o resultado de cada operação", func(t *testing.T) {
+		// Arrange
+		var recebidas []domain.OperacaoLote
+		mockService := &MockUsuarioService{
+			BatchUsersFn: func(ctx context.Context, modo string, operacoes []domain.OperacaoLote) (*domain.ResultadoLote, error) {
+				recebidas = operacoes
+				return &domain.ResultadoLote{Modo: domain.ModoParcial, Aplicado: true, Resultados: []domain.ResultadoOperacao{
+					{Status: http.StatusOK, ID: 1, Versao: 4},
+					{Status: http.StatusPreconditionFailed, Err: service.ErrVersaoDesatualizada},
+				}}, nil
+			},
+		}
+		corpo := `{"modo":"parcial","operacoes":[
+			{"op":"update","id":1,"if_match":"\"3\"","usuario":{"nome":"Ana","email":"ana@email.com"}},
+			{"op":"delete","id":2,"if_match":"\"7\""}]}`
+		req := httptest.NewRequest("POST", "/usuarios/batch", strings.NewReader(corpo))
+		rr := httptest.NewRecorder()
+
+		// Act
+		NewUsuarioHandler(mockService).BatchUsers(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		assert.Equal(t, int64(3), recebidas[0].Versao)
+		assert.Equal(t, int64(7), recebidas[1].Versao)
+
+		var resp BatchResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.True(t, resp.Aplicado)
+		assert.Equal(t, BatchResultadoResponse{Indice: 0, Op: "update", Status: http.StatusOK, ID: 1, ETag: `"4"`}, resp.Resultados[0])
+		assert.Equal(t, "delete", resp.Resultados[1].Op)
+		assert.Equal(t, service.ErrVersaoDesatualizada.Code, resp.Resultados[1].Erro.Code)
+	})
+
+	t.Run("erro - if_match inválido rejeita o lote inteiro", func(t *testing.T) {
+		mockService := &MockUsuarioService{}
+		corpo := `{"operacoes":[{"op":"delete","id":1},{"op":"delete","id":2,"if_match":"W/\"1\""}]}`
+		req := httptest.NewRequest("POST", "/usuarios/batch", strings.NewReader(corpo))
+		rr := httptest.NewRecorder()
+
+		NewUsuarioHandler(mockService).BatchUsers(rr, req)
+
+		var problem Problem
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "operacoes[1].if_match", problem.Errors[0].Field)
+	})
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// CobrancaService é a interface do serviço de dados de cobrança usada pelo AuthHandler.
+type CobrancaService interface {
+	GetBillingDetails(ctx context.Context, usuarioID int64) (*domain.DadosCobranca, error)
+	UpdateBillingDetails(ctx context.Context, usuarioID int64, dados domain.DadosCobranca) (*domain.DadosCobranca, error)
+}
+
+// WithCobranca habilita GET e PUT /auth/me/billing, com os dados de cobrança do próprio usuário.
+func (h *AuthHandler) WithCobranca(s CobrancaService) *AuthHandler {
+	h.cobranca = s
+	return h
+}
+
+// @Summary      Dados de cobrança do usuário autenticado
+// @Description  Devolve o endereço de cobrança e o CPF ou CNPJ do dono do token de acesso.
+// @Tags         auth
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer <access_token>"
+// @Success      200            {object}  domain.DadosCobranca
+// @Failure      401            {object}  Problem
+// @Failure      404            {object}  Problem
+// @Failure      429            {object}  Problem
+// @Failure      500            {object}  Problem
+// @Router       /auth/me/billing [get]
+func (h *AuthHandler) GetBillingDetails(w http.ResponseWriter, r *http.Request) {
+	usuario, ok := h.usuarioAutenticado(w, r)
+	if !ok {
+		return
+	}
+	dados, err := h.cobranca.GetBillingDetails(r.Context(), usuario.ID)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dados)
+}
+
+// @Summary      Atualiza os dados de cobrança do usuário autenticado
+// @Description  Grava o endereço de cobrança (pais com duas letras, ex: BR) e o CPF ou CNPJ,
+// @Description  opcional, com ou sem pontuação. Eles vão antes para o cliente da Stripe de cada
+// @Description  organização de que o usuário é dono; se a Stripe recusar, nada é gravado. O país
+// @Description  escolhe a moeda do checkout das organizações que ainda não assinaram.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                true  "Bearer <access_token>"
+// @Param        dados          body      domain.DadosCobranca  true  "Endereço e documento"
+// @Success      200            {object}  domain.DadosCobranca
+// @Failure      400            {object}  Problem
+// @Failure      401            {object}  Problem
+// @Failure      429            {object}  Problem
+// @Failure      500            {object}  Problem
+// @Failure      502            {object}  Problem
+// @Router       /auth/me/billing [put]
+func (h *AuthHandler) UpdateBillingDetails(w http.ResponseWriter, r *http.Request) {
+	usuario, ok := h.usuarioAutenticado(w, r)
+	if !ok {
+		return
+	}
+	var dados domain.DadosCobranca
+	if err := decodeJSON(w, r, &dados); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	atualizados, err := h.cobranca.UpdateBillingDetails(r.Context(), usuario.ID, dados)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, atualizados)
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// MockCobrancaService guarda os dados de cobrança em memória, por usuário.
+type MockCobrancaService struct {
+	dados map[int64]domain.DadosCobranca
+}
+
+func (m *MockCobrancaService) GetBillingDetails(ctx context.Context, usuarioID int64) (*domain.DadosCobranca, error) {
+	d, ok := m.dados[usuarioID]
+	if !ok {
+		return nil, service.ErrDadosCobrancaNaoEncontrados
+	}
+	return &d, nil
+}
+
+func (m *MockCobrancaService) UpdateBillingDetails(ctx context.Context, usuarioID int64, dados domain.DadosCobranca) (*domain.DadosCobranca, error) {
+	if dados.Endereco.Pais == "" {
+		return nil, service.ErrDadosCobrancaInvalidos.WithFields(domain.FieldError{Field: "endereco.pais", Code: "obrigatorio", Message: "campo obrigatório"})
+	}
+	m.dados[usuarioID] = dados
+	return &dados, nil
+}
+
+func TestAuthHandler_Cobranca(t *testing.T) {
+	mock := &MockCobrancaService{dados: map[int64]domain.DadosCobranca{}}
+	router := NewAuthHandler(&MockAuthService{}).WithCobranca(mock).Routes()
+	requisicao := func(metodo, token, corpo string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(metodo, "/me/billing", strings.NewReader(corpo))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - grava e lê os dados do dono do token", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, requisicao("GET", "acesso", "").Code)
+
+		rr := requisicao("PUT", "acesso", `{"endereco":{"linha1":"Av. Paulista, 1000","cidade":"São Paulo","cep":"01310-100","pais":"BR"},"documento":"11144477735"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "11144477735", mock.dados[7].Documento)
+
+		rr = requisicao("GET", "acesso", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var dados domain.DadosCobranca
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &dados))
+		assert.Equal(t, "São Paulo", dados.Endereco.Cidade)
+	})
+
+	t.Run("erro - sem token, token inválido e dados inválidos", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, requisicao("GET", "", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, requisicao("PUT", "outro", `{}`).Code)
+
+		rr := requisicao("PUT", "acesso", `{"endereco":{"linha1":"Av. Paulista, 1000"}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "endereco.pais")
+	})
+
+	t.Run("erro - rotas ausentes sem o serviço de cobrança", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		NewAuthHandler(&MockAuthService{}).Routes().ServeHTTP(rr, httptest.NewRequest("GET", "/me/billing", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
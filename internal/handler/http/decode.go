@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// maxJSONBodyBytes limita o tamanho dos corpos JSON aceitos pelos handlers (1MB).
+const maxJSONBodyBytes = int64(1 << 20)
+
+var errCorpoMuitoGrande = &domain.Error{Code: "corpo_muito_grande", Status: http.StatusRequestEntityTooLarge, Title: "corpo da requisição muito grande"}
+
+// decodeJSON lê o corpo da requisição em dst de forma estrita: limita o tamanho,
+// rejeita campos desconhecidos e conteúdo extra após o objeto JSON.
+// Os erros devolvidos já são *domain.Error, com o campo problemático quando for possível identificá-lo.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return traduzirErroJSON(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return domain.ErrRequisicaoInvalida.WithDetail("o corpo deve conter um único objeto JSON")
+	}
+	return nil
+}
+
+// traduzirErroJSON converte os erros do encoding/json em erros de domínio com detalhes por campo.
+func traduzirErroJSON(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return errCorpoMuitoGrande.WithDetail(fmt.Sprintf("o limite é de %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &syntaxErr):
+		return domain.ErrRequisicaoInvalida.WithDetail(fmt.Sprintf("JSON malformado na posição %d", syntaxErr.Offset)).Wrap(err)
+	case errors.As(err, &typeErr):
+		return domain.ErrRequisicaoInvalida.WithDetail("corpo da requisição inválido").WithFields(domain.FieldError{
+			Field:   typeErr.Field,
+			Code:    "tipo_invalido",
+			Message: fmt.Sprintf("esperado um valor do tipo %s", typeErr.Type),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// O encoding/json não tem um tipo de erro próprio para campos desconhecidos.
+		campo := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return domain.ErrRequisicaoInvalida.WithDetail("corpo da requisição inválido").WithFields(domain.FieldError{
+			Field:   campo,
+			Code:    "campo_desconhecido",
+			Message: "campo não reconhecido",
+		})
+	case errors.Is(err, io.EOF):
+		return domain.ErrRequisicaoInvalida.WithDetail("o corpo da requisição está vazio")
+	default:
+		return domain.ErrRequisicaoInvalida.WithDetail("corpo da requisição inválido").Wrap(err)
+	}
+}
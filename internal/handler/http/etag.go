@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// formatETag monta o ETag (forte) de um usuário a partir da versão do registro.
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch extrai do cabeçalho If-Match a versão esperada pelo cliente.
+// Devolve zero quando o cabeçalho está ausente ou é "*" (qualquer versão serve).
+// Uma tag fraca ou que não foi gerada por nós nunca corresponde (comparação forte, RFC 9110),
+// então é tratada como falha de pré-condição. Aceitamos apenas uma tag por requisição.
+func parseIfMatch(r *http.Request) (int64, error) {
+	return parseETag(r.Header.Get("If-Match"))
+}
+
+// parseETag interpreta um valor no formato do If-Match. Também é usado no campo
+// if_match das operações de um lote, que seguem as mesmas regras do cabeçalho.
+func parseETag(valor string) (int64, error) {
+	valor = strings.TrimSpace(valor)
+	if valor == "" || valor == "*" {
+		return 0, nil
+	}
+
+	tag, ok := strings.CutPrefix(valor, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if !ok || err != nil || version <= 0 {
+		return 0, service.ErrVersaoDesatualizada.WithDetail("o If-Match não corresponde a nenhuma versão deste usuário")
+	}
+	return version, nil
+}
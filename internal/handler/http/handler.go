@@ -5,30 +5,42 @@ import (
 	"encoding/json"
 	"io" // Importa o pacote io
 	"log/slog"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
 	"github.com/willjrcristo/go-sqlite-db/internal/service"
 )
 
 // Para facilitar os testes, definimos uma interface que o nosso serviço deve satisfazer.
 // O handler vai depender desta interface, não da implementação concreta do serviço.
-// A interface para o nosso serviço agora inclui os métodos da Stripe.
 type UsuarioService interface {
 	CreateUser(ctx context.Context, usuario domain.Usuario) (int64, error)
 	GetUserByID(ctx context.Context, id int64) (*domain.Usuario, error)
-	GetAllUsers(ctx context.Context) ([]domain.Usuario, error)
+	GetAllUsers(ctx context.Context, filtro domain.FiltroUsuarios) ([]domain.Usuario, error)
+	SearchUsers(ctx context.Context, q string, limit int) ([]domain.Usuario, error)
 	UpdateUser(ctx context.Context, id int64, usuario domain.Usuario) error
-	DeleteUser(ctx context.Context, id int64) error
-	CreateCheckoutSession(ctx context.Context, userID int64) (string, error)
-	HandleStripeWebhook(payload []byte, signature string) error
+	PatchUser(ctx context.Context, id int64, formato string, patch []byte, versao int64) (*domain.Usuario, error)
+	DeleteUser(ctx context.Context, id int64, versao int64) error
+	ImportUsers(ctx context.Context, leitor service.LeitorUsuarios) (*domain.RelatorioImportacao, error)
+	ExportUsers(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) error
+	BatchUsers(ctx context.Context, modo string, operacoes []domain.OperacaoLote) (*domain.ResultadoLote, error)
+	ListSessions(ctx context.Context, id int64) ([]domain.Sessao, error)
+	RevokeSessions(ctx context.Context, id int64) error
+	RevokeSession(ctx context.Context, id, sessaoID int64) error
 }
 
 // UsuarioHandler lida com as requisições HTTP para a entidade Usuário gerenciando as rotas de /usuarios.
 type UsuarioHandler struct {
-	service UsuarioService
+	service      UsuarioService
+	limiter      *RateLimiter
+	idempotencia func(http.Handler) http.Handler
+	autenticacao func(http.Handler) http.Handler
+	uso          UsoService
 }
 
 // NewUsuarioHandler cria uma nova instância do UsuarioHandler.
@@ -38,68 +50,118 @@ func NewUsuarioHandler(s UsuarioService) *UsuarioHandler {
 	}
 }
 
+// WithAutenticacao passa a exigir uma API key em todas as rotas de /usuarios, com o escopo
+// adequado a cada uma. Com u, o token de acesso de um usuário também é aceito (veja Autenticar).
+// Sem ela, as rotas ficam abertas.
+func (h *UsuarioHandler) WithAutenticacao(a Autenticador, u AutenticadorUsuarios) *UsuarioHandler {
+	h.autenticacao = Autenticar(a, u)
+	return h
+}
+
+// autenticado aplica o middleware de autenticação, se configurado.
+func (h *UsuarioHandler) autenticado(next http.Handler) http.Handler {
+	if h.autenticacao == nil {
+		return next
+	}
+	return h.autenticacao(next)
+}
+
+// protecao devolve os middlewares de cada rota: o rate limit dela e, com autenticação
+// configurada, o escopo exigido.
+func (h *UsuarioHandler) protecao(escopo, rota string, chave ChaveLimite) chi.Middlewares {
+	mws := chi.Middlewares{h.limitar(rota, chave)}
+	if h.autenticacao != nil {
+		mws = append(mws, RequerEscopo(escopo))
+	}
+	return mws
+}
+
+// WithRateLimiter aplica os limites de requisição às rotas de /usuarios. Sem ele, as rotas não são limitadas.
+func (h *UsuarioHandler) WithRateLimiter(l *RateLimiter) *UsuarioHandler {
+	h.limiter = l
+	return h
+}
+
+// WithIdempotencia passa a aceitar Idempotency-Key na rota que cria usuários
+// (POST /usuarios), guardando as respostas por ttl.
+func (h *UsuarioHandler) WithIdempotencia(store IdempotenciaStore, ttl time.Duration) *UsuarioHandler {
+	h.idempotencia = Idempotente(store, ttl)
+	return h
+}
+
+// idempotente aplica o middleware de Idempotency-Key, se configurado.
+func (h *UsuarioHandler) idempotente(next http.Handler) http.Handler {
+	if h.idempotencia == nil {
+		return next
+	}
+	return h.idempotencia(next)
+}
+
+// WithUso passa a medir as chamadas feitas com o token de acesso de um usuário (veja MedirUso)
+// e habilita GET /usuarios/{id}/uso.
+func (h *UsuarioHandler) WithUso(s UsoService) *UsuarioHandler {
+	h.uso = s
+	return h
+}
+
+// limitar devolve o middleware de rate limit da rota (no-op sem RateLimiter).
+func (h *UsuarioHandler) limitar(rota string, chave ChaveLimite) func(http.Handler) http.Handler {
+	return h.limiter.Limitar(rota, chave)
+}
+
 // Routes define e retorna todas as rotas que este handler gerencia.
 // Manter as rotas junto com o handler deixa o código mais organizado.
 // Routes agora inclui o endpoint para criar o checkout.
 func (h *UsuarioHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.Post("/", h.CreateUser)      // POST /usuarios
-	r.Get("/", h.GetAllUsers)      // GET /usuarios
-	r.Get("/{id}", h.GetUserByID)  // GET /usuarios/{id}
-	r.Put("/{id}", h.UpdateUser)   // PUT /usuarios/{id}
-	r.Delete("/{id}", h.DeleteUser) // DELETE /usuarios/{id}
-	// --- NOVA ROTA ---
-	// POST /usuarios/{id}/criar-checkout
-	r.Post("/{id}/criar-checkout", h.CreateCheckoutSession)
+	leitura, escrita := domain.EscopoUsuariosLeitura, domain.EscopoUsuariosEscrita
 
-	return r
-}
-
-// --- NOVO HANDLER PARA CHECKOUT ---
-// @Summary      Cria uma sessão de checkout na Stripe
-// @Description  Gera uma URL de pagamento para um usuário iniciar uma assinatura
-// @Tags         assinaturas
-// @Produce      json
-// @Param        id   path      int  true  "ID do Usuário"
-// @Success      200  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      409  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
-// @Router       /usuarios/{id}/criar-checkout [post]
-func (h *UsuarioHandler) CreateCheckoutSession(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "ID de usuário inválido")
-		return
+	// Todas as rotas exigem API key (com WithAutenticacao). Cada uma tem o seu escopo e o seu
+	// próprio limite (veja RateLimiter), identificado pelo método e padrão.
+	r.Use(h.autenticado)
+	if h.uso != nil {
+		r.Use(MedirUso(h.uso))
 	}
-
-	checkoutURL, err := h.service.CreateCheckoutSession(r.Context(), id)
-	if err != nil {
-		switch err {
-		case service.ErrUsuarioNaoEncontrado:
-			respondWithError(w, http.StatusNotFound, err.Error())
-		case service.ErrAssinaturaJaAtiva:
-			respondWithError(w, http.StatusConflict, err.Error()) // 409 Conflict é um bom status para este caso
-		default:
-			respondWithError(w, http.StatusInternalServerError, "Erro ao criar sessão de checkout")
+	r.With(h.protecao(escrita, "POST /usuarios", PorCliente)...).With(h.idempotente).Post("/", h.CreateUser) // POST /usuarios
+	r.With(h.protecao(leitura, "GET /usuarios", PorCliente)...).Get("/", h.GetAllUsers)                      // GET /usuarios
+	r.With(h.protecao(leitura, "GET /usuarios/search", PorCliente)...).Get("/search", h.SearchUsers)        // GET /usuarios/search
+	r.With(h.protecao(escrita, "POST /usuarios/import", PorCliente)...).Post("/import", h.ImportUsers)      // POST /usuarios/import
+	r.With(h.protecao(leitura, "GET /usuarios/export", PorCliente)...).Get("/export", h.ExportUsers)        // GET /usuarios/export
+	r.With(h.protecao(escrita, "POST /usuarios/batch", PorCliente)...).Post("/batch", h.BatchUsers)         // POST /usuarios/batch
+
+	// Rotas de um usuário específico: o ID da URL vai para o logger da requisição.
+	r.Group(func(r chi.Router) {
+		r.Use(logUsuarioID)
+		r.With(h.protecao(leitura, "GET /usuarios/{id}", PorCliente)...).Get("/{id}", h.GetUserByID)      // GET /usuarios/{id}
+		r.With(h.protecao(escrita, "PUT /usuarios/{id}", PorCliente)...).Put("/{id}", h.UpdateUser)       // PUT /usuarios/{id}
+		r.With(h.protecao(escrita, "PATCH /usuarios/{id}", PorCliente)...).Patch("/{id}", h.PatchUser)    // PATCH /usuarios/{id}
+		r.With(h.protecao(escrita, "DELETE /usuarios/{id}", PorCliente)...).Delete("/{id}", h.DeleteUser) // DELETE /usuarios/{id}
+
+		r.With(h.protecao(leitura, "GET /usuarios/{id}/sessions", PorCliente)...).Get("/{id}/sessions", h.ListSessions)                              // GET /usuarios/{id}/sessions
+		r.With(h.protecao(escrita, "DELETE /usuarios/{id}/sessions", PorCliente)...).Delete("/{id}/sessions", h.RevokeSessions)                      // DELETE /usuarios/{id}/sessions
+		r.With(h.protecao(escrita, "DELETE /usuarios/{id}/sessions/{sessaoID}", PorCliente)...).Delete("/{id}/sessions/{sessaoID}", h.RevokeSession) // DELETE /usuarios/{id}/sessions/{sessaoID}
+		if h.uso != nil {
+			r.With(h.protecao(leitura, "GET /usuarios/{id}/uso", PorCliente)...).Get("/{id}/uso", h.GetUsage) // GET /usuarios/{id}/uso
 		}
-		return
-	}
+	})
 
-	respondWithJSON(w, http.StatusOK, map[string]string{"checkout_url": checkoutURL})
+	return r
 }
 
-
 // --- NOVO HANDLER PARA O WEBHOOK ---
 // (Criamos uma struct separada para manter a lógica do webhook isolada)
 
+// WebhookService processa os eventos recebidos da Stripe.
+type WebhookService interface {
+	HandleStripeWebhook(ctx context.Context, payload []byte, signature string) error
+}
+
 type StripeWebhookHandler struct {
-	service UsuarioService
+	service WebhookService
 }
 
-func NewStripeWebhookHandler(s UsuarioService) *StripeWebhookHandler {
+func NewStripeWebhookHandler(s WebhookService) *StripeWebhookHandler {
 	return &StripeWebhookHandler{
 		service: s,
 	}
@@ -112,20 +174,16 @@ func (h *StripeWebhookHandler) HandleStripeWebhook(w http.ResponseWriter, r *htt
 	
 	payload, err := io.ReadAll(r.Body)
 	if err != nil {
-		slog.Error("Erro ao ler o corpo do webhook", "error", err)
-		respondWithError(w, http.StatusServiceUnavailable, "Erro ao ler corpo da requisição")
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "Erro ao ler o corpo do webhook", "error", err)
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("erro ao ler corpo da requisição").Wrap(err))
 		return
 	}
 
 	signature := r.Header.Get("Stripe-Signature")
 
-	err = h.service.HandleStripeWebhook(payload, signature)
+	err = h.service.HandleStripeWebhook(r.Context(), payload, signature)
 	if err != nil {
-		if err == service.ErrWebhookStripe {
-			respondWithError(w, http.StatusBadRequest, "Falha na verificação da assinatura do webhook")
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Erro interno ao processar webhook")
-		}
+		respondWithProblem(w, r, err)
 		return
 	}
 
@@ -138,45 +196,105 @@ func (h *StripeWebhookHandler) HandleStripeWebhook(w http.ResponseWriter, r *htt
 // @Summary      Cria um novo usuário
 // @Description  Adiciona um novo usuário ao banco de dados com base nos dados fornecidos
 // @Tags         usuarios
+// @Security     ApiKeyAuth
 // @Accept       json
 // @Produce      json
-// @Param        usuario  body      domain.Usuario  true  "Dados do usuário para criação"
+// @Param        usuario          body      domain.Usuario  true   "Dados do usuário para criação"
+// @Param        Idempotency-Key  header    string          false  "Chave para repetir a requisição com segurança (a resposta fica guardada por 24h)"
 // @Success      201      {object}  domain.Usuario
-// @Failure      400      {object}  map[string]string
-// @Failure      500      {object}  map[string]string
+// @Failure      400      {object}  Problem
+// @Failure      409      {object}  Problem
+// @Failure      413      {object}  Problem
+// @Failure      422      {object}  Problem
+// @Failure      401      {object}  Problem
+// @Failure      403      {object}  Problem
+// @Failure      429      {object}  Problem
+// @Failure      500      {object}  Problem
 // @Router       /usuarios [post]
 func (h *UsuarioHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var usuario domain.Usuario
-	if err := json.NewDecoder(r.Body).Decode(&usuario); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Corpo da requisição inválido")
+	if err := decodeJSON(w, r, &usuario); err != nil {
+		respondWithProblem(w, r, err)
 		return
 	}
 
 	newID, err := h.service.CreateUser(r.Context(), usuario)
 	if err != nil {
-		if err == service.ErrDadosInvalidos {
-			respondWithError(w, http.StatusBadRequest, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Erro ao criar usuário")
-		}
+		respondWithProblem(w, r, err)
 		return
 	}
 
 	usuario.ID = newID
+	usuario.Papel = domain.PapelUsuario // O papel informado no corpo é ignorado; só a CLI o altera.
 	respondWithJSON(w, http.StatusCreated, usuario)
 }
 
 // @Summary      Lista todos os usuários
-// @Description  Retorna uma lista com todos os usuários cadastrados
+// @Description  Retorna uma lista com todos os usuários cadastrados, opcionalmente filtrados por data.
+// @Description  Para sincronização incremental, guarde o maior updated_at recebido e envie-o em updated_since.
 // @Tags         usuarios
+// @Security     ApiKeyAuth
 // @Produce      json
+// @Param        created_after  query     string  false  "Cadastrados depois deste instante (RFC 3339)"
+// @Param        updated_since  query     string  false  "Alterados neste instante ou depois (RFC 3339)"
 // @Success      200  {array}   domain.Usuario
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /usuarios [get]
 func (h *UsuarioHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	usuarios, err := h.service.GetAllUsers(r.Context())
+	filtro, err := parseFiltroUsuarios(r)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Erro ao buscar usuários")
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	usuarios, err := h.service.GetAllUsers(r.Context(), filtro)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, usuarios)
+}
+
+// @Summary      Busca usuários por nome ou e-mail
+// @Description  Busca textual que casa com o início das palavras do nome e do e-mail, sem diferenciar
+// @Description  maiúsculas nem acentos ("joao sil" encontra "João Silva"). Todas as palavras precisam
+// @Description  estar presentes; os resultados vêm ordenados por relevância. Sem FTS5 (API compilada sem
+// @Description  -tags sqlite_fts5), responde 503 busca_indisponivel.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        q      query     string  true   "Texto da busca"
+// @Param        limit  query     int     false  "Máximo de resultados (1 a 100, padrão 20)"
+// @Success      200  {array}   domain.Usuario
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Failure      503  {object}  Problem
+// @Router       /usuarios/search [get]
+func (h *UsuarioHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if valor := r.URL.Query().Get("limit"); valor != "" {
+		var err error
+		limit, err = strconv.Atoi(valor)
+		if err != nil {
+			respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("parâmetros de busca inválidos").WithFields(domain.FieldError{
+				Field:   "limit",
+				Code:    "tipo_invalido",
+				Message: "esperado um número inteiro",
+			}))
+			return
+		}
+	}
+
+	usuarios, err := h.service.SearchUsers(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		respondWithProblem(w, r, err)
 		return
 	}
 	respondWithJSON(w, http.StatusOK, usuarios)
@@ -185,100 +303,178 @@ func (h *UsuarioHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 // @Summary      Busca um usuário por ID
 // @Description  Retorna os dados de um usuário específico com base no seu ID
 // @Tags         usuarios
+// @Security     ApiKeyAuth
 // @Produce      json
 // @Param        id   path      int  true  "ID do Usuário"
 // @Success      200  {object}  domain.Usuario
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Header       200  {string}  ETag  "Versão atual do usuário, para uso no If-Match"
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /usuarios/{id} [get]
 func (h *UsuarioHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
 		return
 	}
 
 	usuario, err := h.service.GetUserByID(r.Context(), id)
 	if err != nil {
-		if err == service.ErrUsuarioNaoEncontrado {
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Erro ao buscar usuário")
-		}
+		respondWithProblem(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(usuario.Version))
 	respondWithJSON(w, http.StatusOK, usuario)
 }
 
 // @Summary      Atualiza um usuário
 // @Description  Atualiza os dados de um usuário existente com base no seu ID
 // @Tags         usuarios
+// @Security     ApiKeyAuth
 // @Accept       json
 // @Produce      json
 // @Param        id       path      int             true  "ID do Usuário"
 // @Param        usuario  body      domain.Usuario  true  "Dados do usuário para atualização"
+// @Param        If-Match header    string          false "ETag obtido no GET; a atualização falha com 412 se o usuário mudou"
 // @Success      204      {string}  string "No Content"
-// @Failure      400      {object}  map[string]string
-// @Failure      404      {object}  map[string]string
-// @Failure      500      {object}  map[string]string
+// @Failure      400      {object}  Problem
+// @Failure      404      {object}  Problem
+// @Failure      409      {object}  Problem
+// @Failure      412      {object}  Problem
+// @Failure      413      {object}  Problem
+// @Failure      401      {object}  Problem
+// @Failure      403      {object}  Problem
+// @Failure      429      {object}  Problem
+// @Failure      500      {object}  Problem
 // @Router       /usuarios/{id} [put]
 func (h *UsuarioHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
 		return
 	}
 
 	var usuario domain.Usuario
-	if err := json.NewDecoder(r.Body).Decode(&usuario); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Corpo da requisição inválido")
+	if err := decodeJSON(w, r, &usuario); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	usuario.Version, err = parseIfMatch(r)
+	if err != nil {
+		respondWithProblem(w, r, err)
 		return
 	}
 
 	err = h.service.UpdateUser(r.Context(), id, usuario)
 	if err != nil {
-		switch err {
-		case service.ErrUsuarioNaoEncontrado:
-			respondWithError(w, http.StatusNotFound, err.Error())
-		case service.ErrDadosInvalidos:
-			respondWithError(w, http.StatusBadRequest, err.Error())
-		default:
-			respondWithError(w, http.StatusInternalServerError, "Erro ao atualizar usuário")
-		}
+		respondWithProblem(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary      Atualiza parcialmente um usuário
+// @Description  Aplica um JSON Merge Patch (RFC 7396) ou um JSON Patch (RFC 6902) aos dados do usuário.
+// @Description  Apenas os campos alterados são gravados; a validação vale para o resultado do patch.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id     path      int     true  "ID do Usuário"
+// @Param        patch  body      object  true  "Documento de patch"
+// @Param        If-Match header  string  false "ETag obtido no GET; o patch falha com 412 se o usuário mudou"
+// @Success      200    {object}  domain.Usuario
+// @Header       200    {string}  ETag  "Nova versão do usuário"
+// @Failure      400    {object}  Problem
+// @Failure      404    {object}  Problem
+// @Failure      409    {object}  Problem
+// @Failure      412    {object}  Problem
+// @Failure      413    {object}  Problem
+// @Failure      415    {object}  Problem
+// @Failure      401    {object}  Problem
+// @Failure      403    {object}  Problem
+// @Failure      429    {object}  Problem
+// @Failure      500    {object}  Problem
+// @Router       /usuarios/{id} [patch]
+func (h *UsuarioHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
+		return
+	}
+
+	// Content-Type "application/json" é aceito como merge patch, o formato mais simples para os clientes.
+	formato, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if formato == "application/json" {
+		formato = service.MergePatch
+	}
+
+	versao, err := parseIfMatch(r)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxJSONBodyBytes))
+	if err != nil {
+		respondWithProblem(w, r, traduzirErroJSON(err))
+		return
+	}
+
+	usuario, err := h.service.PatchUser(r.Context(), id, formato, patch, versao)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(usuario.Version))
+	respondWithJSON(w, http.StatusOK, usuario)
+}
+
 // @Summary      Deleta um usuário
-// @Description  Remove um usuário do banco de dados com base no seu ID
+// @Description  Remove um usuário do banco de dados com base no seu ID. O dono de uma organização não é removido (409 dono_de_organizacao).
 // @Tags         usuarios
+// @Security     ApiKeyAuth
 // @Produce      json
 // @Param        id   path      int  true  "ID do Usuário"
+// @Param        If-Match header string false "ETag obtido no GET; a remoção falha com 412 se o usuário mudou"
 // @Success      204  {string}  string "No Content"
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      404  {object}  Problem
+// @Failure      412  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /usuarios/{id} [delete]
 func (h *UsuarioHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
 		return
 	}
 
-	err = h.service.DeleteUser(r.Context(), id)
+	versao, err := parseIfMatch(r)
 	if err != nil {
-		if err == service.ErrUsuarioNaoEncontrado {
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Erro ao deletar usuário")
-		}
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	err = h.service.DeleteUser(r.Context(), id, versao)
+	if err != nil {
+		respondWithProblem(w, r, err)
 		return
 	}
 
@@ -287,9 +483,45 @@ func (h *UsuarioHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 // --- FUNÇÕES AUXILIARES ---
 
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	slog.Error("API Error", "code", code, "message", message)
-	respondWithJSON(w, code, map[string]string{"error": message})
+// parseFiltroUsuarios lê os filtros de data da listagem a partir da query string (RFC 3339).
+func parseFiltroUsuarios(r *http.Request) (domain.FiltroUsuarios, error) {
+	var filtro domain.FiltroUsuarios
+	var erros []domain.FieldError
+
+	campos := []struct {
+		nome    string
+		destino *time.Time
+	}{
+		{"created_after", &filtro.CreatedAfter},
+		{"updated_since", &filtro.UpdatedSince},
+	}
+	for _, c := range campos {
+		valor := r.URL.Query().Get(c.nome)
+		if valor == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, valor)
+		if err != nil {
+			erros = append(erros, domain.FieldError{Field: c.nome, Code: "data_invalida", Message: "use o formato RFC 3339, ex: 2024-01-31T15:04:05Z"})
+			continue
+		}
+		*c.destino = t
+	}
+
+	if len(erros) > 0 {
+		return filtro, domain.ErrRequisicaoInvalida.WithDetail("parâmetros de filtro inválidos").WithFields(erros...)
+	}
+	return filtro, nil
+}
+
+// logUsuarioID adiciona o ID do usuário da rota ao logger da requisição.
+func logUsuarioID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := chi.URLParam(r, "id"); id != "" {
+			logging.Add(r.Context(), "usuario_id", id)
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/willjrcristo/go-sqlite-db/internal/domain"
 	"github.com/willjrcristo/go-sqlite-db/internal/service"
@@ -21,6 +25,15 @@ import (
 type MockUsuarioService struct {
 	CreateUserFn  func(ctx context.Context, usuario domain.Usuario) (int64, error)
 	GetUserByIDFn func(ctx context.Context, id int64) (*domain.Usuario, error)
+	PatchUserFn   func(ctx context.Context, id int64, formato string, patch []byte, versao int64) (*domain.Usuario, error)
+	ImportUsersFn func(ctx context.Context, leitor service.LeitorUsuarios) (*domain.RelatorioImportacao, error)
+	ExportUsersFn func(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) error
+	SearchUsersFn func(ctx context.Context, q string, limit int) ([]domain.Usuario, error)
+	BatchUsersFn  func(ctx context.Context, modo string, operacoes []domain.OperacaoLote) (*domain.ResultadoLote, error)
+
+	ListSessionsFn   func(ctx context.Context, id int64) ([]domain.Sessao, error)
+	RevokeSessionsFn func(ctx context.Context, id int64) error
+	RevokeSessionFn  func(ctx context.Context, id, sessaoID int64) error
 }
 
 // Implementamos os métodos da interface, mas eles apenas chamam as funções que definimos no mock.
@@ -32,9 +45,35 @@ func (m *MockUsuarioService) GetUserByID(ctx context.Context, id int64) (*domain
 	return m.GetUserByIDFn(ctx, id)
 }
 // OBS: Para um teste completo, você implementaria todos os outros métodos da interface aqui também.
-func (m *MockUsuarioService) GetAllUsers(ctx context.Context) ([]domain.Usuario, error) { return nil, nil }
+func (m *MockUsuarioService) GetAllUsers(ctx context.Context, filtro domain.FiltroUsuarios) ([]domain.Usuario, error) {
+	return nil, nil
+}
 func (m *MockUsuarioService) UpdateUser(ctx context.Context, id int64, usuario domain.Usuario) error { return nil }
-func (m *MockUsuarioService) DeleteUser(ctx context.Context, id int64) error { return nil }
+func (m *MockUsuarioService) DeleteUser(ctx context.Context, id int64, versao int64) error { return nil }
+func (m *MockUsuarioService) PatchUser(ctx context.Context, id int64, formato string, patch []byte, versao int64) (*domain.Usuario, error) {
+	return m.PatchUserFn(ctx, id, formato, patch, versao)
+}
+func (m *MockUsuarioService) ImportUsers(ctx context.Context, leitor service.LeitorUsuarios) (*domain.RelatorioImportacao, error) {
+	return m.ImportUsersFn(ctx, leitor)
+}
+func (m *MockUsuarioService) ExportUsers(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) error {
+	return m.ExportUsersFn(ctx, filtro, fn)
+}
+func (m *MockUsuarioService) SearchUsers(ctx context.Context, q string, limit int) ([]domain.Usuario, error) {
+	return m.SearchUsersFn(ctx, q, limit)
+}
+func (m *MockUsuarioService) BatchUsers(ctx context.Context, modo string, operacoes []domain.OperacaoLote) (*domain.ResultadoLote, error) {
+	return m.BatchUsersFn(ctx, modo, operacoes)
+}
+func (m *MockUsuarioService) ListSessions(ctx context.Context, id int64) ([]domain.Sessao, error) {
+	return m.ListSessionsFn(ctx, id)
+}
+func (m *MockUsuarioService) RevokeSessions(ctx context.Context, id int64) error {
+	return m.RevokeSessionsFn(ctx, id)
+}
+func (m *MockUsuarioService) RevokeSession(ctx context.Context, id, sessaoID int64) error {
+	return m.RevokeSessionFn(ctx, id, sessaoID)
+}
 
 
 // --- Testes do Handler ---
@@ -92,6 +131,61 @@ func TestUsuarioHandler_GetUserByID(t *testing.T) {
 		// Assert
 		assert.Equal(t, http.StatusNotFound, rr.Code)
 	})
+
+	t.Run("erro - erro embrulhado deve virar problem+json com código estável", func(t *testing.T) {
+		// Arrange
+		mockService := &MockUsuarioService{
+			GetUserByIDFn: func(ctx context.Context, id int64) (*domain.Usuario, error) {
+				return nil, fmt.Errorf("buscando usuário %d: %w", id, service.ErrUsuarioNaoEncontrado)
+			},
+		}
+		handler := NewUsuarioHandler(mockService)
+		req := httptest.NewRequest("GET", "/usuarios/7", nil)
+		rr := httptest.NewRecorder()
+		router := chi.NewRouter()
+		router.Use(middleware.RequestID)
+		router.Get("/usuarios/{id}", handler.GetUserByID)
+
+		// Act
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "usuario_nao_encontrado", problem.Code)
+		assert.Equal(t, "/problems/usuario_nao_encontrado", problem.Type)
+		assert.Equal(t, http.StatusNotFound, problem.Status)
+		assert.NotEmpty(t, problem.RequestID)
+		assert.Equal(t, "urn:request:"+problem.RequestID, problem.Instance)
+	})
+
+	t.Run("erro - erro desconhecido deve retornar 500 sem expor a causa", func(t *testing.T) {
+		// Arrange
+		mockService := &MockUsuarioService{
+			GetUserByIDFn: func(ctx context.Context, id int64) (*domain.Usuario, error) {
+				return nil, errors.New("database is locked")
+			},
+		}
+		handler := NewUsuarioHandler(mockService)
+		req := httptest.NewRequest("GET", "/usuarios/1", nil)
+		rr := httptest.NewRecorder()
+		router := chi.NewRouter()
+		router.Get("/usuarios/{id}", handler.GetUserByID)
+
+		// Act
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "database is locked")
+
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "erro_interno", problem.Code)
+	})
 }
 
 func TestUsuarioHandler_CreateUser(t *testing.T) {
@@ -123,4 +217,125 @@ func TestUsuarioHandler_CreateUser(t *testing.T) {
 		assert.Equal(t, int64(5), usuarioRetornado.ID) // Verifica se o ID retornado é o que o mock forneceu
 		assert.Equal(t, usuarioParaCriar.Nome, usuarioRetornado.Nome)
 	})
-}
\ No newline at end of file
+}
+func TestUsuarioHandler_CreateUser_CorpoInvalido(t *testing.T) {
+	casos := []struct {
+		nome         string
+		corpo        string
+		status       int
+		campoComErro string
+	}{
+		{"erro - campo desconhecido deve ser rejeitado", `{"nome":"Ana","email":"ana@email.com","admin":true}`, http.StatusBadRequest, "admin"},
+		{"erro - tipo inválido deve apontar o campo", `{"nome":123,"email":"ana@email.com"}`, http.StatusBadRequest, "nome"},
+		{"erro - mais de um objeto JSON deve ser rejeitado", `{"nome":"Ana"}{"nome":"Bia"}`, http.StatusBadRequest, ""},
+		{"erro - corpo acima do limite deve retornar 413", `{"nome":"` + strings.Repeat("a", int(maxJSONBodyBytes)) + `"}`, http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			// Arrange
+			mockService := &MockUsuarioService{
+				CreateUserFn: func(ctx context.Context, usuario domain.Usuario) (int64, error) {
+					t.Fatal("o serviço não deveria ser chamado")
+					return 0, nil
+				},
+			}
+			handler := NewUsuarioHandler(mockService)
+			req := httptest.NewRequest("POST", "/usuarios", strings.NewReader(c.corpo))
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.CreateUser(rr, req)
+
+			// Assert
+			assert.Equal(t, c.status, rr.Code)
+			var problem Problem
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			if c.campoComErro != "" {
+				assert.Len(t, problem.Errors, 1)
+				assert.Equal(t, c.campoComErro, problem.Errors[0].Field)
+			}
+		})
+	}
+}
+
+func TestUsuarioHandler_PatchUser(t *testing.T) {
+	t.Run("sucesso - repassa a versão do If-Match e devolve o novo ETag", func(t *testing.T) {
+		// Arrange
+		mockService := &MockUsuarioService{
+			PatchUserFn: func(ctx context.Context, id int64, formato string, patch []byte, versao int64) (*domain.Usuario, error) {
+				assert.Equal(t, int64(3), id)
+				assert.Equal(t, service.MergePatch, formato)
+				assert.Equal(t, int64(7), versao)
+				return &domain.Usuario{ID: 3, Nome: "Ana Lima", Email: "ana@email.com", Version: 8}, nil
+			},
+		}
+		handler := NewUsuarioHandler(mockService)
+		req := httptest.NewRequest("PATCH", "/usuarios/3", strings.NewReader(`{"nome":"Ana Lima"}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `"7"`)
+		rr := httptest.NewRecorder()
+		router := chi.NewRouter()
+		router.Patch("/usuarios/{id}", handler.PatchUser)
+
+		// Act
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"8"`, rr.Header().Get("ETag"))
+	})
+
+	t.Run("erro - If-Match com tag fraca retorna 412 sem chamar o serviço", func(t *testing.T) {
+		// Arrange
+		handler := NewUsuarioHandler(&MockUsuarioService{})
+		req := httptest.NewRequest("PATCH", "/usuarios/3", strings.NewReader(`{"nome":"Ana Lima"}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `W/"7"`)
+		rr := httptest.NewRecorder()
+		router := chi.NewRouter()
+		router.Patch("/usuarios/{id}", handler.PatchUser)
+
+		// Act
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	})
+}
+
+func TestUsuarioHandler_SearchUsers(t *testing.T) {
+	t.Run("sucesso - repassa a busca e o limite ao serviço", func(t *testing.T) {
+		// Arrange
+		mockService := &MockUsuarioService{
+			SearchUsersFn: func(ctx context.Context, q string, limit int) ([]domain.Usuario, error) {
+				assert.Equal(t, "joão sil", q)
+				assert.Equal(t, 5, limit)
+				return []domain.Usuario{{ID: 1, Nome: "João Silva", Email: "joao@email.com"}}, nil
+			},
+		}
+		req := httptest.NewRequest("GET", "/usuarios/search?q=jo%C3%A3o+sil&limit=5", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		NewUsuarioHandler(mockService).SearchUsers(rr, req)
+
+		// Assert
+		var usuarios []domain.Usuario
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &usuarios))
+		assert.Equal(t, "João Silva", usuarios[0].Nome)
+	})
+
+	t.Run("erro - limit não numérico", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/usuarios/search?q=ana&limit=dez", nil)
+		rr := httptest.NewRecorder()
+
+		NewUsuarioHandler(&MockUsuarioService{}).SearchUsers(rr, req)
+
+		var problem Problem
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "limit", problem.Errors[0].Field)
+	})
+}
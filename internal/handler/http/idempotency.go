@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/willjrcristo/go-sqlite-db/internal/auth"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+var (
+	errIdempotencyKeyInvalida    = &domain.Error{Code: "idempotency_key_invalida", Status: http.StatusBadRequest, Title: "Idempotency-Key inválida"}
+	errIdempotencyKeyReutilizada = &domain.Error{Code: "idempotency_key_reutilizada", Status: http.StatusUnprocessableEntity, Title: "Idempotency-Key usada em outra requisição"}
+	errRequisicaoEmAndamento     = &domain.Error{Code: "requisicao_em_andamento", Status: http.StatusConflict, Title: "requisição com esta Idempotency-Key em andamento"}
+)
+
+// maxIdempotencyKey é o tamanho máximo aceito para o cabeçalho Idempotency-Key.
+const maxIdempotencyKey = 255
+
+// headersIdempotentes são os cabeçalhos da resposta guardados e repetidos junto com o corpo.
+var headersIdempotentes = []string{"Content-Type", "Location", "ETag"}
+
+// IdempotenciaStore guarda as respostas por Idempotency-Key. É satisfeita por
+// repository.IdempotenciaRepository.
+type IdempotenciaStore interface {
+	Reservar(ctx context.Context, chave, fingerprint string, expira time.Time) (*domain.RespostaIdempotente, error)
+	Concluir(ctx context.Context, chave string, resposta domain.RespostaIdempotente) error
+	Liberar(ctx context.Context, chave string) error
+}
+
+// Idempotente devolve o middleware que torna seguras as novas tentativas de um POST enviado
+// com o cabeçalho Idempotency-Key. A primeira requisição com a chave é executada e a sua
+// resposta fica guardada por ttl; as seguintes recebem a mesma resposta, com
+// Idempotent-Replayed: true, sem executar o handler de novo.
+//
+// A chave só pode ser reutilizada com a mesma requisição (método, URL e corpo): do contrário,
+// responde 422. Enquanto a primeira ainda está em andamento, as repetições recebem 409.
+// Respostas 5xx não são guardadas, para que o cliente possa tentar de novo com a mesma chave.
+func Idempotente(store IdempotenciaStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			chave := r.Header.Get("Idempotency-Key")
+			if chave == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := validarIdempotencyKey(chave); err != nil {
+				respondWithProblem(w, r, err)
+				return
+			}
+			logging.Add(r.Context(), "idempotency_key", chave)
+			// Com autenticação, cada cliente tem o seu próprio espaço de chaves: a mesma
+			// Idempotency-Key enviada por outro cliente nunca devolve a resposta deste.
+			if p, ok := auth.FromContext(r.Context()); ok {
+				chave = p.String() + "|" + chave
+			}
+
+			// O corpo entra no fingerprint e depois é devolvido intacto ao handler.
+			corpo, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxJSONBodyBytes))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					err = errCorpoMuitoGrande.WithDetail(fmt.Sprintf("o limite é de %d bytes", maxBytesErr.Limit))
+				} else {
+					err = domain.ErrRequisicaoInvalida.WithDetail("erro ao ler corpo da requisição").Wrap(err)
+				}
+				respondWithProblem(w, r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(corpo))
+			fingerprint := fingerprintRequisicao(r, corpo)
+
+			existente, err := store.Reservar(r.Context(), chave, fingerprint, time.Now().Add(ttl))
+			if err != nil {
+				respondWithProblem(w, r, err)
+				return
+			}
+			if existente != nil {
+				switch {
+				case existente.Fingerprint != fingerprint:
+					respondWithProblem(w, r, errIdempotencyKeyReutilizada.WithDetail("a chave já foi usada com outro método, URL ou corpo; gere uma nova chave para esta requisição"))
+				case existente.Status == 0:
+					respondWithProblem(w, r, errRequisicaoEmAndamento.WithDetail("aguarde a conclusão da requisição original e tente novamente"))
+				default:
+					repetirResposta(w, r, existente)
+				}
+				return
+			}
+
+			// A partir daqui a chave está reservada: ela precisa ser concluída ou liberada,
+			// inclusive se o cliente desconectar ou o handler entrar em pânico.
+			ctx := context.WithoutCancel(r.Context())
+			concluida := false
+			defer func() {
+				if !concluida {
+					if err := store.Liberar(ctx, chave); err != nil {
+						logging.FromContext(ctx).ErrorContext(ctx, "Erro ao liberar Idempotency-Key", "error", err)
+					}
+				}
+			}()
+
+			var resposta bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&resposta)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			salva := domain.RespostaIdempotente{Status: status, Headers: make(map[string]string), Corpo: resposta.Bytes()}
+			for _, nome := range headersIdempotentes {
+				if valor := ww.Header().Get(nome); valor != "" {
+					salva.Headers[nome] = valor
+				}
+			}
+			if err := store.Concluir(ctx, chave, salva); err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "Erro ao guardar resposta da Idempotency-Key", "error", err)
+				return
+			}
+			concluida = true
+		})
+	}
+}
+
+// validarIdempotencyKey aceita de 1 a 255 caracteres ASCII visíveis (ex: um UUID).
+func validarIdempotencyKey(chave string) error {
+	if len(chave) > maxIdempotencyKey {
+		return errIdempotencyKeyInvalida.WithDetail(fmt.Sprintf("a chave pode ter no máximo %d caracteres", maxIdempotencyKey))
+	}
+	for i := 0; i < len(chave); i++ {
+		if chave[i] < '!' || chave[i] > '~' {
+			return errIdempotencyKeyInvalida.WithDetail("a chave deve conter apenas caracteres ASCII visíveis, como um UUID")
+		}
+	}
+	return nil
+}
+
+// fingerprintRequisicao resume o que torna duas requisições "a mesma": método, URL e corpo.
+func fingerprintRequisicao(r *http.Request, corpo []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(corpo)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// repetirResposta devolve a resposta guardada da requisição original.
+func repetirResposta(w http.ResponseWriter, r *http.Request, resposta *domain.RespostaIdempotente) {
+	logging.Add(r.Context(), "idempotency_replay", true)
+	for nome, valor := range resposta.Headers {
+		w.Header().Set(nome, valor)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(resposta.Status)
+	w.Write(resposta.Corpo)
+}
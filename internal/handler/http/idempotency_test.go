@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// storeIdempotenciaMemoria é uma IdempotenciaStore em memória, sem expiração.
+type storeIdempotenciaMemoria struct {
+	mu        sync.Mutex
+	respostas map[string]domain.RespostaIdempotente
+}
+
+func (s *storeIdempotenciaMemoria) Reservar(ctx context.Context, chave, fingerprint string, expira time.Time) (*domain.RespostaIdempotente, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.respostas[chave]; ok {
+		return &r, nil
+	}
+	s.respostas[chave] = domain.RespostaIdempotente{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *storeIdempotenciaMemoria) Concluir(ctx context.Context, chave string, resposta domain.RespostaIdempotente) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resposta.Fingerprint = s.respostas[chave].Fingerprint
+	s.respostas[chave] = resposta
+	return nil
+}
+
+func (s *storeIdempotenciaMemoria) Liberar(ctx context.Context, chave string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.respostas, chave)
+	return nil
+}
+
+func TestIdempotente(t *testing.T) {
+	novoRouter := func(criar func(ctx context.Context, usuario domain.Usuario) (int64, error)) (http.Handler, *storeIdempotenciaMemoria) {
+		store := &storeIdempotenciaMemoria{respostas: make(map[string]domain.RespostaIdempotente)}
+		h := NewUsuarioHandler(&MockUsuarioService{CreateUserFn: criar}).WithIdempotencia(store, time.Hour)
+		return h.Routes(), store
+	}
+	criarUsuario := func(router http.Handler, chave, corpo string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(corpo))
+		req.Header.Set("Content-Type", "application/json")
+		if chave != "" {
+			req.Header.Set("Idempotency-Key", chave)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	const corpo = `{"nome":"Ana","email":"ana@email.com"}`
+
+	t.Run("sucesso - repete a resposta sem criar o usuário de novo", func(t *testing.T) {
+		chamadas := 0
+		router, _ := novoRouter(func(ctx context.Context, usuario domain.Usuario) (int64, error) {
+			chamadas++
+			return int64(chamadas), nil
+		})
+
+		primeira := criarUsuario(router, "chave-1", corpo)
+		repetida := criarUsuario(router, "chave-1", corpo)
+
+		assert.Equal(t, 1, chamadas)
+		assert.Equal(t, http.StatusCreated, repetida.Code)
+		assert.Equal(t, primeira.Body.String(), repetida.Body.String())
+		assert.Equal(t, "application/json", repetida.Header().Get("Content-Type"))
+		assert.Equal(t, "true", repetida.Header().Get("Idempotent-Replayed"))
+		assert.Empty(t, primeira.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("sucesso - sem o cabeçalho, cada requisição é executada", func(t *testing.T) {
+		chamadas := 0
+		router, _ := novoRouter(func(ctx context.Context, usuario domain.Usuario) (int64, error) {
+			chamadas++
+			return int64(chamadas), nil
+		})
+
+		criarUsuario(router, "", corpo)
+		criarUsuario(router, "", corpo)
+
+		assert.Equal(t, 2, chamadas)
+	})
+
+	t.Run("erro - chave reutilizada com outro corpo retorna 422", func(t *testing.T) {
+		router, _ := novoRouter(func(ctx context.Context, usuario domain.Usuario) (int64, error) { return 1, nil })
+		criarUsuario(router, "chave-1", corpo)
+
+		rr := criarUsuario(router, "chave-1", `{"nome":"Bia","email":"bia@email.com"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		assert.Equal(t, "idempotency_key_reutilizada", problem.Code)
+	})
+
+	t.Run("erro - requisição original em andamento retorna 409", func(t *testing.T) {
+		router, store := novoRouter(func(ctx context.Context, usuario domain.Usuario) (int64, error) { return 1, nil })
+		// Simula a primeira requisição reservada e ainda sem resposta.
+		criarUsuario(router, "chave-1", corpo)
+		resposta := store.respostas["chave-1"]
+		store.respostas["chave-1"] = domain.RespostaIdempotente{Fingerprint: resposta.Fingerprint}
+
+		rr := criarUsuario(router, "chave-1", corpo)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("sucesso - erro 5xx libera a chave para uma nova tentativa", func(t *testing.T) {
+		falhar := true
+		router, _ := novoRouter(func(ctx context.Context, usuario domain.Usuario) (int64, error) {
+			if falhar {
+				return 0, assert.AnError
+			}
+			return 7, nil
+		})
+
+		assert.Equal(t, http.StatusInternalServerError, criarUsuario(router, "chave-1", corpo).Code)
+		falhar = false
+		rr := criarUsuario(router, "chave-1", corpo)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("erro - chave inválida retorna 400", func(t *testing.T) {
+		router, _ := novoRouter(nil)
+
+		assert.Equal(t, http.StatusBadRequest, criarUsuario(router, "chave com espaço", corpo).Code)
+		assert.Equal(t, http.StatusBadRequest, criarUsuario(router, strings.Repeat("a", 256), corpo).Code)
+	})
+}
@@ -0,0 +1,371 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+const (
+	// maxImportBodyBytes limita o tamanho dos arquivos de importação (50MB).
+	maxImportBodyBytes = int64(50 << 20)
+	// exportFlushEvery define de quantos em quantos usuários a exportação envia o que já escreveu.
+	exportFlushEvery = 100
+)
+
+var errFormatoNaoSuportado = &domain.Error{Code: "formato_nao_suportado", Status: http.StatusUnsupportedMediaType, Title: "formato de arquivo não suportado"}
+
+// colunasExportacao são as colunas do CSV exportado, na ordem em que são escritas.
+// Um arquivo exportado pode ser reimportado: as colunas além de nome e email são ignoradas.
+var colunasExportacao = []string{"id", "nome", "email", "created_at", "updated_at"}
+
+// colunasAntigas saíam nas exportações de versões anteriores (a assinatura hoje é da
+// organização) e continuam aceitas, e ignoradas, na importação.
+var colunasAntigas = []string{"subscription_status", "subscription_current_period_end"}
+
+// @Summary      Importa usuários em lote
+// @Description  Recebe um arquivo CSV (com cabeçalho contendo nome e email) ou NDJSON (um objeto por linha).
+// @Description  Cada linha é validada com as mesmas regras do cadastro; as válidas são gravadas em lotes
+// @Description  transacionais e as inválidas são listadas no relatório com o número da linha.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Accept       text/csv
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Param        arquivo  body      string  true  "Conteúdo do arquivo"
+// @Success      200      {object}  domain.RelatorioImportacao
+// @Failure      400      {object}  Problem
+// @Failure      413      {object}  Problem
+// @Failure      415      {object}  Problem
+// @Failure      401      {object}  Problem
+// @Failure      403      {object}  Problem
+// @Failure      429      {object}  Problem
+// @Failure      500      {object}  Problem
+// @Router       /usuarios/import [post]
+func (h *UsuarioHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+
+	formato, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var leitor service.LeitorUsuarios
+	switch formato {
+	case "text/csv":
+		l, err := newCSVLeitor(r.Body)
+		if err != nil {
+			respondWithProblem(w, r, err)
+			return
+		}
+		leitor = l
+	case "application/x-ndjson", "application/ndjson":
+		leitor = newNDJSONLeitor(r.Body)
+	default:
+		respondWithProblem(w, r, errFormatoNaoSuportado.WithDetail("use text/csv ou application/x-ndjson"))
+		return
+	}
+
+	relatorio, err := h.service.ImportUsers(r.Context(), leitor)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	logging.Add(r.Context(), "import_importados", relatorio.Importados, "import_rejeitados", relatorio.Rejeitados)
+	respondWithJSON(w, http.StatusOK, relatorio)
+}
+
+// @Summary      Exporta usuários
+// @Description  Transmite os usuários em CSV ou NDJSON, em ordem de ID, sem montar a lista inteira em memória.
+// @Description  Aceita os mesmos filtros da listagem.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Param        format         query     string  false  "csv (padrão) ou ndjson"
+// @Param        created_after  query     string  false  "Cadastrados depois deste instante (RFC 3339)"
+// @Param        updated_since  query     string  false  "Alterados neste instante ou depois (RFC 3339)"
+// @Success      200  {string}  string  "Arquivo exportado"
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /usuarios/export [get]
+func (h *UsuarioHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseFiltroUsuarios(r)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	var escritor exportador
+	switch formato := r.URL.Query().Get("format"); formato {
+	case "", "csv":
+		escritor = newCSVExportador(w)
+	case "ndjson":
+		escritor = newNDJSONExportador(w)
+	default:
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("parâmetros de exportação inválidos").WithFields(domain.FieldError{
+			Field:   "format",
+			Code:    "formato_invalido",
+			Message: "use csv ou ndjson",
+		}))
+		return
+	}
+
+	// O cabeçalho só é enviado junto com o primeiro usuário: se a consulta falhar antes
+	// disso, ainda dá para responder com um problem+json.
+	rc := http.NewResponseController(w)
+	iniciado := false
+	total := 0
+	err = h.service.ExportUsers(r.Context(), filtro, func(u domain.Usuario) error {
+		if !iniciado {
+			if err := escritor.iniciar(); err != nil {
+				return err
+			}
+			iniciado = true
+		}
+		if err := escritor.escrever(u); err != nil {
+			return err
+		}
+		total++
+		if total%exportFlushEvery == 0 {
+			if err := escritor.flush(); err != nil {
+				return err
+			}
+			// Nem todo ResponseWriter suporta flush; nesse caso a resposta só fica em buffer por mais tempo.
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !iniciado {
+		respondWithProblem(w, r, err)
+		return
+	}
+	if err != nil {
+		// Com a resposta já em andamento não há como trocar o status: registramos o erro
+		// e encerramos a conexão, para o cliente perceber que o arquivo está incompleto.
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "Erro durante a exportação de usuários", "error", err, "exportados", total)
+		panic(http.ErrAbortHandler)
+	}
+	if !iniciado {
+		// Nenhum usuário no filtro: o arquivo sai só com o cabeçalho (no CSV) ou vazio.
+		escritor.iniciar()
+	}
+	escritor.flush()
+	logging.Add(r.Context(), "exportados", total)
+}
+
+// --- LEITORES DE IMPORTAÇÃO ---
+
+// csvLeitor lê usuários de um CSV cujo cabeçalho indica a posição das colunas nome e email.
+type csvLeitor struct {
+	r     *csv.Reader
+	nome  int
+	email int
+}
+
+func newCSVLeitor(body io.Reader) (*csvLeitor, error) {
+	r := csv.NewReader(body)
+	r.ReuseRecord = true
+	r.TrimLeadingSpace = true
+
+	cabecalho, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, domain.ErrRequisicaoInvalida.WithDetail("o arquivo CSV está vazio")
+	}
+	if err != nil {
+		return nil, erroLeituraImportacao(err)
+	}
+
+	l := &csvLeitor{r: r, nome: -1, email: -1}
+	conhecidas := make(map[string]bool, len(colunasExportacao)+len(colunasAntigas))
+	for _, c := range slices.Concat(colunasExportacao, colunasAntigas) {
+		conhecidas[c] = true
+	}
+	var erros []domain.FieldError
+	for i, coluna := range cabecalho {
+		coluna = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(coluna, "\ufeff")))
+		switch {
+		case coluna == "nome":
+			l.nome = i
+		case coluna == "email":
+			l.email = i
+		case !conhecidas[coluna]:
+			erros = append(erros, domain.FieldError{Field: coluna, Code: "coluna_desconhecida", Message: "coluna não reconhecida"})
+		}
+	}
+	for _, obrigatoria := range []struct {
+		nome   string
+		indice int
+	}{{"nome", l.nome}, {"email", l.email}} {
+		if obrigatoria.indice < 0 {
+			erros = append(erros, domain.FieldError{Field: obrigatoria.nome, Code: "coluna_ausente", Message: "coluna obrigatória no cabeçalho"})
+		}
+	}
+	if len(erros) > 0 {
+		return nil, domain.ErrRequisicaoInvalida.WithDetail("cabeçalho do CSV inválido").WithFields(erros...)
+	}
+	return l, nil
+}
+
+func (l *csvLeitor) Ler() (int, domain.Usuario, *domain.Error, error) {
+	registro, err := l.r.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, domain.Usuario{}, nil, io.EOF
+	}
+	// Número de colunas diferente do cabeçalho invalida só a linha; os demais erros de
+	// sintaxe (aspas malformadas, por exemplo) comprometem o restante do arquivo.
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+		return parseErr.StartLine, domain.Usuario{}, domain.ErrRequisicaoInvalida.WithDetail("número de colunas diferente do cabeçalho"), nil
+	}
+	if err != nil {
+		return 0, domain.Usuario{}, nil, erroLeituraImportacao(err)
+	}
+	linha, _ := l.r.FieldPos(0)
+	return linha, domain.Usuario{Nome: registro[l.nome], Email: registro[l.email]}, nil, nil
+}
+
+// ndjsonLeitor lê usuários de um arquivo com um objeto JSON por linha. Linhas em branco são ignoradas.
+type ndjsonLeitor struct {
+	s     *bufio.Scanner
+	linha int
+}
+
+func newNDJSONLeitor(body io.Reader) *ndjsonLeitor {
+	s := bufio.NewScanner(body)
+	s.Buffer(make([]byte, 0, 64*1024), int(maxJSONBodyBytes))
+	return &ndjsonLeitor{s: s}
+}
+
+func (l *ndjsonLeitor) Ler() (int, domain.Usuario, *domain.Error, error) {
+	for l.s.Scan() {
+		l.linha++
+		conteudo := bytes.TrimSpace(l.s.Bytes())
+		if len(conteudo) == 0 {
+			continue
+		}
+
+		// O formato é o mesmo da API (e da exportação): campos somente leitura são aceitos e ignorados.
+		var usuario domain.Usuario
+		dec := json.NewDecoder(bytes.NewReader(conteudo))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&usuario); err != nil {
+			var invalida *domain.Error
+			errors.As(traduzirErroJSON(err), &invalida)
+			return l.linha, domain.Usuario{}, invalida, nil
+		}
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			return l.linha, domain.Usuario{}, domain.ErrRequisicaoInvalida.WithDetail("cada linha deve conter um único objeto JSON"), nil
+		}
+		return l.linha, domain.Usuario{Nome: usuario.Nome, Email: usuario.Email}, nil, nil
+	}
+	if err := l.s.Err(); err != nil {
+		return 0, domain.Usuario{}, nil, erroLeituraImportacao(err)
+	}
+	return 0, domain.Usuario{}, nil, io.EOF
+}
+
+// erroLeituraImportacao converte os erros que impedem a leitura do restante do arquivo.
+func erroLeituraImportacao(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var parseErr *csv.ParseError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return errCorpoMuitoGrande.WithDetail(fmt.Sprintf("o limite é de %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &parseErr):
+		return domain.ErrRequisicaoInvalida.WithDetail(fmt.Sprintf("CSV malformado na linha %d: %v", parseErr.Line, parseErr.Err))
+	case errors.Is(err, bufio.ErrTooLong):
+		return errCorpoMuitoGrande.WithDetail(fmt.Sprintf("cada linha pode ter no máximo %d bytes", maxJSONBodyBytes))
+	default:
+		return domain.ErrRequisicaoInvalida.WithDetail("erro ao ler o arquivo").Wrap(err)
+	}
+}
+
+// --- EXPORTADORES ---
+
+type exportador interface {
+	iniciar() error
+	escrever(u domain.Usuario) error
+	flush() error
+}
+
+// formatarData escreve datas em RFC 3339 (UTC) e deixa vazias as datas não preenchidas.
+func formatarData(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+type csvExportador struct {
+	w   http.ResponseWriter
+	csv *csv.Writer
+}
+
+func newCSVExportador(w http.ResponseWriter) *csvExportador {
+	return &csvExportador{w: w, csv: csv.NewWriter(w)}
+}
+
+func (e *csvExportador) iniciar() error {
+	e.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	e.w.Header().Set("Content-Disposition", `attachment; filename="usuarios.csv"`)
+	e.w.WriteHeader(http.StatusOK)
+	return e.csv.Write(colunasExportacao)
+}
+
+func (e *csvExportador) escrever(u domain.Usuario) error {
+	return e.csv.Write([]string{
+		strconv.FormatInt(u.ID, 10),
+		u.Nome,
+		u.Email,
+		formatarData(u.CreatedAt),
+		formatarData(u.UpdatedAt),
+	})
+}
+
+func (e *csvExportador) flush() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+type ndjsonExportador struct {
+	w   http.ResponseWriter
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONExportador(w http.ResponseWriter) *ndjsonExportador {
+	buf := bufio.NewWriter(w)
+	return &ndjsonExportador{w: w, buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (e *ndjsonExportador) iniciar() error {
+	e.w.Header().Set("Content-Type", "application/x-ndjson")
+	e.w.Header().Set("Content-Disposition", `attachment; filename="usuarios.ndjson"`)
+	e.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// escrever usa o mesmo JSON da API; o Encoder já termina cada objeto com uma quebra de linha.
+func (e *ndjsonExportador) escrever(u domain.Usuario) error {
+	return e.enc.Encode(u)
+}
+
+func (e *ndjsonExportador) flush() error {
+	return e.buf.Flush()
+}
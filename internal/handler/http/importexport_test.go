@@ -0,0 +1,205 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// linhaLida guarda o que o leitor de importação entregou para uma linha do arquivo.
+type linhaLida struct {
+	Linha    int
+	Usuario  domain.Usuario
+	Invalida string
+}
+
+// lerTudo consome o leitor como o serviço faria, até o fim do arquivo ou um erro fatal.
+func lerTudo(leitor service.LeitorUsuarios) ([]linhaLida, error) {
+	var lidas []linhaLida
+	for {
+		linha, u, invalida, err := leitor.Ler()
+		if errors.Is(err, io.EOF) {
+			return lidas, nil
+		}
+		if err != nil {
+			return lidas, err
+		}
+		l := linhaLida{Linha: linha, Usuario: u}
+		if invalida != nil {
+			l.Invalida = invalida.Code
+		}
+		lidas = append(lidas, l)
+	}
+}
+
+func TestUsuarioHandler_ImportUsers(t *testing.T) {
+	importar := func(contentType, corpo string) (*httptest.ResponseRecorder, []linhaLida, error) {
+		var lidas []linhaLida
+		var errLeitura error
+		mockService := &MockUsuarioService{
+			ImportUsersFn: func(ctx context.Context, leitor service.LeitorUsuarios) (*domain.RelatorioImportacao, error) {
+				lidas, errLeitura = lerTudo(leitor)
+				return &domain.RelatorioImportacao{Total: len(lidas), Erros: []domain.ErroLinha{}}, errLeitura
+			},
+		}
+		req := httptest.NewRequest("POST", "/usuarios/import", strings.NewReader(corpo))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		NewUsuarioHandler(mockService).ImportUsers(rr, req)
+		return rr, lidas, errLeitura
+	}
+
+	t.Run("sucesso - csv com colunas em qualquer ordem e colunas da exportação ignoradas", func(t *testing.T) {
+		corpo := "email,id,Nome\r\nana@email.com,7,Ana Souza\r\n\"bia@email.com\",,\"Silva, Bia\"\r\n"
+
+		rr, lidas, err := importar("text/csv; charset=utf-8", corpo)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []linhaLida{
+			{Linha: 2, Usuario: domain.Usuario{Nome: "Ana Souza", Email: "ana@email.com"}},
+			{Linha: 3, Usuario: domain.Usuario{Nome: "Silva, Bia", Email: "bia@email.com"}},
+		}, lidas)
+	})
+
+	t.Run("sucesso - csv exportado antes, com as colunas da assinatura, continua importável", func(t *testing.T) {
+		corpo := "id,nome,email,subscription_status,subscription_current_period_end,created_at,updated_at\n" +
+			"1,Ana Souza,ana@email.com,active,,2024-05-01T12:30:00Z,2024-05-01T12:30:00Z\n"
+
+		_, lidas, err := importar("text/csv", corpo)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []linhaLida{{Linha: 2, Usuario: domain.Usuario{Nome: "Ana Souza", Email: "ana@email.com"}}}, lidas)
+	})
+
+	t.Run("sucesso - linha csv com colunas a mais é rejeitada sem interromper a leitura", func(t *testing.T) {
+		corpo := "nome,email\nAna,ana@email.com,extra\nBia,bia@email.com\n"
+
+		_, lidas, err := importar("text/csv", corpo)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []linhaLida{
+			{Linha: 2, Invalida: domain.ErrRequisicaoInvalida.Code},
+			{Linha: 3, Usuario: domain.Usuario{Nome: "Bia", Email: "bia@email.com"}},
+		}, lidas)
+	})
+
+	t.Run("sucesso - ndjson ignora linhas em branco e campos somente leitura", func(t *testing.T) {
+		corpo := `{"nome":"Ana","email":"ana@email.com","id":9,"papel":"admin"}` + "\n\n" +
+			`{"nome":"Bia","senha":"x"}` + "\n" +
+			`{"nome":` + "\n"
+
+		_, lidas, err := importar("application/x-ndjson", corpo)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []linhaLida{
+			{Linha: 1, Usuario: domain.Usuario{Nome: "Ana", Email: "ana@email.com"}},
+			{Linha: 3, Invalida: domain.ErrRequisicaoInvalida.Code},
+			{Linha: 4, Invalida: domain.ErrRequisicaoInvalida.Code},
+		}, lidas)
+	})
+
+	t.Run("erro - cabeçalho csv sem coluna obrigatória e com coluna desconhecida", func(t *testing.T) {
+		rr, _, _ := importar("text/csv", "nome,telefone\nAna,123\n")
+
+		var problem Problem
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, []domain.FieldError{
+			{Field: "telefone", Code: "coluna_desconhecida", Message: "coluna não reconhecida"},
+			{Field: "email", Code: "coluna_ausente", Message: "coluna obrigatória no cabeçalho"},
+		}, problem.Errors)
+	})
+
+	t.Run("erro - aspas malformadas interrompem a importação", func(t *testing.T) {
+		rr, _, err := importar("text/csv", "nome,email\nAna,ana@email.com\n\"Bia,bia@email.com\nCai\"o,caio@email.com\n")
+
+		assert.True(t, errors.Is(err, domain.ErrRequisicaoInvalida))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("erro - formato não suportado", func(t *testing.T) {
+		rr, _, _ := importar("application/json", `[{"nome":"Ana"}]`)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+}
+
+func TestUsuarioHandler_ExportUsers(t *testing.T) {
+	criado := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	usuarios := []domain.Usuario{
+		{ID: 1, Nome: "Ana Souza", Email: "ana@email.com", CreatedAt: criado, UpdatedAt: criado},
+		{ID: 2, Nome: "Silva, Bia", Email: "bia@email.com", CreatedAt: criado, UpdatedAt: criado},
+	}
+	mockService := &MockUsuarioService{
+		ExportUsersFn: func(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) error {
+			for _, u := range usuarios {
+				if err := fn(u); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	t.Run("sucesso - csv com cabeçalho e datas em RFC 3339", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/usuarios/export", nil)
+		rr := httptest.NewRecorder()
+
+		NewUsuarioHandler(mockService).ExportUsers(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "id,nome,email,created_at,updated_at\n"+
+			"1,Ana Souza,ana@email.com,2024-05-01T12:30:00Z,2024-05-01T12:30:00Z\n"+
+			"2,\"Silva, Bia\",bia@email.com,2024-05-01T12:30:00Z,2024-05-01T12:30:00Z\n", rr.Body.String())
+	})
+
+	t.Run("sucesso - ndjson com um objeto por linha", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/usuarios/export?format=ndjson", nil)
+		rr := httptest.NewRecorder()
+
+		NewUsuarioHandler(mockService).ExportUsers(rr, req)
+
+		linhas := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+		assert.Len(t, linhas, 2)
+		var u domain.Usuario
+		assert.NoError(t, json.Unmarshal([]byte(linhas[1]), &u))
+		assert.Equal(t, "Silva, Bia", u.Nome)
+	})
+
+	t.Run("erro - falha antes do primeiro usuário responde com problem+json", func(t *testing.T) {
+		falha := &MockUsuarioService{
+			ExportUsersFn: func(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) error {
+				return errors.New("banco indisponível")
+			},
+		}
+		req := httptest.NewRequest("GET", "/usuarios/export", nil)
+		rr := httptest.NewRecorder()
+
+		NewUsuarioHandler(falha).ExportUsers(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("erro - formato desconhecido", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/usuarios/export?format=xlsx", nil)
+		rr := httptest.NewRecorder()
+
+		NewUsuarioHandler(mockService).ExportUsers(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
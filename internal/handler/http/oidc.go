@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// cookieStateOIDC amarra o login ao navegador que o iniciou: o callback só é aceito se o
+// state devolvido pelo provedor for o mesmo do cookie (proteção contra login CSRF).
+const cookieStateOIDC = "oidc_state"
+
+// OIDCService é a interface do serviço de login OIDC usada pelo OIDCHandler.
+type OIDCService interface {
+	StartOIDCLogin(ctx context.Context, provedor string) (url string, state string, err error)
+	CompleteOIDCLogin(ctx context.Context, provedor, state, codigo string, origem domain.OrigemSessao) (*domain.Login, error)
+}
+
+// OIDCHandler gerencia as rotas de /auth/oidc, abertas ao navegador do usuário.
+type OIDCHandler struct {
+	service OIDCService
+	limiter *RateLimiter
+}
+
+// NewOIDCHandler cria uma nova instância do OIDCHandler.
+func NewOIDCHandler(s OIDCService) *OIDCHandler {
+	return &OIDCHandler{service: s}
+}
+
+// WithRateLimiter aplica os limites de requisição às rotas de /auth/oidc. Sem ele, as rotas não são limitadas.
+func (h *OIDCHandler) WithRateLimiter(l *RateLimiter) *OIDCHandler {
+	h.limiter = l
+	return h
+}
+
+// Routes define as rotas de /auth/oidc.
+func (h *OIDCHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.With(h.limiter.Limitar("GET /auth/oidc/{provedor}/login", PorCliente)).Get("/{provedor}/login", h.Login)          // GET /auth/oidc/{provedor}/login
+	r.With(h.limiter.Limitar("GET /auth/oidc/{provedor}/callback", PorCliente)).Get("/{provedor}/callback", h.Callback) // GET /auth/oidc/{provedor}/callback
+	return r
+}
+
+// @Summary      Inicia o login por um provedor OIDC
+// @Description  Redireciona o navegador para o provedor (ex: google), com state, nonce e PKCE.
+// @Description  O login deve ser concluído em até 10 minutos.
+// @Tags         auth
+// @Param        provedor  path      string  true  "Nome do provedor configurado em OIDC_PROVIDERS"
+// @Success      302       {string}  string "Redirecionamento para o provedor"
+// @Failure      404       {object}  Problem
+// @Failure      429       {object}  Problem
+// @Failure      502       {object}  Problem
+// @Router       /auth/oidc/{provedor}/login [get]
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	provedor := chi.URLParam(r, "provedor")
+	url, state, err := h.service.StartOIDCLogin(r.Context(), provedor)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieStateOIDC,
+		Value:    state,
+		Path:     "/auth/oidc/" + provedor,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // Lax: o cookie precisa voltar no redirecionamento do provedor.
+	})
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// @Summary      Conclui o login por um provedor OIDC
+// @Description  Recebe o redirecionamento do provedor. A conta do provedor é vinculada ao usuário
+// @Description  pela identidade já conhecida, pelo e-mail verificado pelo provedor ou, se não houver
+// @Description  usuário com o e-mail, a um novo usuário. Devolve o token de acesso do usuário.
+// @Tags         auth
+// @Produce      json
+// @Param        provedor  path      string  true  "Nome do provedor"
+// @Param        state     query     string  true  "State enviado na autorização"
+// @Param        code      query     string  true  "Código de autorização"
+// @Success      200       {object}  domain.Login
+// @Failure      400       {object}  Problem
+// @Failure      403       {object}  Problem
+// @Failure      404       {object}  Problem
+// @Failure      429       {object}  Problem
+// @Failure      502       {object}  Problem
+// @Router       /auth/oidc/{provedor}/callback [get]
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provedor := chi.URLParam(r, "provedor")
+	q := r.URL.Query()
+	// O cookie é de uso único, qualquer que seja o resultado.
+	http.SetCookie(w, &http.Cookie{Name: cookieStateOIDC, Path: "/auth/oidc/" + provedor, MaxAge: -1, HttpOnly: true, Secure: r.TLS != nil})
+
+	if erro := q.Get("error"); erro != "" {
+		// O usuário negou o acesso ou o provedor recusou o pedido.
+		respondWithProblem(w, r, service.ErrLoginOIDCInvalido.
+			WithDetail("o provedor recusou o login: "+erro).
+			WithDetails(map[string]any{"erro_provedor": erro, "descricao_provedor": q.Get("error_description")}))
+		return
+	}
+	state := q.Get("state")
+	cookie, err := r.Cookie(cookieStateOIDC)
+	if state == "" || err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		respondWithProblem(w, r, service.ErrLoginOIDCInvalido.WithDetail("o login não foi iniciado neste navegador ou expirou; comece de novo"))
+		return
+	}
+
+	login, err := h.service.CompleteOIDCLogin(r.Context(), provedor, state, q.Get("code"), origemSessao(r, ""))
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, login)
+}
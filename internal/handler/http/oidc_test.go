@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// MockOIDCService inicia todo login com o state "s1" e conclui com o código "codigo".
+type MockOIDCService struct{}
+
+func (m *MockOIDCService) StartOIDCLogin(ctx context.Context, provedor string) (string, string, error) {
+	if provedor != "google" {
+		return "", "", service.ErrProvedorOIDCDesconhecido
+	}
+	return "https://provedor/authorize?state=s1", "s1", nil
+}
+
+func (m *MockOIDCService) CompleteOIDCLogin(ctx context.Context, provedor, state, codigo string, origem domain.OrigemSessao) (*domain.Login, error) {
+	if codigo != "codigo" {
+		return nil, service.ErrLoginOIDCInvalido
+	}
+	return &domain.Login{UsuarioID: 7, AccessToken: "acesso", TokenType: "Bearer", ExpiresIn: 3600}, nil
+}
+
+func TestOIDCHandler(t *testing.T) {
+	requisicao := func(alvo string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", alvo, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		NewOIDCHandler(&MockOIDCService{}).Routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - redireciona ao provedor e conclui o login com o cookie do state", func(t *testing.T) {
+		rr := requisicao("/google/login", nil)
+
+		require.Equal(t, http.StatusFound, rr.Code)
+		assert.Equal(t, "https://provedor/authorize?state=s1", rr.Header().Get("Location"))
+		cookies := rr.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "s1", cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+
+		rr = requisicao("/google/callback?state=s1&code=codigo", cookies[0])
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var login domain.Login
+		json.NewDecoder(rr.Body).Decode(&login)
+		assert.Equal(t, "acesso", login.AccessToken)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("erro - callback sem o cookie ou com outro state", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, requisicao("/google/callback?state=s1&code=codigo", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, requisicao("/google/callback?state=s2&code=codigo", &http.Cookie{Name: cookieStateOIDC, Value: "s1"}).Code)
+	})
+
+	t.Run("erro - acesso negado no provedor", func(t *testing.T) {
+		rr := requisicao("/google/callback?error=access_denied&state=s1", &http.Cookie{Name: cookieStateOIDC, Value: "s1"})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		assert.Equal(t, "access_denied", problem.Details["erro_provedor"])
+	})
+
+	t.Run("erro - provedor não configurado", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, requisicao("/github/login", nil).Code)
+	})
+}
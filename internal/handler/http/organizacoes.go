@@ -0,0 +1,519 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/willjrcristo/go-sqlite-db/internal/auth"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+// OrganizacaoService é a interface do serviço de organizações usada pelo OrganizacaoHandler.
+type OrganizacaoService interface {
+	CreateOrganization(ctx context.Context, sol domain.Solicitante, nome string) (*domain.Organizacao, error)
+	ListOrganizations(ctx context.Context, sol domain.Solicitante) ([]domain.Organizacao, error)
+	GetOrganization(ctx context.Context, sol domain.Solicitante, id int64) (*domain.Organizacao, error)
+	ListMembers(ctx context.Context, sol domain.Solicitante, id int64) ([]domain.Membro, error)
+	RemoveMember(ctx context.Context, sol domain.Solicitante, id, usuarioID int64) error
+	InviteMember(ctx context.Context, sol domain.Solicitante, id int64, email, papel string) (*domain.Convite, error)
+	ListInvitations(ctx context.Context, sol domain.Solicitante, id int64) ([]domain.Convite, error)
+	RevokeInvitation(ctx context.Context, sol domain.Solicitante, id, conviteID int64) error
+	AcceptInvitation(ctx context.Context, sol domain.Solicitante, token string) (*domain.Organizacao, error)
+	CreateCheckoutSession(ctx context.Context, sol domain.Solicitante, id int64, pedido domain.PedidoCheckout) (string, error)
+	UpdateSeats(ctx context.Context, sol domain.Solicitante, id, assentos int64) (*domain.Organizacao, error)
+}
+
+// CreateOrganizationRequest é o corpo do POST /organizations.
+type CreateOrganizationRequest struct {
+	Nome string `json:"nome" example:"Acme Ltda"`
+}
+
+// InviteMemberRequest é o corpo do POST /organizations/{id}/invitations.
+type InviteMemberRequest struct {
+	Email string `json:"email" example:"bia@email.com"`
+	Papel string `json:"papel,omitempty" example:"membro"` // admin ou membro (padrão).
+}
+
+// AcceptInvitationRequest é o corpo do POST /organizations/invitations/accept.
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// AssentosRequest é o corpo do PUT /organizations/{id}/seats.
+type AssentosRequest struct {
+	Assentos int64 `json:"assentos" example:"5"`
+}
+
+// CheckoutRequest é o corpo (opcional) do POST /organizations/{id}/checkout.
+type CheckoutRequest struct {
+	Assentos          int64  `json:"assentos,omitempty" example:"5"`
+	CodigoPromocional string `json:"codigo_promocional,omitempty" example:"BEMVINDO10"`
+}
+
+// OrganizacaoHandler gerencia as rotas de /organizations. Todas exigem o token de acesso de
+// um usuário, que só enxerga as organizações de que é membro, ou uma credencial com o escopo
+// billing:write, que age sobre qualquer uma (veja domain.Solicitante).
+type OrganizacaoHandler struct {
+	service      OrganizacaoService
+	autenticacao func(http.Handler) http.Handler
+	limiter      *RateLimiter
+	idempotencia func(http.Handler) http.Handler
+	medicao      func(http.Handler) http.Handler
+}
+
+// NewOrganizacaoHandler cria uma nova instância do OrganizacaoHandler.
+func NewOrganizacaoHandler(s OrganizacaoService, a Autenticador, u AutenticadorUsuarios) *OrganizacaoHandler {
+	return &OrganizacaoHandler{service: s, autenticacao: Autenticar(a, u)}
+}
+
+// WithRateLimiter aplica os limites de requisição às rotas de /organizations. Sem ele, as rotas não são limitadas.
+func (h *OrganizacaoHandler) WithRateLimiter(l *RateLimiter) *OrganizacaoHandler {
+	h.limiter = l
+	return h
+}
+
+// WithIdempotencia passa a aceitar Idempotency-Key em POST /organizations e no checkout,
+// guardando as respostas por ttl.
+func (h *OrganizacaoHandler) WithIdempotencia(store IdempotenciaStore, ttl time.Duration) *OrganizacaoHandler {
+	h.idempotencia = Idempotente(store, ttl)
+	return h
+}
+
+// WithMedicao passa a medir as chamadas feitas com o token de acesso de um usuário (veja MedirUso).
+func (h *OrganizacaoHandler) WithMedicao(m Medidor) *OrganizacaoHandler {
+	h.medicao = MedirUso(m)
+	return h
+}
+
+// medido aplica o middleware de medição de consumo, se configurado.
+func (h *OrganizacaoHandler) medido(next http.Handler) http.Handler {
+	if h.medicao == nil {
+		return next
+	}
+	return h.medicao(next)
+}
+
+// idempotente aplica o middleware de Idempotency-Key, se configurado.
+func (h *OrganizacaoHandler) idempotente(next http.Handler) http.Handler {
+	if h.idempotencia == nil {
+		return next
+	}
+	return h.idempotencia(next)
+}
+
+// Routes define as rotas de /organizations.
+func (h *OrganizacaoHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.autenticacao)
+	r.Use(h.medido)
+
+	r.With(h.limiter.Limitar("POST /organizations", PorCliente)).With(h.idempotente).Post("/", h.CreateOrganization) // POST /organizations
+	r.With(h.limiter.Limitar("GET /organizations", PorCliente)).Get("/", h.ListOrganizations)                        // GET /organizations
+	r.With(h.limiter.Limitar("POST /organizations/invitations/accept", PorCliente)).Post("/invitations/accept", h.AcceptInvitation)
+
+	r.Group(func(r chi.Router) {
+		r.Use(logOrganizacaoID)
+		r.With(h.limiter.Limitar("GET /organizations/{id}", PorCliente)).Get("/{id}", h.GetOrganization)
+		r.With(h.limiter.Limitar("GET /organizations/{id}/members", PorCliente)).Get("/{id}/members", h.ListMembers)
+		r.With(h.limiter.Limitar("DELETE /organizations/{id}/members/{usuarioID}", PorCliente)).Delete("/{id}/members/{usuarioID}", h.RemoveMember)
+		r.With(h.limiter.Limitar("POST /organizations/{id}/invitations", PorCliente)).Post("/{id}/invitations", h.InviteMember)
+		r.With(h.limiter.Limitar("GET /organizations/{id}/invitations", PorCliente)).Get("/{id}/invitations", h.ListInvitations)
+		r.With(h.limiter.Limitar("DELETE /organizations/{id}/invitations/{conviteID}", PorCliente)).Delete("/{id}/invitations/{conviteID}", h.RevokeInvitation)
+		// O checkout e a troca de assentos chamam a Stripe: são limitados por organização,
+		// independentemente de quem os dispara.
+		r.With(h.limiter.Limitar("POST /organizations/{id}/checkout", PorOrganizacao)).With(h.idempotente).Post("/{id}/checkout", h.CreateCheckoutSession)
+		r.With(h.limiter.Limitar("PUT /organizations/{id}/seats", PorOrganizacao)).Put("/{id}/seats", h.UpdateSeats)
+	})
+	return r
+}
+
+// @Summary      Cria uma organização
+// @Description  Cria a organização com o usuário do token de acesso como dono. A assinatura é
+// @Description  contratada depois, em /organizations/{id}/checkout.
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        organizacao      body      CreateOrganizationRequest  true   "Nome da organização"
+// @Param        Idempotency-Key  header    string                     false  "Chave para repetir a requisição com segurança (a resposta fica guardada por 24h)"
+// @Success      201              {object}  domain.Organizacao
+// @Failure      400              {object}  Problem
+// @Failure      401              {object}  Problem
+// @Failure      403              {object}  Problem
+// @Failure      429              {object}  Problem
+// @Failure      500              {object}  Problem
+// @Router       /organizations [post]
+func (h *OrganizacaoHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req CreateOrganizationRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	org, err := h.service.CreateOrganization(r.Context(), solicitante(r), req.Nome)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "Organização criada", "organizacao_id", org.ID)
+	respondWithJSON(w, http.StatusCreated, org)
+}
+
+// @Summary      Lista as organizações do usuário
+// @Description  Devolve as organizações de que o usuário do token de acesso é membro, com o papel dele em cada uma.
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Success      200  {array}   domain.Organizacao
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /organizations [get]
+func (h *OrganizacaoHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.service.ListOrganizations(r.Context(), solicitante(r))
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, orgs)
+}
+
+// @Summary      Busca uma organização
+// @Description  Devolve a organização com a assinatura e os assentos contratados e em uso.
+// @Description  Organizações de que o usuário não é membro respondem 404.
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id   path      int  true  "ID da organização"
+// @Success      200  {object}  domain.Organizacao
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /organizations/{id} [get]
+func (h *OrganizacaoHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+
+	org, err := h.service.GetOrganization(r.Context(), solicitante(r), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, org)
+}
+
+// @Summary      Lista os membros de uma organização
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id   path      int  true  "ID da organização"
+// @Success      200  {array}   domain.Membro
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /organizations/{id}/members [get]
+func (h *OrganizacaoHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+
+	membros, err := h.service.ListMembers(r.Context(), solicitante(r), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, membros)
+}
+
+// @Summary      Remove um membro de uma organização
+// @Description  Libera o assento do membro. O dono e os admins removem qualquer membro, exceto o
+// @Description  dono; os demais só podem remover a si mesmos (sair da organização).
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Param        id         path      int  true  "ID da organização"
+// @Param        usuarioID  path      int  true  "ID do usuário"
+// @Success      204        {string}  string "No Content"
+// @Failure      400        {object}  Problem
+// @Failure      401        {object}  Problem
+// @Failure      403        {object}  Problem
+// @Failure      404        {object}  Problem
+// @Failure      409        {object}  Problem
+// @Failure      429        {object}  Problem
+// @Failure      500        {object}  Problem
+// @Router       /organizations/{id}/members/{usuarioID} [delete]
+func (h *OrganizacaoHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+	usuarioID, err := strconv.ParseInt(chi.URLParam(r, "usuarioID"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID do usuário inválido"))
+		return
+	}
+
+	if err := h.service.RemoveMember(r.Context(), solicitante(r), id, usuarioID); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "Membro removido da organização", "usuario_id", usuarioID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Convida alguém para uma organização
+// @Description  Envia por e-mail o link de aceite, válido por 7 dias. O convite ocupa um assento
+// @Description  enquanto estiver pendente; sem assentos livres na assinatura, responde 409
+// @Description  sem_assentos. Convidar de novo o mesmo e-mail substitui o convite anterior.
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                  true  "ID da organização"
+// @Param        convite  body      InviteMemberRequest  true  "E-mail e papel do convidado"
+// @Success      201      {object}  domain.Convite
+// @Failure      400      {object}  Problem
+// @Failure      401      {object}  Problem
+// @Failure      403      {object}  Problem
+// @Failure      404      {object}  Problem
+// @Failure      409      {object}  Problem
+// @Failure      429      {object}  Problem
+// @Failure      500      {object}  Problem
+// @Router       /organizations/{id}/invitations [post]
+func (h *OrganizacaoHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+	var req InviteMemberRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	convite, err := h.service.InviteMember(r.Context(), solicitante(r), id, req.Email, req.Papel)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "Convite enviado", "convite_id", convite.ID)
+	respondWithJSON(w, http.StatusCreated, convite)
+}
+
+// @Summary      Lista os convites pendentes de uma organização
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id   path      int  true  "ID da organização"
+// @Success      200  {array}   domain.Convite
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /organizations/{id}/invitations [get]
+func (h *OrganizacaoHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+
+	convites, err := h.service.ListInvitations(r.Context(), solicitante(r), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, convites)
+}
+
+// @Summary      Revoga um convite pendente
+// @Description  O link enviado deixa de valer e o assento é liberado.
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Param        id         path      int  true  "ID da organização"
+// @Param        conviteID  path      int  true  "ID do convite"
+// @Success      204        {string}  string "No Content"
+// @Failure      400        {object}  Problem
+// @Failure      401        {object}  Problem
+// @Failure      403        {object}  Problem
+// @Failure      404        {object}  Problem
+// @Failure      429        {object}  Problem
+// @Failure      500        {object}  Problem
+// @Router       /organizations/{id}/invitations/{conviteID} [delete]
+func (h *OrganizacaoHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+	conviteID, err := strconv.ParseInt(chi.URLParam(r, "conviteID"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID do convite inválido"))
+		return
+	}
+
+	if err := h.service.RevokeInvitation(r.Context(), solicitante(r), id, conviteID); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Aceita um convite
+// @Description  Recebe o token do link enviado por e-mail e torna o usuário do token de acesso
+// @Description  membro da organização. O usuário precisa ter o e-mail convidado, já verificado.
+// @Tags         organizations
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        token  body      AcceptInvitationRequest  true  "Token recebido por e-mail"
+// @Success      200    {object}  domain.Organizacao
+// @Failure      400    {object}  Problem
+// @Failure      401    {object}  Problem
+// @Failure      403    {object}  Problem
+// @Failure      429    {object}  Problem
+// @Failure      500    {object}  Problem
+// @Router       /organizations/invitations/accept [post]
+func (h *OrganizacaoHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req AcceptInvitationRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	org, err := h.service.AcceptInvitation(r.Context(), solicitante(r), req.Token)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "Convite aceito", "organizacao_id", org.ID)
+	respondWithJSON(w, http.StatusOK, org)
+}
+
+// @Summary      Cria uma sessão de checkout na Stripe para a organização
+// @Description  Gera uma URL de pagamento para a organização assinar, com um assento por membro e
+// @Description  convite pendente ou a quantidade informada, se for maior. O corpo é opcional.
+// @Description  Só o dono e os admins podem assinar, e o e-mail do dono precisa estar verificado.
+// @Description  O codigo_promocional, se informado, precisa ser um código ativo da Stripe e já vem
+// @Description  aplicado; sem ele, o código pode ser digitado na página de pagamento. A moeda é a
+// @Description  da organização, se já assinou, ou a do país do endereço de cobrança do dono ou,
+// @Description  sem ele, a da região do Accept-Language.
+// @Tags         assinaturas
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id               path      int              true   "ID da organização"
+// @Param        checkout         body      CheckoutRequest  false  "Assentos a contratar e código promocional"
+// @Param        Accept-Language  header    string           false  "Idioma do cliente (ex: pt-BR), usado na escolha da moeda"
+// @Param        Idempotency-Key  header    string           false  "Chave para repetir a requisição com segurança (a resposta fica guardada por 24h)"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Router       /organizations/{id}/checkout [post]
+func (h *OrganizacaoHandler) CreateCheckoutSession(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+	var req CheckoutRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &req); err != nil {
+			respondWithProblem(w, r, err)
+			return
+		}
+	}
+
+	pedido := domain.PedidoCheckout{Assentos: req.Assentos, CodigoPromocional: req.CodigoPromocional, Idioma: r.Header.Get("Accept-Language")}
+	checkoutURL, err := h.service.CreateCheckoutSession(r.Context(), solicitante(r), id, pedido)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"checkout_url": checkoutURL})
+}
+
+// @Summary      Altera os assentos da assinatura
+// @Description  Muda a quantidade da assinatura ativa na Stripe, com cobrança proporcional. Não
+// @Description  pode ficar abaixo dos assentos em uso (membros e convites pendentes).
+// @Tags         assinaturas
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int              true  "ID da organização"
+// @Param        assentos  body      AssentosRequest  true  "Nova quantidade de assentos"
+// @Success      200       {object}  domain.Organizacao
+// @Failure      400       {object}  Problem
+// @Failure      401       {object}  Problem
+// @Failure      403       {object}  Problem
+// @Failure      404       {object}  Problem
+// @Failure      409       {object}  Problem
+// @Failure      429       {object}  Problem
+// @Failure      500       {object}  Problem
+// @Failure      502       {object}  Problem
+// @Router       /organizations/{id}/seats [put]
+func (h *OrganizacaoHandler) UpdateSeats(w http.ResponseWriter, r *http.Request) {
+	id, ok := idOrganizacao(w, r)
+	if !ok {
+		return
+	}
+	var req AssentosRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	org, err := h.service.UpdateSeats(r.Context(), solicitante(r), id, req.Assentos)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "Assentos da assinatura alterados", "assentos", org.Assentos)
+	respondWithJSON(w, http.StatusOK, org)
+}
+
+// solicitante descreve quem fez a requisição autenticada: o usuário do token de acesso e se a
+// credencial tem o escopo billing:write.
+func solicitante(r *http.Request) domain.Solicitante {
+	p, _ := auth.FromContext(r.Context())
+	sol := domain.Solicitante{Operador: p.Possui(domain.EscopoBillingEscrita)}
+	if p.Tipo == auth.TipoUsuario {
+		sol.UsuarioID, _ = strconv.ParseInt(p.ID, 10, 64)
+	}
+	return sol
+}
+
+// idOrganizacao lê o ID da organização da URL. Se for inválido, já responde 400 e devolve false.
+func idOrganizacao(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID da organização inválido"))
+		return 0, false
+	}
+	return id, true
+}
+
+// logOrganizacaoID adiciona o ID da organização da URL ao logger da requisição.
+func logOrganizacaoID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.Add(r.Context(), "organizacao_id", chi.URLParam(r, "id"))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/ratelimit"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// MockOrganizacaoService registra o solicitante de cada chamada e responde com a organização 1.
+type MockOrganizacaoService struct {
+	OrganizacaoService
+	solicitantes []domain.Solicitante
+	pedido       domain.PedidoCheckout
+}
+
+func (m *MockOrganizacaoService) CreateOrganization(ctx context.Context, sol domain.Solicitante, nome string) (*domain.Organizacao, error) {
+	m.solicitantes = append(m.solicitantes, sol)
+	return &domain.Organizacao{ID: 1, Nome: nome, Papel: domain.PapelOrgDono}, nil
+}
+
+func (m *MockOrganizacaoService) GetOrganization(ctx context.Context, sol domain.Solicitante, id int64) (*domain.Organizacao, error) {
+	m.solicitantes = append(m.solicitantes, sol)
+	if id != 1 {
+		return nil, service.ErrOrganizacaoNaoEncontrada
+	}
+	return &domain.Organizacao{ID: 1, Nome: "Acme"}, nil
+}
+
+func (m *MockOrganizacaoService) RemoveMember(ctx context.Context, sol domain.Solicitante, id, usuarioID int64) error {
+	return service.ErrDonoNaoRemovivel
+}
+
+func (m *MockOrganizacaoService) CreateCheckoutSession(ctx context.Context, sol domain.Solicitante, id int64, pedido domain.PedidoCheckout) (string, error) {
+	m.pedido = pedido
+	return "https://checkout.stripe.com/c/pay/cs_test", nil
+}
+
+func TestOrganizacaoHandler(t *testing.T) {
+	apiKeys := novoMockAPIKeys()
+	apiKeys.keys["usk_billing_x"] = domain.APIKey{ID: 3, Escopos: []string{domain.EscopoBillingEscrita}}
+	usuarios := mockUsuariosAutenticados{"token-ana": {ID: 7, Papel: domain.PapelUsuario}}
+	novoRouter := func(s *MockOrganizacaoService, l *RateLimiter) http.Handler {
+		return NewOrganizacaoHandler(s, apiKeys, usuarios).WithRateLimiter(l).Routes()
+	}
+	requisicao := func(router http.Handler, metodo, alvo, credencial, corpo string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(metodo, alvo, strings.NewReader(corpo))
+		if credencial != "" {
+			req.Header.Set("Authorization", "Bearer "+credencial)
+		}
+		if corpo != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - identifica o usuário do token e as credenciais de billing", func(t *testing.T) {
+		mockService := &MockOrganizacaoService{}
+		router := novoRouter(mockService, nil)
+
+		rr := requisicao(router, "POST", "/", "token-ana", `{"nome":"Acme"}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		var org domain.Organizacao
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &org))
+		assert.Equal(t, "Acme", org.Nome)
+
+		assert.Equal(t, http.StatusOK, requisicao(router, "GET", "/1", "usk_billing_x", "").Code)
+		assert.Equal(t, []domain.Solicitante{{UsuarioID: 7}, {Operador: true}}, mockService.solicitantes)
+	})
+
+	t.Run("erro - sem credencial, ID inválido e erros do serviço", func(t *testing.T) {
+		router := novoRouter(&MockOrganizacaoService{}, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, requisicao(router, "GET", "/1", "", "").Code)
+		assert.Equal(t, http.StatusBadRequest, requisicao(router, "GET", "/abc", "token-ana", "").Code)
+		assert.Equal(t, http.StatusBadRequest, requisicao(router, "DELETE", "/1/members/abc", "token-ana", "").Code)
+		assert.Equal(t, http.StatusNotFound, requisicao(router, "GET", "/2", "token-ana", "").Code)
+
+		rr := requisicao(router, "DELETE", "/1/members/7", "token-ana", "")
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		assert.Equal(t, "dono_nao_removivel", problem.Code)
+	})
+
+	t.Run("sucesso - checkout com corpo opcional, limitado por organização", func(t *testing.T) {
+		mockService := &MockOrganizacaoService{}
+		limiter := NewRateLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limite{
+			"POST /organizations/{id}/checkout": {Requisicoes: 1, Periodo: time.Minute},
+			RegraPadrao:                         {Requisicoes: 10, Periodo: time.Minute},
+		})
+		router := novoRouter(mockService, limiter)
+
+		rr := requisicao(router, "POST", "/1/checkout", "token-ana", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "checkout_url")
+		assert.Equal(t, http.StatusTooManyRequests, requisicao(router, "POST", "/1/checkout", "usk_billing_x", `{"assentos":3}`).Code)
+
+		assert.Equal(t, http.StatusOK, requisicao(router, "POST", "/2/checkout", "token-ana", `{"assentos":3,"codigo_promocional":"BEMVINDO10"}`).Code)
+		assert.Equal(t, domain.PedidoCheckout{Assentos: 3, CodigoPromocional: "BEMVINDO10"}, mockService.pedido)
+	})
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+// problemContentType é o media type definido pela RFC 7807 para respostas de erro.
+const problemContentType = "application/problem+json"
+
+// Problem é o corpo das respostas de erro da API, no formato da RFC 7807 (problem+json).
+// Além dos membros padrão, expõe o código estável do erro e, quando houver, os erros por campo.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Details   map[string]any      `json:"details,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+}
+
+// respondWithProblem traduz um erro em uma resposta application/problem+json.
+// Erros de domínio (*domain.Error), mesmo embrulhados, definem o status e o código;
+// qualquer outro erro é tratado como erro interno e seus detalhes não são expostos ao cliente.
+func respondWithProblem(w http.ResponseWriter, r *http.Request, err error) {
+	var de *domain.Error
+	if !errors.As(err, &de) {
+		de = domain.ErrInterno.Wrap(err)
+	}
+
+	// Erros do cliente (4xx) são avisos; só os erros do servidor (5xx) são registrados como erro.
+	level := slog.LevelWarn
+	if de.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logging.FromContext(r.Context()).Log(r.Context(), level, "API Error", "code", de.Code, "status", de.Status, "error", err)
+
+	reqID := middleware.GetReqID(r.Context())
+	problem := newProblem(de)
+	problem.RequestID = reqID
+	if reqID != "" {
+		problem.Instance = "urn:request:" + reqID
+	}
+
+	body, mErr := json.Marshal(problem)
+	if mErr != nil {
+		slog.Error("Failed to marshal problem response", "error", mErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(de.Status)
+	w.Write(body)
+}
+
+// newProblem monta o corpo problem+json de um erro de domínio, sem os membros
+// ligados à requisição (instance e request_id).
+func newProblem(de *domain.Error) Problem {
+	return Problem{
+		Type:    "/problems/" + de.Code,
+		Title:   de.Title,
+		Status:  de.Status,
+		Detail:  de.Detail,
+		Code:    de.Code,
+		Details: de.Details,
+		Errors:  de.Fields,
+	}
+}
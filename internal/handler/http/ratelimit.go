@@ -0,0 +1,164 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/ratelimit"
+)
+
+var errLimiteExcedido = &domain.Error{Code: "limite_excedido", Status: http.StatusTooManyRequests, Title: "limite de requisições excedido"}
+
+// RegraPadrao é a chave, nas regras do RateLimiter, do limite aplicado às rotas sem regra própria.
+const RegraPadrao = "*"
+
+// RegraFalhasAutenticacao é a chave, nas regras do RateLimiter, do limite de respostas 401
+// por IP aplicado por LimitarFalhasAutenticacao.
+const RegraFalhasAutenticacao = "falhas-autenticacao"
+
+// ChaveLimite identifica de quem é o bucket consumido por uma requisição.
+type ChaveLimite func(r *http.Request) string
+
+// PorCliente usa a identidade autenticada do cliente (ratelimit.WithCliente) quando houver
+// e, senão, o IP. Com middleware.RealIP antes, o RemoteAddr já é o IP real do cliente.
+func PorCliente(r *http.Request) string {
+	if cliente, ok := ratelimit.Cliente(r.Context()); ok {
+		return "cliente:" + cliente
+	}
+	return "ip:" + ipCliente(r)
+}
+
+// ipCliente devolve o IP de quem fez a requisição, sem a porta.
+func ipCliente(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr // O RealIP grava o IP sem porta.
+	}
+	return ip
+}
+
+// PorOrganizacao usa a organização da URL ({id}), para limitar ações sobre uma mesma
+// organização independentemente de quem as dispara.
+func PorOrganizacao(r *http.Request) string {
+	return "organizacao:" + chi.URLParam(r, "id")
+}
+
+// RateLimiter aplica limites por rota. As regras são indexadas pelo método e padrão da
+// rota (ex: "POST /organizations/{id}/checkout"), com RegraPadrao para as demais.
+type RateLimiter struct {
+	store  ratelimit.Store
+	regras map[string]ratelimit.Limite
+}
+
+// NewRateLimiter cria um RateLimiter sobre o store informado.
+func NewRateLimiter(store ratelimit.Store, regras map[string]ratelimit.Limite) *RateLimiter {
+	return &RateLimiter{store: store, regras: regras}
+}
+
+// Limitar devolve o middleware da rota, que consome uma ficha do bucket da chave a cada
+// requisição e informa o estado do limite nos cabeçalhos RateLimit-*. Sem regra para a rota
+// nem RegraPadrao, ou com RateLimiter nil, a rota não é limitada.
+func (l *RateLimiter) Limitar(rota string, chave ChaveLimite) func(http.Handler) http.Handler {
+	if l == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	limite, ok := l.regras[rota]
+	if !ok {
+		limite, ok = l.regras[RegraPadrao]
+	}
+	if !ok {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	janela := int(math.Ceil(limite.Periodo.Seconds()))
+	politica := fmt.Sprintf("%d;w=%d", limite.Requisicoes, janela)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := l.store.Take(r.Context(), rota+"|"+chave(r), limite)
+			if err != nil {
+				// Uma falha no store não deve derrubar a API: a requisição segue sem limite.
+				logging.FromContext(r.Context()).WarnContext(r.Context(), "Falha ao consultar o rate limit", "rota", rota, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", politica)
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limite.Requisicoes))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Restantes))
+			w.Header().Set("RateLimit-Reset", segundosCheios(res.Reset))
+
+			if !res.Permitido {
+				metrics.RateLimitRejected.WithLabelValues(rota).Inc()
+				w.Header().Set("Retry-After", segundosCheios(res.RetryAfter))
+				respondWithProblem(w, r, errLimiteExcedido.
+					WithDetail(fmt.Sprintf("o limite desta rota é de %d requisições a cada %ds; tente novamente em %ss", limite.Requisicoes, janela, segundosCheios(res.RetryAfter))).
+					WithDetails(map[string]any{"limite": limite.Requisicoes, "janela_segundos": janela}))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LimitarFalhasAutenticacao devolve o middleware que limita, por IP, as requisições que
+// terminam em 401, para que chaves de API, tokens e senhas não possam ser adivinhados por
+// tentativa e erro. Deve vir antes da autenticação: com o bucket vazio, a requisição é
+// recusada sem que a credencial seja conferida, senão a resposta revelaria se ela é válida.
+// Só as falhas consomem fichas, então clientes com credenciais válidas não são afetados.
+// Sem RegraFalhasAutenticacao nem RegraPadrao, ou com RateLimiter nil, nada é limitado.
+func (l *RateLimiter) LimitarFalhasAutenticacao() func(http.Handler) http.Handler {
+	if l == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	limite, ok := l.regras[RegraFalhasAutenticacao]
+	if !ok {
+		limite, ok = l.regras[RegraPadrao]
+	}
+	if !ok {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	janela := int(math.Ceil(limite.Periodo.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			chave := RegraFalhasAutenticacao + "|ip:" + ipCliente(r)
+			res, err := l.store.Consultar(r.Context(), chave, limite)
+			if err != nil {
+				logging.FromContext(r.Context()).WarnContext(r.Context(), "Falha ao consultar o rate limit", "rota", RegraFalhasAutenticacao, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !res.Permitido {
+				metrics.RateLimitRejected.WithLabelValues(RegraFalhasAutenticacao).Inc()
+				w.Header().Set("Retry-After", segundosCheios(res.RetryAfter))
+				respondWithProblem(w, r, errLimiteExcedido.
+					WithDetail(fmt.Sprintf("muitas falhas de autenticação: o limite é de %d a cada %ds; tente novamente em %ss", limite.Requisicoes, janela, segundosCheios(res.RetryAfter))).
+					WithDetails(map[string]any{"limite": limite.Requisicoes, "janela_segundos": janela}))
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			if ww.Status() != http.StatusUnauthorized {
+				return
+			}
+			if _, err := l.store.Take(r.Context(), chave, limite); err != nil {
+				logging.FromContext(r.Context()).WarnContext(r.Context(), "Falha ao registrar falha de autenticação no rate limit", "error", err)
+			}
+		})
+	}
+}
+
+// segundosCheios arredonda para cima, para que o cliente não tente de novo cedo demais.
+func segundosCheios(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
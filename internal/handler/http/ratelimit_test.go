@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/ratelimit"
+)
+
+// storeComErro simula um store de rate limit indisponível.
+type storeComErro struct{}
+
+func (storeComErro) Take(context.Context, string, ratelimit.Limite) (ratelimit.Resultado, error) {
+	return ratelimit.Resultado{}, errors.New("store indisponível")
+}
+
+func (storeComErro) Consultar(context.Context, string, ratelimit.Limite) (ratelimit.Resultado, error) {
+	return ratelimit.Resultado{}, errors.New("store indisponível")
+}
+
+func TestRateLimiter(t *testing.T) {
+	mockService := &MockUsuarioService{
+		GetUserByIDFn: func(ctx context.Context, id int64) (*domain.Usuario, error) {
+			return &domain.Usuario{ID: id, Nome: "Ana", Version: 1}, nil
+		},
+	}
+	regras := map[string]ratelimit.Limite{
+		RegraPadrao: {Requisicoes: 2, Periodo: time.Minute},
+	}
+	novoRouter := func(store ratelimit.Store) http.Handler {
+		return NewUsuarioHandler(mockService).WithRateLimiter(NewRateLimiter(store, regras)).Routes()
+	}
+	requisicao := func(router http.Handler, metodo, alvo, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(metodo, alvo, nil)
+		req.RemoteAddr = ip
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - informa o limite nos cabeçalhos e responde 429 ao excedê-lo", func(t *testing.T) {
+		router := novoRouter(ratelimit.NewMemoryStore())
+
+		rr := requisicao(router, "GET", "/1", "10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+		assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", rr.Header().Get("RateLimit-Reset"))
+
+		requisicao(router, "GET", "/1", "10.0.0.1:1234")
+		rr = requisicao(router, "GET", "/1", "10.0.0.1:4321")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+		assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, problemContentType, rr.Header().Get("Content-Type"))
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		assert.Equal(t, "limite_excedido", problem.Code)
+	})
+
+	t.Run("sucesso - IPs e rotas têm buckets separados", func(t *testing.T) {
+		router := novoRouter(ratelimit.NewMemoryStore())
+		requisicao(router, "GET", "/1", "10.0.0.1")
+		requisicao(router, "GET", "/1", "10.0.0.1")
+
+		assert.Equal(t, http.StatusOK, requisicao(router, "GET", "/1", "10.0.0.2").Code)
+		assert.Equal(t, http.StatusNoContent, requisicao(router, "DELETE", "/1", "10.0.0.1").Code)
+	})
+
+	t.Run("sucesso - cliente autenticado usa o próprio bucket", func(t *testing.T) {
+		router := novoRouter(ratelimit.NewMemoryStore())
+		requisicao(router, "GET", "/1", "10.0.0.1")
+		requisicao(router, "GET", "/1", "10.0.0.1")
+
+		req := httptest.NewRequest("GET", "/1", nil)
+		req.RemoteAddr = "10.0.0.1"
+		req = req.WithContext(ratelimit.WithCliente(req.Context(), "chave-42"))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("sucesso - falha no store não bloqueia a requisição", func(t *testing.T) {
+		rr := requisicao(novoRouter(storeComErro{}), "GET", "/1", "10.0.0.1")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+	})
+}
+
+func TestRateLimiter_LimitarFalhasAutenticacao(t *testing.T) {
+	mockService := &MockUsuarioService{
+		GetUserByIDFn: func(ctx context.Context, id int64) (*domain.Usuario, error) {
+			return &domain.Usuario{ID: id, Nome: "Ana", Version: 1}, nil
+		},
+	}
+	regras := map[string]ratelimit.Limite{
+		RegraFalhasAutenticacao: {Requisicoes: 2, Periodo: time.Minute},
+	}
+	novoRouter := func(store ratelimit.Store) http.Handler {
+		usuarios := NewUsuarioHandler(mockService).WithAutenticacao(novoMockAPIKeys(), nil).Routes()
+		return NewRateLimiter(store, regras).LimitarFalhasAutenticacao()(usuarios)
+	}
+	requisicao := func(router http.Handler, chave, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/1", nil)
+		req.RemoteAddr = ip
+		req.Header.Set("X-API-Key", chave)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - requisições autenticadas não consomem o limite", func(t *testing.T) {
+		router := novoRouter(ratelimit.NewMemoryStore())
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, requisicao(router, "usk_leitura_x", "10.0.0.1:1234").Code)
+		}
+	})
+
+	t.Run("erro - esgotadas as falhas, o IP recebe 429 mesmo com credencial válida", func(t *testing.T) {
+		router := novoRouter(ratelimit.NewMemoryStore())
+		assert.Equal(t, http.StatusUnauthorized, requisicao(router, "usk_errada_1", "10.0.0.1:1234").Code)
+		assert.Equal(t, http.StatusUnauthorized, requisicao(router, "usk_errada_2", "10.0.0.1:4321").Code)
+
+		rr := requisicao(router, "usk_leitura_x", "10.0.0.1:1234")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		assert.Equal(t, "limite_excedido", problem.Code)
+		assert.Equal(t, http.StatusOK, requisicao(router, "usk_leitura_x", "10.0.0.2:1234").Code)
+	})
+
+	t.Run("sucesso - falha no store não bloqueia a requisição", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, requisicao(novoRouter(storeComErro{}), "usk_leitura_x", "10.0.0.1").Code)
+	})
+}
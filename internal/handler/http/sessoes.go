@@ -0,0 +1,103 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+// @Summary      Lista as sessões ativas de um usuário
+// @Description  Devolve as sessões abertas por login e ainda não revogadas nem expiradas, com o
+// @Description  dispositivo, o IP e o User-Agent do login ou da última renovação.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id   path      int  true  "ID do Usuário"
+// @Success      200  {array}   domain.Sessao
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /usuarios/{id}/sessions [get]
+func (h *UsuarioHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
+		return
+	}
+
+	sessoes, err := h.service.ListSessions(r.Context(), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, sessoes)
+}
+
+// @Summary      Revoga todas as sessões de um usuário
+// @Description  Encerra as sessões do usuário: os refresh tokens e os tokens de acesso delas
+// @Description  deixam de valer imediatamente.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "ID do Usuário"
+// @Success      204  {string}  string "No Content"
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /usuarios/{id}/sessions [delete]
+func (h *UsuarioHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
+		return
+	}
+
+	if err := h.service.RevokeSessions(r.Context(), id); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Revoga uma sessão de um usuário
+// @Description  Encerra a sessão, como um logout feito pelo próprio usuário. Revogar uma sessão
+// @Description  já revogada não é erro.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Param        id        path      int  true  "ID do Usuário"
+// @Param        sessaoID  path      int  true  "ID da sessão"
+// @Success      204       {string}  string "No Content"
+// @Failure      400       {object}  Problem
+// @Failure      401       {object}  Problem
+// @Failure      403       {object}  Problem
+// @Failure      404       {object}  Problem
+// @Failure      429       {object}  Problem
+// @Failure      500       {object}  Problem
+// @Router       /usuarios/{id}/sessions/{sessaoID} [delete]
+func (h *UsuarioHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
+		return
+	}
+	sessaoID, err := strconv.ParseInt(chi.URLParam(r, "sessaoID"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID da sessão inválido"))
+		return
+	}
+
+	if err := h.service.RevokeSession(r.Context(), id, sessaoID); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).InfoContext(r.Context(), "Sessão revogada", "sessao_id", sessaoID)
+	w.WriteHeader(http.StatusNoContent)
+}
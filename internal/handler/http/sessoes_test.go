@@ -0,0 +1,65 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+func TestUsuarioHandler_Sessions(t *testing.T) {
+	var revogadas []int64
+	mockService := &MockUsuarioService{
+		ListSessionsFn: func(ctx context.Context, id int64) ([]domain.Sessao, error) {
+			if id != 1 {
+				return nil, service.ErrUsuarioNaoEncontrado
+			}
+			return []domain.Sessao{{ID: 3, UsuarioID: 1, Dispositivo: "iPhone da Ana", IP: "203.0.113.7"}}, nil
+		},
+		RevokeSessionsFn: func(ctx context.Context, id int64) error {
+			revogadas = append(revogadas, 0)
+			return nil
+		},
+		RevokeSessionFn: func(ctx context.Context, id, sessaoID int64) error {
+			if sessaoID != 3 {
+				return service.ErrSessaoNaoEncontrada
+			}
+			revogadas = append(revogadas, sessaoID)
+			return nil
+		},
+	}
+	requisicao := func(metodo, alvo string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		NewUsuarioHandler(mockService).Routes().ServeHTTP(rr, httptest.NewRequest(metodo, alvo, nil))
+		return rr
+	}
+
+	t.Run("sucesso - lista as sessões ativas do usuário", func(t *testing.T) {
+		rr := requisicao("GET", "/1/sessions")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var sessoes []domain.Sessao
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &sessoes))
+		assert.Equal(t, "iPhone da Ana", sessoes[0].Dispositivo)
+	})
+
+	t.Run("sucesso - revoga todas as sessões ou uma só", func(t *testing.T) {
+		revogadas = nil
+
+		assert.Equal(t, http.StatusNoContent, requisicao("DELETE", "/1/sessions").Code)
+		assert.Equal(t, http.StatusNoContent, requisicao("DELETE", "/1/sessions/3").Code)
+		assert.Equal(t, []int64{0, 3}, revogadas)
+	})
+
+	t.Run("erro - usuário ou sessão inexistente e IDs inválidos", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, requisicao("GET", "/2/sessions").Code)
+		assert.Equal(t, http.StatusNotFound, requisicao("DELETE", "/1/sessions/9").Code)
+		assert.Equal(t, http.StatusBadRequest, requisicao("GET", "/abc/sessions").Code)
+		assert.Equal(t, http.StatusBadRequest, requisicao("DELETE", "/1/sessions/abc").Code)
+	})
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/willjrcristo/go-sqlite-db/internal/auth"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+// Medidor registra o consumo dos usuários.
+type Medidor interface {
+	RegistrarUso(ctx context.Context, usuarioID int64, metrica string, quantidade int64, chave string) error
+}
+
+// UsoService é a interface do serviço de consumo usada pelo UsuarioHandler.
+type UsoService interface {
+	Medidor
+	GetUsage(ctx context.Context, id int64) (*domain.Uso, error)
+}
+
+// MedirUso devolve o middleware que conta uma chamada (domain.MetricaChamadasAPI) para cada
+// requisição bem-sucedida (status abaixo de 400) feita com o token de acesso de um usuário;
+// as feitas com API key não são medidas. Deve vir depois da autenticação. Uma falha ao
+// registrar só vai para o log.
+//
+// A chave do evento é gerada aqui para cada chamada. O ID da requisição não serve: ele vem do
+// cabeçalho X-Request-Id quando o cliente o envia, e repeti-lo faria as chamadas seguintes
+// serem descartadas como duplicadas.
+func MedirUso(m Medidor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.FromContext(r.Context())
+			if !ok || p.Tipo != auth.TipoUsuario {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			if ww.Status() >= http.StatusBadRequest {
+				return
+			}
+
+			usuarioID, _ := strconv.ParseInt(p.ID, 10, 64)
+			if err := m.RegistrarUso(r.Context(), usuarioID, domain.MetricaChamadasAPI, 1, rand.Text()); err != nil {
+				logging.FromContext(r.Context()).WarnContext(r.Context(), "Falha ao registrar o consumo", "usuario_id", usuarioID, "error", err)
+			}
+		})
+	}
+}
+
+// @Summary      Consumo do usuário no período atual
+// @Description  Soma as chamadas feitas com o token de acesso do usuário no período de cobrança
+// @Description  atual: o da assinatura da organização que paga o consumo dele ou, sem ela, o mês
+// @Description  corrente (UTC). "reportado" é a parte já enviada ao provedor de cobrança.
+// @Tags         usuarios
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id   path      int  true  "ID do Usuário"
+// @Success      200  {object}  domain.Uso
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /usuarios/{id}/uso [get]
+func (h *UsuarioHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, r, domain.ErrRequisicaoInvalida.WithDetail("ID inválido"))
+		return
+	}
+
+	uso, err := h.uso.GetUsage(r.Context(), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, uso)
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// MockUsoService registra as chamadas medidas e devolve um consumo fixo para o usuário 1.
+type MockUsoService struct {
+	registros []string
+}
+
+func (m *MockUsoService) RegistrarUso(ctx context.Context, usuarioID int64, metrica string, quantidade int64, chave string) error {
+	m.registros = append(m.registros, metrica+":"+chave)
+	return nil
+}
+
+func (m *MockUsoService) GetUsage(ctx context.Context, id int64) (*domain.Uso, error) {
+	if id != 1 {
+		return nil, service.ErrUsuarioNaoEncontrado
+	}
+	return &domain.Uso{UsuarioID: 1, Metricas: []domain.ConsumoMetrica{{Metrica: domain.MetricaChamadasAPI, Quantidade: 12, Reportado: 10}}}, nil
+}
+
+func TestUsuarioHandler_Uso(t *testing.T) {
+	mockService := &MockUsuarioService{
+		GetUserByIDFn: func(ctx context.Context, id int64) (*domain.Usuario, error) {
+			if id != 1 {
+				return nil, service.ErrUsuarioNaoEncontrado
+			}
+			return &domain.Usuario{ID: id, Nome: "Ana", Version: 1}, nil
+		},
+	}
+	usuarios := mockUsuariosAutenticados{"token-admin": {ID: 3, Papel: domain.PapelAdmin}}
+	novoRouter := func(uso *MockUsoService) http.Handler {
+		return middleware.RequestID(NewUsuarioHandler(mockService).WithAutenticacao(novoMockAPIKeys(), usuarios).WithUso(uso).Routes())
+	}
+	requisicao := func(router http.Handler, alvo, credencial string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", alvo, nil)
+		req.Header.Set("Authorization", "Bearer "+credencial)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - devolve o consumo do período atual", func(t *testing.T) {
+		rr := requisicao(novoRouter(&MockUsoService{}), "/1/uso", "usk_leitura_x")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var uso domain.Uso
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &uso))
+		assert.Equal(t, int64(12), uso.Metricas[0].Quantidade)
+		assert.Equal(t, int64(10), uso.Metricas[0].Reportado)
+	})
+
+	t.Run("erro - usuário inexistente e ID inválido", func(t *testing.T) {
+		router := novoRouter(&MockUsoService{})
+
+		assert.Equal(t, http.StatusNotFound, requisicao(router, "/2/uso", "usk_leitura_x").Code)
+		assert.Equal(t, http.StatusBadRequest, requisicao(router, "/abc/uso", "usk_leitura_x").Code)
+	})
+
+	t.Run("sucesso - mede só as chamadas bem-sucedidas com token de usuário", func(t *testing.T) {
+		uso := &MockUsoService{}
+		router := novoRouter(uso)
+
+		assert.Equal(t, http.StatusOK, requisicao(router, "/1", "token-admin").Code)
+		assert.Equal(t, http.StatusNotFound, requisicao(router, "/2", "token-admin").Code)
+		assert.Equal(t, http.StatusOK, requisicao(router, "/1", "usk_leitura_x").Code)
+
+		require.Len(t, uso.registros, 1)
+		assert.Contains(t, uso.registros[0], domain.MetricaChamadasAPI+":")
+		assert.NotEqual(t, domain.MetricaChamadasAPI+":", uso.registros[0], "cada chamada tem uma chave")
+	})
+
+	t.Run("sucesso - X-Request-Id repetido não faz chamadas diferentes compartilharem a chave", func(t *testing.T) {
+		uso := &MockUsoService{}
+		router := novoRouter(uso)
+
+		for range 2 {
+			req := httptest.NewRequest("GET", "/1", nil)
+			req.Header.Set("Authorization", "Bearer token-admin")
+			req.Header.Set("X-Request-Id", "repetido")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+		}
+
+		require.Len(t, uso.registros, 2)
+		assert.NotEqual(t, uso.registros[0], uso.registros[1])
+		assert.NotContains(t, uso.registros[0], "repetido")
+	})
+}
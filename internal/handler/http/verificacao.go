@@ -0,0 +1,455 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// AuthService é a interface do serviço de login, sessões, verificação de e-mail, redefinição
+// de senha e segundo fator (MFA).
+type AuthService interface {
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, senha string) error
+	AuthenticateAccessToken(ctx context.Context, token string) (*domain.Usuario, error)
+	Login(ctx context.Context, email, senha string, origem domain.OrigemSessao) (*domain.Login, error)
+	LoginMFA(ctx context.Context, mfaToken, codigo string, origem domain.OrigemSessao) (*domain.Login, error)
+	Refresh(ctx context.Context, refreshToken string, origem domain.OrigemSessao) (*domain.Login, error)
+	Logout(ctx context.Context, refreshToken string) error
+	EnrollMFA(ctx context.Context, usuario domain.Usuario) (*domain.CadastroMFA, error)
+	ConfirmMFA(ctx context.Context, usuario domain.Usuario, codigo string) ([]string, error)
+	DisableMFA(ctx context.Context, usuario domain.Usuario, codigo string) error
+}
+
+// VerifyEmailRequest é o corpo do POST /auth/verify.
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// EmailRequest é o corpo dos pedidos que só informam o e-mail (reenvio da verificação e nova senha).
+type EmailRequest struct {
+	Email string `json:"email" example:"ana@email.com"`
+}
+
+// ResetPasswordRequest é o corpo do POST /auth/password-reset/confirm.
+type ResetPasswordRequest struct {
+	Token string `json:"token"`
+	Senha string `json:"senha" example:"uma senha longa"`
+}
+
+// LoginRequest é o corpo do POST /auth/login.
+type LoginRequest struct {
+	Email       string `json:"email" example:"ana@email.com"`
+	Senha       string `json:"senha" example:"uma senha longa"`
+	Dispositivo string `json:"dispositivo,omitempty" example:"iPhone da Ana"` // Nome exibido na lista de sessões.
+}
+
+// LoginMFARequest é o corpo do POST /auth/login/mfa.
+type LoginMFARequest struct {
+	MFAToken    string `json:"mfa_token"`
+	Codigo      string `json:"codigo" example:"123456"` // Do aplicativo autenticador ou de recuperação.
+	Dispositivo string `json:"dispositivo,omitempty" example:"iPhone da Ana"`
+}
+
+// RefreshTokenRequest é o corpo do POST /auth/refresh e do POST /auth/logout.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// CodigoMFARequest é o corpo das rotas de /auth/mfa que pedem um código.
+type CodigoMFARequest struct {
+	Codigo string `json:"codigo" example:"123456"`
+}
+
+// CodigosRecuperacaoResponse traz os códigos de recuperação, exibidos só desta vez.
+type CodigosRecuperacaoResponse struct {
+	CodigosRecuperacao []string `json:"codigos_recuperacao" example:"k3j5q-2abx7"`
+}
+
+// AuthHandler gerencia as rotas de /auth. Elas não exigem API key: são chamadas pelo
+// frontend com o token recebido por e-mail ou com o token de acesso do usuário.
+type AuthHandler struct {
+	service  AuthService
+	cobranca CobrancaService
+	limiter  *RateLimiter
+}
+
+// NewAuthHandler cria uma nova instância do AuthHandler.
+func NewAuthHandler(s AuthService) *AuthHandler {
+	return &AuthHandler{service: s}
+}
+
+// WithRateLimiter aplica os limites de requisição às rotas de /auth. Sem ele, as rotas não são limitadas.
+func (h *AuthHandler) WithRateLimiter(l *RateLimiter) *AuthHandler {
+	h.limiter = l
+	return h
+}
+
+// Routes define as rotas de /auth.
+func (h *AuthHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.With(h.limiter.Limitar("POST /auth/verify", PorCliente)).Post("/verify", h.VerifyEmail)                                   // POST /auth/verify
+	r.With(h.limiter.Limitar("POST /auth/verify/resend", PorCliente)).Post("/verify/resend", h.ResendVerification)              // POST /auth/verify/resend
+	r.With(h.limiter.Limitar("POST /auth/password-reset", PorCliente)).Post("/password-reset", h.RequestPasswordReset)          // POST /auth/password-reset
+	r.With(h.limiter.Limitar("POST /auth/password-reset/confirm", PorCliente)).Post("/password-reset/confirm", h.ResetPassword) // POST /auth/password-reset/confirm
+	r.With(h.limiter.Limitar("GET /auth/me", PorCliente)).Get("/me", h.Me)                                                      // GET /auth/me
+	r.With(h.limiter.Limitar("POST /auth/login", PorCliente)).Post("/login", h.Login)                                           // POST /auth/login
+	r.With(h.limiter.Limitar("POST /auth/login/mfa", PorCliente)).Post("/login/mfa", h.LoginMFA)                                // POST /auth/login/mfa
+	r.With(h.limiter.Limitar("POST /auth/refresh", PorCliente)).Post("/refresh", h.Refresh)                                     // POST /auth/refresh
+	r.With(h.limiter.Limitar("POST /auth/logout", PorCliente)).Post("/logout", h.Logout)                                        // POST /auth/logout
+	r.With(h.limiter.Limitar("POST /auth/mfa/enroll", PorCliente)).Post("/mfa/enroll", h.EnrollMFA)                             // POST /auth/mfa/enroll
+	r.With(h.limiter.Limitar("POST /auth/mfa/enroll/confirm", PorCliente)).Post("/mfa/enroll/confirm", h.ConfirmMFA)            // POST /auth/mfa/enroll/confirm
+	r.With(h.limiter.Limitar("POST /auth/mfa/disable", PorCliente)).Post("/mfa/disable", h.DisableMFA)                          // POST /auth/mfa/disable
+	if h.cobranca != nil {
+		r.With(h.limiter.Limitar("GET /auth/me/billing", PorCliente)).Get("/me/billing", h.GetBillingDetails)    // GET /auth/me/billing
+		r.With(h.limiter.Limitar("PUT /auth/me/billing", PorCliente)).Put("/me/billing", h.UpdateBillingDetails) // PUT /auth/me/billing
+	}
+	return r
+}
+
+// @Summary      Confirma o e-mail
+// @Description  Recebe o token do link enviado no cadastro ou na troca de e-mail. O token expira
+// @Description  em 48 horas e deixa de valer se o e-mail mudar. Confirmar de novo não é erro.
+// @Tags         auth
+// @Accept       json
+// @Param        token  body      VerifyEmailRequest  true  "Token recebido por e-mail"
+// @Success      204    {string}  string "No Content"
+// @Failure      400    {object}  Problem
+// @Failure      429    {object}  Problem
+// @Failure      500    {object}  Problem
+// @Router       /auth/verify [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	if err := h.service.VerifyEmail(r.Context(), req.Token); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Reenvia o e-mail de verificação
+// @Description  Sempre responde 202, exista ou não um usuário com o e-mail, para não revelar
+// @Description  quais e-mails estão cadastrados.
+// @Tags         auth
+// @Accept       json
+// @Param        email  body      EmailRequest  true  "E-mail do usuário"
+// @Success      202    {string}  string "Accepted"
+// @Failure      400    {object}  Problem
+// @Failure      429    {object}  Problem
+// @Failure      500    {object}  Problem
+// @Router       /auth/verify/resend [post]
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	if err := h.service.ResendVerification(r.Context(), req.Email); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// @Summary      Pede a redefinição de senha
+// @Description  Envia ao e-mail um link de redefinição válido por 1 hora. Sempre responde 202,
+// @Description  exista ou não um usuário com o e-mail.
+// @Tags         auth
+// @Accept       json
+// @Param        email  body      EmailRequest  true  "E-mail do usuário"
+// @Success      202    {string}  string "Accepted"
+// @Failure      400    {object}  Problem
+// @Failure      429    {object}  Problem
+// @Failure      500    {object}  Problem
+// @Router       /auth/password-reset [post]
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	if err := h.service.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// @Summary      Define a nova senha
+// @Description  Recebe o token do link de redefinição e a nova senha (8 a 128 caracteres).
+// @Description  O token só pode ser usado uma vez.
+// @Description  As sessões abertas antes da troca são revogadas.
+// @Tags         auth
+// @Accept       json
+// @Param        senha  body      ResetPasswordRequest  true  "Token recebido por e-mail e nova senha"
+// @Success      204    {string}  string "No Content"
+// @Failure      400    {object}  Problem
+// @Failure      429    {object}  Problem
+// @Failure      500    {object}  Problem
+// @Router       /auth/password-reset/confirm [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	if err := h.service.ResetPassword(r.Context(), req.Token, req.Senha); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Dados do usuário logado
+// @Description  Devolve o usuário dono do token de acesso emitido no login.
+// @Tags         auth
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer <access_token>"
+// @Success      200            {object}  domain.Usuario
+// @Failure      401            {object}  Problem
+// @Failure      429            {object}  Problem
+// @Failure      500            {object}  Problem
+// @Router       /auth/me [get]
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	usuario, ok := h.usuarioAutenticado(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, usuario)
+}
+
+// @Summary      Login com e-mail e senha
+// @Description  Abre uma sessão e devolve o token de acesso e o refresh token, renovável em
+// @Description  /auth/refresh. Se o usuário tiver MFA ativo, devolve mfa_necessario e
+// @Description  o mfa_token (válido por 5 minutos) a ser enviado com o código em /auth/login/mfa.
+// @Description  mfa_cadastro_obrigatorio indica que o papel do usuário exige MFA e ele ainda não o cadastrou.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        credenciais  body      LoginRequest  true  "E-mail e senha"
+// @Success      200          {object}  domain.Login
+// @Failure      400          {object}  Problem
+// @Failure      401          {object}  Problem
+// @Failure      429          {object}  Problem
+// @Failure      500          {object}  Problem
+// @Router       /auth/login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	login, err := h.service.Login(r.Context(), req.Email, req.Senha, origemSessao(r, req.Dispositivo))
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, login)
+}
+
+// @Summary      Conclui o login com o segundo fator
+// @Description  Recebe o mfa_token do login e um código do aplicativo autenticador (cada código
+// @Description  só vale uma vez) ou um código de recuperação, que é descartado após o uso.
+// @Description  Abre a sessão e devolve o token de acesso e o refresh token.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        codigo  body      LoginMFARequest  true  "Token do desafio e código"
+// @Success      200     {object}  domain.Login
+// @Failure      400     {object}  Problem
+// @Failure      401     {object}  Problem
+// @Failure      429     {object}  Problem
+// @Failure      500     {object}  Problem
+// @Router       /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFARequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	login, err := h.service.LoginMFA(r.Context(), req.MFAToken, req.Codigo, origemSessao(r, req.Dispositivo))
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, login)
+}
+
+// @Summary      Renova o token de acesso
+// @Description  Troca o refresh token por um novo token de acesso e um novo refresh token da
+// @Description  mesma sessão. Cada refresh token vale uma única vez: reapresentar um já trocado
+// @Description  revoga a sessão inteira. A sessão expira 30 dias após o login.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        refresh  body      RefreshTokenRequest  true  "Refresh token da sessão"
+// @Success      200      {object}  domain.Login
+// @Failure      400      {object}  Problem
+// @Failure      401      {object}  Problem
+// @Failure      429      {object}  Problem
+// @Failure      500      {object}  Problem
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	login, err := h.service.Refresh(r.Context(), req.RefreshToken, origemSessao(r, ""))
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, login)
+}
+
+// @Summary      Encerra a sessão
+// @Description  Revoga a sessão do refresh token; o token de acesso dela deixa de valer. Sempre
+// @Description  responde 204, mesmo que a sessão já tenha sido encerrada.
+// @Tags         auth
+// @Accept       json
+// @Param        refresh  body      RefreshTokenRequest  true  "Refresh token da sessão"
+// @Success      204      {string}  string "No Content"
+// @Failure      400      {object}  Problem
+// @Failure      429      {object}  Problem
+// @Failure      500      {object}  Problem
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	if err := h.service.Logout(r.Context(), req.RefreshToken); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Inicia o cadastro do segundo fator (TOTP)
+// @Description  Gera o segredo e a URI otpauth:// a ser exibida como QR code no aplicativo
+// @Description  autenticador. O cadastro só é ativado em /auth/mfa/enroll/confirm; pedir de novo
+// @Description  antes disso troca o segredo.
+// @Tags         auth
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer <access_token>"
+// @Success      200            {object}  domain.CadastroMFA
+// @Failure      401            {object}  Problem
+// @Failure      409            {object}  Problem
+// @Failure      429            {object}  Problem
+// @Failure      500            {object}  Problem
+// @Router       /auth/mfa/enroll [post]
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	usuario, ok := h.usuarioAutenticado(w, r)
+	if !ok {
+		return
+	}
+	cadastro, err := h.service.EnrollMFA(r.Context(), *usuario)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, cadastro)
+}
+
+// @Summary      Ativa o segundo fator
+// @Description  Confirma o cadastro com o primeiro código do aplicativo e devolve 10 códigos de
+// @Description  recuperação de uso único. Eles não serão exibidos de novo. A ativação fica na
+// @Description  trilha de auditoria.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string            true  "Bearer <access_token>"
+// @Param        codigo         body      CodigoMFARequest  true  "Código do aplicativo"
+// @Success      200            {object}  CodigosRecuperacaoResponse
+// @Failure      400            {object}  Problem
+// @Failure      401            {object}  Problem
+// @Failure      409            {object}  Problem
+// @Failure      429            {object}  Problem
+// @Failure      500            {object}  Problem
+// @Router       /auth/mfa/enroll/confirm [post]
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	usuario, ok := h.usuarioAutenticado(w, r)
+	if !ok {
+		return
+	}
+	var req CodigoMFARequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	codigos, err := h.service.ConfirmMFA(r.Context(), *usuario, req.Codigo)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, CodigosRecuperacaoResponse{CodigosRecuperacao: codigos})
+}
+
+// @Summary      Desativa o segundo fator
+// @Description  Exige um código do aplicativo ou de recuperação. Os códigos de recuperação são
+// @Description  descartados e a desativação fica na trilha de auditoria.
+// @Tags         auth
+// @Accept       json
+// @Param        Authorization  header    string            true  "Bearer <access_token>"
+// @Param        codigo         body      CodigoMFARequest  true  "Código do aplicativo ou de recuperação"
+// @Success      204            {string}  string "No Content"
+// @Failure      400            {object}  Problem
+// @Failure      401            {object}  Problem
+// @Failure      409            {object}  Problem
+// @Failure      429            {object}  Problem
+// @Failure      500            {object}  Problem
+// @Router       /auth/mfa/disable [post]
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	usuario, ok := h.usuarioAutenticado(w, r)
+	if !ok {
+		return
+	}
+	var req CodigoMFARequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	if err := h.service.DisableMFA(r.Context(), *usuario, req.Codigo); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// usuarioAutenticado devolve o dono do token de acesso enviado como Bearer. Se não houver
+// token válido, já responde 401 e devolve false.
+func (h *AuthHandler) usuarioAutenticado(w http.ResponseWriter, r *http.Request) (*domain.Usuario, bool) {
+	token := credencial(r)
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="usuarios"`)
+		respondWithProblem(w, r, errNaoAutenticado.WithDetail("envie o token de acesso em Authorization: Bearer <token>"))
+		return nil, false
+	}
+	usuario, err := h.service.AuthenticateAccessToken(r.Context(), token)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="usuarios", error="invalid_token"`)
+		respondWithProblem(w, r, err)
+		return nil, false
+	}
+	return usuario, true
+}
+
+// origemSessao descreve a origem da requisição que abre ou renova uma sessão.
+func origemSessao(r *http.Request, dispositivo string) domain.OrigemSessao {
+	return domain.OrigemSessao{Dispositivo: dispositivo, IP: ipCliente(r), UserAgent: r.UserAgent()}
+}
@@ -0,0 +1,248 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/service"
+)
+
+// MockAuthService aceita apenas o token "valido", a senha "senha-certa", o código MFA
+// "123456" e o refresh token "refresh", e registra os e-mails pedidos, a origem do último
+// login e os logouts.
+type MockAuthService struct {
+	emails        []string
+	senha         string
+	mfaDesativado bool
+	origem        domain.OrigemSessao
+	logouts       []string
+}
+
+func (m *MockAuthService) VerifyEmail(ctx context.Context, token string) error {
+	if token != "valido" {
+		return service.ErrTokenInvalido
+	}
+	return nil
+}
+
+func (m *MockAuthService) ResendVerification(ctx context.Context, email string) error {
+	m.emails = append(m.emails, email)
+	return nil
+}
+
+func (m *MockAuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	m.emails = append(m.emails, email)
+	return nil
+}
+
+func (m *MockAuthService) ResetPassword(ctx context.Context, token, senha string) error {
+	if token != "valido" {
+		return service.ErrTokenInvalido
+	}
+	m.senha = senha
+	return nil
+}
+
+func (m *MockAuthService) AuthenticateAccessToken(ctx context.Context, token string) (*domain.Usuario, error) {
+	if token != "acesso" {
+		return nil, service.ErrTokenAcessoInvalido
+	}
+	return &domain.Usuario{ID: 7, Nome: "Ana"}, nil
+}
+
+func (m *MockAuthService) Login(ctx context.Context, email, senha string, origem domain.OrigemSessao) (*domain.Login, error) {
+	m.origem = origem
+	if senha != "senha-certa" {
+		return nil, service.ErrCredenciaisInvalidas
+	}
+	if email == "admin@email.com" {
+		return &domain.Login{UsuarioID: 1, MFANecessario: true, MFAToken: "desafio", ExpiresIn: 300}, nil
+	}
+	return &domain.Login{UsuarioID: 7, AccessToken: "acesso", TokenType: "Bearer", ExpiresIn: 3600}, nil
+}
+
+func (m *MockAuthService) LoginMFA(ctx context.Context, mfaToken, codigo string, origem domain.OrigemSessao) (*domain.Login, error) {
+	if mfaToken != "desafio" {
+		return nil, service.ErrDesafioMFAInvalido
+	}
+	if codigo != "123456" {
+		return nil, service.ErrCodigoMFAInvalido
+	}
+	return &domain.Login{UsuarioID: 1, AccessToken: "acesso-mfa", TokenType: "Bearer", ExpiresIn: 3600}, nil
+}
+
+func (m *MockAuthService) Refresh(ctx context.Context, refreshToken string, origem domain.OrigemSessao) (*domain.Login, error) {
+	if refreshToken != "refresh" {
+		return nil, service.ErrRefreshTokenInvalido
+	}
+	return &domain.Login{UsuarioID: 7, AccessToken: "acesso-2", TokenType: "Bearer", ExpiresIn: 3600, RefreshToken: "refresh-2", SessaoID: 3}, nil
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, refreshToken string) error {
+	m.logouts = append(m.logouts, refreshToken)
+	return nil
+}
+
+func (m *MockAuthService) EnrollMFA(ctx context.Context, usuario domain.Usuario) (*domain.CadastroMFA, error) {
+	return &domain.CadastroMFA{Segredo: "SEGREDO", URI: "otpauth://totp/x?secret=SEGREDO"}, nil
+}
+
+func (m *MockAuthService) ConfirmMFA(ctx context.Context, usuario domain.Usuario, codigo string) ([]string, error) {
+	if codigo != "123456" {
+		return nil, service.ErrCodigoMFAInvalido
+	}
+	return []string{"aaaaa-bbbbb"}, nil
+}
+
+func (m *MockAuthService) DisableMFA(ctx context.Context, usuario domain.Usuario, codigo string) error {
+	if codigo != "123456" {
+		return service.ErrCodigoMFAInvalido
+	}
+	m.mfaDesativado = true
+	return nil
+}
+
+func TestAuthHandler(t *testing.T) {
+	requisicao := func(mock *MockAuthService, alvo, corpo string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", alvo, strings.NewReader(corpo))
+		rr := httptest.NewRecorder()
+		NewAuthHandler(mock).Routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("sucesso - confirma o e-mail e redefine a senha com token válido", func(t *testing.T) {
+		mock := &MockAuthService{}
+
+		assert.Equal(t, http.StatusNoContent, requisicao(mock, "/verify", `{"token":"valido"}`).Code)
+		assert.Equal(t, http.StatusNoContent, requisicao(mock, "/password-reset/confirm", `{"token":"valido","senha":"nova-senha-123"}`).Code)
+		assert.Equal(t, "nova-senha-123", mock.senha)
+	})
+
+	t.Run("sucesso - pedidos por e-mail respondem 202", func(t *testing.T) {
+		mock := &MockAuthService{}
+
+		assert.Equal(t, http.StatusAccepted, requisicao(mock, "/verify/resend", `{"email":"ana@email.com"}`).Code)
+		assert.Equal(t, http.StatusAccepted, requisicao(mock, "/password-reset", `{"email":"ana@email.com"}`).Code)
+		assert.Equal(t, []string{"ana@email.com", "ana@email.com"}, mock.emails)
+	})
+
+	t.Run("erro - token inválido retorna 400 token_invalido", func(t *testing.T) {
+		rr := requisicao(&MockAuthService{}, "/verify", `{"token":"adulterado"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var problem Problem
+		json.NewDecoder(rr.Body).Decode(&problem)
+		assert.Equal(t, "token_invalido", problem.Code)
+	})
+
+	t.Run("sucesso - /me devolve o usuário do token de acesso", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer acesso")
+		rr := httptest.NewRecorder()
+		NewAuthHandler(&MockAuthService{}).Routes().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var usuario domain.Usuario
+		json.NewDecoder(rr.Body).Decode(&usuario)
+		assert.Equal(t, int64(7), usuario.ID)
+
+		req.Header.Set("Authorization", "Bearer outro")
+		rr = httptest.NewRecorder()
+		NewAuthHandler(&MockAuthService{}).Routes().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("sucesso - login com senha devolve o token ou o desafio de MFA", func(t *testing.T) {
+		rr := requisicao(&MockAuthService{}, "/login", `{"email":"ana@email.com","senha":"senha-certa"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+		var login domain.Login
+		json.NewDecoder(rr.Body).Decode(&login)
+		assert.Equal(t, "acesso", login.AccessToken)
+
+		rr = requisicao(&MockAuthService{}, "/login", `{"email":"admin@email.com","senha":"senha-certa"}`)
+		login = domain.Login{}
+		json.NewDecoder(rr.Body).Decode(&login)
+		assert.True(t, login.MFANecessario)
+		assert.Empty(t, login.AccessToken)
+
+		rr = requisicao(&MockAuthService{}, "/login/mfa", `{"mfa_token":"desafio","codigo":"123456"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		login = domain.Login{}
+		json.NewDecoder(rr.Body).Decode(&login)
+		assert.Equal(t, "acesso-mfa", login.AccessToken)
+	})
+
+	t.Run("sucesso - o login informa a origem da sessão", func(t *testing.T) {
+		mock := &MockAuthService{}
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"email":"ana@email.com","senha":"senha-certa","dispositivo":"iPhone da Ana"}`))
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("User-Agent", "app/1.0")
+		rr := httptest.NewRecorder()
+
+		NewAuthHandler(mock).Routes().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, domain.OrigemSessao{Dispositivo: "iPhone da Ana", IP: "203.0.113.7", UserAgent: "app/1.0"}, mock.origem)
+	})
+
+	t.Run("sucesso - renova com o refresh token e encerra a sessão no logout", func(t *testing.T) {
+		mock := &MockAuthService{}
+
+		rr := requisicao(mock, "/refresh", `{"refresh_token":"refresh"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+		var login domain.Login
+		json.NewDecoder(rr.Body).Decode(&login)
+		assert.Equal(t, "refresh-2", login.RefreshToken)
+
+		assert.Equal(t, http.StatusNoContent, requisicao(mock, "/logout", `{"refresh_token":"refresh-2"}`).Code)
+		assert.Equal(t, []string{"refresh-2"}, mock.logouts)
+	})
+
+	t.Run("erro - refresh token inválido retorna 401", func(t *testing.T) {
+		rr := requisicao(&MockAuthService{}, "/refresh", `{"refresh_token":"reusado"}`)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Body.String(), "refresh_token_invalido")
+	})
+
+	t.Run("erro - senha ou código errados", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, requisicao(&MockAuthService{}, "/login", `{"email":"ana@email.com","senha":"errada"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, requisicao(&MockAuthService{}, "/login/mfa", `{"mfa_token":"desafio","codigo":"000000"}`).Code)
+	})
+
+	t.Run("sucesso - cadastro e desativação do MFA exigem o token de acesso", func(t *testing.T) {
+		mock := &MockAuthService{}
+		comToken := func(alvo, corpo string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", alvo, strings.NewReader(corpo))
+			req.Header.Set("Authorization", "Bearer acesso")
+			rr := httptest.NewRecorder()
+			NewAuthHandler(mock).Routes().ServeHTTP(rr, req)
+			return rr
+		}
+
+		rr := comToken("/mfa/enroll", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var cadastro domain.CadastroMFA
+		json.NewDecoder(rr.Body).Decode(&cadastro)
+		assert.Equal(t, "SEGREDO", cadastro.Segredo)
+
+		rr = comToken("/mfa/enroll/confirm", `{"codigo":"123456"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var codigos CodigosRecuperacaoResponse
+		json.NewDecoder(rr.Body).Decode(&codigos)
+		assert.Equal(t, []string{"aaaaa-bbbbb"}, codigos.CodigosRecuperacao)
+
+		assert.Equal(t, http.StatusNoContent, comToken("/mfa/disable", `{"codigo":"123456"}`).Code)
+		assert.True(t, mock.mfaDesativado)
+
+		assert.Equal(t, http.StatusUnauthorized, requisicao(mock, "/mfa/enroll", "").Code, "sem token")
+	})
+}
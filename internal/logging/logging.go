@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Level é o nível de log global. Por ser um LevelVar, pode ser alterado em tempo de execução
+// (ver LevelHandler) sem recriar o logger.
+var Level = new(slog.LevelVar)
+
+type ctxKey struct{}
+
+// scope guarda o logger da requisição. É um ponteiro no contexto para que atributos adicionados
+// pelos handlers (ver Add) também apareçam no log de acesso emitido pelo Middleware.
+type scope struct {
+	mu     sync.RWMutex
+	logger *slog.Logger
+}
+
+// WithLogger devolve um contexto que carrega o logger informado.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &scope{logger: logger})
+}
+
+// FromContext devolve o logger da requisição, ou o logger padrão se não houver um no contexto.
+func FromContext(ctx context.Context) *slog.Logger {
+	if sc, ok := ctx.Value(ctxKey{}).(*scope); ok {
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return sc.logger
+	}
+	return slog.Default()
+}
+
+// Add acrescenta atributos ao logger da requisição (ex: o ID do usuário manipulado ou autenticado).
+// Fora de uma requisição (sem logger no contexto) não faz nada.
+func Add(ctx context.Context, args ...any) {
+	if sc, ok := ctx.Value(ctxKey{}).(*scope); ok {
+		sc.mu.Lock()
+		sc.logger = sc.logger.With(args...)
+		sc.mu.Unlock()
+	}
+}
+
+// contextHandler adiciona ao registro os dados que só são conhecidos no momento do log,
+// como o padrão da rota do chi (resolvido apenas depois do roteamento).
+type contextHandler struct {
+	slog.Handler
+}
+
+// NewHandler embrulha um slog.Handler para incluir a rota da requisição nos logs emitidos com contexto.
+func NewHandler(h slog.Handler) slog.Handler {
+	return &contextHandler{Handler: h}
+}
+
+func (h *contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			rec.AddAttrs(slog.String("route", pattern))
+		}
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Middleware guarda no contexto um logger com o request_id e, ao final, registra o log de acesso.
+// Substitui o middleware.Logger do chi, que escreve texto puro fora do nosso formato JSON.
+// Deve ser registrado depois de middleware.RequestID e do middleware de tracing.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		logger := slog.Default().With("request_id", middleware.GetReqID(r.Context()))
+		r = r.WithContext(WithLogger(r.Context(), logger))
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		// Usamos o logger do contexto atual: os handlers podem ter adicionado atributos (ex: usuario_id).
+		FromContext(r.Context()).LogAttrs(r.Context(), level, "Requisição HTTP",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
+	})
+}
+
+// LevelHandler expõe o nível de log atual (GET) e permite alterá-lo (PUT) com {"level": "debug"}.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body struct {
+				Level string `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "Corpo da requisição inválido", http.StatusBadRequest)
+				return
+			}
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(body.Level)); err != nil {
+				http.Error(w, "Nível de log inválido (use debug, info, warn ou error)", http.StatusBadRequest)
+				return
+			}
+			anterior := Level.Level()
+			Level.Set(level)
+			FromContext(r.Context()).InfoContext(r.Context(), "Nível de log alterado", "de", anterior.String(), "para", level.String())
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"level": strings.ToLower(Level.Level().String())})
+	})
+}
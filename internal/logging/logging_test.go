@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("sucesso - log de acesso inclui request_id, rota e atributos adicionados pelo handler", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		anterior := slog.Default()
+		slog.SetDefault(slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))))
+		defer slog.SetDefault(anterior)
+
+		router := chi.NewRouter()
+		router.Use(middleware.RequestID)
+		router.Use(Middleware)
+		router.Get("/usuarios/{id}", func(w http.ResponseWriter, r *http.Request) {
+			Add(r.Context(), "usuario_id", chi.URLParam(r, "id"))
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		req := httptest.NewRequest("GET", "/usuarios/42", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		var registro map[string]any
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &registro))
+		assert.Equal(t, "WARN", registro["level"])
+		assert.Equal(t, "42", registro["usuario_id"])
+		assert.Equal(t, "/usuarios/{id}", registro["route"])
+		assert.Equal(t, float64(http.StatusNotFound), registro["status"])
+		assert.NotEmpty(t, registro["request_id"])
+	})
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer Level.Set(Level.Level())
+
+	t.Run("sucesso - deve alterar o nível de log", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/admin/log-level", strings.NewReader(`{"level":"debug"}`))
+		rr := httptest.NewRecorder()
+
+		LevelHandler().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"level":"debug"}`, rr.Body.String())
+		assert.Equal(t, slog.LevelDebug, Level.Level())
+	})
+
+	t.Run("erro - nível inválido deve retornar 400", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/admin/log-level", strings.NewReader(`{"level":"verboso"}`))
+		rr := httptest.NewRecorder()
+
+		LevelHandler().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,136 @@
+// Package mailer envia e-mails transacionais. SMTPMailer é usado em produção; FileMailer e
+// LogMailer permitem desenvolver e testar sem um servidor de e-mail.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+)
+
+// Mensagem é um e-mail em texto simples.
+type Mensagem struct {
+	Para    string
+	Assunto string
+	Texto   string
+}
+
+// Mailer envia mensagens.
+type Mailer interface {
+	Enviar(ctx context.Context, m Mensagem) error
+}
+
+// --- SMTP ---
+
+// SMTPMailer envia pelo servidor SMTP configurado, com STARTTLS quando o servidor oferece.
+type SMTPMailer struct {
+	endereco  string
+	auth      smtp.Auth
+	remetente string
+}
+
+// NewSMTPMailer cria um SMTPMailer. Com usuario vazio, envia sem autenticação.
+func NewSMTPMailer(host string, porta int, usuario, senha, remetente string) *SMTPMailer {
+	var auth smtp.Auth
+	if usuario != "" {
+		auth = smtp.PlainAuth("", usuario, senha, host)
+	}
+	return &SMTPMailer{endereco: net.JoinHostPort(host, strconv.Itoa(porta)), auth: auth, remetente: remetente}
+}
+
+// Enviar entrega a mensagem ao servidor SMTP. O net/smtp não aceita contexto, então o
+// cancelamento só é verificado antes do envio.
+func (s *SMTPMailer) Enviar(ctx context.Context, m Mensagem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(s.endereco, s.auth, s.remetente, []string{m.Para}, formatar(s.remetente, m)); err != nil {
+		return fmt.Errorf("enviando e-mail para %s via %s: %w", m.Para, s.endereco, err)
+	}
+	return nil
+}
+
+// --- Arquivo ---
+
+// FileMailer grava cada mensagem como um arquivo .eml no diretório, que pode ser aberto
+// por qualquer cliente de e-mail.
+type FileMailer struct {
+	dir       string
+	remetente string
+}
+
+// NewFileMailer cria um FileMailer, criando o diretório se necessário.
+func NewFileMailer(dir, remetente string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, remetente: remetente}, nil
+}
+
+func (f *FileMailer) Enviar(ctx context.Context, m Mensagem) error {
+	nome := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000"), idAleatorio())
+	caminho := filepath.Join(f.dir, nome)
+	if err := os.WriteFile(caminho, formatar(f.remetente, m), 0o644); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).InfoContext(ctx, "E-mail gravado em arquivo", "para", m.Para, "assunto", m.Assunto, "arquivo", caminho)
+	return nil
+}
+
+// --- Log ---
+
+// LogMailer apenas registra a mensagem no log, com o texto completo (inclusive links).
+type LogMailer struct{}
+
+func (LogMailer) Enviar(ctx context.Context, m Mensagem) error {
+	logging.FromContext(ctx).InfoContext(ctx, "E-mail não enviado (mailer de log)", "para", m.Para, "assunto", m.Assunto, "texto", m.Texto)
+	return nil
+}
+
+// formatar monta a mensagem no formato RFC 5322, em UTF-8.
+func formatar(remetente string, m Mensagem) []byte {
+	var b bytes.Buffer
+	cabecalhos := [][2]string{
+		{"From", remetente},
+		{"To", m.Para},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Assunto)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + idAleatorio() + "@" + dominio(remetente) + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "8bit"},
+	}
+	for _, c := range cabecalhos {
+		fmt.Fprintf(&b, "%s: %s\r\n", c[0], c[1])
+	}
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Texto, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+func idAleatorio() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// dominio extrai o domínio do remetente ("API <nao-responda@exemplo.com>" -> "exemplo.com").
+func dominio(remetente string) string {
+	_, d, ok := strings.Cut(remetente, "@")
+	if !ok {
+		return "localhost"
+	}
+	return strings.TrimRight(d, ">")
+}
@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "emails")
+	m, err := NewFileMailer(dir, "API <nao-responda@exemplo.com>")
+	require.NoError(t, err)
+
+	err = m.Enviar(context.Background(), Mensagem{Para: "ana@email.com", Assunto: "Confirmação de e-mail", Texto: "Olá!\nClique no link."})
+	require.NoError(t, err)
+
+	arquivos, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.Len(t, arquivos, 1)
+	conteudo, _ := os.ReadFile(arquivos[0])
+	texto := string(conteudo)
+
+	assert.Contains(t, texto, "From: API <nao-responda@exemplo.com>\r\n")
+	assert.Contains(t, texto, "To: ana@email.com\r\n")
+	assert.Contains(t, texto, "Subject: =?utf-8?q?Confirma=C3=A7=C3=A3o_de_e-mail?=\r\n")
+	assert.Contains(t, texto, "@exemplo.com>\r\n")
+	assert.True(t, strings.HasSuffix(texto, "\r\n\r\nOlá!\r\nClique no link."))
+}
@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/stripe/stripe-go/v78"
+)
+
+// Métricas de negócio e de banco de dados.
+// Assim como as métricas HTTP em cmd/api/metrics.go, são registradas no registro padrão via promauto.
+var (
+	// user_signups_total conta os usuários criados com sucesso.
+	UserSignups = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "user_signups_total",
+			Help: "Número total de usuários cadastrados.",
+		},
+	)
+
+	// checkout_sessions_created_total conta as sessões de checkout criadas na Stripe.
+	CheckoutSessionsCreated = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "checkout_sessions_created_total",
+			Help: "Número total de sessões de checkout criadas na Stripe.",
+		},
+	)
+
+	// coupon_redemptions_total conta as assinaturas que passaram a usar um código promocional,
+	// pelo cupom da Stripe do código (a contagem por código fica na tabela coupons).
+	CouponRedemptions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "coupon_redemptions_total",
+			Help: "Número total de resgates de códigos promocionais em assinaturas, por cupom da Stripe.",
+		},
+		[]string{"coupon"},
+	)
+
+	// stripe_webhook_events_total conta os webhooks recebidos por tipo de evento e resultado
+	// ("processed", "ignored", "invalid_signature" ou "error").
+	StripeWebhookEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stripe_webhook_events_total",
+			Help: "Número total de webhooks da Stripe recebidos, por tipo e resultado.",
+		},
+		[]string{"event_type", "outcome"},
+	)
+
+	// stripe_api_request_duration_seconds mede a latência das chamadas à API da Stripe.
+	StripeAPIDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "stripe_api_request_duration_seconds",
+			Help:    "Duração das chamadas à API da Stripe em segundos.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation"},
+	)
+
+	// stripe_api_errors_total conta as chamadas à Stripe que falharam, pelo código de erro da Stripe.
+	StripeAPIErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stripe_api_errors_total",
+			Help: "Número total de erros nas chamadas à API da Stripe.",
+		},
+		[]string{"operation", "code"},
+	)
+
+	// usage_events_total soma o consumo medido dos usuários, por métrica.
+	UsageEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "usage_events_total",
+			Help: "Consumo total medido dos usuários, por métrica.",
+		},
+		[]string{"metric"},
+	)
+
+	// usage_reports_total conta os relatórios de consumo enviados ao provedor de cobrança, por
+	// resultado ("success" ou "error").
+	UsageReports = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "usage_reports_total",
+			Help: "Número total de relatórios de consumo enviados ao provedor de cobrança, por resultado.",
+		},
+		[]string{"outcome"},
+	)
+
+	// customer_syncs_total conta as sincronizações do contato dos clientes com o provedor:
+	// direction "outbound" (perfil para a Stripe, outcome "success" ou "error") ou "inbound"
+	// (webhook customer.updated, outcome "unchanged", "local_wins" ou "stripe_wins").
+	CustomerSyncs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "customer_syncs_total",
+			Help: "Número total de sincronizações do contato dos clientes com o provedor, por direção e resultado.",
+		},
+		[]string{"direction", "outcome"},
+	)
+
+	// db_query_duration_seconds mede a duração das consultas por método do repositório.
+	DBQueryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Duração das consultas ao banco de dados em segundos, por método do repositório.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"method", "outcome"},
+	)
+
+	// cache_requests_total conta as consultas aos caches por resultado ("hit", "miss" ou "error").
+	// A taxa de acerto é hit / (hit + miss).
+	CacheRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Número total de consultas aos caches, por cache e resultado.",
+		},
+		[]string{"cache", "result"},
+	)
+
+	// rate_limit_rejected_total conta as requisições recusadas com 429, por rota.
+	RateLimitRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_rejected_total",
+			Help: "Número total de requisições recusadas por excederem o limite, por rota.",
+		},
+		[]string{"route"},
+	)
+)
+
+// ObservarStripe registra a latência e, em caso de falha, o código de erro de uma chamada à API da Stripe.
+func ObservarStripe(operacao string, start time.Time, err error) {
+	StripeAPIDuration.WithLabelValues(operacao).Observe(time.Since(start).Seconds())
+	if err == nil {
+		return
+	}
+	code := "unknown"
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) {
+		code = string(stripeErr.Type)
+		if stripeErr.Code != "" {
+			code = string(stripeErr.Code)
+		}
+	}
+	StripeAPIErrors.WithLabelValues(operacao, code).Inc()
+}
+
+// Outcome traduz um erro no rótulo "outcome" usado pelas métricas.
+func Outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// statusConhecidos são os status de assinatura que usamos nos painéis.
+var statusConhecidos = []string{"inactive", "active", "past_due", "canceled"}
+
+// SubscriptionCounter retorna a quantidade de organizações por status de assinatura.
+type SubscriptionCounter func(ctx context.Context) (map[string]int64, error)
+
+// subscriptionCollector calcula o gauge de assinaturas consultando o banco a cada scrape,
+// em vez de manter contadores em memória que divergiriam após um restart.
+type subscriptionCollector struct {
+	count   SubscriptionCounter
+	timeout time.Duration
+	desc    *prometheus.Desc
+}
+
+// NewSubscriptionCollector cria o coletor do gauge "subscriptions{status}".
+func NewSubscriptionCollector(count SubscriptionCounter) prometheus.Collector {
+	return &subscriptionCollector{
+		count:   count,
+		timeout: 5 * time.Second,
+		desc: prometheus.NewDesc(
+			"subscriptions",
+			"Número de organizações por status de assinatura (active, past_due, canceled, ...).",
+			[]string{"status"}, nil,
+		),
+	}
+}
+
+func (c *subscriptionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *subscriptionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	totais, err := c.count(ctx)
+	if err != nil {
+		slog.Error("Erro ao contar assinaturas para as métricas", "error", err)
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	// Os status conhecidos são sempre reportados, mesmo zerados, para que os painéis não fiquem sem série.
+	for _, status := range statusConhecidos {
+		if _, ok := totais[status]; !ok {
+			totais[status] = 0
+		}
+	}
+	for status, total := range totais {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(total), status)
+	}
+}
@@ -0,0 +1,379 @@
+// Package oidc implementa o lado cliente do fluxo authorization code do OpenID Connect, com
+// PKCE (S256), state e nonce: descoberta dos endpoints do provedor, montagem da URL de
+// autorização, troca do código pelo ID token e validação dele (assinatura RS256 pelas chaves
+// publicadas no JWKS, issuer, audience, expiração e nonce).
+//
+// Qualquer provedor OIDC serve, inclusive um servidor local de testes: basta o issuer
+// publicar /.well-known/openid-configuration.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTokenInvalido indica um ID token rejeitado na validação (assinatura, issuer,
+	// audience, expiração ou nonce) ou uma resposta de erro do provedor na troca do código.
+	ErrTokenInvalido = errors.New("ID token inválido")
+	// ErrProvedor indica uma falha de comunicação com o provedor ou uma resposta fora do padrão.
+	ErrProvedor = errors.New("falha na comunicação com o provedor OIDC")
+)
+
+const (
+	maxResposta       = 1 << 20     // Limite de leitura das respostas do provedor.
+	toleranciaRelogio = time.Minute // Diferença de relógio aceita em exp e iat.
+	intervaloJWKS     = time.Minute // Intervalo mínimo entre duas buscas do JWKS.
+	escoposPadrao     = "openid email profile"
+)
+
+// Config descreve um provedor. RedirectURL é a URL de callback registrada no provedor.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Escopos pedidos na autorização; vazio usa "openid email profile".
+	Escopos []string
+}
+
+// Perfil é a identidade do usuário extraída do ID token validado.
+type Perfil struct {
+	Subject         string
+	Email           string
+	EmailVerificado bool
+	Nome            string
+}
+
+// Provedor fala com um provedor OIDC. Os endpoints são descobertos na primeira chamada e as
+// chaves do JWKS ficam em memória, sendo buscadas de novo quando aparece um kid desconhecido.
+type Provedor struct {
+	cfg     Config
+	cliente *http.Client
+	agora   func() time.Time
+
+	mu         sync.Mutex
+	endpoints  *descoberta
+	chaves     map[string]*rsa.PublicKey
+	buscaChave time.Time
+}
+
+// descoberta são os campos usados do documento /.well-known/openid-configuration.
+type descoberta struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvedor cria o cliente do provedor. As requisições usam o http.Client informado.
+func NewProvedor(cfg Config, cliente *http.Client) *Provedor {
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	return &Provedor{cfg: cfg, cliente: cliente, agora: time.Now}
+}
+
+// NovoSegredo gera um valor aleatório de 256 bits em base64url, usado como state, nonce e
+// code verifier do PKCE.
+func NovoSegredo() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DesafioPKCE calcula o code challenge S256 do verificador (RFC 7636).
+func DesafioPKCE(verificador string) string {
+	h := sha256.Sum256([]byte(verificador))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// URLAutorizacao monta a URL para onde o navegador do usuário é redirecionado.
+func (p *Provedor) URLAutorizacao(ctx context.Context, state, nonce, verificador string) (string, error) {
+	d, err := p.descobrir(ctx)
+	if err != nil {
+		return "", err
+	}
+	escopos := escoposPadrao
+	if len(p.cfg.Escopos) > 0 {
+		escopos = strings.Join(p.cfg.Escopos, " ")
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {escopos},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {DesafioPKCE(verificador)},
+		"code_challenge_method": {"S256"},
+	}
+	separador := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separador = "&"
+	}
+	return d.AuthorizationEndpoint + separador + q.Encode(), nil
+}
+
+// Trocar troca o código de autorização pelos tokens e devolve o perfil do ID token, depois
+// de validá-lo contra o nonce enviado na autorização.
+func (p *Provedor) Trocar(ctx context.Context, codigo, verificador, nonce string) (*Perfil, error) {
+	d, err := p.descobrir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {codigo},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verificador},
+		"client_id":     {p.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic: as credenciais vão codificadas como em um formulário (RFC 6749, 2.3.1).
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var resposta struct {
+		IDToken   string `json:"id_token"`
+		Erro      string `json:"error"`
+		Descricao string `json:"error_description"`
+	}
+	status, err := p.fazer(req, &resposta)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		if resposta.Erro != "" {
+			// Código inválido, expirado, já usado ou verificador PKCE errado.
+			return nil, fmt.Errorf("%w: %s %s", ErrTokenInvalido, resposta.Erro, resposta.Descricao)
+		}
+		return nil, fmt.Errorf("%w: token endpoint respondeu %d", ErrProvedor, status)
+	}
+	if resposta.IDToken == "" {
+		return nil, fmt.Errorf("%w: resposta sem id_token", ErrProvedor)
+	}
+	return p.validar(ctx, d, resposta.IDToken, nonce)
+}
+
+// claims são os campos usados do ID token.
+type claims struct {
+	Issuer        string    `json:"iss"`
+	Subject       string    `json:"sub"`
+	Audience      audiencia `json:"aud"`
+	AZP           string    `json:"azp"`
+	Expira        int64     `json:"exp"`
+	EmitidoEm     int64     `json:"iat"`
+	Nonce         string    `json:"nonce"`
+	Email         string    `json:"email"`
+	EmailVerified booleano  `json:"email_verified"`
+	Nome          string    `json:"name"`
+}
+
+// audiencia aceita aud como string ou como lista (RFC 7519, 4.1.3).
+type audiencia []string
+
+func (a *audiencia) UnmarshalJSON(b []byte) error {
+	var uma string
+	if err := json.Unmarshal(b, &uma); err == nil {
+		*a = audiencia{uma}
+		return nil
+	}
+	var varias []string
+	if err := json.Unmarshal(b, &varias); err != nil {
+		return err
+	}
+	*a = varias
+	return nil
+}
+
+// booleano aceita true/false e também "true"/"false", que alguns provedores enviam.
+type booleano bool
+
+func (v *booleano) UnmarshalJSON(b []byte) error {
+	*v = booleano(strings.Trim(string(b), `"`) == "true")
+	return nil
+}
+
+// validar confere a assinatura e as claims do ID token.
+func (p *Provedor) validar(ctx context.Context, d *descoberta, token, nonce string) (*Perfil, error) {
+	partes := strings.Split(token, ".")
+	if len(partes) != 3 {
+		return nil, fmt.Errorf("%w: formato inesperado", ErrTokenInvalido)
+	}
+	var cabecalho struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodificarParte(partes[0], &cabecalho); err != nil {
+		return nil, err
+	}
+	if cabecalho.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: algoritmo %q não suportado", ErrTokenInvalido, cabecalho.Alg)
+	}
+	chave, err := p.chave(ctx, cabecalho.Kid)
+	if err != nil {
+		return nil, err
+	}
+	assinatura, err := base64.RawURLEncoding.DecodeString(partes[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: assinatura malformada", ErrTokenInvalido)
+	}
+	h := sha256.Sum256([]byte(partes[0] + "." + partes[1]))
+	if err := rsa.VerifyPKCS1v15(chave, crypto.SHA256, h[:], assinatura); err != nil {
+		return nil, fmt.Errorf("%w: assinatura inválida", ErrTokenInvalido)
+	}
+
+	var c claims
+	if err := decodificarParte(partes[1], &c); err != nil {
+		return nil, err
+	}
+	agora := p.agora()
+	switch {
+	case c.Issuer != d.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q inesperado", ErrTokenInvalido, c.Issuer)
+	case !slices.Contains(c.Audience, p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: token emitido para outro cliente", ErrTokenInvalido)
+	case len(c.Audience) > 1 && c.AZP != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: azp %q inesperado", ErrTokenInvalido, c.AZP)
+	case agora.After(time.Unix(c.Expira, 0).Add(toleranciaRelogio)):
+		return nil, fmt.Errorf("%w: token expirado", ErrTokenInvalido)
+	case time.Unix(c.EmitidoEm, 0).After(agora.Add(toleranciaRelogio)):
+		return nil, fmt.Errorf("%w: token emitido no futuro", ErrTokenInvalido)
+	case c.Nonce == "" || c.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce não confere", ErrTokenInvalido)
+	case c.Subject == "":
+		return nil, fmt.Errorf("%w: token sem sub", ErrTokenInvalido)
+	}
+	return &Perfil{Subject: c.Subject, Email: c.Email, EmailVerificado: bool(c.EmailVerified), Nome: c.Nome}, nil
+}
+
+func decodificarParte(parte string, dst any) error {
+	b, err := base64.RawURLEncoding.DecodeString(parte)
+	if err != nil {
+		return fmt.Errorf("%w: base64 inválido", ErrTokenInvalido)
+	}
+	if err := json.Unmarshal(b, dst); err != nil {
+		return fmt.Errorf("%w: JSON inválido", ErrTokenInvalido)
+	}
+	return nil
+}
+
+// descobrir busca (uma única vez) os endpoints publicados pelo issuer.
+func (p *Provedor) descobrir(ctx context.Context) (*descoberta, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var d descoberta
+	status, err := p.fazer(req, &d)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: descoberta respondeu %d", ErrProvedor, status)
+	}
+	if strings.TrimRight(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer da descoberta (%q) difere do configurado", ErrProvedor, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: descoberta incompleta", ErrProvedor)
+	}
+	p.endpoints = &d
+	return p.endpoints, nil
+}
+
+// chave devolve a chave pública do kid, buscando o JWKS de novo se ela não for conhecida
+// (o provedor pode ter feito rotação), no máximo uma vez por intervaloJWKS.
+func (p *Provedor) chave(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.chaves[kid]; ok {
+		return k, nil
+	}
+	if !p.buscaChave.IsZero() && p.agora().Sub(p.buscaChave) < intervaloJWKS {
+		return nil, fmt.Errorf("%w: chave %q desconhecida", ErrTokenInvalido, kid)
+	}
+	p.buscaChave = p.agora()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoints.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := p.fazer(req, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: JWKS respondeu %d", ErrProvedor, status)
+	}
+
+	chaves := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			continue
+		}
+		chaves[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.chaves = chaves
+	if k, ok := chaves[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: chave %q desconhecida", ErrTokenInvalido, kid)
+}
+
+// fazer executa a requisição e decodifica o corpo JSON em dst, qualquer que seja o status.
+func (p *Provedor) fazer(req *http.Request, dst any) (int, error) {
+	resp, err := p.cliente.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrProvedor, err)
+	}
+	defer resp.Body.Close()
+	corpo, err := io.ReadAll(io.LimitReader(resp.Body, maxResposta))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrProvedor, err)
+	}
+	if err := json.Unmarshal(corpo, dst); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("%w: resposta JSON inválida de %s", ErrProvedor, req.URL.Path)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,171 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servidorFake é um provedor OIDC mínimo: publica a descoberta e o JWKS e, no token
+// endpoint, confere o código e o PKCE e devolve o ID token montado por claims.
+type servidorFake struct {
+	*httptest.Server
+	chave       *rsa.PrivateKey
+	codigo      string
+	desafio     string
+	claims      map[string]any
+	trocas      int
+	buscasJWKS  int
+	credenciais string
+}
+
+func novoServidorFake(t *testing.T) *servidorFake {
+	chave, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	f := &servidorFake{chave: chave, codigo: "codigo-valido"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		f.buscasJWKS++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(chave.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(chave.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		f.trocas++
+		r.ParseForm()
+		usuario, senha, _ := r.BasicAuth()
+		f.credenciais = usuario + ":" + senha
+		if r.PostForm.Get("code") != f.codigo || DesafioPKCE(r.PostForm.Get("code_verifier")) != f.desafio {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": f.assinar(t, "k1", f.claims)})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *servidorFake) assinar(t *testing.T, kid string, claims map[string]any) string {
+	cabecalho, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	corpo, _ := json.Marshal(claims)
+	entrada := base64.RawURLEncoding.EncodeToString(cabecalho) + "." + base64.RawURLEncoding.EncodeToString(corpo)
+	h := sha256.Sum256([]byte(entrada))
+	assinatura, err := rsa.SignPKCS1v15(rand.Reader, f.chave, crypto.SHA256, h[:])
+	require.NoError(t, err)
+	return entrada + "." + base64.RawURLEncoding.EncodeToString(assinatura)
+}
+
+func TestProvedor(t *testing.T) {
+	ctx := context.Background()
+	novo := func(t *testing.T) (*Provedor, *servidorFake) {
+		f := novoServidorFake(t)
+		f.claims = map[string]any{
+			"iss": f.URL, "aud": "cliente", "sub": "123", "nonce": "n1",
+			"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(),
+			"email": "ana@email.com", "email_verified": true, "name": "Ana Souza",
+		}
+		p := NewProvedor(Config{Issuer: f.URL + "/", ClientID: "cliente", ClientSecret: "se/gredo", RedirectURL: "http://api/callback"}, f.Client())
+		return p, f
+	}
+
+	t.Run("sucesso - URL de autorização com state, nonce e desafio PKCE", func(t *testing.T) {
+		p, f := novo(t)
+
+		u, err := p.URLAutorizacao(ctx, "s1", "n1", "verificador")
+
+		require.NoError(t, err)
+		parsed, _ := url.Parse(u)
+		q := parsed.Query()
+		assert.Equal(t, f.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+		assert.Equal(t, "s1", q.Get("state"))
+		assert.Equal(t, "n1", q.Get("nonce"))
+		assert.Equal(t, DesafioPKCE("verificador"), q.Get("code_challenge"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.Equal(t, "openid email profile", q.Get("scope"))
+	})
+
+	t.Run("sucesso - troca o código e valida o ID token", func(t *testing.T) {
+		p, f := novo(t)
+		f.desafio = DesafioPKCE("verificador")
+
+		perfil, err := p.Trocar(ctx, "codigo-valido", "verificador", "n1")
+
+		require.NoError(t, err)
+		assert.Equal(t, &Perfil{Subject: "123", Email: "ana@email.com", EmailVerificado: true, Nome: "Ana Souza"}, perfil)
+		assert.Equal(t, "cliente:se%2Fgredo", f.credenciais)
+	})
+
+	t.Run("erro - verificador PKCE errado é recusado pelo provedor", func(t *testing.T) {
+		p, f := novo(t)
+		f.desafio = DesafioPKCE("verificador")
+
+		_, err := p.Trocar(ctx, "codigo-valido", "outro", "n1")
+
+		assert.ErrorIs(t, err, ErrTokenInvalido)
+	})
+
+	t.Run("erro - claims inválidas", func(t *testing.T) {
+		casos := map[string]func(c map[string]any){
+			"nonce diferente": func(c map[string]any) { c["nonce"] = "outro" },
+			"outro cliente":   func(c map[string]any) { c["aud"] = []string{"outro"} },
+			"outro issuer":    func(c map[string]any) { c["iss"] = "https://evil.example" },
+			"expirado":        func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		}
+		for nome, alterar := range casos {
+			p, f := novo(t)
+			f.desafio = DesafioPKCE("v")
+			alterar(f.claims)
+
+			_, err := p.Trocar(ctx, "codigo-valido", "v", "n1")
+
+			assert.ErrorIs(t, err, ErrTokenInvalido, nome)
+		}
+	})
+
+	t.Run("erro - assinatura com chave desconhecida não busca o JWKS a cada token", func(t *testing.T) {
+		p, f := novo(t)
+		outra, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		d, err := p.descobrir(ctx)
+		require.NoError(t, err)
+
+		valido := f.assinar(t, "k1", f.claims)
+		_, err = p.validar(ctx, d, valido, "n1")
+		require.NoError(t, err)
+
+		f.chave = outra
+		_, err = p.validar(ctx, d, f.assinar(t, "k1", f.claims), "n1")
+		assert.ErrorIs(t, err, ErrTokenInvalido)
+		_, err = p.validar(ctx, d, f.assinar(t, "k2", f.claims), "n1")
+		assert.ErrorIs(t, err, ErrTokenInvalido)
+		_, err = p.validar(ctx, d, f.assinar(t, "k3", f.claims), "n1")
+		assert.ErrorIs(t, err, ErrTokenInvalido)
+		assert.Equal(t, 1, f.buscasJWKS, "kid desconhecido só busca o JWKS de novo depois do intervalo")
+	})
+}
@@ -0,0 +1,176 @@
+// Package ratelimit implementa limitação de requisições por token bucket. O estado dos
+// buckets fica em um Store; MemoryStore serve para uma única instância da API e pode ser
+// trocado por um armazenamento compartilhado (ex: Redis) quando houver várias.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limite define um bucket: até Requisicoes de uma vez (a capacidade), repostas
+// continuamente ao longo de Periodo. Ex: 10 por minuto = uma nova a cada 6s.
+type Limite struct {
+	Requisicoes int
+	Periodo     time.Duration
+}
+
+func (l Limite) String() string {
+	return fmt.Sprintf("%d/%s", l.Requisicoes, l.Periodo)
+}
+
+// taxa devolve quantas requisições são repostas por segundo.
+func (l Limite) taxa() float64 {
+	return float64(l.Requisicoes) / l.Periodo.Seconds()
+}
+
+// Resultado é a decisão sobre uma requisição e o estado do bucket depois dela.
+type Resultado struct {
+	Permitido  bool
+	Restantes  int           // Requisições ainda disponíveis agora.
+	Reset      time.Duration // Tempo até o bucket voltar a ficar cheio.
+	RetryAfter time.Duration // Quando negada, tempo até a próxima requisição ser aceita.
+}
+
+// Store guarda os buckets. Take consome uma ficha do bucket da chave, criando-o cheio se
+// ainda não existir; Consultar informa se haveria ficha, sem consumi-la.
+type Store interface {
+	Take(ctx context.Context, chave string, limite Limite) (Resultado, error)
+	Consultar(ctx context.Context, chave string, limite Limite) (Resultado, error)
+}
+
+// MemoryStore guarda os buckets em memória. Buckets sem uso há mais tempo do que o
+// necessário para se encherem de novo são descartados periodicamente, já que
+// recriá-los cheios dá o mesmo resultado.
+type MemoryStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	ultimaLimpa time.Time
+	agora       func() time.Time
+}
+
+type bucket struct {
+	fichas    float64
+	atualizou time.Time
+	cheioEm   time.Time
+}
+
+// intervaloLimpeza é de quanto em quanto tempo o MemoryStore procura buckets ociosos.
+const intervaloLimpeza = time.Minute
+
+// NewMemoryStore cria um MemoryStore vazio.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), agora: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, chave string, limite Limite) (Resultado, error) {
+	return s.usar(chave, limite, true), nil
+}
+
+func (s *MemoryStore) Consultar(_ context.Context, chave string, limite Limite) (Resultado, error) {
+	return s.usar(chave, limite, false), nil
+}
+
+// usar repõe as fichas do bucket até agora e, se consumir for true e houver ficha, gasta uma.
+func (s *MemoryStore) usar(chave string, limite Limite, consumir bool) Resultado {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agora := s.agora()
+	if agora.Sub(s.ultimaLimpa) > intervaloLimpeza {
+		for k, b := range s.buckets {
+			if agora.After(b.cheioEm) {
+				delete(s.buckets, k)
+			}
+		}
+		s.ultimaLimpa = agora
+	}
+
+	capacidade := float64(limite.Requisicoes)
+	taxa := limite.taxa()
+	b, ok := s.buckets[chave]
+	if !ok {
+		b = &bucket{fichas: capacidade, atualizou: agora}
+		s.buckets[chave] = b
+	}
+	b.fichas = math.Min(capacidade, b.fichas+agora.Sub(b.atualizou).Seconds()*taxa)
+	b.atualizou = agora
+
+	res := Resultado{Permitido: b.fichas >= 1}
+	if res.Permitido && consumir {
+		b.fichas--
+	} else if !res.Permitido {
+		res.RetryAfter = segundos((1 - b.fichas) / taxa)
+	}
+	res.Restantes = int(b.fichas)
+	res.Reset = segundos((capacidade - b.fichas) / taxa)
+	b.cheioEm = agora.Add(res.Reset)
+	return res
+}
+
+func segundos(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// ParseLimite interpreta limites como "10/m", "100/s", "5/10m" ou "1000/h".
+func ParseLimite(s string) (Limite, error) {
+	qtd, periodo, ok := strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(qtd)
+	if !ok || err != nil || n < 1 {
+		return Limite{}, fmt.Errorf("limite %q inválido: use o formato 10/m", s)
+	}
+	// Sem número, a unidade vale 1 (m = 1m).
+	if periodo != "" && (periodo[0] < '0' || periodo[0] > '9') {
+		periodo = "1" + periodo
+	}
+	d, err := time.ParseDuration(periodo)
+	if err != nil || d <= 0 {
+		return Limite{}, fmt.Errorf("limite %q inválido: período deve ser s, m, h ou uma duração como 10m", s)
+	}
+	return Limite{Requisicoes: n, Periodo: d}, nil
+}
+
+// ParseRegras interpreta a configuração de limites por rota, no formato
+// "POST /usuarios=10/m; POST /organizations/{id}/checkout=5/m; *=120/m".
+// A rota é o método seguido do padrão do chi; "*" é o limite das demais rotas.
+func ParseRegras(s string) (map[string]Limite, error) {
+	regras := make(map[string]Limite)
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		rota, valor, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("regra %q inválida: use ROTA=LIMITE", item)
+		}
+		limite, err := ParseLimite(valor)
+		if err != nil {
+			return nil, err
+		}
+		regras[strings.Join(strings.Fields(rota), " ")] = limite
+	}
+	return regras, nil
+}
+
+// --- Identificação do cliente ---
+
+type chaveContexto struct{}
+
+// WithCliente registra no contexto a identidade autenticada do cliente (ex: o ID de uma
+// chave de API), usada por PorCliente no lugar do IP. Só deve ser chamado depois que a
+// credencial foi validada; do contrário, bastaria variar a credencial para escapar do limite.
+func WithCliente(ctx context.Context, cliente string) context.Context {
+	return context.WithValue(ctx, chaveContexto{}, cliente)
+}
+
+// Cliente devolve a identidade registrada por WithCliente, se houver.
+func Cliente(ctx context.Context) (string, bool) {
+	c, ok := ctx.Value(chaveContexto{}).(string)
+	return c, ok && c != ""
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	limite := Limite{Requisicoes: 3, Periodo: 3 * time.Second} // Uma ficha por segundo.
+
+	novo := func() (*MemoryStore, *time.Time) {
+		s := NewMemoryStore()
+		agora := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		s.agora = func() time.Time { return agora }
+		return s, &agora
+	}
+
+	t.Run("sucesso - permite a rajada e depois nega com o tempo de espera", func(t *testing.T) {
+		s, _ := novo()
+
+		for i := 2; i >= 0; i-- {
+			res, _ := s.Take(ctx, "ip:1", limite)
+			assert.True(t, res.Permitido)
+			assert.Equal(t, i, res.Restantes)
+		}
+		res, _ := s.Take(ctx, "ip:1", limite)
+
+		assert.False(t, res.Permitido)
+		assert.Equal(t, time.Second, res.RetryAfter)
+		assert.Equal(t, 3*time.Second, res.Reset)
+	})
+
+	t.Run("sucesso - fichas são repostas com o tempo e chaves são independentes", func(t *testing.T) {
+		s, agora := novo()
+		for i := 0; i < 3; i++ {
+			s.Take(ctx, "ip:1", limite)
+		}
+
+		*agora = agora.Add(1500 * time.Millisecond)
+		res, _ := s.Take(ctx, "ip:1", limite)
+		assert.True(t, res.Permitido)
+		assert.Equal(t, 0, res.Restantes)
+
+		res, _ = s.Take(ctx, "ip:2", limite)
+		assert.Equal(t, 2, res.Restantes)
+	})
+
+	t.Run("sucesso - consultar não consome fichas", func(t *testing.T) {
+		s, _ := novo()
+		s.Take(ctx, "ip:1", limite)
+		s.Take(ctx, "ip:1", limite)
+
+		res, _ := s.Consultar(ctx, "ip:1", limite)
+		assert.True(t, res.Permitido)
+		assert.Equal(t, 1, res.Restantes)
+
+		s.Take(ctx, "ip:1", limite)
+		res, _ = s.Consultar(ctx, "ip:1", limite)
+		assert.False(t, res.Permitido)
+		assert.Equal(t, time.Second, res.RetryAfter)
+	})
+
+	t.Run("sucesso - buckets ociosos são descartados", func(t *testing.T) {
+		s, agora := novo()
+		s.Take(ctx, "ip:1", limite)
+
+		*agora = agora.Add(2 * intervaloLimpeza)
+		s.Take(ctx, "ip:2", limite)
+
+		assert.Len(t, s.buckets, 1)
+	})
+}
+
+func TestParseRegras(t *testing.T) {
+	regras, err := ParseRegras("POST  /usuarios=10/m; POST /usuarios/{id}/criar-checkout=5/10m ;*=100/s")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Limite{
+		"POST /usuarios":                     {Requisicoes: 10, Periodo: time.Minute},
+		"POST /usuarios/{id}/criar-checkout": {Requisicoes: 5, Periodo: 10 * time.Minute},
+		"*":                                  {Requisicoes: 100, Periodo: time.Second},
+	}, regras)
+
+	for _, invalida := range []string{"POST /usuarios", "*=0/m", "*=10/dia", "*=dez/m"} {
+		_, err := ParseRegras(invalida)
+		assert.Error(t, err, invalida)
+	}
+}
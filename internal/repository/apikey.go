@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// APIKeyRepository define a persistência das API keys.
+type APIKeyRepository interface {
+	// Create grava a chave (com o hash do segredo) e devolve o ID gerado.
+	Create(ctx context.Context, key domain.APIKey, hash string) (int64, error)
+	// GetByPrefixo busca a chave e o hash do segredo pelo prefixo; devolve nil se não existir.
+	GetByPrefixo(ctx context.Context, prefixo string) (*domain.APIKey, string, error)
+	List(ctx context.Context) ([]domain.APIKey, error)
+	// Revoke marca a chave como revogada (uma chave já revogada mantém a data original).
+	// Devolve ErrNaoEncontrado se ela não existir.
+	Revoke(ctx context.Context, id int64) error
+	// TouchLastUsed registra o último uso da chave.
+	TouchLastUsed(ctx context.Context, id int64, quando time.Time) error
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	colunasAPIKey       = "id, nome, prefixo, escopos, created_at, last_used_at, revoked_at"
+	queryAPIKeyCreate   = "INSERT INTO api_keys(nome, prefixo, hash, escopos, created_at) VALUES(?, ?, ?, ?, ?)"
+	queryAPIKeyPrefixo  = "SELECT " + colunasAPIKey + ", hash FROM api_keys WHERE prefixo = ?"
+	queryAPIKeyList     = "SELECT " + colunasAPIKey + " FROM api_keys ORDER BY id"
+	queryAPIKeyRevoke   = "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?"
+	queryAPIKeyLastUsed = "UPDATE api_keys SET last_used_at = ? WHERE id = ?"
+)
+
+type apiKeySQLite struct {
+	stmts preparadas
+}
+
+// NewAPIKeyRepository cria o repositório de API keys sobre o banco da aplicação.
+func NewAPIKeyRepository(db *sql.DB) (APIKeyRepository, error) {
+	stmts, err := preparar(db,
+		queryAPIKeyCreate, queryAPIKeyPrefixo, queryAPIKeyList, queryAPIKeyRevoke,
+		queryAPIKeyLastUsed)
+	if err != nil {
+		return nil, err
+	}
+	return &apiKeySQLite{stmts: stmts}, nil
+}
+
+func (r *apiKeySQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *apiKeySQLite) Create(ctx context.Context, key domain.APIKey, hash string) (_ int64, err error) {
+	ctx, end := instrument(ctx, "APIKey.Create", queryAPIKeyCreate)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryAPIKeyCreate].ExecContext(ctx, key.Nome, key.Prefixo, hash, strings.Join(key.Escopos, " "), key.CreatedAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *apiKeySQLite) GetByPrefixo(ctx context.Context, prefixo string) (_ *domain.APIKey, _ string, err error) {
+	ctx, end := instrument(ctx, "APIKey.GetByPrefixo", queryAPIKeyPrefixo)
+	defer func() { end(err) }()
+
+	var hash string
+	key, err := scanAPIKey(r.stmts[queryAPIKeyPrefixo].QueryRowContext(ctx, prefixo), &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &key, hash, nil
+}
+
+func (r *apiKeySQLite) List(ctx context.Context) (_ []domain.APIKey, err error) {
+	ctx, end := instrument(ctx, "APIKey.List", queryAPIKeyList)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryAPIKeyList].QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeySQLite) Revoke(ctx context.Context, id int64) (err error) {
+	ctx, end := instrument(ctx, "APIKey.Revoke", queryAPIKeyRevoke)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryAPIKeyRevoke].ExecContext(ctx, now(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNaoEncontrado
+	}
+	return nil
+}
+
+func (r *apiKeySQLite) TouchLastUsed(ctx context.Context, id int64, quando time.Time) (err error) {
+	ctx, end := instrument(ctx, "APIKey.TouchLastUsed", queryAPIKeyLastUsed)
+	defer func() { end(err) }()
+
+	_, err = r.stmts[queryAPIKeyLastUsed].ExecContext(ctx, quando.UTC(), id)
+	return err
+}
+
+// scanAPIKey lê uma linha com as colunas de colunasAPIKey, seguidas dos destinos extras.
+func scanAPIKey(s scanner, extras ...any) (domain.APIKey, error) {
+	var key domain.APIKey
+	var escopos string
+	var lastUsedAt, revokedAt sql.NullTime
+
+	destinos := append([]any{&key.ID, &key.Nome, &key.Prefixo, &escopos, &key.CreatedAt, &lastUsedAt, &revokedAt}, extras...)
+	if err := s.Scan(destinos...); err != nil {
+		return domain.APIKey{}, err
+	}
+	key.Escopos = strings.Fields(escopos)
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
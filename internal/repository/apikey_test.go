@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewAPIKeyRepository(novoBanco(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	criada := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	id, err := repo.Create(ctx, domain.APIKey{Nome: "job", Prefixo: "usk_abc", Escopos: []string{"usuarios:read", "usuarios:write"}, CreatedAt: criada}, "hash")
+	require.NoError(t, err)
+
+	t.Run("sucesso - busca pelo prefixo com o hash", func(t *testing.T) {
+		key, hash, err := repo.GetByPrefixo(ctx, "usk_abc")
+
+		require.NoError(t, err)
+		assert.Equal(t, "hash", hash)
+		assert.Equal(t, &domain.APIKey{ID: id, Nome: "job", Prefixo: "usk_abc", Escopos: []string{"usuarios:read", "usuarios:write"}, CreatedAt: criada}, key)
+
+		key, _, err = repo.GetByPrefixo(ctx, "usk_outro")
+		assert.NoError(t, err)
+		assert.Nil(t, key)
+	})
+
+	t.Run("sucesso - registra uso e revogação, mantendo a data da primeira revogação", func(t *testing.T) {
+		require.NoError(t, repo.TouchLastUsed(ctx, id, criada.Add(time.Hour)))
+		require.NoError(t, repo.Revoke(ctx, id))
+		keys, _ := repo.List(ctx)
+		revogadaEm := *keys[0].RevokedAt
+
+		require.NoError(t, repo.Revoke(ctx, id))
+		keys, err := repo.List(ctx)
+
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.Equal(t, criada.Add(time.Hour), *keys[0].LastUsedAt)
+		assert.Equal(t, revogadaEm, *keys[0].RevokedAt)
+	})
+
+	t.Run("erro - revogar chave inexistente", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Revoke(ctx, 999), ErrNaoEncontrado)
+	})
+}
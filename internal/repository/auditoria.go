@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// AuditoriaRepository define a persistência da trilha de auditoria. Os eventos só são
+// acrescentados: não há alteração nem remoção.
+type AuditoriaRepository interface {
+	// Registrar grava o evento e devolve o ID gerado.
+	Registrar(ctx context.Context, evento domain.EventoAuditoria) (int64, error)
+	// ListByUsuario devolve os eventos sobre o usuário, do mais antigo ao mais recente.
+	ListByUsuario(ctx context.Context, usuarioID int64) ([]domain.EventoAuditoria, error)
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	queryAuditoriaRegistrar = "INSERT INTO audit_log(ator, acao, usuario_id, detalhes, created_at) VALUES(?, ?, ?, ?, ?)"
+	queryAuditoriaUsuario   = "SELECT id, ator, acao, usuario_id, COALESCE(detalhes, ''), created_at FROM audit_log WHERE usuario_id = ? ORDER BY id"
+)
+
+type auditoriaSQLite struct {
+	stmts preparadas
+}
+
+// NewAuditoriaRepository cria o repositório da trilha de auditoria sobre o banco da aplicação.
+func NewAuditoriaRepository(db *sql.DB) (AuditoriaRepository, error) {
+	stmts, err := preparar(db, queryAuditoriaRegistrar, queryAuditoriaUsuario)
+	if err != nil {
+		return nil, err
+	}
+	return &auditoriaSQLite{stmts: stmts}, nil
+}
+
+func (r *auditoriaSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *auditoriaSQLite) Registrar(ctx context.Context, evento domain.EventoAuditoria) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Auditoria.Registrar", queryAuditoriaRegistrar)
+	defer func() { end(err) }()
+
+	var detalhes sql.NullString
+	if len(evento.Detalhes) > 0 {
+		b, err := json.Marshal(evento.Detalhes)
+		if err != nil {
+			return 0, err
+		}
+		detalhes = sql.NullString{String: string(b), Valid: true}
+	}
+	res, err := r.stmts[queryAuditoriaRegistrar].ExecContext(ctx, evento.Ator, evento.Acao, evento.UsuarioID, detalhes, evento.CreatedAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *auditoriaSQLite) ListByUsuario(ctx context.Context, usuarioID int64) (_ []domain.EventoAuditoria, err error) {
+	ctx, end := instrument(ctx, "Auditoria.ListByUsuario", queryAuditoriaUsuario)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryAuditoriaUsuario].QueryContext(ctx, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	eventos := []domain.EventoAuditoria{}
+	for rows.Next() {
+		var evento domain.EventoAuditoria
+		var detalhes string
+		if err := rows.Scan(&evento.ID, &evento.Ator, &evento.Acao, &evento.UsuarioID, &detalhes, &evento.CreatedAt); err != nil {
+			return nil, err
+		}
+		if detalhes != "" {
+			if err := json.Unmarshal([]byte(detalhes), &evento.Detalhes); err != nil {
+				return nil, err
+			}
+		}
+		eventos = append(eventos, evento)
+	}
+	return eventos, rows.Err()
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+// migrationIndiceBusca cria a tabela virtual usuarios_fts, que exige FTS5.
+const migrationIndiceBusca = "000004_add_usuarios_fts.up.sql"
+
+// novoBanco abre um banco em memória com todas as migrations aplicadas. Sem FTS5 (go test
+// sem -tags sqlite_fts5), a migration do índice de busca é pulada, como a API faz: os
+// repositórios funcionam normalmente e só a busca, testada em search_fts5_test.go, fica de fora.
+func novoBanco(t testing.TB) *sql.DB {
+	t.Helper()
+	return novoBancoAntesDe(t, "")
+}
+
+// novoBancoAntesDe é como novoBanco, mas para antes da migration com o prefixo informado
+// (ex: "000016"), para testar a migração de dados existentes. Com "", aplica todas.
+func novoBancoAntesDe(t testing.TB, prefixo string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1) // Cada conexão teria o seu próprio banco em memória.
+	t.Cleanup(func() { db.Close() })
+	semFTS5 := errors.Is(ChecarFTS5(context.Background(), db), ErrFTS5Indisponivel)
+
+	arquivos, err := filepath.Glob("../../migrations/*.up.sql")
+	require.NoError(t, err)
+	sort.Strings(arquivos)
+	for _, arquivo := range arquivos {
+		if prefixo != "" && filepath.Base(arquivo) >= prefixo {
+			break
+		}
+		if semFTS5 && filepath.Base(arquivo) == migrationIndiceBusca {
+			continue
+		}
+		sqlMigration, err := os.ReadFile(arquivo)
+		require.NoError(t, err)
+		_, err = db.Exec(string(sqlMigration))
+		require.NoError(t, err, arquivo)
+	}
+	return db
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/cache"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+)
+
+// nomeCacheUsuarios identifica o cache de usuários nas métricas.
+const nomeCacheUsuarios = "usuarios"
+
+// cachedRepository é um decorator de UsuarioRepository que guarda em cache o resultado de
+// GetByID. Os demais métodos vão direto para o repositório decorado; os que alteram um
+// usuário invalidam a sua entrada.
+//
+// Falhas do cache nunca falham a requisição: a leitura cai no banco e o erro é registrado.
+type cachedRepository struct {
+	UsuarioRepository
+	store cache.Store
+	ttl   time.Duration
+	grupo singleflight.Group
+
+	// geracao é incrementada a cada invalidação. Uma leitura do banco só vai para o cache
+	// se nenhuma escrita aconteceu enquanto ela rodava; do contrário, poderíamos guardar
+	// uma versão anterior à escrita que acabou de invalidar a entrada.
+	geracao atomic.Uint64
+}
+
+// NewCachedRepository envolve repo com um cache de leitura (read-through) para GetByID,
+// com entradas válidas por ttl.
+func NewCachedRepository(repo UsuarioRepository, store cache.Store, ttl time.Duration) UsuarioRepository {
+	return &cachedRepository{UsuarioRepository: repo, store: store, ttl: ttl}
+}
+
+func chaveUsuario(id int64) string {
+	return "usuario:" + strconv.FormatInt(id, 10)
+}
+
+// GetByID consulta o cache e, na falta, o banco. Leituras simultâneas do mesmo usuário
+// ausente do cache são agrupadas em uma única consulta (singleflight).
+func (c *cachedRepository) GetByID(ctx context.Context, id int64) (*domain.Usuario, error) {
+	chave := chaveUsuario(id)
+
+	dados, ok, err := c.store.Get(ctx, chave)
+	if err != nil {
+		metrics.CacheRequests.WithLabelValues(nomeCacheUsuarios, "error").Inc()
+		logging.FromContext(ctx).WarnContext(ctx, "Erro ao ler do cache", "cache", nomeCacheUsuarios, "error", err)
+	}
+	if ok {
+		var u domain.Usuario
+		if err := gob.NewDecoder(bytes.NewReader(dados)).Decode(&u); err == nil {
+			metrics.CacheRequests.WithLabelValues(nomeCacheUsuarios, "hit").Inc()
+			return &u, nil
+		}
+		// Entrada corrompida ou de um formato antigo: tratamos como ausente.
+	}
+	metrics.CacheRequests.WithLabelValues(nomeCacheUsuarios, "miss").Inc()
+
+	// A consulta compartilhada não herda o cancelamento de quem chegou primeiro,
+	// para que um cliente que desistiu não faça falhar os demais que aguardam.
+	ch := c.grupo.DoChan(chave, func() (any, error) {
+		return c.carregar(context.WithoutCancel(ctx), id, chave)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		u, _ := res.Val.(*domain.Usuario)
+		if u == nil {
+			return nil, nil
+		}
+		copia := *u // Cada chamador recebe a sua cópia.
+		return &copia, nil
+	}
+}
+
+// carregar lê o usuário do banco e, se não houve escrita concorrente, guarda-o no cache.
+// Usuários inexistentes não são guardados.
+func (c *cachedRepository) carregar(ctx context.Context, id int64, chave string) (*domain.Usuario, error) {
+	geracao := c.geracao.Load()
+	u, err := c.UsuarioRepository.GetByID(ctx, id)
+	if err != nil || u == nil || c.geracao.Load() != geracao {
+		return u, err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(u); err != nil {
+		return u, nil
+	}
+	if err := c.store.Set(ctx, chave, buf.Bytes(), c.ttl); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "Erro ao gravar no cache", "cache", nomeCacheUsuarios, "error", err)
+	}
+	return u, nil
+}
+
+// invalidar remove os usuários do cache. É chamado depois da escrita no banco, mesmo
+// quando ela falha, já que uma falha não garante que nada foi alterado.
+func (c *cachedRepository) invalidar(ctx context.Context, ids ...int64) {
+	c.geracao.Add(1)
+	for _, id := range ids {
+		if err := c.store.Delete(ctx, chaveUsuario(id)); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "Erro ao invalidar o cache", "cache", nomeCacheUsuarios, "usuario_id", id, "error", err)
+		}
+	}
+}
+
+func (c *cachedRepository) Update(ctx context.Context, id int64, usuario domain.Usuario) error {
+	defer c.invalidar(ctx, id)
+	return c.UsuarioRepository.Update(ctx, id, usuario)
+}
+
+func (c *cachedRepository) Patch(ctx context.Context, id int64, patch domain.UsuarioPatch) error {
+	defer c.invalidar(ctx, id)
+	return c.UsuarioRepository.Patch(ctx, id, patch)
+}
+
+func (c *cachedRepository) Delete(ctx context.Context, id int64, version int64) error {
+	defer c.invalidar(ctx, id)
+	return c.UsuarioRepository.Delete(ctx, id, version)
+}
+
+func (c *cachedRepository) MarkEmailVerified(ctx context.Context, id int64, email string) error {
+	defer c.invalidar(ctx, id)
+	return c.UsuarioRepository.MarkEmailVerified(ctx, id, email)
+}
+
+func (c *cachedRepository) UpdatePapel(ctx context.Context, id int64, papel string) error {
+	defer c.invalidar(ctx, id)
+	return c.UsuarioRepository.UpdatePapel(ctx, id, papel)
+}
+
+// ExecutarLote invalida, ao fim da transação, os usuários alterados ou removidos pelo lote.
+func (c *cachedRepository) ExecutarLote(ctx context.Context, fn func(Lote) error) error {
+	lote := &loteInvalidador{}
+	defer func() { c.invalidar(ctx, lote.ids...) }()
+	return c.UsuarioRepository.ExecutarLote(ctx, func(l Lote) error {
+		lote.Lote = l
+		return fn(lote)
+	})
+}
+
+// loteInvalidador registra os IDs tocados pelas operações de um lote.
+type loteInvalidador struct {
+	Lote
+	ids []int64
+}
+
+func (l *loteInvalidador) Update(ctx context.Context, id int64, usuario domain.Usuario) (int64, error) {
+	l.ids = append(l.ids, id)
+	return l.Lote.Update(ctx, id, usuario)
+}
+
+func (l *loteInvalidador) Delete(ctx context.Context, id int64, version int64) error {
+	l.ids = append(l.ids, id)
+	return l.Lote.Delete(ctx, id, version)
+}
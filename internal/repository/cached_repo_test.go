@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/cache"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// repoContador implementa só o que o cache usa e conta as leituras que chegam ao "banco".
+type repoContador struct {
+	UsuarioRepository
+	leituras atomic.Int64
+	usuario  domain.Usuario
+	liberar  chan struct{} // Se não for nil, GetByID espera por ele.
+	durante  func()        // Executado no meio da leitura, para simular escritas concorrentes.
+}
+
+func (r *repoContador) GetByID(ctx context.Context, id int64) (*domain.Usuario, error) {
+	r.leituras.Add(1)
+	if r.liberar != nil {
+		<-r.liberar
+	}
+	if r.durante != nil {
+		r.durante()
+	}
+	if id != r.usuario.ID {
+		return nil, nil
+	}
+	u := r.usuario
+	return &u, nil
+}
+
+func (r *repoContador) Update(ctx context.Context, id int64, usuario domain.Usuario) error {
+	r.usuario.Nome = usuario.Nome
+	r.usuario.Version++
+	return nil
+}
+
+func (r *repoContador) ExecutarLote(ctx context.Context, fn func(Lote) error) error {
+	return fn(loteNulo{})
+}
+
+type loteNulo struct{ Lote }
+
+func (loteNulo) Delete(ctx context.Context, id int64, version int64) error { return nil }
+
+func TestCachedRepository(t *testing.T) {
+	ctx := context.Background()
+	novo := func() (*repoContador, UsuarioRepository) {
+		base := &repoContador{usuario: domain.Usuario{ID: 1, Nome: "Ana", Version: 2}}
+		return base, NewCachedRepository(base, cache.NewLRU(100), time.Minute)
+	}
+
+	t.Run("sucesso - segunda leitura vem do cache, com os campos não expostos na API", func(t *testing.T) {
+		base, repo := novo()
+
+		repo.GetByID(ctx, 1)
+		u, err := repo.GetByID(ctx, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), base.leituras.Load())
+		assert.Equal(t, int64(2), u.Version)
+	})
+
+	t.Run("sucesso - escritas invalidam a entrada", func(t *testing.T) {
+		base, repo := novo()
+
+		repo.GetByID(ctx, 1)
+		repo.Update(ctx, 1, domain.Usuario{Nome: "Ana Lima"})
+		u, _ := repo.GetByID(ctx, 1)
+		assert.Equal(t, "Ana Lima", u.Nome)
+
+		repo.ExecutarLote(ctx, func(l Lote) error { return l.Delete(ctx, 1, 0) })
+		repo.GetByID(ctx, 1)
+		assert.Equal(t, int64(3), base.leituras.Load())
+	})
+
+	t.Run("sucesso - usuário inexistente não é guardado", func(t *testing.T) {
+		base, repo := novo()
+
+		u, err := repo.GetByID(ctx, 99)
+		repo.GetByID(ctx, 99)
+
+		assert.NoError(t, err)
+		assert.Nil(t, u)
+		assert.Equal(t, int64(2), base.leituras.Load())
+	})
+
+	t.Run("sucesso - leituras simultâneas viram uma única consulta", func(t *testing.T) {
+		base, repo := novo()
+		base.liberar = make(chan struct{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				u, err := repo.GetByID(ctx, 1)
+				assert.NoError(t, err)
+				assert.Equal(t, "Ana", u.Nome)
+			}()
+		}
+		// Dá tempo para as goroutines chegarem ao singleflight antes de liberar a consulta.
+		time.Sleep(50 * time.Millisecond)
+		close(base.liberar)
+		wg.Wait()
+
+		assert.Equal(t, int64(1), base.leituras.Load())
+	})
+
+	t.Run("sucesso - leitura concorrente com uma escrita não vai para o cache", func(t *testing.T) {
+		base, repo := novo()
+		base.durante = func() {
+			base.durante = nil
+			repo.Update(ctx, 1, domain.Usuario{Nome: "Ana Lima"})
+		}
+
+		repo.GetByID(ctx, 1)
+		u, _ := repo.GetByID(ctx, 1)
+
+		assert.Equal(t, "Ana Lima", u.Nome)
+		assert.Equal(t, int64(2), base.leituras.Load())
+	})
+}
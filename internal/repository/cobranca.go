@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// CobrancaRepository define a persistência dos dados de cobrança dos usuários.
+type CobrancaRepository interface {
+	// Get busca os dados de cobrança do usuário; devolve nil se ele não os cadastrou.
+	Get(ctx context.Context, usuarioID int64) (*domain.DadosCobranca, error)
+	// Salvar grava os dados de cobrança do usuário, substituindo os anteriores.
+	Salvar(ctx context.Context, usuarioID int64, dados domain.DadosCobranca) error
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	queryCobrancaGet    = "SELECT linha1, linha2, cidade, estado, cep, pais, documento, updated_at FROM billing_details WHERE usuario_id = ?"
+	queryCobrancaSalvar = "INSERT INTO billing_details(usuario_id, linha1, linha2, cidade, estado, cep, pais, documento, updated_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?) " +
+		"ON CONFLICT(usuario_id) DO UPDATE SET linha1 = excluded.linha1, linha2 = excluded.linha2, cidade = excluded.cidade, estado = excluded.estado, " +
+		"cep = excluded.cep, pais = excluded.pais, documento = excluded.documento, updated_at = excluded.updated_at"
+)
+
+type cobrancaSQLite struct {
+	stmts preparadas
+}
+
+// NewCobrancaRepository cria o repositório de dados de cobrança sobre o banco da aplicação.
+func NewCobrancaRepository(db *sql.DB) (CobrancaRepository, error) {
+	stmts, err := preparar(db, queryCobrancaGet, queryCobrancaSalvar)
+	if err != nil {
+		return nil, err
+	}
+	return &cobrancaSQLite{stmts: stmts}, nil
+}
+
+func (r *cobrancaSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *cobrancaSQLite) Get(ctx context.Context, usuarioID int64) (_ *domain.DadosCobranca, err error) {
+	ctx, end := instrument(ctx, "Cobranca.Get", queryCobrancaGet)
+	defer func() { end(err) }()
+
+	var d domain.DadosCobranca
+	e := &d.Endereco
+	err = r.stmts[queryCobrancaGet].QueryRowContext(ctx, usuarioID).Scan(&e.Linha1, &e.Linha2, &e.Cidade, &e.Estado, &e.CEP, &e.Pais, &d.Documento, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *cobrancaSQLite) Salvar(ctx context.Context, usuarioID int64, d domain.DadosCobranca) (err error) {
+	ctx, end := instrument(ctx, "Cobranca.Salvar", queryCobrancaSalvar)
+	defer func() { end(err) }()
+
+	e := d.Endereco
+	_, err = r.stmts[queryCobrancaSalvar].ExecContext(ctx, usuarioID, e.Linha1, e.Linha2, e.Cidade, e.Estado, e.CEP, e.Pais, d.Documento, now())
+	return err
+}
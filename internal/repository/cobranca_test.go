@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestCobrancaRepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+	repo, err := NewCobrancaRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	ana, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+
+	t.Run("sucesso - grava, substitui e remove com o usuário", func(t *testing.T) {
+		dados, err := repo.Get(ctx, ana)
+		require.NoError(t, err)
+		assert.Nil(t, dados)
+
+		endereco := domain.Endereco{Linha1: "Av. Paulista, 1000", Cidade: "São Paulo", Estado: "SP", CEP: "01310-100", Pais: "BR"}
+		require.NoError(t, repo.Salvar(ctx, ana, domain.DadosCobranca{Endereco: endereco, Documento: "11144477735"}))
+		endereco.Linha2 = "Conjunto 42"
+		require.NoError(t, repo.Salvar(ctx, ana, domain.DadosCobranca{Endereco: endereco}))
+
+		dados, err = repo.Get(ctx, ana)
+		require.NoError(t, err)
+		require.NotNil(t, dados)
+		assert.Equal(t, endereco, dados.Endereco)
+		assert.Empty(t, dados.Documento)
+		assert.False(t, dados.UpdatedAt.IsZero())
+
+		require.NoError(t, usuarios.Delete(ctx, ana, 0))
+		dados, err = repo.Get(ctx, ana)
+		require.NoError(t, err)
+		assert.Nil(t, dados)
+	})
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// CupomRepository define a persistência dos códigos promocionais espelhados da Stripe.
+type CupomRepository interface {
+	// Salvar grava o código, ou atualiza o já gravado com o mesmo ID da Stripe.
+	Salvar(ctx context.Context, cupom domain.Cupom) error
+	// Buscar busca o código sem diferenciar maiúsculas de minúsculas, preferindo o ativo e o
+	// atualizado mais recentemente; devolve nil se não existir.
+	Buscar(ctx context.Context, codigo string) (*domain.Cupom, error)
+	// BuscarPorStripeID busca o código pelo ID do promotion code; devolve nil se não existir.
+	BuscarPorStripeID(ctx context.Context, stripePromotionCodeID string) (*domain.Cupom, error)
+	// DesativarCupom desativa todos os códigos do cupom da Stripe (ex: quando ele é apagado).
+	DesativarCupom(ctx context.Context, stripeCouponID string) error
+	// RegistrarResgate conta mais um resgate do código, até a próxima sincronização com a Stripe.
+	RegistrarResgate(ctx context.Context, stripePromotionCodeID string) error
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	colunasCupom = "codigo, stripe_promotion_code_id, stripe_coupon_id, nome, percent_off, amount_off, moeda, duracao, duracao_meses, ativo, expires_at, max_resgates, resgates, updated_at"
+
+	queryCupomSalvar = "INSERT INTO coupons(" + colunasCupom + ") VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) " +
+		"ON CONFLICT(stripe_promotion_code_id) DO UPDATE SET codigo = excluded.codigo, stripe_coupon_id = excluded.stripe_coupon_id, nome = excluded.nome, " +
+		"percent_off = excluded.percent_off, amount_off = excluded.amount_off, moeda = excluded.moeda, duracao = excluded.duracao, duracao_meses = excluded.duracao_meses, " +
+		"ativo = excluded.ativo, expires_at = excluded.expires_at, max_resgates = excluded.max_resgates, resgates = excluded.resgates, updated_at = excluded.updated_at"
+	queryCupomBuscar    = "SELECT " + colunasCupom + " FROM coupons WHERE codigo = ? ORDER BY ativo DESC, updated_at DESC LIMIT 1"
+	queryCupomStripe    = "SELECT " + colunasCupom + " FROM coupons WHERE stripe_promotion_code_id = ?"
+	queryCupomDesativar = "UPDATE coupons SET ativo = FALSE, updated_at = ? WHERE stripe_coupon_id = ?"
+	queryCupomResgate   = "UPDATE coupons SET resgates = resgates + 1, updated_at = ? WHERE stripe_promotion_code_id = ?"
+)
+
+type cupomSQLite struct {
+	stmts preparadas
+}
+
+// NewCupomRepository cria o repositório de códigos promocionais sobre o banco da aplicação.
+func NewCupomRepository(db *sql.DB) (CupomRepository, error) {
+	stmts, err := preparar(db,
+		queryCupomSalvar, queryCupomBuscar, queryCupomStripe, queryCupomDesativar,
+		queryCupomResgate)
+	if err != nil {
+		return nil, err
+	}
+	return &cupomSQLite{stmts: stmts}, nil
+}
+
+func (r *cupomSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *cupomSQLite) Salvar(ctx context.Context, c domain.Cupom) (err error) {
+	ctx, end := instrument(ctx, "Cupom.Salvar", queryCupomSalvar)
+	defer func() { end(err) }()
+
+	var expira any
+	if c.ExpiresAt != nil {
+		expira = c.ExpiresAt.UTC()
+	}
+	_, err = r.stmts[queryCupomSalvar].ExecContext(ctx, c.Codigo, c.StripePromotionCodeID, c.StripeCouponID, c.Nome, c.PercentOff, c.AmountOff,
+		c.Moeda, c.Duracao, c.DuracaoMeses, c.Ativo, expira, c.MaxResgates, c.Resgates, now())
+	return err
+}
+
+func (r *cupomSQLite) Buscar(ctx context.Context, codigo string) (_ *domain.Cupom, err error) {
+	ctx, end := instrument(ctx, "Cupom.Buscar", queryCupomBuscar)
+	defer func() { end(err) }()
+
+	c, err := scanCupom(r.stmts[queryCupomBuscar].QueryRowContext(ctx, codigo))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func (r *cupomSQLite) BuscarPorStripeID(ctx context.Context, stripePromotionCodeID string) (_ *domain.Cupom, err error) {
+	ctx, end := instrument(ctx, "Cupom.BuscarPorStripeID", queryCupomStripe)
+	defer func() { end(err) }()
+
+	c, err := scanCupom(r.stmts[queryCupomStripe].QueryRowContext(ctx, stripePromotionCodeID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func (r *cupomSQLite) DesativarCupom(ctx context.Context, stripeCouponID string) (err error) {
+	ctx, end := instrument(ctx, "Cupom.DesativarCupom", queryCupomDesativar)
+	defer func() { end(err) }()
+
+	_, err = r.stmts[queryCupomDesativar].ExecContext(ctx, now(), stripeCouponID)
+	return err
+}
+
+func (r *cupomSQLite) RegistrarResgate(ctx context.Context, stripePromotionCodeID string) (err error) {
+	ctx, end := instrument(ctx, "Cupom.RegistrarResgate", queryCupomResgate)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryCupomResgate].ExecContext(ctx, now(), stripePromotionCodeID)
+	if err != nil {
+		return err
+	}
+	return checarAfetadas(res)
+}
+
+func scanCupom(row scanner) (*domain.Cupom, error) {
+	var c domain.Cupom
+	var expira sql.NullTime
+	err := row.Scan(&c.Codigo, &c.StripePromotionCodeID, &c.StripeCouponID, &c.Nome, &c.PercentOff, &c.AmountOff,
+		&c.Moeda, &c.Duracao, &c.DuracaoMeses, &c.Ativo, &expira, &c.MaxResgates, &c.Resgates, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if expira.Valid {
+		t := expira.Time
+		c.ExpiresAt = &t
+	}
+	return &c, nil
+}
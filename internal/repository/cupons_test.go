@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestCupomRepository(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewCupomRepository(novoBanco(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	expira := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+
+	t.Run("sucesso - grava, atualiza e busca sem diferenciar maiúsculas", func(t *testing.T) {
+		cupom := domain.Cupom{Codigo: "BemVindo10", StripePromotionCodeID: "promo_1", StripeCouponID: "cpn_1", Nome: "Boas-vindas",
+			PercentOff: 10, Duracao: "repeating", DuracaoMeses: 3, Ativo: true, ExpiresAt: &expira, MaxResgates: 5}
+		require.NoError(t, repo.Salvar(ctx, cupom))
+		cupom.Resgates = 2
+		require.NoError(t, repo.Salvar(ctx, cupom))
+		// Um código antigo, já desativado, com o mesmo texto.
+		require.NoError(t, repo.Salvar(ctx, domain.Cupom{Codigo: "BEMVINDO10", StripePromotionCodeID: "promo_0", StripeCouponID: "cpn_0", Duracao: "once"}))
+
+		c, err := repo.Buscar(ctx, "bemvindo10")
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.Equal(t, "promo_1", c.StripePromotionCodeID)
+		assert.Equal(t, int64(2), c.Resgates)
+		assert.Equal(t, float64(10), c.PercentOff)
+		assert.True(t, c.ExpiresAt.Equal(expira))
+
+		c, err = repo.Buscar(ctx, "OUTRO")
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+
+	t.Run("sucesso - conta resgates e desativa os códigos do cupom", func(t *testing.T) {
+		require.NoError(t, repo.RegistrarResgate(ctx, "promo_1"))
+		assert.ErrorIs(t, repo.RegistrarResgate(ctx, "promo_x"), ErrNaoEncontrado)
+		require.NoError(t, repo.DesativarCupom(ctx, "cpn_1"))
+
+		c, err := repo.BuscarPorStripeID(ctx, "promo_1")
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.Equal(t, int64(3), c.Resgates)
+		assert.False(t, c.Ativo)
+
+		c, err = repo.BuscarPorStripeID(ctx, "promo_x")
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+}
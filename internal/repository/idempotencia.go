@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// IdempotenciaRepository guarda as respostas das requisições feitas com Idempotency-Key.
+type IdempotenciaRepository interface {
+	// Reservar registra a chave como em andamento, com validade até expira. Se ela já
+	// existir (e não tiver expirado), nada é alterado e o registro atual é devolvido;
+	// se a reserva foi feita, devolve nil.
+	Reservar(ctx context.Context, chave, fingerprint string, expira time.Time) (*domain.RespostaIdempotente, error)
+	// Concluir grava a resposta da requisição que reservou a chave.
+	Concluir(ctx context.Context, chave string, resposta domain.RespostaIdempotente) error
+	// Liberar remove a reserva, para que a chave possa ser usada em uma nova tentativa.
+	Liberar(ctx context.Context, chave string) error
+	// RemoverExpiradas apaga as chaves vencidas e devolve quantas foram removidas.
+	RemoverExpiradas(ctx context.Context) (int64, error)
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	// Uma chave vencida é substituída pela nova reserva como se não existisse.
+	queryIdempotenciaReservar = `
+		INSERT INTO idempotency_keys(chave, fingerprint, created_at, expires_at) VALUES(?, ?, ?, ?)
+		ON CONFLICT(chave) DO UPDATE SET
+			fingerprint = excluded.fingerprint, status = NULL, headers = NULL, corpo = NULL,
+			created_at = excluded.created_at, expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at`
+	queryIdempotenciaBuscar    = "SELECT fingerprint, status, headers, corpo FROM idempotency_keys WHERE chave = ?"
+	queryIdempotenciaConcluir  = "UPDATE idempotency_keys SET status = ?, headers = ?, corpo = ? WHERE chave = ?"
+	queryIdempotenciaLiberar   = "DELETE FROM idempotency_keys WHERE chave = ? AND status IS NULL"
+	queryIdempotenciaExpiradas = "DELETE FROM idempotency_keys WHERE expires_at <= ?"
+)
+
+type idempotenciaSQLite struct {
+	stmts preparadas
+}
+
+// NewIdempotenciaRepository cria o repositório de Idempotency-Keys sobre o banco da aplicação.
+func NewIdempotenciaRepository(db *sql.DB) (IdempotenciaRepository, error) {
+	stmts, err := preparar(db,
+		queryIdempotenciaReservar, queryIdempotenciaBuscar, queryIdempotenciaConcluir,
+		queryIdempotenciaLiberar, queryIdempotenciaExpiradas)
+	if err != nil {
+		return nil, err
+	}
+	return &idempotenciaSQLite{stmts: stmts}, nil
+}
+
+func (r *idempotenciaSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *idempotenciaSQLite) Reservar(ctx context.Context, chave, fingerprint string, expira time.Time) (_ *domain.RespostaIdempotente, err error) {
+	ctx, end := instrument(ctx, "Idempotencia.Reservar", queryIdempotenciaReservar)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryIdempotenciaReservar].ExecContext(ctx, chave, fingerprint, now(), expira.UTC())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 1 {
+		return nil, err
+	}
+
+	var resposta domain.RespostaIdempotente
+	var status sql.NullInt64
+	var headers sql.NullString
+	err = r.stmts[queryIdempotenciaBuscar].QueryRowContext(ctx, chave).Scan(&resposta.Fingerprint, &status, &headers, &resposta.Corpo)
+	if err != nil {
+		return nil, err
+	}
+	resposta.Status = int(status.Int64)
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &resposta.Headers); err != nil {
+			return nil, err
+		}
+	}
+	return &resposta, nil
+}
+
+func (r *idempotenciaSQLite) Concluir(ctx context.Context, chave string, resposta domain.RespostaIdempotente) (err error) {
+	ctx, end := instrument(ctx, "Idempotencia.Concluir", queryIdempotenciaConcluir)
+	defer func() { end(err) }()
+
+	headers, err := json.Marshal(resposta.Headers)
+	if err != nil {
+		return err
+	}
+	_, err = r.stmts[queryIdempotenciaConcluir].ExecContext(ctx, resposta.Status, string(headers), resposta.Corpo, chave)
+	return err
+}
+
+func (r *idempotenciaSQLite) Liberar(ctx context.Context, chave string) (err error) {
+	ctx, end := instrument(ctx, "Idempotencia.Liberar", queryIdempotenciaLiberar)
+	defer func() { end(err) }()
+
+	_, err = r.stmts[queryIdempotenciaLiberar].ExecContext(ctx, chave)
+	return err
+}
+
+func (r *idempotenciaSQLite) RemoverExpiradas(ctx context.Context) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Idempotencia.RemoverExpiradas", queryIdempotenciaExpiradas)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryIdempotenciaExpiradas].ExecContext(ctx, now())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestIdempotenciaRepository(t *testing.T) {
+	ctx := context.Background()
+	novo := func(t *testing.T) IdempotenciaRepository {
+		repo, err := NewIdempotenciaRepository(novoBanco(t))
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	}
+
+	t.Run("sucesso - reserva, conclui e devolve a resposta guardada", func(t *testing.T) {
+		repo := novo(t)
+		expira := time.Now().Add(time.Hour)
+
+		existente, err := repo.Reservar(ctx, "chave-1", "fp", expira)
+		require.NoError(t, err)
+		assert.Nil(t, existente)
+
+		existente, err = repo.Reservar(ctx, "chave-1", "fp", expira)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.RespostaIdempotente{Fingerprint: "fp"}, existente, "em andamento")
+
+		resposta := domain.RespostaIdempotente{Status: 201, Headers: map[string]string{"Content-Type": "application/json"}, Corpo: []byte(`{"id":1}`)}
+		require.NoError(t, repo.Concluir(ctx, "chave-1", resposta))
+
+		existente, err = repo.Reservar(ctx, "chave-1", "outro-fp", expira)
+		require.NoError(t, err)
+		resposta.Fingerprint = "fp"
+		assert.Equal(t, &resposta, existente)
+	})
+
+	t.Run("sucesso - chave liberada ou vencida pode ser reservada de novo", func(t *testing.T) {
+		repo := novo(t)
+
+		repo.Reservar(ctx, "liberada", "fp", time.Now().Add(time.Hour))
+		require.NoError(t, repo.Liberar(ctx, "liberada"))
+		existente, err := repo.Reservar(ctx, "liberada", "fp", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, existente)
+
+		repo.Reservar(ctx, "vencida", "fp", time.Now().Add(-time.Second))
+		existente, err = repo.Reservar(ctx, "vencida", "outro-fp", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, existente)
+	})
+
+	t.Run("sucesso - remove apenas as chaves vencidas", func(t *testing.T) {
+		repo := novo(t)
+		repo.Reservar(ctx, "vencida", "fp", time.Now().Add(-time.Second))
+		repo.Reservar(ctx, "valida", "fp", time.Now().Add(time.Hour))
+
+		n, err := repo.RemoverExpiradas(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// IdentidadeRepository define a persistência das identidades OIDC e dos logins em andamento.
+type IdentidadeRepository interface {
+	// GetByProvedor busca a identidade pela conta no provedor; devolve nil se não existir.
+	GetByProvedor(ctx context.Context, provedor, subject string) (*domain.Identidade, error)
+	// Create vincula a identidade ao usuário e devolve o ID gerado.
+	Create(ctx context.Context, identidade domain.Identidade) (int64, error)
+	// ListByUsuario devolve as identidades vinculadas ao usuário.
+	ListByUsuario(ctx context.Context, usuarioID int64) ([]domain.Identidade, error)
+	// TouchLastLogin registra o último login pela identidade.
+	TouchLastLogin(ctx context.Context, id int64, quando time.Time) error
+	// RemoverDoUsuario desvincula todas as identidades do usuário e devolve quantas eram.
+	RemoverDoUsuario(ctx context.Context, usuarioID int64) (int64, error)
+
+	// SalvarEstado guarda o login em andamento e apaga os que já venceram.
+	SalvarEstado(ctx context.Context, estado domain.EstadoLogin) error
+	// ConsumirEstado remove e devolve o login em andamento do state; devolve nil se ele não
+	// existir ou já tiver sido consumido. A validade fica a cargo de quem chama.
+	ConsumirEstado(ctx context.Context, state string) (*domain.EstadoLogin, error)
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	colunasIdentidade        = "id, usuario_id, provedor, subject, email, created_at, last_login_at"
+	queryIdentidadeProvedor  = "SELECT " + colunasIdentidade + " FROM identities WHERE provedor = ? AND subject = ?"
+	queryIdentidadeCreate    = "INSERT INTO identities(usuario_id, provedor, subject, email, created_at, last_login_at) VALUES(?, ?, ?, ?, ?, ?)"
+	queryIdentidadeUsuario   = "SELECT " + colunasIdentidade + " FROM identities WHERE usuario_id = ? ORDER BY id"
+	queryIdentidadeLastLogin = "UPDATE identities SET last_login_at = ? WHERE id = ?"
+	queryIdentidadeRemover   = "DELETE FROM identities WHERE usuario_id = ?"
+	queryEstadoLimpar        = "DELETE FROM oidc_states WHERE expires_at <= ?"
+	queryEstadoSalvar        = "INSERT INTO oidc_states(state, provedor, nonce, verificador, expires_at) VALUES(?, ?, ?, ?, ?)"
+	queryEstadoConsumir      = "DELETE FROM oidc_states WHERE state = ? RETURNING provedor, nonce, verificador, expires_at"
+)
+
+type identidadeSQLite struct {
+	stmts preparadas
+}
+
+// NewIdentidadeRepository cria o repositório de identidades sobre o banco da aplicação.
+func NewIdentidadeRepository(db *sql.DB) (IdentidadeRepository, error) {
+	stmts, err := preparar(db,
+		queryIdentidadeProvedor, queryIdentidadeCreate, queryIdentidadeUsuario,
+		queryIdentidadeLastLogin, queryIdentidadeRemover, queryEstadoLimpar, queryEstadoSalvar, queryEstadoConsumir)
+	if err != nil {
+		return nil, err
+	}
+	return &identidadeSQLite{stmts: stmts}, nil
+}
+
+func (r *identidadeSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *identidadeSQLite) GetByProvedor(ctx context.Context, provedor, subject string) (_ *domain.Identidade, err error) {
+	ctx, end := instrument(ctx, "Identidade.GetByProvedor", queryIdentidadeProvedor)
+	defer func() { end(err) }()
+
+	identidade, err := scanIdentidade(r.stmts[queryIdentidadeProvedor].QueryRowContext(ctx, provedor, subject))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identidade, nil
+}
+
+func (r *identidadeSQLite) Create(ctx context.Context, identidade domain.Identidade) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Identidade.Create", queryIdentidadeCreate)
+	defer func() { end(err) }()
+
+	var lastLogin sql.NullTime
+	if identidade.LastLoginAt != nil {
+		lastLogin = sql.NullTime{Time: identidade.LastLoginAt.UTC(), Valid: true}
+	}
+	res, err := r.stmts[queryIdentidadeCreate].ExecContext(ctx, identidade.UsuarioID, identidade.Provedor,
+		identidade.Subject, identidade.Email, identidade.CreatedAt.UTC(), lastLogin)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *identidadeSQLite) ListByUsuario(ctx context.Context, usuarioID int64) (_ []domain.Identidade, err error) {
+	ctx, end := instrument(ctx, "Identidade.ListByUsuario", queryIdentidadeUsuario)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryIdentidadeUsuario].QueryContext(ctx, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identidades := []domain.Identidade{}
+	for rows.Next() {
+		identidade, err := scanIdentidade(rows)
+		if err != nil {
+			return nil, err
+		}
+		identidades = append(identidades, identidade)
+	}
+	return identidades, rows.Err()
+}
+
+func (r *identidadeSQLite) TouchLastLogin(ctx context.Context, id int64, quando time.Time) (err error) {
+	ctx, end := instrument(ctx, "Identidade.TouchLastLogin", queryIdentidadeLastLogin)
+	defer func() { end(err) }()
+
+	_, err = r.stmts[queryIdentidadeLastLogin].ExecContext(ctx, quando.UTC(), id)
+	return err
+}
+
+func (r *identidadeSQLite) RemoverDoUsuario(ctx context.Context, usuarioID int64) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Identidade.RemoverDoUsuario", queryIdentidadeRemover)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryIdentidadeRemover].ExecContext(ctx, usuarioID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *identidadeSQLite) SalvarEstado(ctx context.Context, estado domain.EstadoLogin) (err error) {
+	ctx, end := instrument(ctx, "Identidade.SalvarEstado", queryEstadoSalvar)
+	defer func() { end(err) }()
+
+	// A tabela só guarda logins de poucos minutos; limpar a cada novo login basta para mantê-la pequena.
+	if _, err := r.stmts[queryEstadoLimpar].ExecContext(ctx, now()); err != nil {
+		return err
+	}
+	_, err = r.stmts[queryEstadoSalvar].ExecContext(ctx, estado.State, estado.Provedor, estado.Nonce, estado.Verificador, estado.ExpiresAt.UTC())
+	return err
+}
+
+func (r *identidadeSQLite) ConsumirEstado(ctx context.Context, state string) (_ *domain.EstadoLogin, err error) {
+	ctx, end := instrument(ctx, "Identidade.ConsumirEstado", queryEstadoConsumir)
+	defer func() { end(err) }()
+
+	estado := domain.EstadoLogin{State: state}
+	err = r.stmts[queryEstadoConsumir].QueryRowContext(ctx, state).Scan(&estado.Provedor, &estado.Nonce, &estado.Verificador, &estado.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &estado, nil
+}
+
+// scanIdentidade lê uma linha com as colunas de colunasIdentidade.
+func scanIdentidade(s scanner) (domain.Identidade, error) {
+	var identidade domain.Identidade
+	var lastLogin sql.NullTime
+	if err := s.Scan(&identidade.ID, &identidade.UsuarioID, &identidade.Provedor, &identidade.Subject,
+		&identidade.Email, &identidade.CreatedAt, &lastLogin); err != nil {
+		return domain.Identidade{}, err
+	}
+	if lastLogin.Valid {
+		identidade.LastLoginAt = &lastLogin.Time
+	}
+	return identidade, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestIdentidadeRepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	repo, err := NewIdentidadeRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+
+	usuarioID, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+	criada := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("sucesso - vincula e busca pela conta no provedor", func(t *testing.T) {
+		id, err := repo.Create(ctx, domain.Identidade{UsuarioID: usuarioID, Provedor: "google", Subject: "123", Email: "ana@email.com", CreatedAt: criada})
+		require.NoError(t, err)
+		require.NoError(t, repo.TouchLastLogin(ctx, id, criada.Add(time.Hour)))
+
+		identidade, err := repo.GetByProvedor(ctx, "google", "123")
+
+		require.NoError(t, err)
+		ultimo := criada.Add(time.Hour)
+		assert.Equal(t, &domain.Identidade{ID: id, UsuarioID: usuarioID, Provedor: "google", Subject: "123", Email: "ana@email.com", CreatedAt: criada, LastLoginAt: &ultimo}, identidade)
+
+		identidade, err = repo.GetByProvedor(ctx, "outro", "123")
+		assert.NoError(t, err)
+		assert.Nil(t, identidade)
+	})
+
+	t.Run("erro - a mesma conta não pode ser vinculada duas vezes", func(t *testing.T) {
+		_, err := repo.Create(ctx, domain.Identidade{UsuarioID: usuarioID, Provedor: "google", Subject: "123", Email: "ana@email.com", CreatedAt: criada})
+		assert.Error(t, err)
+	})
+
+	t.Run("sucesso - desvincula todas as identidades do usuário", func(t *testing.T) {
+		outroID, err := usuarios.Create(ctx, domain.Usuario{Nome: "Bia", Email: "bia@email.com"})
+		require.NoError(t, err)
+		for _, subject := range []string{"b1", "b2"} {
+			_, err := repo.Create(ctx, domain.Identidade{UsuarioID: outroID, Provedor: "github", Subject: subject, Email: "bia@email.com", CreatedAt: criada})
+			require.NoError(t, err)
+		}
+
+		n, err := repo.RemoverDoUsuario(ctx, outroID)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		identidades, err := repo.ListByUsuario(ctx, outroID)
+		require.NoError(t, err)
+		assert.Empty(t, identidades)
+		identidades, err = repo.ListByUsuario(ctx, usuarioID)
+		require.NoError(t, err)
+		assert.Len(t, identidades, 1, "as identidades dos outros usuários ficam")
+	})
+
+	t.Run("sucesso - o state é consumido uma única vez", func(t *testing.T) {
+		expira := time.Now().Add(10 * time.Minute).UTC().Truncate(time.Second)
+		require.NoError(t, repo.SalvarEstado(ctx, domain.EstadoLogin{State: "s1", Provedor: "google", Nonce: "n1", Verificador: "v1", ExpiresAt: expira}))
+
+		estado, err := repo.ConsumirEstado(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.EstadoLogin{State: "s1", Provedor: "google", Nonce: "n1", Verificador: "v1", ExpiresAt: expira}, estado)
+
+		estado, err = repo.ConsumirEstado(ctx, "s1")
+		assert.NoError(t, err)
+		assert.Nil(t, estado)
+	})
+
+	t.Run("sucesso - remover o usuário remove as identidades", func(t *testing.T) {
+		require.NoError(t, usuarios.Delete(ctx, usuarioID, 0))
+
+		identidades, err := repo.ListByUsuario(ctx, usuarioID)
+
+		require.NoError(t, err)
+		assert.Empty(t, identidades)
+	})
+}
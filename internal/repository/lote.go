@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// ErrNaoEncontrado indica que o registro alterado em um lote não existe.
+var ErrNaoEncontrado = errors.New("registro não encontrado")
+
+// Lote executa escritas dentro de uma mesma transação, preparando cada instrução
+// uma única vez e reaproveitando-a em todas as operações do lote.
+//
+// Update e Delete fazem o mesmo compare-and-swap de UsuarioRepository e, como não há
+// leitura prévia no serviço, distinguem o registro inexistente (ErrNaoEncontrado)
+// da versão desatualizada (ErrVersaoDesatualizada).
+type Lote interface {
+	Create(ctx context.Context, usuario domain.Usuario) (int64, error)
+	// Update devolve a nova versão do registro.
+	Update(ctx context.Context, id int64, usuario domain.Usuario) (int64, error)
+	Delete(ctx context.Context, id int64, version int64) error
+	// Isolar executa fn em um savepoint: se fn falhar, apenas as alterações feitas
+	// por ela são desfeitas e a transação do lote continua utilizável.
+	Isolar(ctx context.Context, fn func() error) error
+}
+
+// loteSQLite implementa Lote sobre uma transação, usando as instruções já preparadas
+// pelo repositório. As versões delas vinculadas à transação são fechadas ao fim do lote.
+type loteSQLite struct {
+	tx         *sql.Tx
+	preparados *statements
+	stmts      map[*sql.Stmt]*sql.Stmt
+	savepoints int
+}
+
+// Instruções próprias do lote (a inserção é a mesma de Create).
+const (
+	queryLoteUpdate = `
+		UPDATE usuarios SET nome = ?, email = ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND (? = 0 OR version = ?)
+		RETURNING version`
+	queryLoteDelete = "DELETE FROM usuarios WHERE id = ? AND (? = 0 OR version = ?)"
+	queryLoteExiste = "SELECT 1 FROM usuarios WHERE id = ?"
+)
+
+// ExecutarLote abre uma transação, entrega o Lote para fn e faz commit se fn não
+// retornar erro; caso contrário, desfaz tudo e devolve o erro de fn.
+func (r *sqliteRepository) ExecutarLote(ctx context.Context, fn func(Lote) error) (err error) {
+	ctx, end := instrument(ctx, "ExecutarLote", "BEGIN")
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	lote := &loteSQLite{tx: tx, preparados: r.stmts, stmts: make(map[*sql.Stmt]*sql.Stmt)}
+	defer lote.fechar()
+
+	if err := fn(lote); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// stmt devolve a versão da instrução preparada vinculada à transação do lote.
+func (l *loteSQLite) stmt(ctx context.Context, preparado *sql.Stmt) *sql.Stmt {
+	if s, ok := l.stmts[preparado]; ok {
+		return s
+	}
+	s := l.tx.StmtContext(ctx, preparado)
+	l.stmts[preparado] = s
+	return s
+}
+
+func (l *loteSQLite) fechar() {
+	for _, s := range l.stmts {
+		s.Close()
+	}
+}
+
+func (l *loteSQLite) Create(ctx context.Context, usuario domain.Usuario) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Lote.Create", queryCreate)
+	defer func() { end(err) }()
+
+	agora := now()
+	res, err := l.stmt(ctx, l.preparados.create).ExecContext(ctx, usuario.Nome, usuario.Email, agora, agora)
+	if err != nil {
+		return 0, traduzirErroEmail(err)
+	}
+	return res.LastInsertId()
+}
+
+func (l *loteSQLite) Update(ctx context.Context, id int64, usuario domain.Usuario) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Lote.Update", queryLoteUpdate)
+	defer func() { end(err) }()
+
+	var versao int64
+	err = l.stmt(ctx, l.preparados.loteUpdate).QueryRowContext(ctx, usuario.Nome, usuario.Email, now(), id, usuario.Version, usuario.Version).Scan(&versao)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, l.motivoSemAlteracao(ctx, id)
+	}
+	if err != nil {
+		return 0, traduzirErroEmail(err)
+	}
+	return versao, nil
+}
+
+func (l *loteSQLite) Delete(ctx context.Context, id int64, version int64) (err error) {
+	ctx, end := instrument(ctx, "Lote.Delete", queryLoteDelete)
+	defer func() { end(err) }()
+
+	res, err := l.stmt(ctx, l.preparados.loteDelete).ExecContext(ctx, id, version, version)
+	if err != nil {
+		return traduzirErroRemocao(err)
+	}
+	err = checarVersao(res)
+	if errors.Is(err, ErrVersaoDesatualizada) {
+		return l.motivoSemAlteracao(ctx, id)
+	}
+	return err
+}
+
+// motivoSemAlteracao explica por que um UPDATE/DELETE não afetou nenhuma linha.
+func (l *loteSQLite) motivoSemAlteracao(ctx context.Context, id int64) error {
+	var existe int
+	err := l.stmt(ctx, l.preparados.loteExiste).QueryRowContext(ctx, id).Scan(&existe)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNaoEncontrado
+	}
+	if err != nil {
+		return err
+	}
+	return ErrVersaoDesatualizada
+}
+
+func (l *loteSQLite) Isolar(ctx context.Context, fn func() error) error {
+	l.savepoints++
+	nome := fmt.Sprintf("lote_op_%d", l.savepoints)
+
+	if _, err := l.tx.ExecContext(ctx, "SAVEPOINT "+nome); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := l.tx.ExecContext(ctx, "ROLLBACK TO "+nome); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		// ROLLBACK TO mantém o savepoint na pilha; RELEASE o remove sem desfazer mais nada.
+		if _, relErr := l.tx.ExecContext(ctx, "RELEASE "+nome); relErr != nil {
+			return errors.Join(err, relErr)
+		}
+		return err
+	}
+	_, err := l.tx.ExecContext(ctx, "RELEASE "+nome)
+	return err
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// MFARepository define a persistência do segundo fator (TOTP) e dos códigos de recuperação.
+type MFARepository interface {
+	// GetMFA busca o cadastro do usuário, pendente ou ativo; devolve nil se não existir.
+	GetMFA(ctx context.Context, usuarioID int64) (*domain.MFA, error)
+	// SalvarPendente grava um novo cadastro pendente, substituindo o pendente anterior.
+	// Devolve ErrNaoEncontrado se o usuário já tiver um cadastro ativo.
+	SalvarPendente(ctx context.Context, mfa domain.MFA) error
+	// Confirmar ativa o cadastro pendente e troca os códigos de recuperação pelos hashes
+	// informados, em uma única transação. Devolve ErrNaoEncontrado se não houver cadastro pendente.
+	Confirmar(ctx context.Context, usuarioID int64, hashesRecuperacao []string) error
+	// RegistrarPasso guarda o intervalo do código aceito, desde que ele seja posterior ao do
+	// último código aceito. Devolve false se o código (ou um mais novo) já tiver sido usado.
+	RegistrarPasso(ctx context.Context, usuarioID, passo int64) (bool, error)
+	// UsarCodigoRecuperacao marca o código como usado. Devolve false se ele não existir ou já
+	// tiver sido usado.
+	UsarCodigoRecuperacao(ctx context.Context, usuarioID int64, hash string) (bool, error)
+	// Remover apaga o cadastro e os códigos de recuperação do usuário.
+	Remover(ctx context.Context, usuarioID int64) error
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	queryMFAGet      = "SELECT usuario_id, segredo, confirmado_em, COALESCE(ultimo_passo, 0), created_at FROM mfa WHERE usuario_id = ?"
+	queryMFAPendente = `
+		INSERT INTO mfa(usuario_id, segredo, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(usuario_id) DO UPDATE SET segredo = excluded.segredo, ultimo_passo = NULL, created_at = excluded.created_at
+		WHERE mfa.confirmado_em IS NULL`
+	queryMFAConfirmar       = "UPDATE mfa SET confirmado_em = ? WHERE usuario_id = ? AND confirmado_em IS NULL"
+	queryMFAPasso           = "UPDATE mfa SET ultimo_passo = ? WHERE usuario_id = ? AND COALESCE(ultimo_passo, 0) < ?"
+	queryMFARemover         = "DELETE FROM mfa WHERE usuario_id = ?"
+	queryRecuperacaoRemover = "DELETE FROM mfa_recovery_codes WHERE usuario_id = ?"
+	queryRecuperacaoCriar   = "INSERT INTO mfa_recovery_codes(usuario_id, hash) VALUES(?, ?)"
+	queryRecuperacaoUsar    = "UPDATE mfa_recovery_codes SET used_at = ? WHERE usuario_id = ? AND hash = ? AND used_at IS NULL"
+)
+
+type mfaSQLite struct {
+	db    *sql.DB
+	stmts preparadas
+}
+
+// NewMFARepository cria o repositório de MFA sobre o banco da aplicação.
+func NewMFARepository(db *sql.DB) (MFARepository, error) {
+	stmts, err := preparar(db,
+		queryMFAGet, queryMFAPendente, queryMFAConfirmar, queryMFAPasso, queryMFARemover,
+		queryRecuperacaoRemover, queryRecuperacaoCriar, queryRecuperacaoUsar)
+	if err != nil {
+		return nil, err
+	}
+	return &mfaSQLite{db: db, stmts: stmts}, nil
+}
+
+func (r *mfaSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *mfaSQLite) GetMFA(ctx context.Context, usuarioID int64) (_ *domain.MFA, err error) {
+	ctx, end := instrument(ctx, "MFA.GetMFA", queryMFAGet)
+	defer func() { end(err) }()
+
+	var mfa domain.MFA
+	var confirmado sql.NullTime
+	err = r.stmts[queryMFAGet].QueryRowContext(ctx, usuarioID).Scan(&mfa.UsuarioID, &mfa.Segredo, &confirmado, &mfa.UltimoPasso, &mfa.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if confirmado.Valid {
+		mfa.ConfirmadoEm = &confirmado.Time
+	}
+	return &mfa, nil
+}
+
+func (r *mfaSQLite) SalvarPendente(ctx context.Context, mfa domain.MFA) (err error) {
+	ctx, end := instrument(ctx, "MFA.SalvarPendente", queryMFAPendente)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryMFAPendente].ExecContext(ctx, mfa.UsuarioID, mfa.Segredo, mfa.CreatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return checarAfetadas(res)
+}
+
+func (r *mfaSQLite) Confirmar(ctx context.Context, usuarioID int64, hashesRecuperacao []string) (err error) {
+	ctx, end := instrument(ctx, "MFA.Confirmar", queryMFAConfirmar)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	res, err := tx.StmtContext(ctx, r.stmts[queryMFAConfirmar]).ExecContext(ctx, now(), usuarioID)
+	if err != nil {
+		return err
+	}
+	if err := checarAfetadas(res); err != nil {
+		return err
+	}
+	if _, err := tx.StmtContext(ctx, r.stmts[queryRecuperacaoRemover]).ExecContext(ctx, usuarioID); err != nil {
+		return err
+	}
+	for _, hash := range hashesRecuperacao {
+		if _, err := tx.StmtContext(ctx, r.stmts[queryRecuperacaoCriar]).ExecContext(ctx, usuarioID, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *mfaSQLite) RegistrarPasso(ctx context.Context, usuarioID, passo int64) (_ bool, err error) {
+	ctx, end := instrument(ctx, "MFA.RegistrarPasso", queryMFAPasso)
+	defer func() { end(err) }()
+
+	// A comparação fica no UPDATE para que duas requisições simultâneas com o mesmo código
+	// não sejam ambas aceitas.
+	res, err := r.stmts[queryMFAPasso].ExecContext(ctx, passo, usuarioID, passo)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *mfaSQLite) UsarCodigoRecuperacao(ctx context.Context, usuarioID int64, hash string) (_ bool, err error) {
+	ctx, end := instrument(ctx, "MFA.UsarCodigoRecuperacao", queryRecuperacaoUsar)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryRecuperacaoUsar].ExecContext(ctx, now(), usuarioID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *mfaSQLite) Remover(ctx context.Context, usuarioID int64) (err error) {
+	ctx, end := instrument(ctx, "MFA.Remover", queryMFARemover)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	if _, err := tx.StmtContext(ctx, r.stmts[queryRecuperacaoRemover]).ExecContext(ctx, usuarioID); err != nil {
+		return err
+	}
+	if _, err := tx.StmtContext(ctx, r.stmts[queryMFARemover]).ExecContext(ctx, usuarioID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checarAfetadas devolve ErrNaoEncontrado se a instrução não alterou nenhuma linha.
+func checarAfetadas(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNaoEncontrado
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestMFARepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	repo, err := NewMFARepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+
+	usuarioID, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+	criado := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("sucesso - o cadastro pendente é substituído até ser confirmado", func(t *testing.T) {
+		mfa, err := repo.GetMFA(ctx, usuarioID)
+		require.NoError(t, err)
+		assert.Nil(t, mfa)
+
+		require.NoError(t, repo.SalvarPendente(ctx, domain.MFA{UsuarioID: usuarioID, Segredo: "AAAA", CreatedAt: criado}))
+		require.NoError(t, repo.SalvarPendente(ctx, domain.MFA{UsuarioID: usuarioID, Segredo: "BBBB", CreatedAt: criado}))
+		mfa, err = repo.GetMFA(ctx, usuarioID)
+		require.NoError(t, err)
+		assert.Equal(t, "BBBB", mfa.Segredo)
+		assert.False(t, mfa.Ativo())
+
+		require.NoError(t, repo.Confirmar(ctx, usuarioID, []string{"h1", "h2"}))
+		mfa, err = repo.GetMFA(ctx, usuarioID)
+		require.NoError(t, err)
+		assert.True(t, mfa.Ativo())
+
+		assert.ErrorIs(t, repo.SalvarPendente(ctx, domain.MFA{UsuarioID: usuarioID, Segredo: "CCCC", CreatedAt: criado}), ErrNaoEncontrado)
+		assert.ErrorIs(t, repo.Confirmar(ctx, usuarioID, nil), ErrNaoEncontrado, "já confirmado")
+	})
+
+	t.Run("sucesso - códigos TOTP e de recuperação são de uso único", func(t *testing.T) {
+		ok, err := repo.RegistrarPasso(ctx, usuarioID, 100)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, _ = repo.RegistrarPasso(ctx, usuarioID, 100)
+		assert.False(t, ok, "o mesmo intervalo")
+		ok, _ = repo.RegistrarPasso(ctx, usuarioID, 99)
+		assert.False(t, ok, "um intervalo anterior")
+		ok, _ = repo.RegistrarPasso(ctx, usuarioID, 101)
+		assert.True(t, ok)
+
+		ok, err = repo.UsarCodigoRecuperacao(ctx, usuarioID, "h1")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, _ = repo.UsarCodigoRecuperacao(ctx, usuarioID, "h1")
+		assert.False(t, ok)
+		ok, _ = repo.UsarCodigoRecuperacao(ctx, usuarioID, "inexistente")
+		assert.False(t, ok)
+	})
+
+	t.Run("sucesso - remover apaga o cadastro e os códigos", func(t *testing.T) {
+		require.NoError(t, repo.Remover(ctx, usuarioID))
+
+		mfa, err := repo.GetMFA(ctx, usuarioID)
+		require.NoError(t, err)
+		assert.Nil(t, mfa)
+		ok, _ := repo.UsarCodigoRecuperacao(ctx, usuarioID, "h2")
+		assert.False(t, ok)
+	})
+}
+
+func TestAuditoriaRepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	repo, err := NewAuditoriaRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+
+	usuarioID, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+	quando := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("sucesso - registra e lista os eventos do usuário, mesmo depois de removido", func(t *testing.T) {
+		_, err := repo.Registrar(ctx, domain.EventoAuditoria{Ator: "cli", Acao: domain.AcaoPapelAlterado, UsuarioID: usuarioID,
+			Detalhes: map[string]any{"de": "usuario", "para": "admin"}, CreatedAt: quando})
+		require.NoError(t, err)
+		_, err = repo.Registrar(ctx, domain.EventoAuditoria{Ator: "usuario:1", Acao: domain.AcaoMFAAtivado, UsuarioID: usuarioID, CreatedAt: quando})
+		require.NoError(t, err)
+		require.NoError(t, usuarios.Delete(ctx, usuarioID, 0))
+
+		eventos, err := repo.ListByUsuario(ctx, usuarioID)
+
+		require.NoError(t, err)
+		require.Len(t, eventos, 2)
+		assert.Equal(t, domain.AcaoPapelAlterado, eventos[0].Acao)
+		assert.Equal(t, map[string]any{"de": "usuario", "para": "admin"}, eventos[0].Detalhes)
+		assert.Equal(t, quando, eventos[0].CreatedAt)
+		assert.Equal(t, "usuario:1", eventos[1].Ator)
+		assert.Nil(t, eventos[1].Detalhes)
+	})
+}
+
+func TestUpdatePapel(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+
+	t.Run("sucesso - novos usuários são comuns e o papel pode ser alterado", func(t *testing.T) {
+		id, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+		require.NoError(t, err)
+		u, err := usuarios.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, domain.PapelUsuario, u.Papel)
+
+		require.NoError(t, usuarios.UpdatePapel(ctx, id, domain.PapelAdmin))
+
+		u, err = usuarios.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, domain.PapelAdmin, u.Papel)
+		assert.Equal(t, int64(2), u.Version)
+	})
+
+	t.Run("erro - usuário inexistente", func(t *testing.T) {
+		assert.ErrorIs(t, usuarios.UpdatePapel(ctx, 999, domain.PapelAdmin), ErrNaoEncontrado)
+	})
+}
@@ -0,0 +1,397 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// ErrSemAssentos indica que todos os assentos da organização já estão ocupados.
+var ErrSemAssentos = errors.New("organização sem assentos livres")
+
+// OrganizacaoRepository define a persistência das organizações, dos seus membros e dos
+// convites. Toda consulta sobre membros e convites é restrita à organização informada.
+type OrganizacaoRepository interface {
+	// Criar grava a organização com o usuário como dono e devolve o ID gerado.
+	Criar(ctx context.Context, org domain.Organizacao, donoID int64) (int64, error)
+	// Get busca a organização com os assentos em uso; devolve nil se não existir.
+	Get(ctx context.Context, id int64) (*domain.Organizacao, error)
+	// GetByStripeID busca a organização pelo cliente da Stripe; devolve nil se não existir.
+	GetByStripeID(ctx context.Context, stripeCustomerID string) (*domain.Organizacao, error)
+	// ListDoUsuario devolve as organizações de que o usuário é membro, com o papel dele em cada uma.
+	ListDoUsuario(ctx context.Context, usuarioID int64) ([]domain.Organizacao, error)
+	// AtualizarAssinatura grava o cliente, a assinatura (com a moeda), os assentos contratados e o cupom.
+	AtualizarAssinatura(ctx context.Context, id int64, org domain.Organizacao) error
+	// CountBySubscriptionStatus agrupa as organizações pelo status da assinatura.
+	CountBySubscriptionStatus(ctx context.Context) (map[string]int64, error)
+
+	// Papel devolve o papel do usuário na organização, ou "" se ele não for membro.
+	Papel(ctx context.Context, orgID, usuarioID int64) (string, error)
+	// MembroPorEmail informa se algum membro da organização usa o e-mail.
+	MembroPorEmail(ctx context.Context, orgID int64, email string) (bool, error)
+	// ListMembros devolve os membros da organização, do mais antigo ao mais recente.
+	ListMembros(ctx context.Context, orgID int64) ([]domain.Membro, error)
+	// RemoverMembro tira o usuário da organização. Devolve ErrNaoEncontrado se ele não for membro.
+	RemoverMembro(ctx context.Context, orgID, usuarioID int64) error
+
+	// CriarConvite revoga os convites pendentes para o mesmo e-mail e grava o novo, desde que
+	// haja um assento livre (ErrSemAssentos, senão). Devolve o ID gerado.
+	CriarConvite(ctx context.Context, convite domain.Convite, hash string) (int64, error)
+	// ListConvites devolve os convites pendentes da organização, do mais recente ao mais antigo.
+	ListConvites(ctx context.Context, orgID int64) ([]domain.Convite, error)
+	// BuscarConvite busca o convite pelo hash do token, pendente ou não; devolve nil se não existir.
+	BuscarConvite(ctx context.Context, hash string) (*domain.Convite, error)
+	// AceitarConvite marca o convite como aceito e torna o usuário membro com o papel dele, em
+	// uma única transação. Devolve ErrNaoEncontrado se o convite não estiver mais pendente.
+	AceitarConvite(ctx context.Context, convite domain.Convite, usuarioID int64) error
+	// RevogarConvite revoga o convite pendente da organização. Devolve ErrNaoEncontrado se ele
+	// não existir, for de outra organização ou não estiver mais pendente.
+	RevogarConvite(ctx context.Context, orgID, conviteID int64) error
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	// Membros mais convites pendentes; o único parâmetro é o instante atual.
+	subqueryAssentosEmUso = "(SELECT COUNT(*) FROM memberships m WHERE m.organizacao_id = o.id) + " +
+		"(SELECT COUNT(*) FROM invitations i WHERE i.organizacao_id = o.id AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > ?)"
+	colunasOrganizacao = "o.id, o.nome, COALESCE(o.stripe_customer_id, ''), COALESCE(o.stripe_subscription_id, ''), o.subscription_status, " +
+		"o.subscription_current_period_end, COALESCE(o.moeda, ''), o.assentos, " + subqueryAssentosEmUso + ", COALESCE(o.cupom, ''), o.created_at"
+	colunasConvite = "id, organizacao_id, email, papel, convidado_por, created_at, expires_at, accepted_at, revoked_at"
+
+	queryOrgCriar       = "INSERT INTO organizations(nome, created_at) VALUES(?, ?)"
+	queryOrgGet         = "SELECT " + colunasOrganizacao + ", '' FROM organizations o WHERE o.id = ?"
+	queryOrgStripe      = "SELECT " + colunasOrganizacao + ", '' FROM organizations o WHERE o.stripe_customer_id = ?"
+	queryOrgDoUsuario   = "SELECT " + colunasOrganizacao + ", p.papel FROM organizations o JOIN memberships p ON p.organizacao_id = o.id WHERE p.usuario_id = ? ORDER BY o.id"
+	queryOrgAssinatura  = "UPDATE organizations SET stripe_customer_id = NULLIF(?, ''), stripe_subscription_id = NULLIF(?, ''), subscription_status = ?, subscription_current_period_end = ?, moeda = NULLIF(?, ''), assentos = ?, cupom = NULLIF(?, '') WHERE id = ?"
+	queryOrgPorStatus   = "SELECT subscription_status, COUNT(*) FROM organizations GROUP BY subscription_status"
+	queryMembroCriar    = "INSERT INTO memberships(organizacao_id, usuario_id, papel, created_at) VALUES(?, ?, ?, ?) ON CONFLICT DO NOTHING"
+	queryMembroPapel    = "SELECT papel FROM memberships WHERE organizacao_id = ? AND usuario_id = ?"
+	queryMembroEmail    = "SELECT COUNT(*) FROM memberships m JOIN usuarios u ON u.id = m.usuario_id WHERE m.organizacao_id = ? AND u.email = ?"
+	queryMembros        = "SELECT m.usuario_id, u.nome, u.email, m.papel, m.created_at FROM memberships m JOIN usuarios u ON u.id = m.usuario_id WHERE m.organizacao_id = ? ORDER BY m.created_at, m.usuario_id"
+	queryMembroRemover  = "DELETE FROM memberships WHERE organizacao_id = ? AND usuario_id = ?"
+	queryConviteTrocar  = "UPDATE invitations SET revoked_at = ? WHERE organizacao_id = ? AND email = ? AND accepted_at IS NULL AND revoked_at IS NULL"
+	queryConviteCriar   = "INSERT INTO invitations(organizacao_id, email, papel, hash, convidado_por, created_at, expires_at) SELECT o.id, ?, ?, ?, ?, ?, ? FROM organizations o WHERE o.id = ? AND (o.assentos = 0 OR " + subqueryAssentosEmUso + " < o.assentos)"
+	queryConvites       = "SELECT " + colunasConvite + " FROM invitations WHERE organizacao_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ? ORDER BY id DESC"
+	queryConviteHash    = "SELECT " + colunasConvite + " FROM invitations WHERE hash = ?"
+	queryConviteAceitar = "UPDATE invitations SET accepted_at = ? WHERE id = ? AND organizacao_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?"
+	queryConviteRevogar = "UPDATE invitations SET revoked_at = ? WHERE id = ? AND organizacao_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?"
+)
+
+type organizacaoSQLite struct {
+	db    *sql.DB
+	stmts preparadas
+}
+
+// NewOrganizacaoRepository cria o repositório de organizações sobre o banco da aplicação.
+func NewOrganizacaoRepository(db *sql.DB) (OrganizacaoRepository, error) {
+	stmts, err := preparar(db,
+		queryOrgCriar, queryOrgGet, queryOrgStripe, queryOrgDoUsuario, queryOrgAssinatura,
+		queryOrgPorStatus, queryMembroCriar, queryMembroPapel, queryMembroEmail, queryMembros,
+		queryMembroRemover, queryConviteTrocar, queryConviteCriar, queryConvites, queryConviteHash,
+		queryConviteAceitar, queryConviteRevogar)
+	if err != nil {
+		return nil, err
+	}
+	return &organizacaoSQLite{db: db, stmts: stmts}, nil
+}
+
+func (r *organizacaoSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *organizacaoSQLite) Criar(ctx context.Context, org domain.Organizacao, donoID int64) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Organizacao.Criar", queryOrgCriar)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	agora := now()
+	res, err := tx.StmtContext(ctx, r.stmts[queryOrgCriar]).ExecContext(ctx, org.Nome, agora)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.StmtContext(ctx, r.stmts[queryMembroCriar]).ExecContext(ctx, id, donoID, domain.PapelOrgDono, agora); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *organizacaoSQLite) Get(ctx context.Context, id int64) (_ *domain.Organizacao, err error) {
+	ctx, end := instrument(ctx, "Organizacao.Get", queryOrgGet)
+	defer func() { end(err) }()
+
+	org, err := scanOrganizacao(r.stmts[queryOrgGet].QueryRowContext(ctx, now(), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return org, err
+}
+
+func (r *organizacaoSQLite) GetByStripeID(ctx context.Context, stripeCustomerID string) (_ *domain.Organizacao, err error) {
+	ctx, end := instrument(ctx, "Organizacao.GetByStripeID", queryOrgStripe)
+	defer func() { end(err) }()
+
+	org, err := scanOrganizacao(r.stmts[queryOrgStripe].QueryRowContext(ctx, now(), stripeCustomerID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return org, err
+}
+
+func (r *organizacaoSQLite) ListDoUsuario(ctx context.Context, usuarioID int64) (_ []domain.Organizacao, err error) {
+	ctx, end := instrument(ctx, "Organizacao.ListDoUsuario", queryOrgDoUsuario)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryOrgDoUsuario].QueryContext(ctx, now(), usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []domain.Organizacao{}
+	for rows.Next() {
+		org, err := scanOrganizacao(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, *org)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *organizacaoSQLite) AtualizarAssinatura(ctx context.Context, id int64, org domain.Organizacao) (err error) {
+	ctx, end := instrument(ctx, "Organizacao.AtualizarAssinatura", queryOrgAssinatura)
+	defer func() { end(err) }()
+
+	var fimPeriodo any
+	if org.SubscriptionCurrentPeriodEnd != nil {
+		fimPeriodo = org.SubscriptionCurrentPeriodEnd.UTC()
+	}
+	res, err := r.stmts[queryOrgAssinatura].ExecContext(ctx, org.StripeCustomerID, org.StripeSubscriptionID,
+		org.SubscriptionStatus, fimPeriodo, org.Moeda, org.Assentos, org.Cupom, id)
+	if err != nil {
+		return err
+	}
+	return checarAfetadas(res)
+}
+
+func (r *organizacaoSQLite) CountBySubscriptionStatus(ctx context.Context) (_ map[string]int64, err error) {
+	ctx, end := instrument(ctx, "Organizacao.CountBySubscriptionStatus", queryOrgPorStatus)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryOrgPorStatus].QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totais := make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var total int64
+		if err := rows.Scan(&status, &total); err != nil {
+			return nil, err
+		}
+		totais[status] = total
+	}
+	return totais, rows.Err()
+}
+
+func (r *organizacaoSQLite) Papel(ctx context.Context, orgID, usuarioID int64) (_ string, err error) {
+	ctx, end := instrument(ctx, "Organizacao.Papel", queryMembroPapel)
+	defer func() { end(err) }()
+
+	var papel string
+	err = r.stmts[queryMembroPapel].QueryRowContext(ctx, orgID, usuarioID).Scan(&papel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return papel, err
+}
+
+func (r *organizacaoSQLite) MembroPorEmail(ctx context.Context, orgID int64, email string) (_ bool, err error) {
+	ctx, end := instrument(ctx, "Organizacao.MembroPorEmail", queryMembroEmail)
+	defer func() { end(err) }()
+
+	var n int
+	err = r.stmts[queryMembroEmail].QueryRowContext(ctx, orgID, email).Scan(&n)
+	return n > 0, err
+}
+
+func (r *organizacaoSQLite) ListMembros(ctx context.Context, orgID int64) (_ []domain.Membro, err error) {
+	ctx, end := instrument(ctx, "Organizacao.ListMembros", queryMembros)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryMembros].QueryContext(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	membros := []domain.Membro{}
+	for rows.Next() {
+		var m domain.Membro
+		if err := rows.Scan(&m.UsuarioID, &m.Nome, &m.Email, &m.Papel, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		membros = append(membros, m)
+	}
+	return membros, rows.Err()
+}
+
+func (r *organizacaoSQLite) RemoverMembro(ctx context.Context, orgID, usuarioID int64) (err error) {
+	ctx, end := instrument(ctx, "Organizacao.RemoverMembro", queryMembroRemover)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[queryMembroRemover].ExecContext(ctx, orgID, usuarioID)
+	if err != nil {
+		return err
+	}
+	return checarAfetadas(res)
+}
+
+func (r *organizacaoSQLite) CriarConvite(ctx context.Context, convite domain.Convite, hash string) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Organizacao.CriarConvite", queryConviteCriar)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	agora := now()
+	// O convite substituído libera o assento antes da contagem.
+	if _, err := tx.StmtContext(ctx, r.stmts[queryConviteTrocar]).ExecContext(ctx, agora, convite.OrganizacaoID, convite.Email); err != nil {
+		return 0, err
+	}
+	// A checagem dos assentos fica no próprio INSERT: de dois convites simultâneos para o
+	// último assento, só um é gravado.
+	res, err := tx.StmtContext(ctx, r.stmts[queryConviteCriar]).ExecContext(ctx, convite.Email, convite.Papel, hash, convite.ConvidadoPor,
+		convite.CreatedAt.UTC(), convite.ExpiresAt.UTC(), convite.OrganizacaoID, agora)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, ErrSemAssentos
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *organizacaoSQLite) ListConvites(ctx context.Context, orgID int64) (_ []domain.Convite, err error) {
+	ctx, end := instrument(ctx, "Organizacao.ListConvites", queryConvites)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryConvites].QueryContext(ctx, orgID, now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	convites := []domain.Convite{}
+	for rows.Next() {
+		convite, err := scanConvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		convites = append(convites, *convite)
+	}
+	return convites, rows.Err()
+}
+
+func (r *organizacaoSQLite) BuscarConvite(ctx context.Context, hash string) (_ *domain.Convite, err error) {
+	ctx, end := instrument(ctx, "Organizacao.BuscarConvite", queryConviteHash)
+	defer func() { end(err) }()
+
+	convite, err := scanConvite(r.stmts[queryConviteHash].QueryRowContext(ctx, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return convite, err
+}
+
+func (r *organizacaoSQLite) AceitarConvite(ctx context.Context, convite domain.Convite, usuarioID int64) (err error) {
+	ctx, end := instrument(ctx, "Organizacao.AceitarConvite", queryConviteAceitar)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	agora := now()
+	res, err := tx.StmtContext(ctx, r.stmts[queryConviteAceitar]).ExecContext(ctx, agora, convite.ID, convite.OrganizacaoID, agora)
+	if err != nil {
+		return err
+	}
+	if err := checarAfetadas(res); err != nil {
+		return err
+	}
+	if _, err := tx.StmtContext(ctx, r.stmts[queryMembroCriar]).ExecContext(ctx, convite.OrganizacaoID, usuarioID, convite.Papel, agora); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *organizacaoSQLite) RevogarConvite(ctx context.Context, orgID, conviteID int64) (err error) {
+	ctx, end := instrument(ctx, "Organizacao.RevogarConvite", queryConviteRevogar)
+	defer func() { end(err) }()
+
+	agora := now()
+	res, err := r.stmts[queryConviteRevogar].ExecContext(ctx, agora, conviteID, orgID, agora)
+	if err != nil {
+		return err
+	}
+	return checarAfetadas(res)
+}
+
+func scanOrganizacao(row scanner) (*domain.Organizacao, error) {
+	var o domain.Organizacao
+	var fimPeriodo sql.NullTime
+	err := row.Scan(&o.ID, &o.Nome, &o.StripeCustomerID, &o.StripeSubscriptionID, &o.SubscriptionStatus,
+		&fimPeriodo, &o.Moeda, &o.Assentos, &o.AssentosEmUso, &o.Cupom, &o.CreatedAt, &o.Papel)
+	if err != nil {
+		return nil, err
+	}
+	if fimPeriodo.Valid {
+		t := fimPeriodo.Time
+		o.SubscriptionCurrentPeriodEnd = &t
+	}
+	return &o, nil
+}
+
+func scanConvite(row scanner) (*domain.Convite, error) {
+	var c domain.Convite
+	var aceito, revogado sql.NullTime
+	err := row.Scan(&c.ID, &c.OrganizacaoID, &c.Email, &c.Papel, &c.ConvidadoPor, &c.CreatedAt, &c.ExpiresAt, &aceito, &revogado)
+	if err != nil {
+		return nil, err
+	}
+	if aceito.Valid {
+		t := aceito.Time
+		c.AcceptedAt = &t
+	}
+	if revogado.Valid {
+		t := revogado.Time
+		c.RevokedAt = &t
+	}
+	return &c, nil
+}
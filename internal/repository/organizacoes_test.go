@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestOrganizacaoRepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+	repo, err := NewOrganizacaoRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	ana, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+	bia, err := usuarios.Create(ctx, domain.Usuario{Nome: "Bia", Email: "bia@email.com"})
+	require.NoError(t, err)
+
+	agora := time.Now().UTC().Truncate(time.Second)
+	convite := func(orgID int64, email string, expira time.Time) domain.Convite {
+		return domain.Convite{OrganizacaoID: orgID, Email: email, Papel: domain.PapelOrgMembro, ConvidadoPor: ana, CreatedAt: agora, ExpiresAt: expira}
+	}
+
+	t.Run("sucesso - cria a organização com o dono e lista as do usuário", func(t *testing.T) {
+		id, err := repo.Criar(ctx, domain.Organizacao{Nome: "Acme"}, ana)
+		require.NoError(t, err)
+
+		org, err := repo.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "Acme", org.Nome)
+		assert.Equal(t, "inactive", org.SubscriptionStatus)
+		assert.Equal(t, int64(1), org.AssentosEmUso)
+		papel, err := repo.Papel(ctx, id, ana)
+		require.NoError(t, err)
+		assert.Equal(t, domain.PapelOrgDono, papel)
+		papel, err = repo.Papel(ctx, id, bia)
+		require.NoError(t, err)
+		assert.Empty(t, papel)
+
+		orgs, err := repo.ListDoUsuario(ctx, ana)
+		require.NoError(t, err)
+		require.Len(t, orgs, 1)
+		assert.Equal(t, domain.PapelOrgDono, orgs[0].Papel)
+		orgs, err = repo.ListDoUsuario(ctx, bia)
+		require.NoError(t, err)
+		assert.Empty(t, orgs)
+
+		org, err = repo.Get(ctx, 999)
+		require.NoError(t, err)
+		assert.Nil(t, org)
+	})
+
+	t.Run("sucesso - grava a assinatura e busca pelo cliente da Stripe", func(t *testing.T) {
+		id, err := repo.Criar(ctx, domain.Organizacao{Nome: "Beta"}, ana)
+		require.NoError(t, err)
+		fim := agora.Add(30 * 24 * time.Hour)
+
+		require.NoError(t, repo.AtualizarAssinatura(ctx, id, domain.Organizacao{
+			StripeCustomerID: "cus_beta", StripeSubscriptionID: "sub_beta", SubscriptionStatus: "active",
+			SubscriptionCurrentPeriodEnd: &fim, Moeda: "brl", Assentos: 3, Cupom: "BEMVINDO10",
+		}))
+
+		org, err := repo.GetByStripeID(ctx, "cus_beta")
+		require.NoError(t, err)
+		assert.Equal(t, id, org.ID)
+		assert.Equal(t, int64(3), org.Assentos)
+		assert.Equal(t, "BEMVINDO10", org.Cupom)
+		assert.Equal(t, "brl", org.Moeda)
+		assert.True(t, org.SubscriptionCurrentPeriodEnd.Equal(fim))
+		totais, err := repo.CountBySubscriptionStatus(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), totais["active"])
+
+		assert.ErrorIs(t, repo.AtualizarAssinatura(ctx, 999, domain.Organizacao{SubscriptionStatus: "active"}), ErrNaoEncontrado)
+	})
+
+	t.Run("sucesso - convites ocupam assentos até serem aceitos, revogados ou vencerem", func(t *testing.T) {
+		id, err := repo.Criar(ctx, domain.Organizacao{Nome: "Gama"}, ana)
+		require.NoError(t, err)
+		require.NoError(t, repo.AtualizarAssinatura(ctx, id, domain.Organizacao{SubscriptionStatus: "active", Assentos: 2}))
+
+		_, err = repo.CriarConvite(ctx, convite(id, "vencido@email.com", agora.Add(-time.Minute)), "c0")
+		require.NoError(t, err, "o vencido não conta")
+		primeiro, err := repo.CriarConvite(ctx, convite(id, "bia@email.com", agora.Add(time.Hour)), "c1")
+		require.NoError(t, err)
+		_, err = repo.CriarConvite(ctx, convite(id, "caio@email.com", agora.Add(time.Hour)), "c2")
+		assert.ErrorIs(t, err, ErrSemAssentos)
+
+		// Reenviar para o mesmo e-mail substitui o convite, sem ocupar outro assento.
+		segundo, err := repo.CriarConvite(ctx, convite(id, "bia@email.com", agora.Add(time.Hour)), "c3")
+		require.NoError(t, err)
+		antigo, err := repo.BuscarConvite(ctx, "c1")
+		require.NoError(t, err)
+		assert.Equal(t, primeiro, antigo.ID)
+		assert.NotNil(t, antigo.RevokedAt)
+		convites, err := repo.ListConvites(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, convites, 1)
+		assert.Equal(t, segundo, convites[0].ID)
+
+		atual, err := repo.BuscarConvite(ctx, "c3")
+		require.NoError(t, err)
+		require.NoError(t, repo.AceitarConvite(ctx, *atual, bia))
+		assert.ErrorIs(t, repo.AceitarConvite(ctx, *atual, bia), ErrNaoEncontrado)
+		papel, err := repo.Papel(ctx, id, bia)
+		require.NoError(t, err)
+		assert.Equal(t, domain.PapelOrgMembro, papel)
+		membro, err := repo.MembroPorEmail(ctx, id, "bia@email.com")
+		require.NoError(t, err)
+		assert.True(t, membro)
+
+		org, err := repo.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), org.AssentosEmUso)
+		_, err = repo.CriarConvite(ctx, convite(id, "caio@email.com", agora.Add(time.Hour)), "c4")
+		assert.ErrorIs(t, err, ErrSemAssentos)
+
+		require.NoError(t, repo.RemoverMembro(ctx, id, bia))
+		assert.ErrorIs(t, repo.RemoverMembro(ctx, id, bia), ErrNaoEncontrado)
+		caio, err := repo.CriarConvite(ctx, convite(id, "caio@email.com", agora.Add(time.Hour)), "c5")
+		require.NoError(t, err)
+		require.NoError(t, repo.RevogarConvite(ctx, id, caio))
+		assert.ErrorIs(t, repo.RevogarConvite(ctx, id, caio), ErrNaoEncontrado)
+	})
+
+	t.Run("erro - membros e convites de outra organização ficam de fora", func(t *testing.T) {
+		acme, err := repo.Criar(ctx, domain.Organizacao{Nome: "Delta"}, ana)
+		require.NoError(t, err)
+		outra, err := repo.Criar(ctx, domain.Organizacao{Nome: "Épsilon"}, bia)
+		require.NoError(t, err)
+		conviteID, err := repo.CriarConvite(ctx, convite(outra, "caio@email.com", agora.Add(time.Hour)), "d1")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, repo.RevogarConvite(ctx, acme, conviteID), ErrNaoEncontrado)
+		assert.ErrorIs(t, repo.RemoverMembro(ctx, acme, bia), ErrNaoEncontrado)
+		membros, err := repo.ListMembros(ctx, acme)
+		require.NoError(t, err)
+		require.Len(t, membros, 1)
+		assert.Equal(t, "ana@email.com", membros[0].Email)
+		convites, err := repo.ListConvites(ctx, acme)
+		require.NoError(t, err)
+		assert.Empty(t, convites)
+	})
+
+	caio, err := usuarios.Create(ctx, domain.Usuario{Nome: "Caio", Email: "caio@email.com"})
+	require.NoError(t, err)
+	zeta, err := repo.Criar(ctx, domain.Organizacao{Nome: "Zeta"}, caio)
+	require.NoError(t, err)
+
+	t.Run("sucesso - remover o usuário o tira das organizações", func(t *testing.T) {
+		dani, err := usuarios.Create(ctx, domain.Usuario{Nome: "Dani", Email: "dani@email.com"})
+		require.NoError(t, err)
+		c := convite(zeta, "dani@email.com", agora.Add(time.Hour))
+		c.ID, err = repo.CriarConvite(ctx, c, "z1")
+		require.NoError(t, err)
+		require.NoError(t, repo.AceitarConvite(ctx, c, dani))
+
+		require.NoError(t, usuarios.Delete(ctx, dani, 0))
+
+		membros, err := repo.ListMembros(ctx, zeta)
+		require.NoError(t, err)
+		require.Len(t, membros, 1)
+		assert.Equal(t, "caio@email.com", membros[0].Email)
+	})
+
+	t.Run("erro - o dono de uma organização não é removido", func(t *testing.T) {
+		assert.ErrorIs(t, usuarios.Delete(ctx, caio, 0), ErrDonoDeOrganizacao)
+		err := usuarios.ExecutarLote(ctx, func(lote Lote) error { return lote.Delete(ctx, caio, 0) })
+		assert.ErrorIs(t, err, ErrDonoDeOrganizacao)
+
+		u, err := usuarios.GetByID(ctx, caio)
+		require.NoError(t, err)
+		assert.NotNil(t, u)
+		papel, err := repo.Papel(ctx, zeta, caio)
+		require.NoError(t, err)
+		assert.Equal(t, domain.PapelOrgDono, papel)
+	})
+}
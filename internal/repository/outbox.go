@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// OutboxClienteRepository define a caixa de saída das alterações de perfil a enviar ao
+// cliente da Stripe. As pendências são criadas pelo trigger usuarios_perfil_alterado, na
+// mesma transação da alteração do usuário.
+type OutboxClienteRepository interface {
+	// Enfileirar cria uma pendência para o usuário fora do trigger (ex: para reenviar os
+	// valores locais depois de uma alteração feita na Stripe).
+	Enfileirar(ctx context.Context, usuarioID int64) error
+	// Pendentes devolve até limite pendências não enviadas com menos de maxTentativas, das
+	// mais antigas para as mais novas.
+	Pendentes(ctx context.Context, maxTentativas, limite int) ([]domain.PendenciaCliente, error)
+	// MarcarEnviadas registra que as pendências chegaram ao provedor.
+	MarcarEnviadas(ctx context.Context, ids []int64) error
+	// MarcarFalha conta mais uma tentativa das pendências e guarda o erro.
+	MarcarFalha(ctx context.Context, ids []int64, erro string) error
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	queryOutboxEnfileirar = "INSERT INTO customer_sync_outbox(usuario_id, created_at) VALUES(?, ?)"
+	queryOutboxPendentes  = "SELECT id, usuario_id, tentativas, created_at FROM customer_sync_outbox WHERE sent_at IS NULL AND tentativas < ? ORDER BY id LIMIT ?"
+	queryOutboxEnviada    = "UPDATE customer_sync_outbox SET sent_at = ?, ultimo_erro = NULL WHERE id = ? AND sent_at IS NULL"
+	queryOutboxFalha      = "UPDATE customer_sync_outbox SET tentativas = tentativas + 1, ultimo_erro = ? WHERE id = ? AND sent_at IS NULL"
+)
+
+type outboxClienteSQLite struct {
+	db    *sql.DB
+	stmts preparadas
+}
+
+// NewOutboxClienteRepository cria a caixa de saída dos clientes sobre o banco da aplicação.
+func NewOutboxClienteRepository(db *sql.DB) (OutboxClienteRepository, error) {
+	stmts, err := preparar(db,
+		queryOutboxEnfileirar, queryOutboxPendentes, queryOutboxEnviada, queryOutboxFalha)
+	if err != nil {
+		return nil, err
+	}
+	return &outboxClienteSQLite{db: db, stmts: stmts}, nil
+}
+
+func (r *outboxClienteSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *outboxClienteSQLite) Enfileirar(ctx context.Context, usuarioID int64) (err error) {
+	ctx, end := instrument(ctx, "OutboxCliente.Enfileirar", queryOutboxEnfileirar)
+	defer func() { end(err) }()
+
+	_, err = r.stmts[queryOutboxEnfileirar].ExecContext(ctx, usuarioID, now())
+	return err
+}
+
+func (r *outboxClienteSQLite) Pendentes(ctx context.Context, maxTentativas, limite int) (_ []domain.PendenciaCliente, err error) {
+	ctx, end := instrument(ctx, "OutboxCliente.Pendentes", queryOutboxPendentes)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryOutboxPendentes].QueryContext(ctx, maxTentativas, limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pendentes []domain.PendenciaCliente
+	for rows.Next() {
+		var p domain.PendenciaCliente
+		if err := rows.Scan(&p.ID, &p.UsuarioID, &p.Tentativas, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		pendentes = append(pendentes, p)
+	}
+	return pendentes, rows.Err()
+}
+
+func (r *outboxClienteSQLite) MarcarEnviadas(ctx context.Context, ids []int64) (err error) {
+	ctx, end := instrument(ctx, "OutboxCliente.MarcarEnviadas", queryOutboxEnviada)
+	defer func() { end(err) }()
+
+	return r.atualizar(ctx, ids, queryOutboxEnviada, now())
+}
+
+func (r *outboxClienteSQLite) MarcarFalha(ctx context.Context, ids []int64, erro string) (err error) {
+	ctx, end := instrument(ctx, "OutboxCliente.MarcarFalha", queryOutboxFalha)
+	defer func() { end(err) }()
+
+	return r.atualizar(ctx, ids, queryOutboxFalha, erro)
+}
+
+// atualizar executa query (com o valor e o ID da pendência) para cada pendência, em uma
+// única transação.
+func (r *outboxClienteSQLite) atualizar(ctx context.Context, ids []int64, query string, valor any) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	for _, id := range ids {
+		if _, err := tx.StmtContext(ctx, r.stmts[query]).ExecContext(ctx, valor, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
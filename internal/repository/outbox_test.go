@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestOutboxClienteRepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+	orgs, err := NewOrganizacaoRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { orgs.Close() })
+	repo, err := NewOutboxClienteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	ana, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+	bia, err := usuarios.Create(ctx, domain.Usuario{Nome: "Bia", Email: "bia@email.com"})
+	require.NoError(t, err)
+
+	// Ana é dona de Acme, que já é cliente da Stripe; Bia é dona de uma organização que nunca assinou.
+	acme, err := orgs.Criar(ctx, domain.Organizacao{Nome: "Acme"}, ana)
+	require.NoError(t, err)
+	require.NoError(t, orgs.AtualizarAssinatura(ctx, acme, domain.Organizacao{StripeCustomerID: "cus_acme", SubscriptionStatus: "active", Assentos: 5}))
+	_, err = orgs.Criar(ctx, domain.Organizacao{Nome: "Sem assinatura"}, bia)
+	require.NoError(t, err)
+
+	t.Run("sucesso - o trigger enfileira só as alterações de nome e e-mail dos donos de clientes", func(t *testing.T) {
+		require.NoError(t, usuarios.Update(ctx, ana, domain.Usuario{Nome: "Ana Souza", Email: "ana@email.com"}))
+		require.NoError(t, usuarios.Update(ctx, ana, domain.Usuario{Nome: "Ana Souza", Email: "ana@email.com"}))
+		email := "ana.souza@email.com"
+		require.NoError(t, usuarios.Patch(ctx, ana, domain.UsuarioPatch{Email: &email}))
+		require.NoError(t, usuarios.Update(ctx, bia, domain.Usuario{Nome: "Bia Lima", Email: "bia@email.com"}))
+		require.NoError(t, usuarios.UpdatePassword(ctx, ana, "hash"))
+
+		pendentes, err := repo.Pendentes(ctx, 10, 100)
+		require.NoError(t, err)
+		require.Len(t, pendentes, 2, "a alteração sem mudança, a de Bia e a de senha não contam")
+		assert.Equal(t, ana, pendentes[0].UsuarioID)
+		assert.WithinDuration(t, time.Now(), pendentes[0].CreatedAt, time.Minute)
+		assert.Equal(t, time.UTC, pendentes[0].CreatedAt.Location(), "o trigger grava no mesmo formato que o resto do schema")
+
+		require.NoError(t, repo.MarcarEnviadas(ctx, []int64{pendentes[0].ID, pendentes[1].ID}))
+		pendentes, err = repo.Pendentes(ctx, 10, 100)
+		require.NoError(t, err)
+		assert.Empty(t, pendentes)
+	})
+
+	t.Run("erro - falhas contam tentativas até o limite", func(t *testing.T) {
+		require.NoError(t, repo.Enfileirar(ctx, ana))
+		pendentes, err := repo.Pendentes(ctx, 2, 100)
+		require.NoError(t, err)
+		require.Len(t, pendentes, 1)
+		ids := []int64{pendentes[0].ID}
+
+		require.NoError(t, repo.MarcarFalha(ctx, ids, "stripe indisponível"))
+		pendentes, err = repo.Pendentes(ctx, 2, 100)
+		require.NoError(t, err)
+		require.Len(t, pendentes, 1)
+		assert.Equal(t, 1, pendentes[0].Tentativas)
+
+		require.NoError(t, repo.MarcarFalha(ctx, ids, "stripe indisponível"))
+		pendentes, err = repo.Pendentes(ctx, 2, 100)
+		require.NoError(t, err)
+		assert.Empty(t, pendentes, "esgotou as tentativas")
+	})
+
+	t.Run("sucesso - remove as pendências com o usuário", func(t *testing.T) {
+		// O dono não pode ser removido, então Ana deixa de sê-lo antes.
+		require.NoError(t, repo.Enfileirar(ctx, ana))
+		_, err := db.ExecContext(ctx, "DELETE FROM memberships WHERE usuario_id = ?", ana)
+		require.NoError(t, err)
+		require.NoError(t, usuarios.Delete(ctx, ana, 0))
+
+		var total int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM customer_sync_outbox").Scan(&total))
+		assert.Zero(t, total)
+	})
+}
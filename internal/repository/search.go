@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// maxTermosBusca limita quantas palavras da busca viram termos da consulta FTS5.
+const maxTermosBusca = 8
+
+// ErrFTS5Indisponivel indica que o SQLite foi compilado sem o módulo FTS5.
+var ErrFTS5Indisponivel = errors.New("SQLite sem suporte a FTS5: compile com -tags sqlite_fts5")
+
+// ChecarFTS5 verifica se o SQLite em uso tem o módulo FTS5, exigido pela busca de usuários.
+// Deve ser chamada antes das migrations: sem FTS5, a tabela virtual usuarios_fts não pode ser
+// criada e a busca fica desligada.
+func ChecarFTS5(ctx context.Context, db *sql.DB) error {
+	var habilitado bool
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&habilitado); err != nil {
+		return err
+	}
+	if !habilitado {
+		return ErrFTS5Indisponivel
+	}
+	return nil
+}
+
+// IndiceBuscaExiste informa se o banco tem o índice de busca (a tabela usuarios_fts).
+func IndiceBuscaExiste(ctx context.Context, db *sql.DB) (bool, error) {
+	var existe bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'usuarios_fts')").Scan(&existe)
+	return existe, err
+}
+
+// termosBusca converte o texto digitado em uma expressão MATCH do FTS5: cada palavra vira
+// um termo de prefixo entre aspas ("ana"* "sou"*), combinados com AND. Pontuação e
+// operadores do FTS5 no texto são descartados, então a entrada do usuário nunca é
+// interpretada como sintaxe de consulta. Devolve "" se não sobrar nenhuma palavra.
+func termosBusca(texto string) string {
+	palavras := strings.FieldsFunc(texto, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(palavras) > maxTermosBusca {
+		palavras = palavras[:maxTermosBusca]
+	}
+	termos := make([]string, len(palavras))
+	for i, p := range palavras {
+		termos[i] = `"` + p + `"*`
+	}
+	return strings.Join(termos, " ")
+}
+
+// Search busca usuários por partes do nome ou do e-mail, ignorando maiúsculas e acentos.
+// Cada palavra da busca casa com o início de uma palavra indexada (busca por prefixo) e
+// todas precisam estar presentes. Os resultados vêm ordenados por relevância (bm25),
+// com ocorrências no nome pesando mais que no e-mail. Sem FTS5, devolve ErrFTS5Indisponivel.
+func (r *sqliteRepository) Search(ctx context.Context, query string, limit int) (_ []domain.Usuario, err error) {
+	ctx, end := instrument(ctx, "Search", querySearch)
+	defer func() { end(err) }()
+
+	if r.stmts.search == nil {
+		return nil, ErrFTS5Indisponivel
+	}
+	termos := termosBusca(query)
+	if termos == "" {
+		return []domain.Usuario{}, nil
+	}
+
+	rows, err := r.stmts.search.QueryContext(ctx, termos, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usuarios := []domain.Usuario{}
+	for rows.Next() {
+		u, err := scanUsuario(rows)
+		if err != nil {
+			return nil, err
+		}
+		usuarios = append(usuarios, u)
+	}
+	return usuarios, rows.Err()
+}
@@ -0,0 +1,95 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestSQLiteRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	require.NoError(t, ChecarFTS5(ctx, db), "a busca exige o SQLite com FTS5")
+	repo, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	existe, err := IndiceBuscaExiste(ctx, db)
+	require.NoError(t, err)
+	require.True(t, existe)
+
+	for _, u := range []domain.Usuario{
+		{Nome: "João Silva", Email: "joao.silva@email.com"},
+		{Nome: "Maria Conceição", Email: "maria@empresa.com.br"},
+		{Nome: "Ana Souza", Email: "ana.joaquina@email.com"},
+	} {
+		_, err := repo.Create(ctx, u)
+		require.NoError(t, err)
+	}
+
+	nomes := func(usuarios []domain.Usuario) []string {
+		var n []string
+		for _, u := range usuarios {
+			n = append(n, u.Nome)
+		}
+		return n
+	}
+
+	t.Run("sucesso - ignora acentos e maiúsculas nos dois sentidos", func(t *testing.T) {
+		usuarios, err := repo.Search(ctx, "CONCEICAO", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Maria Conceição"}, nomes(usuarios))
+
+		usuarios, err = repo.Search(ctx, "joão", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"João Silva"}, nomes(usuarios))
+	})
+
+	t.Run("sucesso - busca por prefixo, com o nome mais relevante que o e-mail", func(t *testing.T) {
+		usuarios, err := repo.Search(ctx, "jo", 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"João Silva", "Ana Souza"}, nomes(usuarios))
+	})
+
+	t.Run("sucesso - partes do e-mail e todas as palavras obrigatórias", func(t *testing.T) {
+		usuarios, err := repo.Search(ctx, "maria@empre", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Maria Conceição"}, nomes(usuarios))
+
+		usuarios, err = repo.Search(ctx, "joao souza", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, usuarios)
+	})
+
+	t.Run("sucesso - operadores do FTS5 no texto são tratados como palavras", func(t *testing.T) {
+		usuarios, err := repo.Search(ctx, `ana" OR NEAR(* -`, 10)
+
+		assert.NoError(t, err)
+		assert.Empty(t, usuarios)
+	})
+
+	t.Run("sucesso - índice acompanha alterações e remoções", func(t *testing.T) {
+		usuarios, err := repo.Search(ctx, "silva", 10)
+		require.NoError(t, err)
+		require.Len(t, usuarios, 1)
+		id := usuarios[0].ID
+
+		require.NoError(t, repo.Update(ctx, id, domain.Usuario{Nome: "João Pereira", Email: "joao.pereira@email.com"}))
+		usuarios, err = repo.Search(ctx, "silva", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, usuarios)
+		usuarios, err = repo.Search(ctx, "pereira", 10)
+		assert.NoError(t, err)
+		assert.Len(t, usuarios, 1)
+
+		require.NoError(t, repo.Delete(ctx, id, 0))
+		usuarios, err = repo.Search(ctx, "pereira", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, usuarios)
+	})
+}
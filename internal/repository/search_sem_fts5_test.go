@@ -0,0 +1,30 @@
+//go:build !sqlite_fts5
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestSQLiteRepository_SearchSemFTS5(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	require.ErrorIs(t, ChecarFTS5(ctx, db), ErrFTS5Indisponivel)
+	repo, err := NewSQLiteRepository(db)
+	require.NoError(t, err, "o repositório sobe sem o índice de busca")
+	t.Cleanup(func() { repo.Close() })
+
+	_, err = repo.Create(ctx, domain.Usuario{Nome: "João Silva", Email: "joao.silva@email.com"})
+	require.NoError(t, err)
+
+	_, err = repo.Search(ctx, "joao", 10)
+	assert.ErrorIs(t, err, ErrFTS5Indisponivel)
+	existe, err := IndiceBuscaExiste(ctx, db)
+	require.NoError(t, err)
+	assert.False(t, existe)
+}
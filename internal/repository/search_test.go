@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermosBusca(t *testing.T) {
+	assert.Equal(t, `"ana"* "souza"* "em"*`, termosBusca("ana.souza@em"))
+	assert.Equal(t, `"Conceição"*`, termosBusca(`  "Conceição"  `))
+	assert.Equal(t, "", termosBusca(`"* - ()`))
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// SessaoRepository define a persistência das sessões de login e dos refresh tokens de cada
+// uma. Só o hash dos refresh tokens é guardado.
+type SessaoRepository interface {
+	// CriarSessao grava a sessão com o seu primeiro refresh token e devolve o ID gerado.
+	CriarSessao(ctx context.Context, sessao domain.Sessao, hashRefresh string) (int64, error)
+	// GetSessao busca a sessão, ativa ou não; devolve nil se não existir.
+	GetSessao(ctx context.Context, id int64) (*domain.Sessao, error)
+	// BuscarRefreshToken devolve a sessão do refresh token e se ele já foi trocado. O ID é
+	// zero se o token não existir.
+	BuscarRefreshToken(ctx context.Context, hash string) (sessaoID int64, usado bool, err error)
+	// Rotacionar marca o refresh token atual como usado, grava o novo e atualiza a origem e o
+	// último uso da sessão, em uma única transação. Devolve false se o token atual já tiver
+	// sido usado (por exemplo, por uma requisição simultânea).
+	Rotacionar(ctx context.Context, sessaoID int64, hashAtual, hashNovo string, origem domain.OrigemSessao) (bool, error)
+	// ListAtivas devolve as sessões do usuário não revogadas nem expiradas, da mais recente à
+	// mais antiga.
+	ListAtivas(ctx context.Context, usuarioID int64) ([]domain.Sessao, error)
+	// Revogar revoga a sessão do usuário. Devolve ErrNaoEncontrado se ela não existir, for de
+	// outro usuário ou já tiver sido revogada.
+	Revogar(ctx context.Context, usuarioID, sessaoID int64, motivo string) error
+	// RevogarTodas revoga as sessões ainda não revogadas do usuário e devolve quantas eram.
+	RevogarTodas(ctx context.Context, usuarioID int64, motivo string) (int64, error)
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	colunasSessao     = "id, usuario_id, dispositivo, ip, user_agent, com_mfa, created_at, last_used_at, expires_at, revoked_at, COALESCE(motivo_revogacao, '')"
+	querySessaoCriar  = "INSERT INTO sessoes(usuario_id, dispositivo, ip, user_agent, com_mfa, created_at, last_used_at, expires_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?)"
+	querySessaoGet    = "SELECT " + colunasSessao + " FROM sessoes WHERE id = ?"
+	querySessaoAtivas = "SELECT " + colunasSessao + " FROM sessoes WHERE usuario_id = ? AND revoked_at IS NULL AND expires_at > ? ORDER BY last_used_at DESC, id DESC"
+	querySessaoUsada  = "UPDATE sessoes SET ip = ?, user_agent = ?, last_used_at = ? WHERE id = ?"
+	querySessaoRevoga = "UPDATE sessoes SET revoked_at = ?, motivo_revogacao = ? WHERE id = ? AND usuario_id = ? AND revoked_at IS NULL"
+	querySessaoTodas  = "UPDATE sessoes SET revoked_at = ?, motivo_revogacao = ? WHERE usuario_id = ? AND revoked_at IS NULL"
+	queryRefreshCriar = "INSERT INTO refresh_tokens(sessao_id, hash, created_at) VALUES(?, ?, ?)"
+	queryRefreshGet   = "SELECT sessao_id, used_at IS NOT NULL FROM refresh_tokens WHERE hash = ?"
+	queryRefreshUsar  = "UPDATE refresh_tokens SET used_at = ? WHERE sessao_id = ? AND hash = ? AND used_at IS NULL"
+)
+
+type sessaoSQLite struct {
+	db    *sql.DB
+	stmts preparadas
+}
+
+// NewSessaoRepository cria o repositório de sessões sobre o banco da aplicação.
+func NewSessaoRepository(db *sql.DB) (SessaoRepository, error) {
+	stmts, err := preparar(db,
+		querySessaoCriar, querySessaoGet, querySessaoAtivas, querySessaoUsada, querySessaoRevoga,
+		querySessaoTodas, queryRefreshCriar, queryRefreshGet, queryRefreshUsar)
+	if err != nil {
+		return nil, err
+	}
+	return &sessaoSQLite{db: db, stmts: stmts}, nil
+}
+
+func (r *sessaoSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *sessaoSQLite) CriarSessao(ctx context.Context, sessao domain.Sessao, hashRefresh string) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Sessao.CriarSessao", querySessaoCriar)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	res, err := tx.StmtContext(ctx, r.stmts[querySessaoCriar]).ExecContext(ctx, sessao.UsuarioID, sessao.Dispositivo, sessao.IP, sessao.UserAgent,
+		sessao.ComMFA, sessao.CreatedAt.UTC(), sessao.LastUsedAt.UTC(), sessao.ExpiresAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.StmtContext(ctx, r.stmts[queryRefreshCriar]).ExecContext(ctx, id, hashRefresh, sessao.CreatedAt.UTC()); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *sessaoSQLite) GetSessao(ctx context.Context, id int64) (_ *domain.Sessao, err error) {
+	ctx, end := instrument(ctx, "Sessao.GetSessao", querySessaoGet)
+	defer func() { end(err) }()
+
+	sessao, err := scanSessao(r.stmts[querySessaoGet].QueryRowContext(ctx, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sessao, err
+}
+
+func (r *sessaoSQLite) BuscarRefreshToken(ctx context.Context, hash string) (sessaoID int64, usado bool, err error) {
+	ctx, end := instrument(ctx, "Sessao.BuscarRefreshToken", queryRefreshGet)
+	defer func() { end(err) }()
+
+	err = r.stmts[queryRefreshGet].QueryRowContext(ctx, hash).Scan(&sessaoID, &usado)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return sessaoID, usado, err
+}
+
+func (r *sessaoSQLite) Rotacionar(ctx context.Context, sessaoID int64, hashAtual, hashNovo string, origem domain.OrigemSessao) (_ bool, err error) {
+	ctx, end := instrument(ctx, "Sessao.Rotacionar", queryRefreshUsar)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	agora := now()
+	// Como em RegistrarPasso, a condição fica no UPDATE: de duas renovações simultâneas com o
+	// mesmo token, só uma é aceita.
+	res, err := tx.StmtContext(ctx, r.stmts[queryRefreshUsar]).ExecContext(ctx, agora, sessaoID, hashAtual)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.StmtContext(ctx, r.stmts[queryRefreshCriar]).ExecContext(ctx, sessaoID, hashNovo, agora); err != nil {
+		return false, err
+	}
+	if _, err := tx.StmtContext(ctx, r.stmts[querySessaoUsada]).ExecContext(ctx, origem.IP, origem.UserAgent, agora, sessaoID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *sessaoSQLite) ListAtivas(ctx context.Context, usuarioID int64) (_ []domain.Sessao, err error) {
+	ctx, end := instrument(ctx, "Sessao.ListAtivas", querySessaoAtivas)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[querySessaoAtivas].QueryContext(ctx, usuarioID, now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessoes := []domain.Sessao{}
+	for rows.Next() {
+		sessao, err := scanSessao(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessoes = append(sessoes, *sessao)
+	}
+	return sessoes, rows.Err()
+}
+
+func (r *sessaoSQLite) Revogar(ctx context.Context, usuarioID, sessaoID int64, motivo string) (err error) {
+	ctx, end := instrument(ctx, "Sessao.Revogar", querySessaoRevoga)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[querySessaoRevoga].ExecContext(ctx, now(), motivo, sessaoID, usuarioID)
+	if err != nil {
+		return err
+	}
+	return checarAfetadas(res)
+}
+
+func (r *sessaoSQLite) RevogarTodas(ctx context.Context, usuarioID int64, motivo string) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Sessao.RevogarTodas", querySessaoTodas)
+	defer func() { end(err) }()
+
+	res, err := r.stmts[querySessaoTodas].ExecContext(ctx, now(), motivo, usuarioID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanSessao(row scanner) (*domain.Sessao, error) {
+	var s domain.Sessao
+	var revogada sql.NullTime
+	err := row.Scan(&s.ID, &s.UsuarioID, &s.Dispositivo, &s.IP, &s.UserAgent, &s.ComMFA,
+		&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &revogada, &s.MotivoRevogacao)
+	if err != nil {
+		return nil, err
+	}
+	if revogada.Valid {
+		t := revogada.Time
+		s.RevokedAt = &t
+	}
+	return &s, nil
+}
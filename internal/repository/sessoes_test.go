@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestSessaoRepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	repo, err := NewSessaoRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	agora := time.Now().UTC().Truncate(time.Second)
+	nova := func(usuarioID int64, expira time.Time) domain.Sessao {
+		return domain.Sessao{UsuarioID: usuarioID, Dispositivo: "notebook", IP: "10.0.0.1", UserAgent: "curl/8",
+			CreatedAt: agora, LastUsedAt: agora, ExpiresAt: expira}
+	}
+
+	t.Run("sucesso - cria a sessão com o primeiro refresh token", func(t *testing.T) {
+		id, err := repo.CriarSessao(ctx, nova(1, agora.Add(time.Hour)), "r1")
+		require.NoError(t, err)
+
+		sessao, err := repo.GetSessao(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "notebook", sessao.Dispositivo)
+		assert.True(t, sessao.Ativa(agora))
+		sessaoID, usado, err := repo.BuscarRefreshToken(ctx, "r1")
+		require.NoError(t, err)
+		assert.Equal(t, id, sessaoID)
+		assert.False(t, usado)
+
+		sessao, err = repo.GetSessao(ctx, 999)
+		require.NoError(t, err)
+		assert.Nil(t, sessao)
+		sessaoID, _, err = repo.BuscarRefreshToken(ctx, "inexistente")
+		require.NoError(t, err)
+		assert.Zero(t, sessaoID)
+	})
+
+	t.Run("sucesso - rotação aceita cada refresh token uma única vez", func(t *testing.T) {
+		id, err := repo.CriarSessao(ctx, nova(2, agora.Add(time.Hour)), "a1")
+		require.NoError(t, err)
+
+		ok, err := repo.Rotacionar(ctx, id, "a1", "a2", domain.OrigemSessao{IP: "10.0.0.2", UserAgent: "app/2"})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = repo.Rotacionar(ctx, id, "a1", "a3", domain.OrigemSessao{})
+		require.NoError(t, err)
+		assert.False(t, ok, "o token já foi trocado")
+
+		_, usado, _ := repo.BuscarRefreshToken(ctx, "a1")
+		assert.True(t, usado)
+		_, usado, _ = repo.BuscarRefreshToken(ctx, "a2")
+		assert.False(t, usado)
+		sessaoID, _, _ := repo.BuscarRefreshToken(ctx, "a3")
+		assert.Zero(t, sessaoID, "a rotação recusada não grava o novo token")
+		sessao, err := repo.GetSessao(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.2", sessao.IP)
+		assert.Equal(t, "app/2", sessao.UserAgent)
+		assert.Equal(t, "notebook", sessao.Dispositivo)
+	})
+
+	t.Run("sucesso - lista só as ativas e revoga uma ou todas", func(t *testing.T) {
+		primeira, err := repo.CriarSessao(ctx, nova(3, agora.Add(time.Hour)), "b1")
+		require.NoError(t, err)
+		segunda, err := repo.CriarSessao(ctx, nova(3, agora.Add(time.Hour)), "b2")
+		require.NoError(t, err)
+		_, err = repo.CriarSessao(ctx, nova(3, agora.Add(-time.Minute)), "b3")
+		require.NoError(t, err)
+
+		sessoes, err := repo.ListAtivas(ctx, 3)
+		require.NoError(t, err)
+		require.Len(t, sessoes, 2, "a expirada fica de fora")
+		assert.Equal(t, segunda, sessoes[0].ID)
+
+		assert.ErrorIs(t, repo.Revogar(ctx, 4, primeira, domain.MotivoRevogada), ErrNaoEncontrado, "sessão de outro usuário")
+		require.NoError(t, repo.Revogar(ctx, 3, primeira, domain.MotivoLogout))
+		assert.ErrorIs(t, repo.Revogar(ctx, 3, primeira, domain.MotivoLogout), ErrNaoEncontrado, "já revogada")
+		sessao, err := repo.GetSessao(ctx, primeira)
+		require.NoError(t, err)
+		assert.False(t, sessao.Ativa(agora))
+		assert.Equal(t, domain.MotivoLogout, sessao.MotivoRevogacao)
+
+		n, err := repo.RevogarTodas(ctx, 3, domain.MotivoUsuarioRemovido)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n, "a segunda e a expirada")
+		sessoes, err = repo.ListAtivas(ctx, 3)
+		require.NoError(t, err)
+		assert.Empty(t, sessoes)
+	})
+}
@@ -3,196 +3,403 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	// Importa o pacote time
+	"github.com/mattn/go-sqlite3"
 	"github.com/willjrcristo/go-sqlite-db/internal/domain" // Ajuste o nome do seu módulo se necessário
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/willjrcristo/go-sqlite-db/internal/repository")
+
+// now devolve o instante usado em created_at/updated_at. Sempre em UTC, para que as
+// comparações dos filtros (feitas sobre o texto gravado pelo driver) respeitem a ordem cronológica.
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// ErrVersaoDesatualizada indica que o registro foi alterado desde que a versão esperada foi lida.
+var ErrVersaoDesatualizada = errors.New("versão do registro desatualizada")
+
+// ErrEmailEmUso indica que outro usuário já usa o e-mail (sem diferenciar maiúsculas).
+var ErrEmailEmUso = errors.New("e-mail já usado por outro usuário")
+
+// traduzirErroEmail converte a violação do índice único de e-mails em ErrEmailEmUso.
+func traduzirErroEmail(err error) error {
+	var se sqlite3.Error
+	if errors.As(err, &se) && se.ExtendedCode == sqlite3.ErrConstraintUnique && strings.Contains(se.Error(), "idx_usuarios_email_unico") {
+		return fmt.Errorf("%w: %v", ErrEmailEmUso, err)
+	}
+	return err
+}
+
+// ErrDonoDeOrganizacao indica que o usuário é dono de uma organização e não pode ser removido.
+var ErrDonoDeOrganizacao = errors.New("usuário é dono de uma organização")
+
+// traduzirErroRemocao converte a recusa do trigger usuarios_dono_nao_removido em ErrDonoDeOrganizacao.
+func traduzirErroRemocao(err error) error {
+	var se sqlite3.Error
+	if errors.As(err, &se) && se.ExtendedCode == sqlite3.ErrConstraintTrigger && strings.Contains(se.Error(), "dono_de_organizacao") {
+		return fmt.Errorf("%w: %v", ErrDonoDeOrganizacao, err)
+	}
+	return err
+}
+
 // UsuarioRepository define a interface para as operações de persistência de usuários.
 type UsuarioRepository interface {
+	// Create, CreateMany, Update e Patch devolvem ErrEmailEmUso se outro usuário já usar o e-mail,
+	// e Delete devolve ErrDonoDeOrganizacao se o usuário for dono de uma organização.
 	Create(ctx context.Context, usuario domain.Usuario) (int64, error)
-	GetAll(ctx context.Context) ([]domain.Usuario, error)
+	GetAll(ctx context.Context, filtro domain.FiltroUsuarios) ([]domain.Usuario, error)
+	// Iterate percorre os usuários em streaming (usado na exportação).
+	Iterate(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) error
+	// CreateMany insere um lote de usuários em uma única transação (usado na importação).
+	CreateMany(ctx context.Context, usuarios []domain.Usuario) ([]int64, error)
 	GetByID(ctx context.Context, id int64) (*domain.Usuario, error)
+	// Search faz a busca textual por nome e e-mail (FTS5), ordenada por relevância.
+	Search(ctx context.Context, query string, limit int) ([]domain.Usuario, error)
+	// Update, Patch e Delete fazem compare-and-swap na coluna version: com uma versão
+	// esperada diferente de zero, só alteram o registro se ela ainda for a atual e,
+	// caso contrário, retornam ErrVersaoDesatualizada.
 	Update(ctx context.Context, id int64, usuario domain.Usuario) error
-	Delete(ctx context.Context, id int64) error
-
-	// Método específico para atualizar apenas os detalhes da assinatura.
-	UpdateSubscriptionDetails(ctx context.Context, id int64, usuario domain.Usuario) error
-	// Método para buscar um usuário pelo seu ID de cliente na Stripe.
-	GetByStripeID(ctx context.Context, stripeID string) (*domain.Usuario, error)
+	// Patch grava apenas os campos presentes no patch (usado pelo PATCH /usuarios/{id}).
+	Patch(ctx context.Context, id int64, patch domain.UsuarioPatch) error
+	Delete(ctx context.Context, id int64, version int64) error
+	// ExecutarLote roda várias escritas em uma única transação (usado no POST /usuarios/batch).
+	ExecutarLote(ctx context.Context, fn func(Lote) error) error
+
+	// GetByEmail busca o usuário pelo e-mail (usado nos pedidos de verificação e de nova senha).
+	GetByEmail(ctx context.Context, email string) (*domain.Usuario, error)
+	// MarkEmailVerified marca o e-mail como verificado se ele ainda for o do usuário;
+	// do contrário, devolve ErrNaoEncontrado.
+	MarkEmailVerified(ctx context.Context, id int64, email string) error
+	// GetPasswordHash e UpdatePassword leem e gravam o hash da senha, que fica fora de domain.Usuario.
+	GetPasswordHash(ctx context.Context, id int64) (string, error)
+	UpdatePassword(ctx context.Context, id int64, hash string) error
+	// UpdatePapel altera o papel do usuário; devolve ErrNaoEncontrado se ele não existir.
+	UpdatePapel(ctx context.Context, id int64, papel string) error
+
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
 }
 
 // sqliteRepository é a implementação do UsuarioRepository para SQLite.
 type sqliteRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	stmts *statements
 }
 
 // NewSQLiteRepository é a fábrica que cria uma nova instância do nosso repositório.
-func NewSQLiteRepository(db *sql.DB) UsuarioRepository {
-	return &sqliteRepository{
-		db: db,
+// Todas as instruções são preparadas aqui, uma única vez, então as migrations já devem
+// ter sido aplicadas; um erro indica que o esquema do banco não é o esperado.
+func NewSQLiteRepository(db *sql.DB) (UsuarioRepository, error) {
+	stmts, err := prepararStatements(context.Background(), db)
+	if err != nil {
+		return nil, err
 	}
+	return &sqliteRepository{
+		db:    db,
+		stmts: stmts,
+	}, nil
 }
 
-// Create não precisa de alterações. Os campos de assinatura terão seus valores padrão do DB.
-func (r *sqliteRepository) Create(ctx context.Context, usuario domain.Usuario) (int64, error) {
-	stmt, err := r.db.PrepareContext(ctx, "INSERT INTO usuarios(nome, email) VALUES(?, ?)")
-	if err != nil {
-		return 0, err
+// Close fecha as instruções preparadas. O *sql.DB continua aberto e é responsabilidade de quem o criou.
+func (r *sqliteRepository) Close() error {
+	return r.stmts.close()
+}
+
+// instrument abre um span de cliente para uma consulta, com a instrução SQL como atributo,
+// e devolve a função que finaliza o span e registra a duração no histograma do método.
+func instrument(ctx context.Context, metodo, query string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "sqliteRepository."+metodo,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(telemetry.DBAttributes(metodo, query)...),
+	)
+	return ctx, func(err error) {
+		duracao := time.Since(start)
+		metrics.DBQueryDuration.WithLabelValues(metodo, metrics.Outcome(err)).Observe(duracao.Seconds())
+		telemetry.EndSpan(span, err)
+
+		logger := logging.FromContext(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Erro na consulta ao banco de dados", "method", metodo, "duration", duracao, "error", err)
+			return
+		}
+		logger.DebugContext(ctx, "Consulta ao banco de dados executada", "method", metodo, "duration", duracao)
 	}
-	defer stmt.Close()
+}
+
+// Create insere o usuário. Os campos de assinatura terão seus valores padrão do DB;
+// created_at e updated_at são preenchidos aqui.
+func (r *sqliteRepository) Create(ctx context.Context, usuario domain.Usuario) (_ int64, err error) {
+	ctx, end := instrument(ctx, "Create", queryCreate)
+	defer func() { end(err) }()
 
-	res, err := stmt.ExecContext(ctx, usuario.Nome, usuario.Email)
+	agora := now()
+	res, err := r.stmts.create.ExecContext(ctx, usuario.Nome, usuario.Email, agora, agora)
 	if err != nil {
-		return 0, err
+		return 0, traduzirErroEmail(err)
 	}
 
 	return res.LastInsertId()
 }
 
-func (r *sqliteRepository) GetAll(ctx context.Context) ([]domain.Usuario, error) {
-	// Query atualizada para incluir os novos campos.
-	query := `
-		SELECT id, nome, email,
-		       stripe_customer_id, stripe_subscription_id, subscription_status, subscription_current_period_end
-		FROM usuarios`
+// CreateMany insere vários usuários em uma única transação, reaproveitando o mesmo
+// prepared statement. Ou todos são gravados, ou nenhum; os IDs voltam na ordem da entrada.
+func (r *sqliteRepository) CreateMany(ctx context.Context, usuarios []domain.Usuario) (_ []int64, err error) {
+	ctx, end := instrument(ctx, "CreateMany", queryCreate)
+	defer func() { end(err) }()
 
-	rows, err := r.db.QueryContext(ctx, query)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback() // Sem efeito depois do Commit.
 
-	var usuarios []domain.Usuario
-	for rows.Next() {
-		var u domain.Usuario
-		// Usamos tipos Null* para lidar com possíveis valores NULL do banco.
-		var stripeCustomerID, stripeSubscriptionID, subscriptionStatus sql.NullString
-		var subscriptionCurrentPeriodEnd sql.NullTime
-
-		if err := rows.Scan(
-			&u.ID, &u.Nome, &u.Email,
-			&stripeCustomerID, &stripeSubscriptionID, &subscriptionStatus, &subscriptionCurrentPeriodEnd,
-		); err != nil {
+	stmt := tx.StmtContext(ctx, r.stmts.create)
+	defer stmt.Close()
+
+	agora := now()
+	ids := make([]int64, 0, len(usuarios))
+	for _, u := range usuarios {
+		res, err := stmt.ExecContext(ctx, u.Nome, u.Email, agora, agora)
+		if err != nil {
+			return nil, traduzirErroEmail(err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
 			return nil, err
 		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
 
-		// Atribuímos os valores para a struct, tratando os casos nulos.
-		u.StripeCustomerID = stripeCustomerID.String
-		u.StripeSubscriptionID = stripeSubscriptionID.String
-		u.SubscriptionStatus = subscriptionStatus.String
-		u.SubscriptionCurrentPeriodEnd = subscriptionCurrentPeriodEnd.Time
-
+// GetAll lista os usuários que atendem ao filtro, em ordem de ID.
+func (r *sqliteRepository) GetAll(ctx context.Context, filtro domain.FiltroUsuarios) ([]domain.Usuario, error) {
+	var usuarios []domain.Usuario
+	err := r.Iterate(ctx, filtro, func(u domain.Usuario) error {
 		usuarios = append(usuarios, u)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return usuarios, nil
 }
 
-func (r *sqliteRepository) GetByID(ctx context.Context, id int64) (*domain.Usuario, error) {
-	// Query atualizada para incluir os novos campos.
-	query := `
-		SELECT id, nome, email,
-		       stripe_customer_id, stripe_subscription_id, subscription_status, subscription_current_period_end
-		FROM usuarios WHERE id = ?`
-
-	row := r.db.QueryRowContext(ctx, query, id)
+// Iterate percorre os usuários que atendem ao filtro, em ordem de ID, chamando fn para
+// cada um à medida que as linhas são lidas, sem carregar o resultado inteiro em memória.
+// Se fn retornar erro, a iteração é interrompida e o erro é devolvido.
+func (r *sqliteRepository) Iterate(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) (err error) {
+	filtros := 0
+	var args []any
+	if !filtro.CreatedAfter.IsZero() {
+		filtros |= filtroCreatedAfter
+		args = append(args, filtro.CreatedAfter.UTC())
+	}
+	if !filtro.UpdatedSince.IsZero() {
+		filtros |= filtroUpdatedSince
+		args = append(args, filtro.UpdatedSince.UTC())
+	}
+	ctx, end := instrument(ctx, "Iterate", queryIterate(filtros))
+	defer func() { end(err) }()
 
-	var u domain.Usuario
-	var stripeCustomerID, stripeSubscriptionID, subscriptionStatus sql.NullString
-	var subscriptionCurrentPeriodEnd sql.NullTime
+	rows, err := r.stmts.iterate[filtros].QueryContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-	if err := row.Scan(
-		&u.ID, &u.Nome, &u.Email,
-		&stripeCustomerID, &stripeSubscriptionID, &subscriptionStatus, &subscriptionCurrentPeriodEnd,
-	); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+	for rows.Next() {
+		u, err := scanUsuario(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
 		}
-		return nil, err
 	}
+	return rows.Err()
+}
 
-	u.StripeCustomerID = stripeCustomerID.String
-	u.StripeSubscriptionID = stripeSubscriptionID.String
-	u.SubscriptionStatus = subscriptionStatus.String
-	u.SubscriptionCurrentPeriodEnd = subscriptionCurrentPeriodEnd.Time
+// GetByID devolve nil, sem erro, quando o usuário não existe.
+func (r *sqliteRepository) GetByID(ctx context.Context, id int64) (_ *domain.Usuario, err error) {
+	ctx, end := instrument(ctx, "GetByID", queryGetByID)
+	defer func() { end(err) }()
 
+	u, err := scanUsuario(r.stmts.getByID.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 	return &u, nil
 }
 
-// Update (para nome e e-mail) continua o mesmo.
-func (r *sqliteRepository) Update(ctx context.Context, id int64, usuario domain.Usuario) error {
-	stmt, err := r.db.PrepareContext(ctx, "UPDATE usuarios SET nome = ?, email = ? WHERE id = ?")
+// Update altera nome e e-mail, incrementando a versão do registro.
+// A versão esperada vem de usuario.Version (zero para não verificar).
+func (r *sqliteRepository) Update(ctx context.Context, id int64, usuario domain.Usuario) (err error) {
+	ctx, end := instrument(ctx, "Update", queryUpdate)
+	defer func() { end(err) }()
+
+	res, err := r.stmts.update.ExecContext(ctx, usuario.Nome, usuario.Email, now(), id, usuario.Version, usuario.Version)
 	if err != nil {
-		return err
+		return traduzirErroEmail(err)
 	}
-	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, usuario.Nome, usuario.Email, id)
-	return err
+	return checarVersao(res)
 }
 
-// Delete continua o mesmo.
-func (r *sqliteRepository) Delete(ctx context.Context, id int64) error {
-	stmt, err := r.db.PrepareContext(ctx, "DELETE FROM usuarios WHERE id = ?")
+// Patch grava apenas as colunas alteradas, para não sobrescrever campos que o cliente
+// não enviou (e que podem ter sido alterados por outra requisição).
+func (r *sqliteRepository) Patch(ctx context.Context, id int64, patch domain.UsuarioPatch) (err error) {
+	campos := 0
+	var args []any
+	if patch.Nome != nil {
+		campos |= patchNome
+		args = append(args, *patch.Nome)
+	}
+	if patch.Email != nil {
+		campos |= patchEmail
+		args = append(args, *patch.Email)
+	}
+	if campos == 0 {
+		return nil
+	}
+	args = append(args, now(), id, patch.Version, patch.Version)
+
+	ctx, end := instrument(ctx, "Patch", queryPatch(campos))
+	defer func() { end(err) }()
+
+	res, err := r.stmts.patch[campos].ExecContext(ctx, args...)
 	if err != nil {
-		return err
+		return traduzirErroEmail(err)
 	}
-	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, id)
-	return err
+	return checarVersao(res)
 }
 
-// UpdateSubscriptionDetails atualiza apenas os campos relacionados à assinatura Stripe.
-func (r *sqliteRepository) UpdateSubscriptionDetails(ctx context.Context, id int64, usuario domain.Usuario) error {
-	query := `
-		UPDATE usuarios
-		SET stripe_customer_id = ?, stripe_subscription_id = ?,
-		    subscription_status = ?, subscription_current_period_end = ?
-		WHERE id = ?`
+// Delete remove o usuário, verificando a versão esperada quando ela for diferente de zero.
+func (r *sqliteRepository) Delete(ctx context.Context, id int64, version int64) (err error) {
+	ctx, end := instrument(ctx, "Delete", queryDelete)
+	defer func() { end(err) }()
+
+	res, err := r.stmts.delete.ExecContext(ctx, id, version, version)
+	if err != nil {
+		return traduzirErroRemocao(err)
+	}
+	return checarVersao(res)
+}
 
-	stmt, err := r.db.PrepareContext(ctx, query)
+// checarVersao traduz um UPDATE/DELETE que não afetou nenhuma linha em ErrVersaoDesatualizada.
+// Os serviços verificam a existência do usuário antes de alterá-lo, então nenhuma linha
+// afetada significa que a versão mudou (ou que o registro foi removido) nesse intervalo.
+func checarVersao(res sql.Result) error {
+	n, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	if n == 0 {
+		return ErrVersaoDesatualizada
+	}
+	return nil
+}
 
-	_, err = stmt.ExecContext(ctx,
-		usuario.StripeCustomerID,
-		usuario.StripeSubscriptionID,
-		usuario.SubscriptionStatus,
-		usuario.SubscriptionCurrentPeriodEnd,
-		id,
-	)
-	return err
+// GetByEmail busca o usuário pelo e-mail, sem diferenciar maiúsculas.
+func (r *sqliteRepository) GetByEmail(ctx context.Context, email string) (_ *domain.Usuario, err error) {
+	ctx, end := instrument(ctx, "GetByEmail", queryGetByEmail)
+	defer func() { end(err) }()
+
+	u, err := scanUsuario(r.stmts.getByEmail.QueryRowContext(ctx, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
 }
 
-// GetByStripeID busca um usuário pelo seu Stripe Customer ID.
-func (r *sqliteRepository) GetByStripeID(ctx context.Context, stripeID string) (*domain.Usuario, error) {
-	query := `
-		SELECT id, nome, email,
-		       stripe_customer_id, stripe_subscription_id, subscription_status, subscription_current_period_end
-		FROM usuarios WHERE stripe_customer_id = ?`
+// MarkEmailVerified registra a verificação do e-mail, desde que ele ainda seja o do usuário.
+// Devolve ErrNaoEncontrado se o usuário não existir ou o e-mail tiver mudado.
+func (r *sqliteRepository) MarkEmailVerified(ctx context.Context, id int64, email string) (err error) {
+	ctx, end := instrument(ctx, "MarkEmailVerified", queryMarkEmailVerified)
+	defer func() { end(err) }()
 
-	row := r.db.QueryRowContext(ctx, query, stripeID)
+	agora := now()
+	res, err := r.stmts.markEmailVerified.ExecContext(ctx, agora, agora, id, email)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNaoEncontrado
+	}
+	return nil
+}
 
-	var u domain.Usuario
-	var stripeCustomerID, stripeSubscriptionID, subscriptionStatus sql.NullString
-	var subscriptionCurrentPeriodEnd sql.NullTime
+// GetPasswordHash devolve o hash da senha do usuário (vazio se ele ainda não definiu uma).
+// Devolve ErrNaoEncontrado se o usuário não existir.
+func (r *sqliteRepository) GetPasswordHash(ctx context.Context, id int64) (_ string, err error) {
+	ctx, end := instrument(ctx, "GetPasswordHash", queryGetPasswordHash)
+	defer func() { end(err) }()
 
-	if err := row.Scan(
-		&u.ID, &u.Nome, &u.Email,
-		&stripeCustomerID, &stripeSubscriptionID, &subscriptionStatus, &subscriptionCurrentPeriodEnd,
-	); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Retorna nil, nil se não for encontrado, o que é um estado válido.
-		}
-		return nil, err
+	var hash string
+	err = r.stmts.getPasswordHash.QueryRowContext(ctx, id).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNaoEncontrado
 	}
+	return hash, err
+}
 
-	u.StripeCustomerID = stripeCustomerID.String
-	u.StripeSubscriptionID = stripeSubscriptionID.String
-	u.SubscriptionStatus = subscriptionStatus.String
-	u.SubscriptionCurrentPeriodEnd = subscriptionCurrentPeriodEnd.Time
+// UpdatePassword grava o novo hash da senha. Não altera version nem updated_at, já que a
+// senha não faz parte da representação do usuário.
+func (r *sqliteRepository) UpdatePassword(ctx context.Context, id int64, hash string) (err error) {
+	ctx, end := instrument(ctx, "UpdatePassword", queryUpdatePassword)
+	defer func() { end(err) }()
 
-	return &u, nil
+	res, err := r.stmts.updatePassword.ExecContext(ctx, hash, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNaoEncontrado
+	}
+	return nil
 }
 
+// UpdatePapel grava o novo papel do usuário.
+func (r *sqliteRepository) UpdatePapel(ctx context.Context, id int64, papel string) (err error) {
+	ctx, end := instrument(ctx, "UpdatePapel", queryUpdatePapel)
+	defer func() { end(err) }()
+
+	res, err := r.stmts.updatePapel.ExecContext(ctx, papel, now(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNaoEncontrado
+	}
+	return nil
+}
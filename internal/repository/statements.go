@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// colunasUsuario são as colunas lidas por scanUsuario, na mesma ordem.
+const colunasUsuario = `id, nome, email,
+		       version, created_at, updated_at, email_verified_at, papel`
+
+// Instruções fixas do repositório, preparadas uma única vez em NewSQLiteRepository.
+const (
+	queryCreate  = "INSERT INTO usuarios(nome, email, created_at, updated_at) VALUES(?, ?, ?, ?)"
+	queryGetByID = "SELECT " + colunasUsuario + " FROM usuarios WHERE id = ?"
+	queryUpdate  = `
+		UPDATE usuarios SET nome = ?, email = ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND (? = 0 OR version = ?)`
+	queryDelete     = "DELETE FROM usuarios WHERE id = ? AND (? = 0 OR version = ?)"
+	queryGetByEmail = "SELECT " + colunasUsuario + " FROM usuarios WHERE lower(email) = lower(?)"
+	// A verificação só vale para o e-mail que recebeu o token; a data da primeira verificação é mantida.
+	queryMarkEmailVerified = `
+		UPDATE usuarios
+		SET email_verified_at = COALESCE(email_verified_at, ?), version = version + 1, updated_at = ?
+		WHERE id = ? AND email = ?`
+	queryGetPasswordHash = "SELECT COALESCE(password_hash, '') FROM usuarios WHERE id = ?"
+	queryUpdatePassword  = "UPDATE usuarios SET password_hash = ? WHERE id = ?"
+	queryUpdatePapel     = "UPDATE usuarios SET papel = ?, version = version + 1, updated_at = ? WHERE id = ?"
+	// A busca fica em uma subconsulta para que as colunas de usuarios_fts (nome, email)
+	// não colidam com as de usuarios na lista de colunas compartilhada.
+	querySearch = `
+		SELECT ` + colunasUsuario + `
+		FROM usuarios
+		JOIN (
+			SELECT rowid AS fts_id, bm25(usuarios_fts, 2.0, 1.0) AS relevancia
+			FROM usuarios_fts
+			WHERE usuarios_fts MATCH ?
+		) busca ON busca.fts_id = usuarios.id
+		ORDER BY busca.relevancia, id
+		LIMIT ?`
+)
+
+// Filtros opcionais de Iterate. Cada combinação tem a sua própria instrução preparada.
+const (
+	filtroCreatedAfter = 1 << iota
+	filtroUpdatedSince
+	totalFiltros = 1 << iota
+)
+
+// queryIterate monta a listagem com as condições indicadas em filtros.
+func queryIterate(filtros int) string {
+	query := "SELECT " + colunasUsuario + " FROM usuarios"
+	var condicoes []string
+	if filtros&filtroCreatedAfter != 0 {
+		condicoes = append(condicoes, "created_at > ?")
+	}
+	if filtros&filtroUpdatedSince != 0 {
+		condicoes = append(condicoes, "updated_at >= ?")
+	}
+	if len(condicoes) > 0 {
+		query += " WHERE " + strings.Join(condicoes, " AND ")
+	}
+	return query + " ORDER BY id"
+}
+
+// Campos que um patch pode alterar. Como no Iterate, cada combinação é preparada à parte.
+const (
+	patchNome = 1 << iota
+	patchEmail
+	totalPatches = 1 << iota
+)
+
+// queryPatch monta o UPDATE apenas com as colunas indicadas em campos.
+func queryPatch(campos int) string {
+	var colunas []string
+	if campos&patchNome != 0 {
+		colunas = append(colunas, "nome = ?")
+	}
+	if campos&patchEmail != 0 {
+		colunas = append(colunas, "email = ?")
+	}
+	colunas = append(colunas, "version = version + 1", "updated_at = ?")
+	return "UPDATE usuarios SET " + strings.Join(colunas, ", ") + " WHERE id = ? AND (? = 0 OR version = ?)"
+}
+
+// statements reúne as instruções preparadas do repositório. Um *sql.Stmt é seguro para uso
+// concorrente e o database/sql o prepara de novo, sob demanda, em cada conexão do pool.
+type statements struct {
+	create, getByID, update, delete    *sql.Stmt
+	search                             *sql.Stmt
+	getByEmail, markEmailVerified      *sql.Stmt
+	getPasswordHash, updatePassword    *sql.Stmt
+	updatePapel                        *sql.Stmt
+	loteUpdate, loteDelete, loteExiste *sql.Stmt
+	iterate                            [totalFiltros]*sql.Stmt
+	patch                              [totalPatches]*sql.Stmt // O índice zero (patch vazio) não é usado.
+}
+
+// prepararStatements prepara todas as instruções. Se alguma falhar, fecha as já preparadas.
+// Sem FTS5 não há índice de busca, e a instrução da busca fica nil.
+func prepararStatements(ctx context.Context, db *sql.DB) (_ *statements, err error) {
+	semBusca := ChecarFTS5(ctx, db)
+	if semBusca != nil && !errors.Is(semBusca, ErrFTS5Indisponivel) {
+		return nil, semBusca
+	}
+	s := &statements{}
+	consultas := []struct {
+		destino **sql.Stmt
+		query   string
+	}{
+		{&s.create, queryCreate},
+		{&s.getByID, queryGetByID},
+		{&s.update, queryUpdate},
+		{&s.delete, queryDelete},
+		{&s.getByEmail, queryGetByEmail},
+		{&s.markEmailVerified, queryMarkEmailVerified},
+		{&s.getPasswordHash, queryGetPasswordHash},
+		{&s.updatePassword, queryUpdatePassword},
+		{&s.updatePapel, queryUpdatePapel},
+		{&s.loteUpdate, queryLoteUpdate},
+		{&s.loteDelete, queryLoteDelete},
+		{&s.loteExiste, queryLoteExiste},
+	}
+	if semBusca == nil {
+		consultas = append(consultas, struct {
+			destino **sql.Stmt
+			query   string
+		}{&s.search, querySearch})
+	}
+	for filtros := range s.iterate {
+		consultas = append(consultas, struct {
+			destino **sql.Stmt
+			query   string
+		}{&s.iterate[filtros], queryIterate(filtros)})
+	}
+	for campos := 1; campos < totalPatches; campos++ {
+		consultas = append(consultas, struct {
+			destino **sql.Stmt
+			query   string
+		}{&s.patch[campos], queryPatch(campos)})
+	}
+
+	defer func() {
+		if err != nil {
+			s.close()
+		}
+	}()
+	for _, c := range consultas {
+		stmt, err := db.PrepareContext(ctx, c.query)
+		if err != nil {
+			return nil, fmt.Errorf("preparando %q: %w", strings.Join(strings.Fields(c.query), " "), err)
+		}
+		*c.destino = stmt
+	}
+	return s, nil
+}
+
+// close fecha todas as instruções preparadas e devolve os erros encontrados.
+func (s *statements) close() error {
+	todos := []*sql.Stmt{
+		s.create, s.getByID, s.update, s.delete,
+		s.search,
+		s.getByEmail, s.markEmailVerified,
+		s.getPasswordHash, s.updatePassword,
+		s.updatePapel,
+		s.loteUpdate, s.loteDelete, s.loteExiste,
+	}
+	todos = append(todos, s.iterate[:]...)
+	todos = append(todos, s.patch[:]...)
+
+	var erros []error
+	for _, stmt := range todos {
+		if stmt != nil {
+			erros = append(erros, stmt.Close())
+		}
+	}
+	return errors.Join(erros...)
+}
+
+// preparadas guarda as instruções fixas dos demais repositórios, indexadas pela própria query.
+// Como em statements, elas são preparadas uma única vez, na construção do repositório.
+type preparadas map[string]*sql.Stmt
+
+// preparar prepara as queries informadas. Se alguma falhar, fecha as já preparadas.
+func preparar(db *sql.DB, queries ...string) (_ preparadas, err error) {
+	p := make(preparadas, len(queries))
+	defer func() {
+		if err != nil {
+			p.close()
+		}
+	}()
+	for _, query := range queries {
+		stmt, err := db.PrepareContext(context.Background(), query)
+		if err != nil {
+			return nil, fmt.Errorf("preparando %q: %w", strings.Join(strings.Fields(query), " "), err)
+		}
+		p[query] = stmt
+	}
+	return p, nil
+}
+
+// close fecha todas as instruções preparadas e devolve os erros encontrados.
+func (p preparadas) close() error {
+	var erros []error
+	for _, stmt := range p {
+		erros = append(erros, stmt.Close())
+	}
+	return errors.Join(erros...)
+}
+
+// scanner é satisfeito por *sql.Row e *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUsuario lê uma linha com as colunas de colunasUsuario.
+func scanUsuario(s scanner) (domain.Usuario, error) {
+	var u domain.Usuario
+	// Usamos tipos Null* para lidar com possíveis valores NULL do banco.
+	var createdAt, updatedAt, emailVerifiedAt sql.NullTime
+
+	if err := s.Scan(
+		&u.ID, &u.Nome, &u.Email,
+		&u.Version, &createdAt, &updatedAt, &emailVerifiedAt, &u.Papel,
+	); err != nil {
+		return domain.Usuario{}, err
+	}
+
+	// Atribuímos os valores para a struct, tratando os casos nulos.
+	u.CreatedAt = createdAt.Time
+	u.UpdatedAt = updatedAt.Time
+	if emailVerifiedAt.Valid {
+		u.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	return u, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// Os benchmarks comparam as instruções preparadas na construção do repositório com o
+// comportamento anterior, em que cada chamada fazia PrepareContext e stmt.Close.
+//
+//	go test -tags sqlite_fts5 -bench . -benchmem ./internal/repository/
+
+// getByIDSemCache reproduz o GetByID que preparava a instrução a cada chamada.
+func getByIDSemCache(ctx context.Context, db *sql.DB, id int64) (domain.Usuario, error) {
+	stmt, err := db.PrepareContext(ctx, queryGetByID)
+	if err != nil {
+		return domain.Usuario{}, err
+	}
+	defer stmt.Close()
+	return scanUsuario(stmt.QueryRowContext(ctx, id))
+}
+
+// createSemCache reproduz o Create que preparava a instrução a cada chamada.
+func createSemCache(ctx context.Context, db *sql.DB, u domain.Usuario) error {
+	stmt, err := db.PrepareContext(ctx, queryCreate)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	agora := now()
+	_, err = stmt.ExecContext(ctx, u.Nome, u.Email, agora, agora)
+	return err
+}
+
+func novoRepositorioBenchmark(b *testing.B) (*sql.DB, UsuarioRepository) {
+	db := novoBanco(b)
+	repo, err := NewSQLiteRepository(db)
+	require.NoError(b, err)
+	b.Cleanup(func() { repo.Close() })
+
+	ctx := context.Background()
+	for i := 0; i < 1000; i++ {
+		_, err := repo.Create(ctx, domain.Usuario{Nome: "Usuário Benchmark", Email: fmt.Sprintf("bench%d@email.com", i)})
+		require.NoError(b, err)
+	}
+	return db, repo
+}
+
+func BenchmarkGetByID(b *testing.B) {
+	ctx := context.Background()
+	db, repo := novoRepositorioBenchmark(b)
+
+	b.Run("preparado", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; b.Loop(); i++ {
+			if _, err := repo.GetByID(ctx, int64(i%1000+1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("sem_cache", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; b.Loop(); i++ {
+			if _, err := getByIDSemCache(ctx, db, int64(i%1000+1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCreate(b *testing.B) {
+	ctx := context.Background()
+	db, repo := novoRepositorioBenchmark(b)
+	u := domain.Usuario{Nome: "Usuário Benchmark", Email: "bench@email.com"}
+
+	b.Run("preparado", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := repo.Create(ctx, u); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("sem_cache", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := createSemCache(ctx, db, u); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
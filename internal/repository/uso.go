@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// ErrEventosJaAgregados indica que algum dos eventos já foi agregado em outro relatório (por
+// exemplo, por outra instância reportando ao mesmo tempo).
+var ErrEventosJaAgregados = errors.New("eventos de uso já agregados")
+
+// UsoRepository define a persistência do consumo medido e dos relatórios enviados ao provedor
+// de cobrança.
+type UsoRepository interface {
+	// Registrar grava o evento. Um evento com a chave de outro já gravado é ignorado.
+	Registrar(ctx context.Context, evento domain.EventoUso) error
+	// Consumo soma os eventos do usuário em [inicio, fim) por métrica, com a parte já reportada.
+	Consumo(ctx context.Context, usuarioID int64, inicio, fim time.Time) ([]domain.ConsumoMetrica, error)
+	// OrganizacaoCobrada devolve a organização que paga o consumo do usuário: entre as de
+	// assinatura ativa de que ele é membro, a que ele é dono ou, senão, a mais antiga para ele.
+	// Devolve nil se não houver nenhuma.
+	OrganizacaoCobrada(ctx context.Context, usuarioID int64) (*domain.Organizacao, error)
+
+	// EventosPendentes devolve até limite eventos ainda não agregados, ocorridos a partir de
+	// desde, com a organização cobrada de cada um. Eventos de usuários sem organização cobrada
+	// ficam de fora.
+	EventosPendentes(ctx context.Context, desde time.Time, limite int) ([]domain.EventoUso, error)
+	// CriarRelatorios grava os relatórios e marca os eventos de cada um como agregados, em uma
+	// única transação. Devolve ErrEventosJaAgregados se algum evento já estava em outro relatório.
+	CriarRelatorios(ctx context.Context, relatorios []domain.RelatorioUso) error
+	// RelatoriosPendentes devolve até limite relatórios ainda não aceitos pelo provedor que
+	// falharam menos de maxTentativas vezes, dos que menos falharam aos que mais falharam.
+	RelatoriosPendentes(ctx context.Context, maxTentativas, limite int) ([]domain.RelatorioUso, error)
+	// MarcarReportados registra que o provedor aceitou os relatórios.
+	MarcarReportados(ctx context.Context, ids []int64) error
+	// MarcarFalha conta mais uma tentativa dos relatórios e guarda o erro.
+	MarcarFalha(ctx context.Context, ids []int64, erro string) error
+	// Close libera as instruções preparadas. Deve ser chamado antes de fechar o *sql.DB.
+	Close() error
+}
+
+const (
+	// Organizações de assinatura ativa do usuário, da preferida para a cobrança à menos
+	// preferida; completada com o usuário ("?" ou uma coluna) e a ordem.
+	fromCobranca  = " FROM memberships m JOIN organizations o ON o.id = m.organizacao_id WHERE o.subscription_status IN ('active', 'trialing') AND o.stripe_customer_id IS NOT NULL AND m.usuario_id = "
+	ordemCobranca = " ORDER BY m.papel = '" + domain.PapelOrgDono + "' DESC, m.created_at, m.organizacao_id LIMIT 1"
+
+	colunasRelatorio = "r.id, r.organizacao_id, COALESCE(o.stripe_customer_id, ''), r.metrica, r.quantidade, r.periodo_inicio, r.momento, r.identificador, r.tentativas"
+
+	queryUsoRegistrar    = "INSERT INTO usage_events(usuario_id, metrica, quantidade, chave, ocorrido_em) VALUES(?, ?, ?, NULLIF(?, ''), ?) ON CONFLICT(chave) DO NOTHING"
+	queryUsoConsumo      = "SELECT e.metrica, SUM(e.quantidade), COALESCE(SUM(CASE WHEN r.reported_at IS NOT NULL THEN e.quantidade END), 0) FROM usage_events e LEFT JOIN usage_reports r ON r.id = e.report_id WHERE e.usuario_id = ? AND e.ocorrido_em >= ? AND e.ocorrido_em < ? GROUP BY e.metrica ORDER BY e.metrica"
+	queryUsoOrgCobrada   = "SELECT " + colunasOrganizacao + ", m.papel" + fromCobranca + "?" + ordemCobranca
+	queryUsoPendentes    = "SELECT id, usuario_id, organizacao_id, metrica, quantidade, ocorrido_em FROM (SELECT e.id, e.usuario_id, (SELECT m.organizacao_id" + fromCobranca + "e.usuario_id" + ordemCobranca + ") AS organizacao_id, e.metrica, e.quantidade, e.ocorrido_em FROM usage_events e WHERE e.report_id IS NULL AND e.ocorrido_em >= ?) WHERE organizacao_id IS NOT NULL ORDER BY id LIMIT ?"
+	queryUsoAgregar      = "UPDATE usage_events SET report_id = ? WHERE id = ? AND report_id IS NULL"
+	queryRelatorioCriar  = "INSERT INTO usage_reports(organizacao_id, metrica, quantidade, periodo_inicio, momento, identificador, created_at) VALUES(?, ?, ?, ?, ?, ?, ?)"
+	queryRelatorios      = "SELECT " + colunasRelatorio + " FROM usage_reports r JOIN organizations o ON o.id = r.organizacao_id WHERE r.reported_at IS NULL AND r.tentativas < ? ORDER BY r.tentativas, r.id LIMIT ?"
+	queryRelatorioAceito = "UPDATE usage_reports SET reported_at = ?, ultimo_erro = NULL WHERE id = ? AND reported_at IS NULL"
+	queryRelatorioFalha  = "UPDATE usage_reports SET tentativas = tentativas + 1, ultimo_erro = ? WHERE id = ? AND reported_at IS NULL"
+)
+
+type usoSQLite struct {
+	db    *sql.DB
+	stmts preparadas
+}
+
+// NewUsoRepository cria o repositório de consumo sobre o banco da aplicação.
+func NewUsoRepository(db *sql.DB) (UsoRepository, error) {
+	stmts, err := preparar(db,
+		queryUsoRegistrar, queryUsoConsumo, queryUsoOrgCobrada, queryUsoPendentes, queryUsoAgregar,
+		queryRelatorioCriar, queryRelatorios, queryRelatorioAceito, queryRelatorioFalha)
+	if err != nil {
+		return nil, err
+	}
+	return &usoSQLite{db: db, stmts: stmts}, nil
+}
+
+func (r *usoSQLite) Close() error {
+	return r.stmts.close()
+}
+
+func (r *usoSQLite) Registrar(ctx context.Context, evento domain.EventoUso) (err error) {
+	ctx, end := instrument(ctx, "Uso.Registrar", queryUsoRegistrar)
+	defer func() { end(err) }()
+
+	_, err = r.stmts[queryUsoRegistrar].ExecContext(ctx, evento.UsuarioID, evento.Metrica, evento.Quantidade, evento.Chave, evento.OcorridoEm.UTC())
+	return err
+}
+
+func (r *usoSQLite) Consumo(ctx context.Context, usuarioID int64, inicio, fim time.Time) (_ []domain.ConsumoMetrica, err error) {
+	ctx, end := instrument(ctx, "Uso.Consumo", queryUsoConsumo)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryUsoConsumo].QueryContext(ctx, usuarioID, inicio.UTC(), fim.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consumo := []domain.ConsumoMetrica{}
+	for rows.Next() {
+		var c domain.ConsumoMetrica
+		if err := rows.Scan(&c.Metrica, &c.Quantidade, &c.Reportado); err != nil {
+			return nil, err
+		}
+		consumo = append(consumo, c)
+	}
+	return consumo, rows.Err()
+}
+
+func (r *usoSQLite) OrganizacaoCobrada(ctx context.Context, usuarioID int64) (_ *domain.Organizacao, err error) {
+	ctx, end := instrument(ctx, "Uso.OrganizacaoCobrada", queryUsoOrgCobrada)
+	defer func() { end(err) }()
+
+	org, err := scanOrganizacao(r.stmts[queryUsoOrgCobrada].QueryRowContext(ctx, now(), usuarioID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return org, err
+}
+
+func (r *usoSQLite) EventosPendentes(ctx context.Context, desde time.Time, limite int) (_ []domain.EventoUso, err error) {
+	ctx, end := instrument(ctx, "Uso.EventosPendentes", queryUsoPendentes)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryUsoPendentes].QueryContext(ctx, desde.UTC(), limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var eventos []domain.EventoUso
+	for rows.Next() {
+		var e domain.EventoUso
+		if err := rows.Scan(&e.ID, &e.UsuarioID, &e.OrganizacaoID, &e.Metrica, &e.Quantidade, &e.OcorridoEm); err != nil {
+			return nil, err
+		}
+		eventos = append(eventos, e)
+	}
+	return eventos, rows.Err()
+}
+
+func (r *usoSQLite) CriarRelatorios(ctx context.Context, relatorios []domain.RelatorioUso) (err error) {
+	ctx, end := instrument(ctx, "Uso.CriarRelatorios", queryRelatorioCriar)
+	defer func() { end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	agora := now()
+	for _, rel := range relatorios {
+		res, err := tx.StmtContext(ctx, r.stmts[queryRelatorioCriar]).ExecContext(ctx, rel.OrganizacaoID, rel.Metrica, rel.Quantidade,
+			rel.PeriodoInicio.UTC(), rel.Momento.UTC(), rel.Identificador, agora)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, eventoID := range rel.EventoIDs {
+			res, err := tx.StmtContext(ctx, r.stmts[queryUsoAgregar]).ExecContext(ctx, id, eventoID)
+			if err != nil {
+				return err
+			}
+			if err := checarAfetadas(res); errors.Is(err, ErrNaoEncontrado) {
+				return ErrEventosJaAgregados
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *usoSQLite) RelatoriosPendentes(ctx context.Context, maxTentativas, limite int) (_ []domain.RelatorioUso, err error) {
+	ctx, end := instrument(ctx, "Uso.RelatoriosPendentes", queryRelatorios)
+	defer func() { end(err) }()
+
+	rows, err := r.stmts[queryRelatorios].QueryContext(ctx, maxTentativas, limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relatorios []domain.RelatorioUso
+	for rows.Next() {
+		var rel domain.RelatorioUso
+		err := rows.Scan(&rel.ID, &rel.OrganizacaoID, &rel.StripeCustomerID, &rel.Metrica, &rel.Quantidade,
+			&rel.PeriodoInicio, &rel.Momento, &rel.Identificador, &rel.Tentativas)
+		if err != nil {
+			return nil, err
+		}
+		relatorios = append(relatorios, rel)
+	}
+	return relatorios, rows.Err()
+}
+
+func (r *usoSQLite) MarcarReportados(ctx context.Context, ids []int64) (err error) {
+	ctx, end := instrument(ctx, "Uso.MarcarReportados", queryRelatorioAceito)
+	defer func() { end(err) }()
+
+	return r.atualizarRelatorios(ctx, ids, queryRelatorioAceito, now())
+}
+
+func (r *usoSQLite) MarcarFalha(ctx context.Context, ids []int64, erro string) (err error) {
+	ctx, end := instrument(ctx, "Uso.MarcarFalha", queryRelatorioFalha)
+	defer func() { end(err) }()
+
+	return r.atualizarRelatorios(ctx, ids, queryRelatorioFalha, erro)
+}
+
+// atualizarRelatorios executa query (com o valor e o ID do relatório) para cada relatório, em
+// uma única transação.
+func (r *usoSQLite) atualizarRelatorios(ctx context.Context, ids []int64, query string, valor any) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Sem efeito depois do Commit.
+
+	for _, id := range ids {
+		if _, err := tx.StmtContext(ctx, r.stmts[query]).ExecContext(ctx, valor, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestUsoRepository(t *testing.T) {
+	ctx := context.Background()
+	db := novoBanco(t)
+	usuarios, err := NewSQLiteRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { usuarios.Close() })
+	orgs, err := NewOrganizacaoRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { orgs.Close() })
+	repo, err := NewUsoRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	ana, err := usuarios.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+	bia, err := usuarios.Create(ctx, domain.Usuario{Nome: "Bia", Email: "bia@email.com"})
+	require.NoError(t, err)
+
+	// Ana é dona de Acme (assinatura ativa) e Bia só existe, sem organização cobrada.
+	acme, err := orgs.Criar(ctx, domain.Organizacao{Nome: "Acme"}, ana)
+	require.NoError(t, err)
+	require.NoError(t, orgs.AtualizarAssinatura(ctx, acme, domain.Organizacao{StripeCustomerID: "cus_acme", SubscriptionStatus: "active", Assentos: 5}))
+	inativa, err := orgs.Criar(ctx, domain.Organizacao{Nome: "Inativa"}, ana)
+	require.NoError(t, err)
+
+	agora := time.Now().UTC().Truncate(time.Second)
+	evento := func(usuarioID int64, chave string, quantidade int64, quando time.Time) domain.EventoUso {
+		return domain.EventoUso{UsuarioID: usuarioID, Metrica: domain.MetricaChamadasAPI, Quantidade: quantidade, Chave: chave, OcorridoEm: quando}
+	}
+
+	t.Run("sucesso - registra sem duplicar a chave e soma o consumo do período", func(t *testing.T) {
+		require.NoError(t, repo.Registrar(ctx, evento(ana, "req-1", 1, agora.Add(-time.Hour))))
+		require.NoError(t, repo.Registrar(ctx, evento(ana, "req-1", 1, agora.Add(-time.Hour))))
+		require.NoError(t, repo.Registrar(ctx, evento(ana, "req-2", 2, agora.Add(-time.Minute))))
+		require.NoError(t, repo.Registrar(ctx, evento(ana, "req-3", 4, agora.AddDate(0, -2, 0))))
+		require.NoError(t, repo.Registrar(ctx, evento(bia, "req-4", 1, agora)))
+
+		consumo, err := repo.Consumo(ctx, ana, agora.Add(-24*time.Hour), agora.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ConsumoMetrica{{Metrica: domain.MetricaChamadasAPI, Quantidade: 3}}, consumo)
+
+		consumo, err = repo.Consumo(ctx, ana, agora.Add(time.Hour), agora.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, consumo)
+	})
+
+	t.Run("sucesso - a organização cobrada é a de assinatura ativa", func(t *testing.T) {
+		org, err := repo.OrganizacaoCobrada(ctx, ana)
+		require.NoError(t, err)
+		require.NotNil(t, org)
+		assert.Equal(t, acme, org.ID)
+		assert.NotEqual(t, inativa, org.ID)
+		assert.Equal(t, "cus_acme", org.StripeCustomerID)
+
+		org, err = repo.OrganizacaoCobrada(ctx, bia)
+		require.NoError(t, err)
+		assert.Nil(t, org)
+	})
+
+	t.Run("sucesso - agrega os pendentes e acompanha o envio dos relatórios", func(t *testing.T) {
+		pendentes, err := repo.EventosPendentes(ctx, agora.AddDate(0, 0, -35), 100)
+		require.NoError(t, err)
+		require.Len(t, pendentes, 2, "o evento antigo e o da Bia, sem organização cobrada, ficam de fora")
+		assert.Equal(t, acme, pendentes[0].OrganizacaoID)
+
+		rel := domain.RelatorioUso{OrganizacaoID: acme, Metrica: domain.MetricaChamadasAPI, Quantidade: 3, PeriodoInicio: agora.AddDate(0, -1, 0),
+			Momento: agora, Identificador: "uso_1", EventoIDs: []int64{pendentes[0].ID, pendentes[1].ID}}
+		require.NoError(t, repo.CriarRelatorios(ctx, []domain.RelatorioUso{rel}))
+		rel.Identificador = "uso_2"
+		assert.ErrorIs(t, repo.CriarRelatorios(ctx, []domain.RelatorioUso{rel}), ErrEventosJaAgregados)
+
+		pendentes, err = repo.EventosPendentes(ctx, agora.AddDate(0, 0, -35), 100)
+		require.NoError(t, err)
+		assert.Empty(t, pendentes)
+
+		relatorios, err := repo.RelatoriosPendentes(ctx, 3, 10)
+		require.NoError(t, err)
+		require.Len(t, relatorios, 1, "o relatório recusado pela transação não fica gravado")
+		assert.Equal(t, "cus_acme", relatorios[0].StripeCustomerID)
+		assert.Equal(t, int64(3), relatorios[0].Quantidade)
+
+		ids := []int64{relatorios[0].ID}
+		require.NoError(t, repo.MarcarFalha(ctx, ids, "stripe indisponível"))
+		relatorios, err = repo.RelatoriosPendentes(ctx, 3, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, relatorios[0].Tentativas)
+		relatorios, err = repo.RelatoriosPendentes(ctx, 1, 10)
+		require.NoError(t, err)
+		assert.Empty(t, relatorios, "relatórios que falharam demais não são mais tentados")
+
+		require.NoError(t, repo.MarcarReportados(ctx, ids))
+		relatorios, err = repo.RelatoriosPendentes(ctx, 3, 10)
+		require.NoError(t, err)
+		assert.Empty(t, relatorios)
+
+		consumo, err := repo.Consumo(ctx, ana, agora.Add(-24*time.Hour), agora.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ConsumoMetrica{{Metrica: domain.MetricaChamadasAPI, Quantidade: 3, Reportado: 3}}, consumo)
+	})
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestSQLiteRepository_VerificacaoESenha(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLiteRepository(novoBanco(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	id, err := repo.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+
+	t.Run("sucesso - marca o e-mail como verificado e a troca de e-mail desfaz a marca", func(t *testing.T) {
+		require.NoError(t, repo.MarkEmailVerified(ctx, id, "ana@email.com"))
+		usuario, err := repo.GetByEmail(ctx, "ana@email.com")
+		require.NoError(t, err)
+		require.NotNil(t, usuario.EmailVerifiedAt)
+
+		email := "ana.nova@email.com"
+		require.NoError(t, repo.Patch(ctx, id, domain.UsuarioPatch{Email: &email}))
+		usuario, err = repo.GetByID(ctx, id)
+
+		require.NoError(t, err)
+		assert.Nil(t, usuario.EmailVerifiedAt)
+		assert.ErrorIs(t, repo.MarkEmailVerified(ctx, id, "ana@email.com"), ErrNaoEncontrado, "o e-mail antigo não pode ser confirmado")
+	})
+
+	t.Run("sucesso - grava e lê o hash da senha", func(t *testing.T) {
+		hash, err := repo.GetPasswordHash(ctx, id)
+		require.NoError(t, err)
+		assert.Empty(t, hash)
+
+		require.NoError(t, repo.UpdatePassword(ctx, id, "pbkdf2-sha256$1$sal$hash"))
+		hash, err = repo.GetPasswordHash(ctx, id)
+
+		require.NoError(t, err)
+		assert.Equal(t, "pbkdf2-sha256$1$sal$hash", hash)
+	})
+
+	t.Run("erro - usuário inexistente", func(t *testing.T) {
+		_, err := repo.GetPasswordHash(ctx, 999)
+		assert.ErrorIs(t, err, ErrNaoEncontrado)
+		assert.ErrorIs(t, repo.UpdatePassword(ctx, 999, "x"), ErrNaoEncontrado)
+
+		usuario, err := repo.GetByEmail(ctx, "ninguem@email.com")
+		assert.NoError(t, err)
+		assert.Nil(t, usuario)
+	})
+}
+
+func TestSQLiteRepository_EmailUnico(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLiteRepository(novoBanco(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	ana, err := repo.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+	require.NoError(t, err)
+	bia, err := repo.Create(ctx, domain.Usuario{Nome: "Bia", Email: "bia@email.com"})
+	require.NoError(t, err)
+
+	t.Run("sucesso - busca pelo e-mail sem diferenciar maiúsculas", func(t *testing.T) {
+		usuario, err := repo.GetByEmail(ctx, "Ana@Email.COM")
+		require.NoError(t, err)
+		require.NotNil(t, usuario)
+		assert.Equal(t, ana, usuario.ID)
+	})
+
+	t.Run("erro - e-mail de outro usuário, com ou sem maiúsculas", func(t *testing.T) {
+		_, err := repo.Create(ctx, domain.Usuario{Nome: "Outra Ana", Email: "ANA@email.com"})
+		assert.ErrorIs(t, err, ErrEmailEmUso)
+
+		_, err = repo.CreateMany(ctx, []domain.Usuario{{Nome: "Caio", Email: "caio@email.com"}, {Nome: "Bia", Email: "bia@email.com"}})
+		assert.ErrorIs(t, err, ErrEmailEmUso)
+		caio, err := repo.GetByEmail(ctx, "caio@email.com")
+		require.NoError(t, err)
+		assert.Nil(t, caio, "o lote inteiro é desfeito")
+
+		assert.ErrorIs(t, repo.Update(ctx, bia, domain.Usuario{Nome: "Bia", Email: "ana@email.com"}), ErrEmailEmUso)
+		email := "Ana@email.com"
+		assert.ErrorIs(t, repo.Patch(ctx, bia, domain.UsuarioPatch{Email: &email}), ErrEmailEmUso)
+
+		err = repo.ExecutarLote(ctx, func(lote Lote) error {
+			_, err := lote.Create(ctx, domain.Usuario{Nome: "Ana", Email: "ana@email.com"})
+			return err
+		})
+		assert.ErrorIs(t, err, ErrEmailEmUso)
+	})
+}
+
+func TestMigration_EmailUnico(t *testing.T) {
+	migration, err := os.ReadFile("../../migrations/000016_add_unique_email.up.sql")
+	require.NoError(t, err)
+
+	t.Run("sucesso - passa os e-mails para minúsculas sem desfazer a verificação nem sincronizar a Stripe", func(t *testing.T) {
+		db := novoBancoAntesDe(t, "000016")
+		_, err := db.Exec(`INSERT INTO usuarios(id, nome, email, email_verified_at) VALUES
+			(1, 'Ana', ' Ana@Email.com', NULL),
+			(2, 'Bia', 'Bia@Email.com', '2026-01-01 00:00:00+00:00'),
+			(3, 'Caio', 'caio@email.com', NULL);
+			INSERT INTO organizations(id, nome, stripe_customer_id, created_at) VALUES (1, 'Acme', 'cus_1', '2026-01-01 00:00:00+00:00');
+			INSERT INTO memberships(organizacao_id, usuario_id, papel, created_at) VALUES (1, 2, 'dono', '2026-01-01 00:00:00+00:00')`)
+		require.NoError(t, err)
+
+		_, err = db.Exec(string(migration))
+		require.NoError(t, err)
+
+		rows, err := db.Query("SELECT id, email, email_verified_at IS NOT NULL FROM usuarios ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+		type linha struct {
+			id         int64
+			email      string
+			verificado bool
+		}
+		var linhas []linha
+		for rows.Next() {
+			var l linha
+			require.NoError(t, rows.Scan(&l.id, &l.email, &l.verificado))
+			linhas = append(linhas, l)
+		}
+		require.NoError(t, rows.Err())
+
+		assert.Equal(t, []linha{
+			{1, "ana@email.com", false},
+			{2, "bia@email.com", true}, // Passar para minúsculas mantém a verificação.
+			{3, "caio@email.com", false},
+		}, linhas)
+		var pendentes int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM customer_sync_outbox").Scan(&pendentes))
+		assert.Zero(t, pendentes, "o e-mail da dona não mudou de fato")
+
+		_, err = db.Exec("INSERT INTO usuarios(nome, email) VALUES('Caio', 'CAIO@email.com')")
+		assert.Error(t, err, "o índice único ignora maiúsculas")
+		_, err = db.Exec("UPDATE usuarios SET nome = 'Bia Souza' WHERE id = 2")
+		require.NoError(t, err)
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM customer_sync_outbox").Scan(&pendentes))
+		assert.Equal(t, 1, pendentes, "o trigger do outbox foi recriado")
+	})
+
+	t.Run("erro - e-mails que só diferem nas maiúsculas abortam a migração sem remover ninguém", func(t *testing.T) {
+		db := novoBancoAntesDe(t, "000016")
+		_, err := db.Exec(`INSERT INTO usuarios(id, nome, email) VALUES
+			(1, 'Ana', 'ana@email.com'),
+			(2, 'Ana', ' ANA@email.com'),
+			(3, 'Bia', 'bia@email.com')`)
+		require.NoError(t, err)
+
+		_, err = db.Exec(string(migration))
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mesmo e-mail ignorando maiúsculas")
+		var total int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM usuarios").Scan(&total))
+		assert.Equal(t, 3, total)
+		var email string
+		require.NoError(t, db.QueryRow("SELECT email FROM usuarios WHERE id = 2").Scan(&email))
+		assert.Equal(t, " ANA@email.com", email)
+	})
+}
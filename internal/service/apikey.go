@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+	"github.com/willjrcristo/go-sqlite-db/internal/validation"
+)
+
+// Erros das API keys.
+var (
+	ErrAPIKeyInvalida       = &domain.Error{Code: "api_key_invalida", Status: http.StatusUnauthorized, Title: "API key inválida ou revogada"}
+	ErrAPIKeyNaoEncontrada  = &domain.Error{Code: "api_key_nao_encontrada", Status: http.StatusNotFound, Title: "API key não encontrada"}
+	ErrDadosAPIKeyInvalidos = &domain.Error{Code: "dados_api_key_invalidos", Status: http.StatusBadRequest, Title: "dados da API key inválidos"}
+)
+
+// Formato das chaves: "usk_" + identificador + "_" + segredo, ex: usk_k3j5q2ab_7x...
+// O prefixo ("usk_" + identificador) é guardado em claro para localizar a chave; o segredo,
+// com 160 bits aleatórios, só existe na resposta da criação.
+const (
+	apiKeyPrefixo      = "usk_"
+	apiKeyIDBytes      = 5  // 8 caracteres em base32.
+	apiKeySegredoBytes = 20 // 32 caracteres em base32.
+	apiKeyNomeMaxLen   = 100
+	apiKeyIntervaloUso = time.Minute // last_used_at é atualizado no máximo uma vez por intervalo.
+)
+
+var base32Chave = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// APIKeyService cria, lista, revoga e autentica API keys.
+type APIKeyService struct {
+	repo  repository.APIKeyRepository
+	agora func() time.Time
+}
+
+// NewAPIKeyService cria uma nova instância do APIKeyService.
+func NewAPIKeyService(repo repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo, agora: time.Now}
+}
+
+// CreateAPIKey gera uma nova chave com os escopos informados. A chave completa é devolvida
+// apenas aqui: depois disso, só o hash fica guardado.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, nome string, escopos []string) (_ string, _ *domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	nome = strings.Join(strings.Fields(nome), " ")
+	erros := validation.Validate(validation.F("nome", nome, validation.Required(), validation.MaxLength(apiKeyNomeMaxLen)))
+	if len(escopos) == 0 {
+		erros = append(erros, domain.FieldError{Field: "escopos", Code: "obrigatorio", Message: "informe ao menos um escopo"})
+	}
+	for i, escopo := range escopos {
+		if !slices.Contains(domain.Escopos, escopo) {
+			erros = append(erros, domain.FieldError{
+				Field:   fmt.Sprintf("escopos[%d]", i),
+				Code:    "escopo_desconhecido",
+				Message: "use um de: " + strings.Join(domain.Escopos, ", "),
+			})
+		}
+	}
+	if len(erros) > 0 {
+		return "", nil, ErrDadosAPIKeyInvalidos.WithFields(erros...)
+	}
+
+	id, segredo := make([]byte, apiKeyIDBytes), make([]byte, apiKeySegredoBytes)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(segredo); err != nil {
+		return "", nil, err
+	}
+	key := domain.APIKey{
+		Nome:      nome,
+		Prefixo:   apiKeyPrefixo + strings.ToLower(base32Chave.EncodeToString(id)),
+		Escopos:   slices.Compact(slices.Sorted(slices.Values(escopos))),
+		CreatedAt: s.agora().UTC(),
+	}
+	chave := key.Prefixo + "_" + strings.ToLower(base32Chave.EncodeToString(segredo))
+
+	key.ID, err = s.repo.Create(ctx, key, hashAPIKey(chave))
+	if err != nil {
+		return "", nil, err
+	}
+	return chave, &key, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) (_ []domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.ListAPIKeys")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	return s.repo.List(ctx)
+}
+
+// RevokeAPIKey revoga a chave imediatamente. Revogar uma chave já revogada não é erro.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id int64) (err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.RevokeAPIKey", trace.WithAttributes(attribute.Int64("api_key.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	err = s.repo.Revoke(ctx, id)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return ErrAPIKeyNaoEncontrada
+	}
+	return err
+}
+
+// AuthenticateAPIKey verifica a chave e devolve os seus dados. Chaves inexistentes,
+// malformadas ou revogadas resultam todas em ErrAPIKeyInvalida, sem distinção para o cliente.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, chave string) (_ *domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.AuthenticateAPIKey")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	prefixo, ok := prefixoAPIKey(chave)
+	if !ok {
+		return nil, ErrAPIKeyInvalida.WithDetail("formato de chave não reconhecido")
+	}
+	key, hash, err := s.repo.GetByPrefixo(ctx, prefixo)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(hash), []byte(hashAPIKey(chave))) != 1 {
+		return nil, ErrAPIKeyInvalida
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyInvalida.WithDetail("a chave foi revogada")
+	}
+
+	agora := s.agora()
+	if key.LastUsedAt == nil || agora.Sub(*key.LastUsedAt) >= apiKeyIntervaloUso {
+		// Falhar ao registrar o uso não deve impedir a requisição.
+		if err := s.repo.TouchLastUsed(ctx, key.ID, agora); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "Erro ao registrar o uso da API key", "api_key_id", key.ID, "error", err)
+		}
+	}
+	return key, nil
+}
+
+// prefixoAPIKey extrai o prefixo de uma chave no formato usk_<id>_<segredo>.
+func prefixoAPIKey(chave string) (string, bool) {
+	resto, ok := strings.CutPrefix(chave, apiKeyPrefixo)
+	if !ok {
+		return "", false
+	}
+	id, segredo, ok := strings.Cut(resto, "_")
+	if !ok || id == "" || segredo == "" {
+		return "", false
+	}
+	return apiKeyPrefixo + id, true
+}
+
+// hashAPIKey calcula o hash guardado no banco. Como o segredo tem alta entropia, um SHA-256
+// simples basta; não há o que ganhar com um hash lento como o de senhas.
+func hashAPIKey(chave string) string {
+	h := sha256.Sum256([]byte(chave))
+	return hex.EncodeToString(h[:])
+}
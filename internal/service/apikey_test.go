@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// apiKeyRepoFake guarda as chaves em memória, indexadas pelo prefixo.
+type apiKeyRepoFake struct {
+	repository.APIKeyRepository
+	keys   map[string]domain.APIKey
+	hashes map[string]string
+	usos   int
+}
+
+func novoAPIKeyRepoFake() *apiKeyRepoFake {
+	return &apiKeyRepoFake{keys: make(map[string]domain.APIKey), hashes: make(map[string]string)}
+}
+
+func (f *apiKeyRepoFake) Create(ctx context.Context, key domain.APIKey, hash string) (int64, error) {
+	key.ID = int64(len(f.keys) + 1)
+	f.keys[key.Prefixo], f.hashes[key.Prefixo] = key, hash
+	return key.ID, nil
+}
+
+func (f *apiKeyRepoFake) GetByPrefixo(ctx context.Context, prefixo string) (*domain.APIKey, string, error) {
+	key, ok := f.keys[prefixo]
+	if !ok {
+		return nil, "", nil
+	}
+	return &key, f.hashes[prefixo], nil
+}
+
+func (f *apiKeyRepoFake) TouchLastUsed(ctx context.Context, id int64, quando time.Time) error {
+	f.usos++
+	for prefixo, key := range f.keys {
+		if key.ID == id {
+			key.LastUsedAt = &quando
+			f.keys[prefixo] = key
+		}
+	}
+	return nil
+}
+
+func TestAPIKeyService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sucesso - cria a chave e a autentica, guardando apenas o hash", func(t *testing.T) {
+		repo := novoAPIKeyRepoFake()
+		s := NewAPIKeyService(repo)
+
+		chave, key, err := s.CreateAPIKey(ctx, " Job  noturno ", []string{domain.EscopoUsuariosLeitura, domain.EscopoBillingEscrita, domain.EscopoUsuariosLeitura})
+		require.NoError(t, err)
+
+		assert.Regexp(t, regexp.MustCompile(`^usk_[a-z2-7]{8}_[a-z2-7]{32}$`), chave)
+		assert.Equal(t, "Job noturno", key.Nome)
+		assert.Equal(t, chave[:12], key.Prefixo)
+		assert.Equal(t, []string{domain.EscopoBillingEscrita, domain.EscopoUsuariosLeitura}, key.Escopos)
+		assert.NotContains(t, repo.hashes[key.Prefixo], chave[13:])
+
+		autenticada, err := s.AuthenticateAPIKey(ctx, chave)
+		assert.NoError(t, err)
+		assert.Equal(t, key.ID, autenticada.ID)
+	})
+
+	t.Run("sucesso - registra o último uso no máximo uma vez por intervalo", func(t *testing.T) {
+		repo := novoAPIKeyRepoFake()
+		s := NewAPIKeyService(repo)
+		agora := time.Now()
+		s.agora = func() time.Time { return agora }
+		chave, _, _ := s.CreateAPIKey(ctx, "job", []string{domain.EscopoUsuariosLeitura})
+
+		s.AuthenticateAPIKey(ctx, chave)
+		s.AuthenticateAPIKey(ctx, chave)
+		assert.Equal(t, 1, repo.usos)
+
+		agora = agora.Add(apiKeyIntervaloUso)
+		s.AuthenticateAPIKey(ctx, chave)
+		assert.Equal(t, 2, repo.usos)
+	})
+
+	t.Run("erro - chave errada, malformada ou revogada", func(t *testing.T) {
+		repo := novoAPIKeyRepoFake()
+		s := NewAPIKeyService(repo)
+		chave, key, _ := s.CreateAPIKey(ctx, "job", []string{domain.EscopoUsuariosLeitura})
+
+		for _, invalida := range []string{chave[:len(chave)-1] + "x", "usk_naoexiste_abc", "Bearer xyz", key.Prefixo} {
+			_, err := s.AuthenticateAPIKey(ctx, invalida)
+			assert.ErrorIs(t, err, ErrAPIKeyInvalida, invalida)
+		}
+
+		revogada := repo.keys[key.Prefixo]
+		revogada.RevokedAt = &revogada.CreatedAt
+		repo.keys[key.Prefixo] = revogada
+		_, err := s.AuthenticateAPIKey(ctx, chave)
+		assert.ErrorIs(t, err, ErrAPIKeyInvalida)
+	})
+
+	t.Run("erro - nome vazio e escopo desconhecido", func(t *testing.T) {
+		s := NewAPIKeyService(novoAPIKeyRepoFake())
+
+		_, _, err := s.CreateAPIKey(ctx, " ", []string{"usuarios:admin"})
+
+		var de *domain.Error
+		require.True(t, errors.As(err, &de))
+		assert.Equal(t, ErrDadosAPIKeyInvalidos.Code, de.Code)
+		assert.Len(t, de.Fields, 2)
+		assert.Equal(t, "escopos[0]", de.Fields[1].Field)
+	})
+}
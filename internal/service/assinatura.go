@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/checkout/session"
+	"github.com/stripe/stripe-go/v78/customer"
+	"github.com/stripe/stripe-go/v78/subscription"
+	"github.com/stripe/stripe-go/v78/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// Erros da assinatura das organizações.
+var (
+	ErrAssinaturaJaAtiva  = &domain.Error{Code: "assinatura_ja_ativa", Status: http.StatusConflict, Title: "a organização já possui uma assinatura ativa"}
+	ErrAssinaturaInativa  = &domain.Error{Code: "assinatura_inativa", Status: http.StatusConflict, Title: "a organização não possui uma assinatura ativa"}
+	ErrAssentosEmUso      = &domain.Error{Code: "assentos_em_uso", Status: http.StatusConflict, Title: "a quantidade de assentos é menor que a de assentos em uso"}
+	ErrWebhookStripe      = &domain.Error{Code: "webhook_stripe_invalido", Status: http.StatusBadRequest, Title: "erro ao processar webhook da stripe"}
+	ErrProvedorPagamento  = &domain.Error{Code: "provedor_pagamento_indisponivel", Status: http.StatusBadGateway, Title: "falha na comunicação com o provedor de pagamento"}
+	ErrSemContatoCobranca = &domain.Error{Code: "sem_contato_cobranca", Status: http.StatusConflict, Title: "a organização não tem um dono para receber as cobranças"}
+)
+
+// CreateCheckoutSession cria uma sessão de pagamento na Stripe para a organização, com um
+// assento por membro e convite pendente (ou assentos, se for maior). O cliente da Stripe
+// leva o nome da organização e o e-mail do dono, que precisa estar verificado. O código
+// promocional, se informado, é validado antes (veja WithCupons) e já vem aplicado; sem ele,
+// o cliente pode digitar um na página da Stripe. O preço é o da moeda escolhida por
+// PlanoCheckout.Preco, e os impostos seguem o plano (veja WithPlano).
+func (s *OrganizacaoService) CreateCheckoutSession(ctx context.Context, sol domain.Solicitante, id int64, pedido domain.PedidoCheckout) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.CreateCheckoutSession", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	org, err := s.acessar(ctx, sol, id, papeisGerentes...)
+	if err != nil {
+		return "", err
+	}
+	if pedido.Assentos < 0 {
+		return "", ErrDadosOrganizacaoInvalidos.WithFields(domain.FieldError{Field: "assentos", Code: "invalido", Message: "não pode ser negativo"})
+	}
+	// Regra de negócio: não permitir criar uma nova sessão se a assinatura já estiver ativa.
+	if org.AssinaturaAtiva() {
+		return "", ErrAssinaturaJaAtiva.WithDetail("altere a quantidade de assentos em PUT /organizations/{id}/seats")
+	}
+	dono, err := s.contatoCobranca(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if dono.EmailVerifiedAt == nil {
+		return "", ErrEmailNaoVerificado.WithDetail("o dono da organização precisa confirmar o e-mail antes de assinar")
+	}
+	var cupom *domain.Cupom
+	if codigo := strings.TrimSpace(pedido.CodigoPromocional); codigo != "" {
+		if cupom, err = s.validarCupom(ctx, codigo); err != nil {
+			return "", err
+		}
+		span.SetAttributes(attribute.String("cupom.codigo", cupom.Codigo))
+	}
+	dadosDono, err := s.dadosCobrancaDono(ctx, dono)
+	if err != nil {
+		return "", err
+	}
+	var pais string
+	if dadosDono != nil {
+		pais = dadosDono.Endereco.Pais
+	}
+	moeda, preco := s.plano.Preco(org.Moeda, pais, pedido.Idioma)
+	span.SetAttributes(attribute.String("checkout.moeda", moeda))
+
+	stripeCustomerID := org.StripeCustomerID
+	// Se a organização ainda não for um cliente na Stripe, crie um.
+	if stripeCustomerID == "" {
+		params := &stripe.CustomerParams{
+			Name:  stripe.String(org.Nome),
+			Email: stripe.String(dono.Email),
+		}
+		if dadosDono != nil {
+			params.Address = enderecoStripe(dadosDono.Endereco)
+			if dadosDono.Documento != "" {
+				params.TaxIDData = []*stripe.CustomerTaxIDDataParams{{Type: stripe.String(dadosDono.TipoDocumento()), Value: stripe.String(dadosDono.Documento)}}
+			}
+		}
+		params.AddMetadata("organizacao_id", strconv.FormatInt(id, 10))
+		params.AddMetadata(billing.MetadadoContato, dono.Nome)
+		params.Context = ctx
+		start := time.Now()
+		c, err := customer.New(params)
+		metrics.ObservarStripe("customer.create", start, err)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "Falha ao criar cliente na Stripe", "error", err)
+			return "", ErrProvedorPagamento.Wrap(err)
+		}
+		stripeCustomerID = c.ID
+		// Salva o novo ID do cliente no nosso banco
+		org.StripeCustomerID = stripeCustomerID
+		if err := s.repo.AtualizarAssinatura(ctx, id, *org); err != nil {
+			return "", err
+		}
+	}
+
+	// IMPORTANTE: Substitua as URLs pelas suas; os preços vêm do plano (veja WithPlano).
+	params := &stripe.CheckoutSessionParams{
+		Customer:          stripe.String(stripeCustomerID),
+		ClientReferenceID: stripe.String(strconv.FormatInt(id, 10)),
+		Mode:              stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		SuccessURL:        stripe.String("http://localhost:3000/sucesso?session_id={CHECKOUT_SESSION_ID}"), // URL do seu frontend
+		CancelURL:         stripe.String("http://localhost:3000/cancelou"),                                 // URL do seu frontend
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price:    stripe.String(preco),
+				Quantity: stripe.Int64(max(pedido.Assentos, org.AssentosEmUso, 1)),
+			},
+		},
+	}
+	switch {
+	case s.plano.ImpostoAutomatico:
+		// O Stripe Tax calcula o imposto pelo endereço, que o cliente confirma (ou corrige) na
+		// página da Stripe; o tax ID informado lá também vai para o cliente.
+		params.AutomaticTax = &stripe.CheckoutSessionAutomaticTaxParams{Enabled: stripe.Bool(true)}
+		params.CustomerUpdate = &stripe.CheckoutSessionCustomerUpdateParams{Address: stripe.String("auto"), Name: stripe.String("auto")}
+		params.TaxIDCollection = &stripe.CheckoutSessionTaxIDCollectionParams{Enabled: stripe.Bool(true)}
+		params.BillingAddressCollection = stripe.String("required")
+	case len(s.plano.TaxasImposto) > 0:
+		params.LineItems[0].DynamicTaxRates = stripe.StringSlice(s.plano.TaxasImposto)
+		params.BillingAddressCollection = stripe.String("required")
+	}
+	if cupom != nil {
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{{PromotionCode: stripe.String(cupom.StripePromotionCodeID)}}
+	} else {
+		// A Stripe não aceita os dois juntos: o código digitado lá é validado por ela.
+		params.AllowPromotionCodes = stripe.Bool(true)
+	}
+	params.Context = ctx
+
+	start := time.Now()
+	sess, err := session.New(params)
+	metrics.ObservarStripe("checkout.session.create", start, err)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Falha ao criar a sessão de checkout na Stripe", "error", err)
+		return "", ErrProvedorPagamento.Wrap(err)
+	}
+	metrics.CheckoutSessionsCreated.Inc()
+
+	return sess.URL, nil
+}
+
+// UpdateSeats altera a quantidade de assentos da assinatura ativa na Stripe, com cobrança
+// proporcional. A quantidade não pode ficar abaixo dos assentos em uso.
+func (s *OrganizacaoService) UpdateSeats(ctx context.Context, sol domain.Solicitante, id, assentos int64) (_ *domain.Organizacao, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.UpdateSeats", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	org, err := s.acessar(ctx, sol, id, papeisGerentes...)
+	if err != nil {
+		return nil, err
+	}
+	if assentos < 1 {
+		return nil, ErrDadosOrganizacaoInvalidos.WithFields(domain.FieldError{Field: "assentos", Code: "invalido", Message: "deve ser pelo menos 1"})
+	}
+	if assentos < org.AssentosEmUso {
+		return nil, ErrAssentosEmUso.
+			WithDetail("remova membros ou revogue convites pendentes antes de reduzir os assentos").
+			WithDetails(map[string]any{"assentos_em_uso": org.AssentosEmUso})
+	}
+	if !org.AssinaturaAtiva() || org.StripeSubscriptionID == "" {
+		return nil, ErrAssinaturaInativa.WithDetail("assine pelo checkout da organização")
+	}
+
+	subParams := &stripe.SubscriptionParams{}
+	subParams.Context = ctx
+	start := time.Now()
+	sub, err := subscription.Get(org.StripeSubscriptionID, subParams)
+	metrics.ObservarStripe("subscription.get", start, err)
+	if err != nil {
+		return nil, ErrProvedorPagamento.Wrap(err)
+	}
+	if sub.Items == nil || len(sub.Items.Data) == 0 {
+		return nil, ErrProvedorPagamento.WithDetail("a assinatura não tem itens")
+	}
+	params := &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{ID: stripe.String(sub.Items.Data[0].ID), Quantity: stripe.Int64(assentos)},
+		},
+		ProrationBehavior: stripe.String("create_prorations"),
+	}
+	params.Context = ctx
+	start = time.Now()
+	_, err = subscription.Update(org.StripeSubscriptionID, params)
+	metrics.ObservarStripe("subscription.update", start, err)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Falha ao alterar os assentos na Stripe", "error", err)
+		return nil, ErrProvedorPagamento.Wrap(err)
+	}
+
+	// O webhook customer.subscription.updated confirma a quantidade depois.
+	org.Assentos = assentos
+	if err := s.repo.AtualizarAssinatura(ctx, id, *org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// HandleStripeWebhook processa os eventos recebidos da Stripe, atualizando a assinatura e os
+// assentos da organização dona do cliente e, em customer.updated, o contato dela (veja
+// WithSincronizacaoClientes).
+func (s *OrganizacaoService) HandleStripeWebhook(ctx context.Context, payload []byte, signature string) (err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.HandleStripeWebhook")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	// IMPORTANTE: Obtenha este segredo do Dashboard da Stripe (seção Webhooks)
+	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+
+	// 1. Verificar a assinatura do evento
+	event, err := webhook.ConstructEvent(payload, signature, webhookSecret)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Erro ao verificar a assinatura do webhook", "error", err)
+		metrics.StripeWebhookEvents.WithLabelValues("unknown", "invalid_signature").Inc()
+		return ErrWebhookStripe.Wrap(err)
+	}
+	span.SetAttributes(attribute.String("stripe.event_type", string(event.Type)))
+
+	outcome := "processed"
+	defer func() {
+		if err != nil {
+			outcome = "error"
+		}
+		metrics.StripeWebhookEvents.WithLabelValues(string(event.Type), outcome).Inc()
+	}()
+
+	// 2. Processar o evento com base no seu tipo
+	switch event.Type {
+	case "checkout.session.completed":
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+			return err
+		}
+
+		// Obtenha a assinatura completa para ter a data de expiração e a quantidade
+		subParams := &stripe.SubscriptionParams{}
+		subParams.Context = ctx
+		start := time.Now()
+		sub, err := subscription.Get(session.Subscription.ID, subParams)
+		metrics.ObservarStripe("subscription.get", start, err)
+		if err != nil {
+			return err
+		}
+		ignorado, err := s.sincronizarAssinatura(ctx, session.Customer.ID, sub)
+		if ignorado {
+			outcome = "ignored"
+		}
+		return err
+
+	case "customer.subscription.updated", "customer.subscription.deleted":
+		var sub stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
+			return err
+		}
+		ignorado, err := s.sincronizarAssinatura(ctx, sub.Customer.ID, &sub)
+		if ignorado {
+			outcome = "ignored"
+		}
+		return err
+
+	case "customer.updated":
+		if s.outbox == nil {
+			outcome = "ignored"
+			return nil
+		}
+		var c stripe.Customer
+		if err := json.Unmarshal(event.Data.Raw, &c); err != nil {
+			return err
+		}
+		ignorado, err := s.receberCliente(ctx, &c, time.Unix(event.Created, 0))
+		if ignorado {
+			outcome = "ignored"
+		}
+		return err
+
+	case "promotion_code.created", "promotion_code.updated", "coupon.deleted":
+		if s.cupons == nil {
+			outcome = "ignored"
+			return nil
+		}
+		return s.espelharCupom(ctx, event)
+
+	default:
+		logging.FromContext(ctx).InfoContext(ctx, "Webhook da Stripe recebido, mas não tratado", "event_type", event.Type)
+		outcome = "ignored"
+	}
+
+	return nil
+}
+
+// sincronizarAssinatura grava na organização do cliente o estado da assinatura e o código
+// promocional em vigor, contando um resgate quando ele muda. Clientes sem organização são
+// ignorados (ignorado = true).
+func (s *OrganizacaoService) sincronizarAssinatura(ctx context.Context, stripeCustomerID string, sub *stripe.Subscription) (ignorado bool, err error) {
+	org, err := s.repo.GetByStripeID(ctx, stripeCustomerID)
+	if err != nil || org == nil {
+		return org == nil && err == nil, err
+	}
+	fimPeriodo := time.Unix(sub.CurrentPeriodEnd, 0)
+	org.StripeSubscriptionID = sub.ID
+	org.SubscriptionStatus = string(sub.Status)
+	org.SubscriptionCurrentPeriodEnd = &fimPeriodo
+	if sub.Items != nil && len(sub.Items.Data) > 0 {
+		org.Assentos = sub.Items.Data[0].Quantity
+	}
+	// A moeda da assinatura fica fixa: a Stripe não deixa um cliente ter assinaturas em
+	// moedas diferentes.
+	org.Moeda = string(sub.Currency)
+	codigo, promoID, cupomID, err := s.codigoDoDesconto(ctx, sub)
+	if err != nil {
+		return false, err
+	}
+	resgatado := codigo != "" && codigo != org.Cupom
+	org.Cupom = codigo
+	if err := s.repo.AtualizarAssinatura(ctx, org.ID, *org); err != nil {
+		return false, err
+	}
+	if resgatado {
+		s.registrarResgate(ctx, codigo, promoID, cupomID)
+	}
+	return false, nil
+}
+
+// contatoCobranca devolve o dono da organização, que recebe as cobranças da Stripe.
+func (s *OrganizacaoService) contatoCobranca(ctx context.Context, id int64) (*domain.Usuario, error) {
+	membros, err := s.repo.ListMembros(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range membros {
+		if m.Papel != domain.PapelOrgDono {
+			continue
+		}
+		dono, err := s.usuarios.GetByID(ctx, m.UsuarioID)
+		if err != nil || dono != nil {
+			return dono, err
+		}
+	}
+	return nil, ErrSemContatoCobranca
+}
+
+// enderecoStripe converte o endereço de cobrança para os parâmetros da Stripe.
+func enderecoStripe(e domain.Endereco) *stripe.AddressParams {
+	return &stripe.AddressParams{
+		Line1:      stripe.String(e.Linha1),
+		Line2:      stripe.String(e.Linha2),
+		City:       stripe.String(e.Cidade),
+		State:      stripe.String(e.Estado),
+		PostalCode: stripe.String(e.CEP),
+		Country:    stripe.String(e.Pais),
+	}
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/mailer"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+	"github.com/willjrcristo/go-sqlite-db/internal/tokens"
+	"github.com/willjrcristo/go-sqlite-db/internal/validation"
+)
+
+// Erros dos tokens emitidos pelo AuthService. ErrTokenInvalido cobre os links enviados por
+// e-mail: tokens malformados, adulterados, expirados ou que já perderam o vínculo com o
+// usuário (e-mail ou senha alterados depois do envio).
+var (
+	ErrTokenInvalido       = &domain.Error{Code: "token_invalido", Status: http.StatusBadRequest, Title: "token inválido ou expirado"}
+	ErrTokenAcessoInvalido = &domain.Error{Code: "token_acesso_invalido", Status: http.StatusUnauthorized, Title: "token de acesso inválido ou expirado"}
+)
+
+// Finalidades e validade dos tokens enviados por e-mail e do token de acesso emitido no login.
+// O token de acesso tem uma finalidade própria quando o login passou pelo segundo fator.
+const (
+	finalidadeVerificarEmail = "verificar_email"
+	finalidadeRedefinirSenha = "redefinir_senha"
+	finalidadeAcesso         = "acesso"
+	finalidadeAcessoMFA      = "acesso_mfa"
+	validadeVerificacao      = 48 * time.Hour
+	validadeRedefinicao      = time.Hour
+	validadeAcesso           = time.Hour
+)
+
+// AuthService cuida do login, da verificação de e-mail, da redefinição de senha, do segundo
+// fator (veja mfa.go), das sessões (veja sessoes.go) e dos tokens de acesso. Os links
+// enviados apontam para o frontend em urlBase, que chama os endpoints de /auth com o token.
+type AuthService struct {
+	repo    repository.UsuarioRepository
+	mailer  mailer.Mailer
+	tokens  *tokens.Assinador
+	urlBase string
+
+	mfa          repository.MFARepository
+	emissorMFA   string
+	papeisComMFA []string
+	auditoria    repository.AuditoriaRepository
+	sessoes      repository.SessaoRepository
+	agora        func() time.Time
+}
+
+// NewAuthService cria uma nova instância do AuthService.
+func NewAuthService(repo repository.UsuarioRepository, m mailer.Mailer, t *tokens.Assinador, urlBase string) *AuthService {
+	return &AuthService{repo: repo, mailer: m, tokens: t, urlBase: strings.TrimRight(urlBase, "/"), agora: time.Now}
+}
+
+// SendVerification envia ao usuário o link de confirmação do e-mail atual.
+func (s *AuthService) SendVerification(ctx context.Context, usuario domain.Usuario) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.SendVerification", trace.WithAttributes(attribute.Int64("usuario.id", usuario.ID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	token, err := s.tokens.Gerar(finalidadeVerificarEmail, usuario.ID, tokens.Vinculo(usuario.Email), validadeVerificacao)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Enviar(ctx, mailer.Mensagem{
+		Para:    usuario.Email,
+		Assunto: "Confirme o seu e-mail",
+		Texto: fmt.Sprintf("Olá, %s!\n\nPara confirmar que este e-mail é seu, acesse o link abaixo em até %d horas:\n\n%s\n\nSe você não se cadastrou, ignore esta mensagem.\n",
+			usuario.Nome, int(validadeVerificacao.Hours()), s.link("/verificar-email", token)),
+	})
+}
+
+// ResendVerification reenvia o link de confirmação. Para não revelar quais e-mails estão
+// cadastrados, não há erro quando o e-mail não existe ou já foi verificado.
+func (s *AuthService) ResendVerification(ctx context.Context, email string) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ResendVerification")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	usuario, err := s.buscarPorEmail(ctx, email)
+	if err != nil || usuario == nil || usuario.EmailVerifiedAt != nil {
+		return err
+	}
+	return s.SendVerification(ctx, *usuario)
+}
+
+// VerifyEmail confirma o e-mail do usuário do token, se ele ainda for o mesmo do envio.
+// Confirmar de novo um e-mail já verificado não é erro.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.VerifyEmail")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	claims, err := s.validarToken(token, finalidadeVerificarEmail)
+	if err != nil {
+		return err
+	}
+	usuario, err := s.repo.GetByID(ctx, claims.UsuarioID)
+	if err != nil {
+		return err
+	}
+	if usuario == nil || tokens.Vinculo(usuario.Email) != claims.Vinculo {
+		return ErrTokenInvalido.WithDetail("o e-mail do usuário mudou desde o envio do link")
+	}
+	if usuario.EmailVerifiedAt != nil {
+		return nil
+	}
+	err = s.repo.MarkEmailVerified(ctx, usuario.ID, usuario.Email)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return ErrTokenInvalido.WithDetail("o e-mail do usuário mudou desde o envio do link")
+	}
+	return err
+}
+
+// RequestPasswordReset envia o link de redefinição de senha. Como em ResendVerification,
+// e-mails não cadastrados não geram erro.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.RequestPasswordReset")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	usuario, err := s.buscarPorEmail(ctx, email)
+	if err != nil || usuario == nil {
+		return err
+	}
+	hash, err := s.repo.GetPasswordHash(ctx, usuario.ID)
+	if err != nil {
+		return err
+	}
+	token, err := s.tokens.Gerar(finalidadeRedefinirSenha, usuario.ID, vinculoRedefinicao(hash, usuario.Email), validadeRedefinicao)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Enviar(ctx, mailer.Mensagem{
+		Para:    usuario.Email,
+		Assunto: "Redefinição de senha",
+		Texto: fmt.Sprintf("Olá, %s!\n\nRecebemos um pedido para redefinir a sua senha. Para escolher uma nova, acesse o link abaixo em até %d minutos:\n\n%s\n\nSe você não fez o pedido, ignore esta mensagem: a senha atual continua valendo.\n",
+			usuario.Nome, int(validadeRedefinicao.Minutes()), s.link("/redefinir-senha", token)),
+	})
+}
+
+// ResetPassword grava a nova senha do usuário do token. O token deixa de valer assim que a
+// senha muda, e como só chega por e-mail, também confirma o e-mail do usuário. Com
+// WithSessoes, as sessões abertas antes da troca são revogadas, já que a senha antiga pode
+// ter sido usada por outra pessoa.
+func (s *AuthService) ResetPassword(ctx context.Context, token, senha string) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ResetPassword")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if erros := validation.Validate(validation.F("senha", senha, validation.Required(), validation.Length(senhaMinLen, senhaMaxLen))); len(erros) > 0 {
+		return ErrDadosInvalidos.WithFields(erros...)
+	}
+	claims, err := s.validarToken(token, finalidadeRedefinirSenha)
+	if err != nil {
+		return err
+	}
+	usuario, err := s.repo.GetByID(ctx, claims.UsuarioID)
+	if err != nil {
+		return err
+	}
+	if usuario == nil {
+		return ErrTokenInvalido
+	}
+	atual, err := s.repo.GetPasswordHash(ctx, usuario.ID)
+	if err != nil {
+		return err
+	}
+	if vinculoRedefinicao(atual, usuario.Email) != claims.Vinculo {
+		return ErrTokenInvalido.WithDetail("o link já foi usado ou a conta mudou desde o pedido")
+	}
+
+	novo, err := hashSenha(senha)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, usuario.ID, novo); err != nil {
+		return err
+	}
+	if usuario.EmailVerifiedAt == nil {
+		if err := s.repo.MarkEmailVerified(ctx, usuario.ID, usuario.Email); err != nil && !errors.Is(err, repository.ErrNaoEncontrado) {
+			return err
+		}
+	}
+	var sessoes int64
+	if s.sessoes != nil {
+		if sessoes, err = s.sessoes.RevogarTodas(ctx, usuario.ID, domain.MotivoSenhaRedefinida); err != nil {
+			return err
+		}
+	}
+	logging.FromContext(ctx).InfoContext(ctx, "Senha redefinida", "usuario_id", usuario.ID, "sessoes_revogadas", sessoes)
+	s.auditar(ctx, atorUsuario(usuario.ID), usuario.ID, domain.AcaoSenhaRedefinida, map[string]any{"sessoes_revogadas": sessoes})
+	return nil
+}
+
+// emitirAcesso emite o token de acesso do usuário que acabou de fazer login e, com
+// WithSessoes, abre a sessão e devolve o seu primeiro refresh token. comMFA indica que o
+// login passou pelo segundo fator.
+func (s *AuthService) emitirAcesso(ctx context.Context, usuario domain.Usuario, comMFA bool, origem domain.OrigemSessao) (*domain.Login, error) {
+	if s.sessoes == nil {
+		return s.tokenDeAcesso(usuario, comMFA, 0)
+	}
+	sessaoID, refresh, err := s.abrirSessao(ctx, usuario.ID, comMFA, origem)
+	if err != nil {
+		return nil, err
+	}
+	login, err := s.tokenDeAcesso(usuario, comMFA, sessaoID)
+	if err != nil {
+		return nil, err
+	}
+	login.RefreshToken = refresh
+	return login, nil
+}
+
+// tokenDeAcesso gera o token de acesso, enviado como Bearer nas rotas que agem em nome do
+// usuário. Ele deixa de valer se o e-mail mudar ou, com sessaoID, se a sessão for revogada.
+func (s *AuthService) tokenDeAcesso(usuario domain.Usuario, comMFA bool, sessaoID int64) (*domain.Login, error) {
+	finalidade := finalidadeAcesso
+	if comMFA {
+		finalidade = finalidadeAcessoMFA
+	}
+	token, err := s.tokens.GerarNaSessao(finalidade, usuario.ID, tokens.Vinculo(usuario.Email), sessaoID, validadeAcesso)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Login{
+		UsuarioID:   usuario.ID,
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(validadeAcesso.Seconds()),
+		SessaoID:    sessaoID,
+	}, nil
+}
+
+// AuthenticateAccessToken devolve o usuário dono do token de acesso.
+func (s *AuthService) AuthenticateAccessToken(ctx context.Context, token string) (_ *domain.Usuario, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.AuthenticateAccessToken")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	usuario, _, err := s.validarAcesso(ctx, token)
+	return usuario, err
+}
+
+// validarAcesso devolve o usuário dono do token de acesso e se o login passou pelo segundo fator.
+func (s *AuthService) validarAcesso(ctx context.Context, token string) (*domain.Usuario, bool, error) {
+	comMFA := true
+	claims, err := s.tokens.Validar(token, finalidadeAcessoMFA)
+	if err != nil {
+		comMFA = false
+		claims, err = s.tokens.Validar(token, finalidadeAcesso)
+	}
+	if err != nil {
+		return nil, false, ErrTokenAcessoInvalido
+	}
+	usuario, err := s.repo.GetByID(ctx, claims.UsuarioID)
+	if err != nil {
+		return nil, false, err
+	}
+	if usuario == nil || tokens.Vinculo(usuario.Email) != claims.Vinculo {
+		return nil, false, ErrTokenAcessoInvalido
+	}
+	if claims.Sessao != 0 {
+		ativa, err := s.sessaoAtiva(ctx, usuario.ID, claims.Sessao)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ativa {
+			return nil, false, ErrTokenAcessoInvalido
+		}
+	}
+	return usuario, comMFA, nil
+}
+
+func (s *AuthService) buscarPorEmail(ctx context.Context, email string) (*domain.Usuario, error) {
+	email = strings.TrimSpace(email)
+	if erros := validation.Validate(validation.F("email", email, validation.Required(), validation.MaxLength(emailMaxLen), validation.Email())); len(erros) > 0 {
+		return nil, ErrDadosInvalidos.WithFields(erros...)
+	}
+	return s.repo.GetByEmail(ctx, email)
+}
+
+func (s *AuthService) validarToken(token, finalidade string) (tokens.Claims, error) {
+	claims, err := s.tokens.Validar(token, finalidade)
+	switch {
+	case errors.Is(err, tokens.ErrExpirado):
+		return claims, ErrTokenInvalido.WithDetail("o link expirou; peça um novo")
+	case err != nil:
+		return claims, ErrTokenInvalido
+	}
+	return claims, nil
+}
+
+func (s *AuthService) link(caminho, token string) string {
+	return s.urlBase + caminho + "?token=" + url.QueryEscape(token)
+}
+
+// vinculoRedefinicao amarra o token de redefinição à senha e ao e-mail do momento do pedido.
+func vinculoRedefinicao(hashSenha, email string) string {
+	return tokens.Vinculo(hashSenha + "\x00" + email)
+}
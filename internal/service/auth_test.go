@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/mailer"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/tokens"
+)
+
+// authRepoFake guarda um único usuário e a sua senha em memória.
+type authRepoFake struct {
+	repository.UsuarioRepository
+	usuario      domain.Usuario
+	hash         string
+	falhaRemocao error // Devolvido por Delete, se definido.
+}
+
+func (f *authRepoFake) GetByID(ctx context.Context, id int64) (*domain.Usuario, error) {
+	if id != f.usuario.ID {
+		return nil, nil
+	}
+	u := f.usuario
+	return &u, nil
+}
+
+func (f *authRepoFake) GetByEmail(ctx context.Context, email string) (*domain.Usuario, error) {
+	if email != f.usuario.Email {
+		return nil, nil
+	}
+	return f.GetByID(ctx, f.usuario.ID)
+}
+
+func (f *authRepoFake) MarkEmailVerified(ctx context.Context, id int64, email string) error {
+	if id != f.usuario.ID || email != f.usuario.Email {
+		return repository.ErrNaoEncontrado
+	}
+	agora := time.Now()
+	f.usuario.EmailVerifiedAt = &agora
+	return nil
+}
+
+func (f *authRepoFake) GetPasswordHash(ctx context.Context, id int64) (string, error) {
+	return f.hash, nil
+}
+
+func (f *authRepoFake) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	f.hash = hash
+	return nil
+}
+
+// mailerFake guarda as mensagens em vez de enviá-las.
+type mailerFake struct {
+	enviadas []mailer.Mensagem
+}
+
+func (m *mailerFake) Enviar(ctx context.Context, msg mailer.Mensagem) error {
+	m.enviadas = append(m.enviadas, msg)
+	return nil
+}
+
+var tokenNoLink = regexp.MustCompile(`\?token=(\S+)`)
+
+// tokenEnviado extrai o token do link da última mensagem enviada.
+func tokenEnviado(t *testing.T, m *mailerFake) string {
+	t.Helper()
+	require.NotEmpty(t, m.enviadas)
+	grupos := tokenNoLink.FindStringSubmatch(m.enviadas[len(m.enviadas)-1].Texto)
+	require.Len(t, grupos, 2)
+	token, err := url.QueryUnescape(grupos[1])
+	require.NoError(t, err)
+	return token
+}
+
+func novoAuthService() (*AuthService, *authRepoFake, *mailerFake) {
+	repo := &authRepoFake{usuario: domain.Usuario{ID: 7, Nome: "Ana", Email: "ana@email.com"}}
+	m := &mailerFake{}
+	return NewAuthService(repo, m, tokens.NewAssinador([]byte("segredo")), "https://app.exemplo.com/"), repo, m
+}
+
+func TestAuthService_VerifyEmail(t *testing.T) {
+	t.Run("sucesso - o link enviado confirma o e-mail", func(t *testing.T) {
+		s, repo, m := novoAuthService()
+
+		require.NoError(t, s.SendVerification(context.Background(), repo.usuario))
+
+		assert.Equal(t, "ana@email.com", m.enviadas[0].Para)
+		assert.Contains(t, m.enviadas[0].Texto, "https://app.exemplo.com/verificar-email?token=")
+		assert.NoError(t, s.VerifyEmail(context.Background(), tokenEnviado(t, m)))
+		assert.NotNil(t, repo.usuario.EmailVerifiedAt)
+	})
+
+	t.Run("erro - token de e-mail antigo não vale depois da troca", func(t *testing.T) {
+		s, repo, m := novoAuthService()
+		require.NoError(t, s.SendVerification(context.Background(), repo.usuario))
+		repo.usuario.Email = "ana.nova@email.com"
+
+		err := s.VerifyEmail(context.Background(), tokenEnviado(t, m))
+
+		assert.ErrorIs(t, err, ErrTokenInvalido)
+		assert.Nil(t, repo.usuario.EmailVerifiedAt)
+	})
+
+	t.Run("erro - token de redefinição não serve para verificação", func(t *testing.T) {
+		s, _, m := novoAuthService()
+		require.NoError(t, s.RequestPasswordReset(context.Background(), "ana@email.com"))
+
+		assert.ErrorIs(t, s.VerifyEmail(context.Background(), tokenEnviado(t, m)), ErrTokenInvalido)
+	})
+
+	t.Run("sucesso - reenvio é silencioso para e-mail desconhecido ou já verificado", func(t *testing.T) {
+		s, repo, m := novoAuthService()
+
+		assert.NoError(t, s.ResendVerification(context.Background(), "outra@email.com"))
+		agora := time.Now()
+		repo.usuario.EmailVerifiedAt = &agora
+		assert.NoError(t, s.ResendVerification(context.Background(), "ana@email.com"))
+		assert.Empty(t, m.enviadas)
+	})
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	iteracoesOriginais := iteracoesSenha
+	iteracoesSenha = 1000
+	t.Cleanup(func() { iteracoesSenha = iteracoesOriginais })
+
+	t.Run("sucesso - grava a nova senha e o link só vale uma vez", func(t *testing.T) {
+		s, repo, m := novoAuthService()
+		require.NoError(t, s.RequestPasswordReset(context.Background(), "ana@email.com"))
+		token := tokenEnviado(t, m)
+
+		require.NoError(t, s.ResetPassword(context.Background(), token, "nova-senha-123"))
+
+		assert.True(t, verificarSenha("nova-senha-123", repo.hash))
+		assert.False(t, verificarSenha("outra-senha-123", repo.hash))
+		assert.NotNil(t, repo.usuario.EmailVerifiedAt, "o link chegou pelo e-mail, que fica confirmado")
+		assert.ErrorIs(t, s.ResetPassword(context.Background(), token, "mais-uma-senha"), ErrTokenInvalido)
+	})
+
+	t.Run("erro - senha curta é rejeitada antes do token", func(t *testing.T) {
+		s, _, _ := novoAuthService()
+
+		err := s.ResetPassword(context.Background(), "qualquer", "curta")
+
+		var derr *domain.Error
+		require.ErrorAs(t, err, &derr)
+		assert.Equal(t, "dados_invalidos", derr.Code)
+		assert.Equal(t, "senha", derr.Fields[0].Field)
+	})
+
+	t.Run("sucesso - pedido para e-mail desconhecido não envia nada", func(t *testing.T) {
+		s, _, m := novoAuthService()
+
+		assert.NoError(t, s.RequestPasswordReset(context.Background(), "outra@email.com"))
+		assert.Empty(t, m.enviadas)
+	})
+}
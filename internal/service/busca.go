@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+	"github.com/willjrcristo/go-sqlite-db/internal/validation"
+)
+
+// Limites da busca de usuários.
+const (
+	buscaMaxLen       = 100
+	buscaLimitePadrao = 20
+	buscaLimiteMax    = 100
+)
+
+// ErrBuscaIndisponivel indica que a API foi compilada sem FTS5 (sem -tags sqlite_fts5) e por
+// isso não tem o índice de busca.
+var ErrBuscaIndisponivel = &domain.Error{Code: "busca_indisponivel", Status: http.StatusServiceUnavailable, Title: "a busca de usuários não está disponível neste servidor"}
+
+// SearchUsers busca usuários por partes do nome ou do e-mail, ignorando maiúsculas e acentos,
+// e devolve os mais relevantes primeiro. Com limit zero, usa o limite padrão.
+func (s *UsuarioService) SearchUsers(ctx context.Context, q string, limit int) (_ []domain.Usuario, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.SearchUsers", trace.WithAttributes(attribute.Int("search.limit", limit)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	q = strings.TrimSpace(q)
+	erros := validation.Validate(validation.F("q", q, validation.Required(), validation.MaxLength(buscaMaxLen)))
+	if limit == 0 {
+		limit = buscaLimitePadrao
+	}
+	if limit < 1 || limit > buscaLimiteMax {
+		erros = append(erros, domain.FieldError{Field: "limit", Code: "fora_do_intervalo", Message: fmt.Sprintf("deve estar entre 1 e %d", buscaLimiteMax)})
+	}
+	if len(erros) > 0 {
+		return nil, domain.ErrRequisicaoInvalida.WithDetail("parâmetros de busca inválidos").WithFields(erros...)
+	}
+
+	usuarios, err := s.repo.Search(ctx, q, limit)
+	if errors.Is(err, repository.ErrFTS5Indisponivel) {
+		return nil, ErrBuscaIndisponivel.Wrap(err)
+	}
+	return usuarios, err
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// buscaRepoFake registra os argumentos recebidos por Search.
+type buscaRepoFake struct {
+	repository.UsuarioRepository
+	query string
+	limit int
+	err   error
+}
+
+func (f *buscaRepoFake) Search(ctx context.Context, query string, limit int) ([]domain.Usuario, error) {
+	f.query, f.limit = query, limit
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []domain.Usuario{}, nil
+}
+
+func TestUsuarioService_SearchUsers(t *testing.T) {
+	t.Run("sucesso - remove espaços e aplica o limite padrão", func(t *testing.T) {
+		repo := &buscaRepoFake{}
+		s := NewUsuarioService(repo)
+
+		_, err := s.SearchUsers(context.Background(), "  ana  ", 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ana", repo.query)
+		assert.Equal(t, buscaLimitePadrao, repo.limit)
+	})
+
+	t.Run("erro - busca vazia, longa demais ou limite fora do intervalo", func(t *testing.T) {
+		s := NewUsuarioService(&buscaRepoFake{})
+
+		_, err := s.SearchUsers(context.Background(), "   ", buscaLimiteMax+1)
+
+		var de *domain.Error
+		assert.True(t, errors.As(err, &de))
+		assert.Equal(t, []domain.FieldError{
+			{Field: "q", Code: "obrigatorio", Message: "campo obrigatório"},
+			{Field: "limit", Code: "fora_do_intervalo", Message: "deve estar entre 1 e 100"},
+		}, de.Fields)
+
+		_, err = s.SearchUsers(context.Background(), strings.Repeat("a", buscaMaxLen+1), 10)
+		assert.True(t, errors.Is(err, domain.ErrRequisicaoInvalida))
+	})
+
+	t.Run("erro - API sem FTS5 responde que a busca está indisponível", func(t *testing.T) {
+		s := NewUsuarioService(&buscaRepoFake{err: repository.ErrFTS5Indisponivel})
+
+		_, err := s.SearchUsers(context.Background(), "ana", 10)
+
+		var de *domain.Error
+		assert.True(t, errors.As(err, &de))
+		assert.Equal(t, ErrBuscaIndisponivel.Code, de.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, de.Status)
+	})
+}
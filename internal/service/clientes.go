@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v78"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// Políticas de conflito entre o perfil do dono da organização e o cliente da Stripe, quando o
+// contato do cliente é alterado do lado da Stripe (Dashboard ou portal do cliente). Um e-mail
+// alterado na Stripe é sempre desfeito com o do perfil.
+const (
+	// PoliticaClienteLocal mantém o perfil do usuário e desfaz a alteração na Stripe.
+	PoliticaClienteLocal = "local"
+	// PoliticaClienteStripe grava no nome do usuário o contato alterado na Stripe.
+	PoliticaClienteStripe = "stripe"
+	// PoliticaClienteRecente fica com o lado alterado por último: o evento da Stripe contra
+	// o updated_at do usuário.
+	PoliticaClienteRecente = "newest"
+)
+
+// PoliticasCliente são as políticas de conflito aceitas.
+var PoliticasCliente = []string{PoliticaClienteLocal, PoliticaClienteStripe, PoliticaClienteRecente}
+
+const (
+	// loteClientes limita as pendências tratadas em cada rodada de SincronizarClientes.
+	loteClientes = 100
+	// maxTentativasCliente é quantas vezes uma pendência é enviada antes de ficar só
+	// registrada (com o último erro) em customer_sync_outbox.
+	maxTentativasCliente = 10
+)
+
+// WithSincronizacaoClientes habilita a sincronização do nome e do e-mail do dono com o cliente
+// da Stripe de cada organização: as alterações do perfil ficam na caixa de saída e são
+// enviadas ao provedor por SincronizarClientes; as feitas na Stripe chegam pelo webhook
+// customer.updated e são resolvidas pela política (veja PoliticasCliente).
+func (s *OrganizacaoService) WithSincronizacaoClientes(outbox repository.OutboxClienteRepository, p billing.Provider, politica string) *OrganizacaoService {
+	s.outbox, s.provider, s.politica = outbox, p, politica
+	return s
+}
+
+// SincronizarClientes faz uma rodada de envio da caixa de saída: cada usuário com alterações
+// pendentes tem os valores atuais enviados a todos os clientes das organizações de que ele é
+// dono. Uma falha fica registrada e é repetida na rodada seguinte. Devolve quantos usuários
+// foram sincronizados.
+func (s *OrganizacaoService) SincronizarClientes(ctx context.Context) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.SincronizarClientes")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	pendentes, err := s.outbox.Pendentes(ctx, maxTentativasCliente, loteClientes)
+	if err != nil || len(pendentes) == 0 {
+		return 0, err
+	}
+	// Várias alterações do mesmo usuário viram um só envio, com os valores atuais.
+	var usuarios []int64
+	ids := make(map[int64][]int64)
+	for _, p := range pendentes {
+		if _, ok := ids[p.UsuarioID]; !ok {
+			usuarios = append(usuarios, p.UsuarioID)
+		}
+		ids[p.UsuarioID] = append(ids[p.UsuarioID], p.ID)
+	}
+
+	var erros []error
+	enviados := 0
+	for _, usuarioID := range usuarios {
+		if err := s.enviarCliente(ctx, usuarioID); err != nil {
+			metrics.CustomerSyncs.WithLabelValues("outbound", "error").Inc()
+			erros = append(erros, fmt.Errorf("usuário %d: %w", usuarioID, err))
+			if errFalha := s.outbox.MarcarFalha(ctx, ids[usuarioID], err.Error()); errFalha != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "Falha ao registrar o erro da sincronização do cliente", "error", errFalha)
+			}
+			continue
+		}
+		metrics.CustomerSyncs.WithLabelValues("outbound", "success").Inc()
+		if err := s.outbox.MarcarEnviadas(ctx, ids[usuarioID]); err != nil {
+			return enviados, err
+		}
+		enviados++
+	}
+	return enviados, errors.Join(erros...)
+}
+
+// enviarCliente envia o nome e o e-mail atuais do usuário aos clientes das organizações de que
+// ele é dono. Um usuário removido não tem o que enviar.
+func (s *OrganizacaoService) enviarCliente(ctx context.Context, usuarioID int64) error {
+	usuario, err := s.usuarios.GetByID(ctx, usuarioID)
+	if err != nil || usuario == nil {
+		return err
+	}
+	orgs, err := s.repo.ListDoUsuario(ctx, usuarioID)
+	if err != nil {
+		return err
+	}
+	for _, org := range orgs {
+		if org.Papel != domain.PapelOrgDono || org.StripeCustomerID == "" {
+			continue
+		}
+		c := billing.Cliente{ID: org.StripeCustomerID, Email: usuario.Email, Contato: usuario.Nome}
+		if err := s.provider.AtualizarCliente(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receberCliente trata o webhook customer.updated. Se o e-mail ou o contato do cliente não
+// divergem do dono da organização (o eco das alterações enviadas por SincronizarClientes, por
+// exemplo), nada é feito; senão, a política decide se o contato vai para o nome do dono. O
+// e-mail da Stripe nunca vai para o perfil: ele é a identidade de login e só muda pelo próprio
+// usuário, com nova verificação, então a Stripe recebe de volta o e-mail do perfil.
+func (s *OrganizacaoService) receberCliente(ctx context.Context, c *stripe.Customer, alteradoEm time.Time) (ignorado bool, err error) {
+	org, err := s.repo.GetByStripeID(ctx, c.ID)
+	if err != nil || org == nil {
+		return true, err
+	}
+	dono, err := s.contatoCobranca(ctx, org.ID)
+	if errors.Is(err, ErrSemContatoCobranca) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Os campos vazios na Stripe (ex: clientes criados antes do metadado de contato) não divergem.
+	recebido := domain.Usuario{Nome: c.Metadata[billing.MetadadoContato], Email: c.Email}
+	normalizarUsuario(&recebido)
+	if recebido.Nome == "" {
+		recebido.Nome = dono.Nome
+	}
+	if recebido.Email == "" {
+		recebido.Email = dono.Email
+	}
+	nomeDiverge, emailDiverge := recebido.Nome != dono.Nome, !strings.EqualFold(recebido.Email, dono.Email)
+	if !nomeDiverge && !emailDiverge {
+		metrics.CustomerSyncs.WithLabelValues("inbound", "unchanged").Inc()
+		return false, nil
+	}
+
+	log := logging.FromContext(ctx).With("organizacao_id", org.ID, "usuario_id", dono.ID, "politica", s.politica)
+	stripeVence := nomeDiverge && (s.politica == PoliticaClienteStripe || (s.politica == PoliticaClienteRecente && alteradoEm.After(dono.UpdatedAt)))
+	if stripeVence {
+		if err := validarUsuario(domain.Usuario{Nome: recebido.Nome, Email: dono.Email}); err != nil {
+			log.WarnContext(ctx, "Alteração do cliente na Stripe recusada; mantendo o perfil do dono", "error", err)
+			stripeVence = false
+		}
+	}
+	if !stripeVence {
+		// A caixa de saída devolve à Stripe os valores do perfil.
+		metrics.CustomerSyncs.WithLabelValues("inbound", "local_wins").Inc()
+		log.InfoContext(ctx, "Cliente alterado na Stripe; reenviando o perfil do dono", "email_divergente", emailDiverge)
+		return false, s.outbox.Enfileirar(ctx, dono.ID)
+	}
+
+	// Um conflito de versão devolve erro, e a Stripe reenvia o evento para nova comparação.
+	if err := s.usuarios.Patch(ctx, dono.ID, domain.UsuarioPatch{Version: dono.Version, Nome: &recebido.Nome}); err != nil {
+		return false, err
+	}
+	metrics.CustomerSyncs.WithLabelValues("inbound", "stripe_wins").Inc()
+	log.InfoContext(ctx, "Nome do dono atualizado pela Stripe", "email_divergente", emailDiverge)
+	if emailDiverge {
+		return false, s.outbox.Enfileirar(ctx, dono.ID)
+	}
+	return false, nil
+}
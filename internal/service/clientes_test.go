@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/webhook"
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// outboxClienteFake guarda as pendências em memória; enviadas indica as já enviadas.
+type outboxClienteFake struct {
+	repository.OutboxClienteRepository
+	pendencias []domain.PendenciaCliente
+	enviadas   map[int64]bool
+}
+
+func (f *outboxClienteFake) Enfileirar(ctx context.Context, usuarioID int64) error {
+	f.pendencias = append(f.pendencias, domain.PendenciaCliente{ID: int64(len(f.pendencias) + 1), UsuarioID: usuarioID, CreatedAt: time.Now()})
+	return nil
+}
+
+func (f *outboxClienteFake) Pendentes(ctx context.Context, maxTentativas, limite int) ([]domain.PendenciaCliente, error) {
+	var pendentes []domain.PendenciaCliente
+	for _, p := range f.pendencias {
+		if !f.enviadas[p.ID] && p.Tentativas < maxTentativas {
+			pendentes = append(pendentes, p)
+		}
+	}
+	return pendentes, nil
+}
+
+func (f *outboxClienteFake) MarcarEnviadas(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		f.enviadas[id] = true
+	}
+	return nil
+}
+
+func (f *outboxClienteFake) MarcarFalha(ctx context.Context, ids []int64, erro string) error {
+	for _, id := range ids {
+		f.pendencias[id-1].Tentativas++
+	}
+	return nil
+}
+
+func (f *usuariosOIDCFake) Patch(ctx context.Context, id int64, patch domain.UsuarioPatch) error {
+	u := &f.usuarios[id-1]
+	if patch.Version != 0 && patch.Version != u.Version {
+		return repository.ErrVersaoDesatualizada
+	}
+	if patch.Nome != nil {
+		u.Nome = *patch.Nome
+	}
+	if patch.Email != nil {
+		u.Email, u.EmailVerifiedAt = *patch.Email, nil
+	}
+	u.Version++
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func TestOrganizacaoService_SincronizacaoClientes(t *testing.T) {
+	ctx := context.Background()
+	verificado := time.Now()
+	alteradoEm := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	novo := func(politica string) (*OrganizacaoService, *usuariosOIDCFake, *outboxClienteFake, *billing.StubProvider) {
+		usuarios := &usuariosOIDCFake{usuarios: []domain.Usuario{
+			{ID: 1, Nome: "Ana", Email: "ana@email.com", EmailVerifiedAt: &verificado, UpdatedAt: alteradoEm, Version: 1},
+			{ID: 2, Nome: "Bia", Email: "bia@email.com", EmailVerifiedAt: &verificado, UpdatedAt: alteradoEm, Version: 1},
+		}}
+		repo := novoOrgRepoFake(usuarios)
+		// Ana é dona de Acme e de Beta, clientes da Stripe, e membro de Gama, de Bia.
+		repo.orgs[1] = &domain.Organizacao{ID: 1, Nome: "Acme", StripeCustomerID: "cus_acme"}
+		repo.orgs[2] = &domain.Organizacao{ID: 2, Nome: "Beta", StripeCustomerID: "cus_beta"}
+		repo.orgs[3] = &domain.Organizacao{ID: 3, Nome: "Gama", StripeCustomerID: "cus_gama"}
+		repo.papeis[1] = map[int64]string{1: domain.PapelOrgDono}
+		repo.papeis[2] = map[int64]string{1: domain.PapelOrgDono}
+		repo.papeis[3] = map[int64]string{2: domain.PapelOrgDono, 1: domain.PapelOrgMembro}
+		outbox, provider := &outboxClienteFake{enviadas: map[int64]bool{}}, &billing.StubProvider{}
+		s := NewOrganizacaoService(repo, usuarios, &mailerFake{}, "http://app.local").WithSincronizacaoClientes(outbox, provider, politica)
+		return s, usuarios, outbox, provider
+	}
+
+	t.Run("sucesso - envia os valores atuais uma vez por usuário a todos os clientes de que ele é dono", func(t *testing.T) {
+		s, usuarios, outbox, provider := novo(PoliticaClienteLocal)
+		require.NoError(t, outbox.Enfileirar(ctx, 1))
+		require.NoError(t, outbox.Enfileirar(ctx, 1))
+		usuarios.usuarios[0].Email = "ana.souza@email.com"
+
+		n, err := s.SincronizarClientes(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, map[string]billing.Cliente{
+			"cus_acme": {ID: "cus_acme", Email: "ana.souza@email.com", Contato: "Ana"},
+			"cus_beta": {ID: "cus_beta", Email: "ana.souza@email.com", Contato: "Ana"},
+		}, provider.Clientes, "Gama é de Bia")
+
+		n, err = s.SincronizarClientes(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("erro - envio recusado fica pendente para a próxima rodada", func(t *testing.T) {
+		s, _, outbox, provider := novo(PoliticaClienteLocal)
+		require.NoError(t, outbox.Enfileirar(ctx, 1))
+		provider.Err = errors.New("stripe indisponível")
+
+		_, err := s.SincronizarClientes(ctx)
+		assert.ErrorIs(t, err, provider.Err)
+		assert.Equal(t, 1, outbox.pendencias[0].Tentativas)
+
+		provider.Err = nil
+		n, err := s.SincronizarClientes(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Len(t, provider.Clientes, 2)
+	})
+
+	// receber entrega um customer.updated assinado, criado em alteradoEm mais delta.
+	receber := func(t *testing.T, s *OrganizacaoService, delta time.Duration, cliente string) error {
+		t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_teste")
+		payload := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Secret: "whsec_teste", Payload: []byte(fmt.Sprintf(
+			`{"id":"evt_1","object":"event","api_version":%q,"type":"customer.updated","created":%d,"data":{"object":%s}}`,
+			stripe.APIVersion, alteradoEm.Add(delta).Unix(), cliente))})
+		return s.HandleStripeWebhook(ctx, payload.Payload, payload.Header)
+	}
+	alterado := `{"id":"cus_acme","object":"customer","email":"financeiro@acme.com","metadata":{"contato_nome":"Ana Souza"}}`
+
+	t.Run("sucesso - eco das alterações enviadas não muda nada", func(t *testing.T) {
+		s, usuarios, outbox, _ := novo(PoliticaClienteStripe)
+
+		require.NoError(t, receber(t, s, time.Hour, `{"id":"cus_acme","object":"customer","email":"ANA@email.com","metadata":{}}`))
+		require.NoError(t, receber(t, s, time.Hour, `{"id":"cus_outro","object":"customer","email":"x@email.com"}`))
+
+		assert.Equal(t, "ana@email.com", usuarios.usuarios[0].Email)
+		assert.Empty(t, outbox.pendencias)
+	})
+
+	t.Run("sucesso - política local reenvia o perfil do dono", func(t *testing.T) {
+		s, usuarios, outbox, _ := novo(PoliticaClienteLocal)
+
+		require.NoError(t, receber(t, s, time.Hour, alterado))
+
+		assert.Equal(t, "ana@email.com", usuarios.usuarios[0].Email)
+		require.Len(t, outbox.pendencias, 1)
+		assert.Equal(t, int64(1), outbox.pendencias[0].UsuarioID)
+	})
+
+	t.Run("sucesso - política stripe grava o contato no nome do dono, mas não o e-mail", func(t *testing.T) {
+		s, usuarios, outbox, _ := novo(PoliticaClienteStripe)
+
+		require.NoError(t, receber(t, s, -time.Hour, alterado))
+
+		ana := usuarios.usuarios[0]
+		assert.Equal(t, "Ana Souza", ana.Nome)
+		assert.Equal(t, "ana@email.com", ana.Email)
+		assert.NotNil(t, ana.EmailVerifiedAt)
+		require.Len(t, outbox.pendencias, 1, "o e-mail do perfil volta para a Stripe")
+		assert.Equal(t, int64(1), outbox.pendencias[0].UsuarioID)
+	})
+
+	t.Run("sucesso - política stripe só com o contato alterado não reenvia o perfil", func(t *testing.T) {
+		s, usuarios, outbox, _ := novo(PoliticaClienteStripe)
+
+		require.NoError(t, receber(t, s, time.Hour, `{"id":"cus_acme","object":"customer","email":"ana@email.com","metadata":{"contato_nome":"Ana Souza"}}`))
+
+		assert.Equal(t, "Ana Souza", usuarios.usuarios[0].Nome)
+		assert.Empty(t, outbox.pendencias)
+	})
+
+	t.Run("sucesso - e-mail alterado na Stripe é desfeito em qualquer política", func(t *testing.T) {
+		for _, politica := range PoliticasCliente {
+			s, usuarios, outbox, _ := novo(politica)
+
+			require.NoError(t, receber(t, s, time.Hour, `{"id":"cus_acme","object":"customer","email":"financeiro@acme.com","metadata":{"contato_nome":"Ana"}}`))
+
+			ana := usuarios.usuarios[0]
+			assert.Equal(t, "ana@email.com", ana.Email, politica)
+			assert.Equal(t, int64(1), ana.Version, "o perfil não é alterado (%s)", politica)
+			assert.Len(t, outbox.pendencias, 1, politica)
+		}
+	})
+
+	t.Run("sucesso - política newest fica com o lado alterado por último", func(t *testing.T) {
+		s, usuarios, outbox, _ := novo(PoliticaClienteRecente)
+
+		require.NoError(t, receber(t, s, -time.Hour, alterado))
+		assert.Equal(t, "Ana", usuarios.usuarios[0].Nome, "o perfil é mais recente")
+		assert.Len(t, outbox.pendencias, 1)
+
+		require.NoError(t, receber(t, s, time.Hour, alterado))
+		assert.Equal(t, "Ana Souza", usuarios.usuarios[0].Nome, "a Stripe é mais recente")
+		assert.Equal(t, "ana@email.com", usuarios.usuarios[0].Email)
+	})
+
+	t.Run("erro - contato inválido vindo da Stripe não chega ao perfil", func(t *testing.T) {
+		s, usuarios, outbox, _ := novo(PoliticaClienteStripe)
+
+		require.NoError(t, receber(t, s, time.Hour, `{"id":"cus_acme","object":"customer","email":"nao-e-email","metadata":{"contato_nome":"A"}}`))
+
+		assert.Equal(t, "Ana", usuarios.usuarios[0].Nome)
+		assert.Equal(t, "ana@email.com", usuarios.usuarios[0].Email)
+		assert.Len(t, outbox.pendencias, 1, "o perfil volta para a Stripe")
+	})
+}
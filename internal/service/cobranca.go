@@ -0,0 +1,214 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+	"github.com/willjrcristo/go-sqlite-db/internal/validation"
+)
+
+// Erros dos dados de cobrança.
+var (
+	ErrDadosCobrancaNaoEncontrados = &domain.Error{Code: "dados_cobranca_nao_encontrados", Status: http.StatusNotFound, Title: "o usuário não cadastrou os dados de cobrança"}
+	ErrDadosCobrancaInvalidos      = &domain.Error{Code: "dados_cobranca_invalidos", Status: http.StatusBadRequest, Title: "dados de cobrança inválidos"}
+)
+
+// precoPadrao é o price ID usado quando o plano não tem nenhum preço configurado.
+const precoPadrao = "price_SEU_PRICE_ID_AQUI" // Crie um produto e preço no Dashboard da Stripe
+
+var (
+	codigoPais = regexp.MustCompile(`^[A-Z]{2}$`)
+
+	// moedasPorPais indica a moeda local de cada país atendido; os demais ficam com a moeda padrão.
+	moedasPorPais = map[string]string{
+		"BR": "brl", "US": "usd", "GB": "gbp", "CA": "cad", "AU": "aud", "MX": "mxn", "AR": "ars", "CL": "clp", "CO": "cop",
+		"PT": "eur", "ES": "eur", "FR": "eur", "DE": "eur", "IT": "eur", "NL": "eur", "BE": "eur", "IE": "eur", "AT": "eur", "FI": "eur", "LU": "eur", "GR": "eur",
+	}
+)
+
+// PlanoCheckout configura os preços e os impostos do checkout das organizações.
+type PlanoCheckout struct {
+	// Precos traz o price ID da Stripe de cada moeda (ISO 4217 em minúsculas, ex: "brl").
+	Precos map[string]string
+	// MoedaPadrao é usada quando nem o país nem o idioma indicam uma moeda com preço.
+	MoedaPadrao string
+	// ImpostoAutomatico liga o Stripe Tax, que calcula o imposto pelo endereço do cliente.
+	ImpostoAutomatico bool
+	// TaxasImposto são tax rates da Stripe; a Stripe aplica a do país do cliente. Ignoradas
+	// com ImpostoAutomatico.
+	TaxasImposto []string
+}
+
+// Preco escolhe a moeda e o price ID do checkout: a moeda já usada pela organização, senão a
+// do país do endereço de cobrança, senão a das regiões do Accept-Language (na ordem de
+// preferência) e, por fim, a MoedaPadrao. Só contam as moedas com preço no plano.
+func (p PlanoCheckout) Preco(moedaOrganizacao, pais, acceptLanguage string) (moeda, preco string) {
+	candidatas := []string{moedaOrganizacao, moedasPorPais[pais]}
+	for _, regiao := range regioesIdioma(acceptLanguage) {
+		candidatas = append(candidatas, moedasPorPais[regiao])
+	}
+	for _, m := range append(candidatas, p.MoedaPadrao) {
+		if preco, ok := p.Precos[m]; ok && m != "" {
+			return m, preco
+		}
+	}
+	return p.MoedaPadrao, precoPadrao
+}
+
+// regioesIdioma devolve as regiões (ex: "BR" em "pt-BR") do Accept-Language, da maior para a
+// menor preferência (q). Idiomas sem região são ignorados.
+func regioesIdioma(acceptLanguage string) []string {
+	type idioma struct {
+		regiao string
+		q      float64
+	}
+	var idiomas []idioma
+	for _, parte := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(parte), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		// A região é a primeira subtag de duas letras depois do idioma (pt-BR, zh-Hant-TW).
+		subtags := strings.Split(tag, "-")
+		for _, sub := range subtags[min(1, len(subtags)):] {
+			if len(sub) == 2 && q > 0 {
+				idiomas = append(idiomas, idioma{strings.ToUpper(sub), q})
+				break
+			}
+		}
+	}
+	slices.SortStableFunc(idiomas, func(a, b idioma) int { return cmp.Compare(b.q, a.q) })
+	regioes := make([]string, len(idiomas))
+	for i, id := range idiomas {
+		regioes[i] = id.regiao
+	}
+	return regioes
+}
+
+// WithPlano define os preços por moeda e os impostos do checkout. Sem ele, o checkout usa um
+// só preço, sem impostos.
+func (s *OrganizacaoService) WithPlano(p PlanoCheckout) *OrganizacaoService {
+	s.plano = p
+	return s
+}
+
+// WithDadosCobranca habilita os dados de cobrança dos usuários, que vão pelo provedor para o
+// cliente da Stripe das organizações de que eles são donos e escolhem a moeda do checkout.
+func (s *OrganizacaoService) WithDadosCobranca(repo repository.CobrancaRepository, p billing.Provider) *OrganizacaoService {
+	s.cobranca, s.provider = repo, p
+	return s
+}
+
+// GetBillingDetails devolve os dados de cobrança do usuário.
+func (s *OrganizacaoService) GetBillingDetails(ctx context.Context, usuarioID int64) (_ *domain.DadosCobranca, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.GetBillingDetails", trace.WithAttributes(attribute.Int64("usuario.id", usuarioID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	dados, err := s.cobranca.Get(ctx, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if dados == nil {
+		return nil, ErrDadosCobrancaNaoEncontrados
+	}
+	return dados, nil
+}
+
+// UpdateBillingDetails valida e grava os dados de cobrança do usuário. Antes, eles são
+// enviados pelo provedor ao cliente da Stripe de cada organização de que ele é dono (endereço
+// e tax ID); se o provedor recusar, nada é gravado.
+func (s *OrganizacaoService) UpdateBillingDetails(ctx context.Context, usuarioID int64, dados domain.DadosCobranca) (_ *domain.DadosCobranca, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.UpdateBillingDetails", trace.WithAttributes(attribute.Int64("usuario.id", usuarioID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	dados = normalizarDadosCobranca(dados)
+	if err := validarDadosCobranca(dados); err != nil {
+		return nil, err
+	}
+
+	orgs, err := s.repo.ListDoUsuario(ctx, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	for _, org := range orgs {
+		if org.Papel != domain.PapelOrgDono || org.StripeCustomerID == "" {
+			continue
+		}
+		if err := s.provider.AtualizarDadosCobranca(ctx, dadosCobrancaProvedor(org.StripeCustomerID, dados)); err != nil {
+			return nil, ErrProvedorPagamento.Wrap(err)
+		}
+	}
+
+	if err := s.cobranca.Salvar(ctx, usuarioID, dados); err != nil {
+		return nil, err
+	}
+	return s.cobranca.Get(ctx, usuarioID)
+}
+
+// dadosCobrancaDono devolve os dados de cobrança do dono da organização, ou nil.
+func (s *OrganizacaoService) dadosCobrancaDono(ctx context.Context, dono *domain.Usuario) (*domain.DadosCobranca, error) {
+	if s.cobranca == nil {
+		return nil, nil
+	}
+	return s.cobranca.Get(ctx, dono.ID)
+}
+
+func normalizarDadosCobranca(d domain.DadosCobranca) domain.DadosCobranca {
+	e := &d.Endereco
+	e.Linha1, e.Linha2 = strings.TrimSpace(e.Linha1), strings.TrimSpace(e.Linha2)
+	e.Cidade, e.Estado = strings.TrimSpace(e.Cidade), strings.TrimSpace(e.Estado)
+	e.CEP, e.Pais = strings.TrimSpace(e.CEP), strings.ToUpper(strings.TrimSpace(e.Pais))
+	// O documento é guardado só com os dígitos: "111.444.777-35" vira "11144477735".
+	d.Documento = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(".-/ ", r) {
+			return -1
+		}
+		return r
+	}, d.Documento)
+	return d
+}
+
+func validarDadosCobranca(d domain.DadosCobranca) error {
+	e := d.Endereco
+	campos := []validation.Field{
+		validation.F("endereco.linha1", e.Linha1, validation.Required(), validation.MaxLength(200)),
+		validation.F("endereco.linha2", e.Linha2, validation.MaxLength(200)),
+		validation.F("endereco.cidade", e.Cidade, validation.Required(), validation.MaxLength(100)),
+		validation.F("endereco.estado", e.Estado, validation.MaxLength(100)),
+		validation.F("endereco.cep", e.CEP, validation.Required(), validation.MaxLength(20)),
+		validation.F("endereco.pais", e.Pais, validation.Required(), validation.Matches(codigoPais, "use o código de duas letras do país (ex: BR)")),
+	}
+	if d.Documento != "" {
+		campos = append(campos, validation.F("documento", d.Documento, validation.CPFouCNPJ()))
+	}
+	if erros := validation.Validate(campos...); len(erros) > 0 {
+		return ErrDadosCobrancaInvalidos.WithFields(erros...)
+	}
+	return nil
+}
+
+// dadosCobrancaProvedor converte os dados de cobrança para o cliente do provedor.
+func dadosCobrancaProvedor(clienteID string, d domain.DadosCobranca) billing.DadosCobranca {
+	e := d.Endereco
+	return billing.DadosCobranca{
+		ClienteID:     clienteID,
+		Endereco:      billing.Endereco{Linha1: e.Linha1, Linha2: e.Linha2, Cidade: e.Cidade, Estado: e.Estado, CEP: e.CEP, Pais: e.Pais},
+		TipoDocumento: d.TipoDocumento(),
+		Documento:     d.Documento,
+	}
+}
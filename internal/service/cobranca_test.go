@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// cobrancaRepoFake guarda os dados de cobrança em memória, por usuário.
+type cobrancaRepoFake struct {
+	repository.CobrancaRepository
+	dados map[int64]domain.DadosCobranca
+}
+
+func (f *cobrancaRepoFake) Get(ctx context.Context, usuarioID int64) (*domain.DadosCobranca, error) {
+	d, ok := f.dados[usuarioID]
+	if !ok {
+		return nil, nil
+	}
+	return &d, nil
+}
+
+func (f *cobrancaRepoFake) Salvar(ctx context.Context, usuarioID int64, dados domain.DadosCobranca) error {
+	dados.UpdatedAt = time.Now()
+	f.dados[usuarioID] = dados
+	return nil
+}
+
+func TestPlanoCheckout_Preco(t *testing.T) {
+	plano := PlanoCheckout{Precos: map[string]string{"brl": "price_brl", "usd": "price_usd", "eur": "price_eur"}, MoedaPadrao: "usd"}
+
+	casos := []struct {
+		nome                   string
+		moedaOrg, pais, idioma string
+		moeda, preco           string
+	}{
+		{"moeda da organização vence", "eur", "BR", "pt-BR", "eur", "price_eur"},
+		{"país do endereço", "", "BR", "en-US", "brl", "price_brl"},
+		{"país sem preço cai no idioma", "", "GB", "pt-BR", "brl", "price_brl"},
+		{"idioma pela preferência", "", "", "en-US;q=0.5, pt-PT;q=0.9, pt", "eur", "price_eur"},
+		{"idioma sem região", "", "", "pt", "usd", "price_usd"},
+		{"moeda da organização sem preço", "jpy", "", "", "usd", "price_usd"},
+	}
+	for _, c := range casos {
+		moeda, preco := plano.Preco(c.moedaOrg, c.pais, c.idioma)
+		assert.Equal(t, c.moeda, moeda, c.nome)
+		assert.Equal(t, c.preco, preco, c.nome)
+	}
+
+	moeda, preco := PlanoCheckout{}.Preco("", "BR", "pt-BR")
+	assert.Empty(t, moeda)
+	assert.Equal(t, precoPadrao, preco, "sem preços configurados, o checkout usa o preço de exemplo")
+}
+
+func TestOrganizacaoService_DadosCobranca(t *testing.T) {
+	ctx := context.Background()
+	verificado := time.Now()
+	novo := func() (*OrganizacaoService, *orgRepoFake, *cobrancaRepoFake, *billing.StubProvider) {
+		usuarios := &usuariosOIDCFake{usuarios: []domain.Usuario{{ID: 1, Nome: "Ana", Email: "ana@email.com", EmailVerifiedAt: &verificado}}}
+		repo, cobranca, provider := novoOrgRepoFake(usuarios), &cobrancaRepoFake{dados: map[int64]domain.DadosCobranca{}}, &billing.StubProvider{}
+		s := NewOrganizacaoService(repo, usuarios, &mailerFake{}, "http://localhost:3000").WithDadosCobranca(cobranca, provider)
+		return s, repo, cobranca, provider
+	}
+	endereco := domain.Endereco{Linha1: " Av. Paulista, 1000 ", Cidade: "São Paulo", Estado: "SP", CEP: "01310-100", Pais: "br"}
+
+	t.Run("sucesso - normaliza e grava os dados sem cliente na Stripe", func(t *testing.T) {
+		s, _, cobranca, provider := novo()
+		_, err := s.GetBillingDetails(ctx, 1)
+		assert.ErrorIs(t, err, ErrDadosCobrancaNaoEncontrados)
+		// A organização ainda não assinou, então não há cliente da Stripe para atualizar.
+		_, err = s.CreateOrganization(ctx, domain.Solicitante{UsuarioID: 1}, "Acme")
+		require.NoError(t, err)
+
+		dados, err := s.UpdateBillingDetails(ctx, 1, domain.DadosCobranca{Endereco: endereco, Documento: "111.444.777-35"})
+		require.NoError(t, err)
+		assert.Equal(t, "Av. Paulista, 1000", dados.Endereco.Linha1)
+		assert.Equal(t, "BR", dados.Endereco.Pais)
+		assert.Equal(t, "11144477735", dados.Documento)
+		assert.Equal(t, domain.DocumentoCPF, dados.TipoDocumento())
+		assert.Contains(t, cobranca.dados, int64(1))
+
+		lidos, err := s.GetBillingDetails(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, dados.Endereco, lidos.Endereco)
+		assert.Empty(t, provider.DadosCobranca)
+	})
+
+	t.Run("sucesso - envia os dados ao cliente da Stripe das organizações de que o usuário é dono", func(t *testing.T) {
+		s, repo, cobranca, provider := novo()
+		org, err := s.CreateOrganization(ctx, domain.Solicitante{UsuarioID: 1}, "Acme")
+		require.NoError(t, err)
+		repo.orgs[org.ID].StripeCustomerID = "cus_acme"
+		// Ana é só membro de Beta: o cliente dela não recebe os dados.
+		repo.orgs[2] = &domain.Organizacao{ID: 2, Nome: "Beta", StripeCustomerID: "cus_beta"}
+		repo.papeis[2] = map[int64]string{1: domain.PapelOrgMembro}
+
+		_, err = s.UpdateBillingDetails(ctx, 1, domain.DadosCobranca{Endereco: endereco, Documento: "11.222.333/0001-81"})
+		require.NoError(t, err)
+
+		require.Len(t, provider.DadosCobranca, 1)
+		enviados := provider.DadosCobranca["cus_acme"]
+		assert.Equal(t, billing.Endereco{Linha1: "Av. Paulista, 1000", Cidade: "São Paulo", Estado: "SP", CEP: "01310-100", Pais: "BR"}, enviados.Endereco)
+		assert.Equal(t, domain.DocumentoCNPJ, enviados.TipoDocumento)
+		assert.Equal(t, "11222333000181", enviados.Documento)
+		assert.Contains(t, cobranca.dados, int64(1))
+	})
+
+	t.Run("erro - provedor recusa os dados e nada é gravado", func(t *testing.T) {
+		s, repo, cobranca, provider := novo()
+		org, err := s.CreateOrganization(ctx, domain.Solicitante{UsuarioID: 1}, "Acme")
+		require.NoError(t, err)
+		repo.orgs[org.ID].StripeCustomerID = "cus_acme"
+		provider.Err = errors.New("tax id inválido")
+
+		_, err = s.UpdateBillingDetails(ctx, 1, domain.DadosCobranca{Endereco: endereco})
+		assert.ErrorIs(t, err, ErrProvedorPagamento)
+		assert.Empty(t, cobranca.dados)
+	})
+
+	t.Run("erro - endereço incompleto e documento inválido", func(t *testing.T) {
+		s, _, cobranca, _ := novo()
+
+		_, err := s.UpdateBillingDetails(ctx, 1, domain.DadosCobranca{Endereco: domain.Endereco{Pais: "Brasil"}, Documento: "111.444.777-00"})
+		var domErr *domain.Error
+		require.ErrorAs(t, err, &domErr)
+		assert.Equal(t, ErrDadosCobrancaInvalidos.Code, domErr.Code)
+		campos := make([]string, len(domErr.Fields))
+		for i, f := range domErr.Fields {
+			campos[i] = f.Field
+		}
+		assert.ElementsMatch(t, []string{"endereco.linha1", "endereco.cidade", "endereco.cep", "endereco.pais", "documento"}, campos)
+		assert.Empty(t, cobranca.dados)
+	})
+
+	t.Run("erro - checkout com endereço e idioma ainda exige o e-mail verificado", func(t *testing.T) {
+		s, repo, _, _ := novo()
+		s.WithPlano(PlanoCheckout{Precos: map[string]string{"brl": "price_brl"}, MoedaPadrao: "brl", ImpostoAutomatico: true})
+		org, err := s.CreateOrganization(ctx, domain.Solicitante{UsuarioID: 1}, "Acme")
+		require.NoError(t, err)
+		repo.usuarios.usuarios[0].EmailVerifiedAt = nil
+
+		_, err = s.CreateCheckoutSession(ctx, domain.Solicitante{UsuarioID: 1}, org.ID, domain.PedidoCheckout{Idioma: "pt-BR"})
+		assert.ErrorIs(t, err, ErrEmailNaoVerificado)
+		_, err = s.CreateCheckoutSession(ctx, domain.Solicitante{UsuarioID: 1}, org.ID, domain.PedidoCheckout{Assentos: -1})
+		assert.ErrorIs(t, err, ErrDadosOrganizacaoInvalidos)
+	})
+}
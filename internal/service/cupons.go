@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/promotioncode"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// ErrCupomInvalido indica um código promocional que não existe, não está ativo, expirou ou
+// atingiu o limite de resgates.
+var ErrCupomInvalido = &domain.Error{Code: "cupom_invalido", Status: http.StatusBadRequest, Title: "código promocional inválido ou expirado"}
+
+// WithCupons habilita os códigos promocionais no checkout, validados contra o espelho local
+// dos promotion codes da Stripe, que os webhooks e SincronizarCupons mantêm atualizado. Sem
+// ele, o checkout recusa códigos informados (o cliente ainda pode digitar um na Stripe).
+func (s *OrganizacaoService) WithCupons(repo repository.CupomRepository) *OrganizacaoService {
+	s.cupons = repo
+	return s
+}
+
+// SincronizarCupons copia para o banco todos os promotion codes da Stripe, ativos ou não.
+// Serve para a carga inicial e para corrigir webhooks perdidos; devolve quantos foram gravados.
+func (s *OrganizacaoService) SincronizarCupons(ctx context.Context) (n int, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.SincronizarCupons")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	params := &stripe.PromotionCodeListParams{}
+	params.Limit = stripe.Int64(100)
+	params.Context = ctx
+	start := time.Now()
+	it := promotioncode.List(params)
+	for it.Next() {
+		if err := s.cupons.Salvar(ctx, cupomDaStripe(it.PromotionCode())); err != nil {
+			return n, err
+		}
+		n++
+	}
+	metrics.ObservarStripe("promotion_code.list", start, it.Err())
+	if err := it.Err(); err != nil {
+		return n, ErrProvedorPagamento.Wrap(err)
+	}
+	return n, nil
+}
+
+// validarCupom busca o código informado no checkout e confere se ele ainda pode ser resgatado.
+func (s *OrganizacaoService) validarCupom(ctx context.Context, codigo string) (*domain.Cupom, error) {
+	if s.cupons == nil {
+		return nil, ErrCupomInvalido.WithDetail("o checkout não aceita códigos promocionais")
+	}
+	cupom, err := s.cupons.Buscar(ctx, codigo)
+	if err != nil {
+		return nil, err
+	}
+	if !cupom.Valido(s.agora()) {
+		return nil, ErrCupomInvalido.WithFields(domain.FieldError{Field: "codigo_promocional", Code: "invalido",
+			Message: "não existe, não está ativo, expirou ou atingiu o limite de resgates"})
+	}
+	return cupom, nil
+}
+
+// espelharCupom aplica ao banco os webhooks de promotion codes e cupons da Stripe.
+func (s *OrganizacaoService) espelharCupom(ctx context.Context, event stripe.Event) error {
+	if event.Type == "coupon.deleted" {
+		var cupom stripe.Coupon
+		if err := json.Unmarshal(event.Data.Raw, &cupom); err != nil {
+			return err
+		}
+		return s.cupons.DesativarCupom(ctx, cupom.ID)
+	}
+	var pc stripe.PromotionCode
+	if err := json.Unmarshal(event.Data.Raw, &pc); err != nil {
+		return err
+	}
+	return s.cupons.Salvar(ctx, cupomDaStripe(&pc))
+}
+
+// codigoDoDesconto devolve o código promocional do desconto em vigor na assinatura, o ID do
+// promotion code e o ID do cupom da Stripe por trás dele. Um cupom aplicado sem código (ex:
+// pelo Dashboard) é identificado pelo ID do cupom.
+func (s *OrganizacaoService) codigoDoDesconto(ctx context.Context, sub *stripe.Subscription) (codigo, promoID, cupomID string, err error) {
+	d := sub.Discount
+	if d == nil {
+		return "", "", "", nil
+	}
+	if d.Coupon != nil {
+		cupomID = d.Coupon.ID
+	}
+	switch {
+	case d.PromotionCode == nil || d.PromotionCode.ID == "":
+		return cupomID, "", cupomID, nil
+	case d.PromotionCode.Code != "":
+		return d.PromotionCode.Code, d.PromotionCode.ID, cupomID, nil
+	}
+	// Nos webhooks o promotion code vem só com o ID; o texto do código está no espelho local.
+	promoID = d.PromotionCode.ID
+	if s.cupons != nil {
+		cupom, err := s.cupons.BuscarPorStripeID(ctx, promoID)
+		if err != nil {
+			return "", "", "", err
+		}
+		if cupom != nil {
+			if cupomID == "" {
+				cupomID = cupom.StripeCouponID
+			}
+			return cupom.Codigo, promoID, cupomID, nil
+		}
+	}
+	return promoID, promoID, cupomID, nil
+}
+
+// registrarResgate conta o resgate do código pela assinatura. A métrica é rotulada pelo ID do
+// cupom da Stripe, e não pelo código, que é digitado pelo cliente e multiplicaria as séries;
+// a contagem por código fica no espelho local, em dia até a Stripe informar o total no próximo
+// promotion_code.updated ou SincronizarCupons.
+func (s *OrganizacaoService) registrarResgate(ctx context.Context, codigo, promoID, cupomID string) {
+	if cupomID == "" {
+		cupomID = "unknown"
+	}
+	metrics.CouponRedemptions.WithLabelValues(cupomID).Inc()
+	if s.cupons == nil || promoID == "" {
+		return
+	}
+	if err := s.cupons.RegistrarResgate(ctx, promoID); err != nil && !errors.Is(err, repository.ErrNaoEncontrado) {
+		logging.FromContext(ctx).WarnContext(ctx, "Falha ao contar o resgate do código promocional", "codigo", codigo, "error", err)
+	}
+}
+
+// cupomDaStripe converte um promotion code da Stripe, com o cupom expandido, para o espelho
+// local. O código expira no que vier primeiro entre a validade dele e a do cupom.
+func cupomDaStripe(pc *stripe.PromotionCode) domain.Cupom {
+	c := domain.Cupom{
+		Codigo:                pc.Code,
+		StripePromotionCodeID: pc.ID,
+		Ativo:                 pc.Active,
+		MaxResgates:           pc.MaxRedemptions,
+		Resgates:              pc.TimesRedeemed,
+	}
+	if pc.ExpiresAt > 0 {
+		t := time.Unix(pc.ExpiresAt, 0).UTC()
+		c.ExpiresAt = &t
+	}
+	if cp := pc.Coupon; cp != nil {
+		c.StripeCouponID = cp.ID
+		c.Nome = cp.Name
+		c.PercentOff = cp.PercentOff
+		c.AmountOff = cp.AmountOff
+		c.Moeda = string(cp.Currency)
+		c.Duracao = string(cp.Duration)
+		c.DuracaoMeses = cp.DurationInMonths
+		c.Ativo = c.Ativo && cp.Valid
+		if cp.RedeemBy > 0 {
+			t := time.Unix(cp.RedeemBy, 0).UTC()
+			if c.ExpiresAt == nil || t.Before(*c.ExpiresAt) {
+				c.ExpiresAt = &t
+			}
+		}
+	}
+	return c
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/webhook"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// cupomRepoFake guarda os códigos promocionais em memória, pelo ID do promotion code.
+type cupomRepoFake struct {
+	repository.CupomRepository
+	cupons map[string]*domain.Cupom
+}
+
+func (f *cupomRepoFake) Salvar(ctx context.Context, cupom domain.Cupom) error {
+	f.cupons[cupom.StripePromotionCodeID] = &cupom
+	return nil
+}
+
+func (f *cupomRepoFake) Buscar(ctx context.Context, codigo string) (*domain.Cupom, error) {
+	for _, c := range f.cupons {
+		if strings.EqualFold(c.Codigo, codigo) {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *cupomRepoFake) BuscarPorStripeID(ctx context.Context, id string) (*domain.Cupom, error) {
+	return f.cupons[id], nil
+}
+
+func (f *cupomRepoFake) RegistrarResgate(ctx context.Context, id string) error {
+	c, ok := f.cupons[id]
+	if !ok {
+		return repository.ErrNaoEncontrado
+	}
+	c.Resgates++
+	return nil
+}
+
+func TestOrganizacaoService_Cupons(t *testing.T) {
+	ctx := context.Background()
+	verificado := time.Now()
+	ontem := time.Now().Add(-24 * time.Hour)
+	ana := domain.Solicitante{UsuarioID: 1}
+	novo := func() (*OrganizacaoService, *orgRepoFake, *cupomRepoFake) {
+		usuarios := &usuariosOIDCFake{usuarios: []domain.Usuario{{ID: 1, Nome: "Ana", Email: "ana@email.com", EmailVerifiedAt: &verificado}}}
+		repo, cupons := novoOrgRepoFake(usuarios), &cupomRepoFake{cupons: map[string]*domain.Cupom{
+			"promo_1": {Codigo: "BEMVINDO10", StripePromotionCodeID: "promo_1", StripeCouponID: "cpn_bemvindo", PercentOff: 10, Ativo: true},
+			"promo_2": {Codigo: "VENCIDO", StripePromotionCodeID: "promo_2", Ativo: true, ExpiresAt: &ontem},
+			"promo_3": {Codigo: "ESGOTADO", StripePromotionCodeID: "promo_3", Ativo: true, MaxResgates: 2, Resgates: 2},
+		}}
+		s := NewOrganizacaoService(repo, usuarios, &mailerFake{}, "http://app.local").WithCupons(cupons)
+		return s, repo, cupons
+	}
+
+	t.Run("erro - checkout recusa código desconhecido, expirado ou esgotado", func(t *testing.T) {
+		s, _, _ := novo()
+		org, err := s.CreateOrganization(ctx, ana, "Acme")
+		require.NoError(t, err)
+
+		for _, codigo := range []string{"NAOEXISTE", "VENCIDO", "esgotado"} {
+			_, err = s.CreateCheckoutSession(ctx, ana, org.ID, domain.PedidoCheckout{CodigoPromocional: codigo})
+			assert.ErrorIs(t, err, ErrCupomInvalido, codigo)
+		}
+
+		s.cupons = nil
+		_, err = s.CreateCheckoutSession(ctx, ana, org.ID, domain.PedidoCheckout{CodigoPromocional: "BEMVINDO10"})
+		assert.ErrorIs(t, err, ErrCupomInvalido, "sem WithCupons nenhum código é aceito")
+	})
+
+	t.Run("sucesso - registra o código da assinatura e conta o resgate uma só vez", func(t *testing.T) {
+		s, repo, cupons := novo()
+		org, err := s.CreateOrganization(ctx, ana, "Acme")
+		require.NoError(t, err)
+		repo.orgs[org.ID].StripeCustomerID = "cus_acme"
+
+		sub := &stripe.Subscription{ID: "sub_1", Status: stripe.SubscriptionStatusActive,
+			Discount: &stripe.Discount{PromotionCode: &stripe.PromotionCode{ID: "promo_1"}}}
+		_, err = s.sincronizarAssinatura(ctx, "cus_acme", sub)
+		require.NoError(t, err)
+		_, err = s.sincronizarAssinatura(ctx, "cus_acme", sub)
+		require.NoError(t, err)
+
+		assert.Equal(t, "BEMVINDO10", repo.orgs[org.ID].Cupom)
+		assert.Equal(t, int64(1), cupons.cupons["promo_1"].Resgates)
+
+		sub.Discount = nil
+		_, err = s.sincronizarAssinatura(ctx, "cus_acme", sub)
+		require.NoError(t, err)
+		assert.Empty(t, repo.orgs[org.ID].Cupom, "o desconto terminou")
+	})
+
+	t.Run("sucesso - o resgate é identificado pelo cupom da Stripe, e não pelo código", func(t *testing.T) {
+		s, _, _ := novo()
+		casos := []struct {
+			nome     string
+			desconto *stripe.Discount
+			codigo   string
+			cupomID  string
+		}{
+			{"código do espelho local", &stripe.Discount{PromotionCode: &stripe.PromotionCode{ID: "promo_1"}}, "BEMVINDO10", "cpn_bemvindo"},
+			{"cupom do desconto", &stripe.Discount{Coupon: &stripe.Coupon{ID: "cpn_x"}, PromotionCode: &stripe.PromotionCode{ID: "promo_9", Code: "QUALQUER"}}, "QUALQUER", "cpn_x"},
+			{"cupom sem código", &stripe.Discount{Coupon: &stripe.Coupon{ID: "cpn_dash"}}, "cpn_dash", "cpn_dash"},
+		}
+		for _, c := range casos {
+			codigo, _, cupomID, err := s.codigoDoDesconto(ctx, &stripe.Subscription{Discount: c.desconto})
+			require.NoError(t, err, c.nome)
+			assert.Equal(t, c.codigo, codigo, c.nome)
+			assert.Equal(t, c.cupomID, cupomID, c.nome)
+		}
+	})
+
+	t.Run("sucesso - espelha os promotion codes recebidos por webhook", func(t *testing.T) {
+		s, _, cupons := novo()
+		t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_teste")
+		evento := func(tipo, objeto string) error {
+			payload := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Secret: "whsec_teste", Payload: []byte(fmt.Sprintf(
+				`{"id":"evt_1","object":"event","api_version":%q,"type":%q,"data":{"object":%s}}`, stripe.APIVersion, tipo, objeto))})
+			return s.HandleStripeWebhook(ctx, payload.Payload, payload.Header)
+		}
+
+		require.NoError(t, evento("promotion_code.created", `{"id":"promo_4","object":"promotion_code","code":"BLACKFRIDAY","active":true,
+			"max_redemptions":100,"times_redeemed":7,"expires_at":4102444800,
+			"coupon":{"id":"cpn_bf","object":"coupon","name":"Black Friday","amount_off":5000,"currency":"brl","duration":"once","valid":true,"redeem_by":4070908800}}`))
+
+		cupom := cupons.cupons["promo_4"]
+		require.NotNil(t, cupom)
+		assert.Equal(t, "BLACKFRIDAY", cupom.Codigo)
+		assert.Equal(t, "cpn_bf", cupom.StripeCouponID)
+		assert.Equal(t, int64(5000), cupom.AmountOff)
+		assert.Equal(t, "brl", cupom.Moeda)
+		assert.Equal(t, int64(7), cupom.Resgates)
+		assert.True(t, cupom.Ativo)
+		assert.Equal(t, int64(4070908800), cupom.ExpiresAt.Unix(), "vale a validade do cupom, anterior à do código")
+	})
+
+	t.Run("sucesso - assinatura de cliente sem organização é ignorada", func(t *testing.T) {
+		s, repo, _ := novo()
+		ignorado, err := s.sincronizarAssinatura(ctx, "cus_desconhecido", &stripe.Subscription{ID: "sub_x"})
+		require.NoError(t, err)
+		assert.True(t, ignorado, "o webhook deve contar o evento com outcome ignored")
+		assert.Empty(t, repo.orgs)
+
+		org, err := s.CreateOrganization(ctx, ana, "Acme")
+		require.NoError(t, err)
+		repo.orgs[org.ID].StripeCustomerID = "cus_acme"
+		ignorado, err = s.sincronizarAssinatura(ctx, "cus_acme", &stripe.Subscription{ID: "sub_1"})
+		require.NoError(t, err)
+		assert.False(t, ignorado)
+	})
+}
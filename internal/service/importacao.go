@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// Limites da importação em lote.
+const (
+	tamanhoLoteImportacao = 500  // Usuários gravados por transação.
+	maxErrosImportacao    = 1000 // Erros por linha listados no relatório.
+)
+
+// LeitorUsuarios entrega, um a um, os usuários de um arquivo de importação.
+//
+// Ler devolve a linha do arquivo (a partir de 1) e o usuário lido. Se só aquela linha
+// estiver malformada, o motivo vem em invalida e a leitura pode continuar; err indica
+// uma falha que impede ler o restante do arquivo. Ao fim do arquivo, err é io.EOF.
+type LeitorUsuarios interface {
+	Ler() (linha int, usuario domain.Usuario, invalida *domain.Error, err error)
+}
+
+// ImportUsers importa os usuários lidos de leitor. Cada linha passa pelas mesmas regras
+// de CreateUser; as válidas são gravadas em lotes, cada lote em uma transação, e as
+// inválidas entram no relatório com o motivo da rejeição. Um e-mail já cadastrado, ou
+// repetido no arquivo, rejeita a linha em vez de desfazer o lote inteiro.
+//
+// Se a importação for interrompida por um erro fatal (arquivo corrompido, falha no banco),
+// os lotes já gravados permanecem e o erro devolvido traz quantos usuários foram importados.
+func (s *UsuarioService) ImportUsers(ctx context.Context, leitor LeitorUsuarios) (_ *domain.RelatorioImportacao, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.ImportUsers")
+	relatorio := &domain.RelatorioImportacao{Erros: []domain.ErroLinha{}}
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("import.total", relatorio.Total),
+			attribute.Int("import.importados", relatorio.Importados),
+			attribute.Int("import.rejeitados", relatorio.Rejeitados),
+		)
+		telemetry.EndSpan(span, err)
+	}()
+
+	lote := make([]domain.Usuario, 0, tamanhoLoteImportacao)
+	emails := make(map[string]bool) // E-mails das linhas aceitas até aqui.
+	gravar := func() error {
+		if len(lote) == 0 {
+			return nil
+		}
+		if _, err := s.repo.CreateMany(ctx, lote); err != nil {
+			return err
+		}
+		relatorio.Importados += len(lote)
+		metrics.UserSignups.Add(float64(len(lote)))
+		lote = lote[:0]
+		return nil
+	}
+	interromper := func(err error) error {
+		var de *domain.Error
+		if !errors.As(err, &de) {
+			de = domain.ErrInterno.Wrap(err)
+		}
+		return de.WithDetails(map[string]any{"importados": relatorio.Importados, "linhas_lidas": relatorio.Total})
+	}
+
+	for {
+		linha, usuario, erroLinha, err := leitor.Ler()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, interromper(err)
+		}
+		relatorio.Total++
+
+		if erroLinha == nil {
+			normalizarUsuario(&usuario)
+			if err := validarUsuario(usuario); err != nil {
+				errors.As(err, &erroLinha)
+			}
+		}
+		if erroLinha == nil {
+			emUso := emails[usuario.Email]
+			if !emUso {
+				existente, err := s.repo.GetByEmail(ctx, usuario.Email)
+				if err != nil {
+					return nil, interromper(err)
+				}
+				emUso = existente != nil
+			}
+			if emUso {
+				erroLinha = ErrEmailEmUso.WithFields(domain.FieldError{Field: "email", Code: "email_em_uso", Message: "o e-mail já é usado por outro usuário"})
+			}
+		}
+		if erroLinha != nil {
+			relatorio.Rejeitados++
+			if len(relatorio.Erros) < maxErrosImportacao {
+				relatorio.Erros = append(relatorio.Erros, domain.ErroLinha{Linha: linha, Detail: erroLinha.Detail, Erros: erroLinha.Fields})
+			} else {
+				relatorio.ErrosOmitidos++
+			}
+			continue
+		}
+
+		lote = append(lote, usuario)
+		emails[usuario.Email] = true
+		if len(lote) == tamanhoLoteImportacao {
+			if err := gravar(); err != nil {
+				return nil, interromper(err)
+			}
+		}
+	}
+	if err := gravar(); err != nil {
+		return nil, interromper(err)
+	}
+	return relatorio, nil
+}
+
+// ExportUsers percorre os usuários que atendem ao filtro, em ordem de ID, chamando fn
+// para cada um sem carregar a lista inteira em memória.
+func (s *UsuarioService) ExportUsers(ctx context.Context, filtro domain.FiltroUsuarios, fn func(domain.Usuario) error) (err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.ExportUsers")
+	total := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("export.total", total))
+		telemetry.EndSpan(span, err)
+	}()
+
+	return s.repo.Iterate(ctx, filtro, func(u domain.Usuario) error {
+		total++
+		return fn(u)
+	})
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// importRepoFake registra os lotes gravados por ImportUsers.
+type importRepoFake struct {
+	repository.UsuarioRepository
+	lotes      [][]domain.Usuario
+	falha      error
+	existentes map[string]bool // E-mails já cadastrados.
+}
+
+func (f *importRepoFake) GetByEmail(ctx context.Context, email string) (*domain.Usuario, error) {
+	if f.existentes[email] {
+		return &domain.Usuario{Email: email}, nil
+	}
+	return nil, nil
+}
+
+func (f *importRepoFake) CreateMany(ctx context.Context, usuarios []domain.Usuario) ([]int64, error) {
+	if f.falha != nil {
+		return nil, f.falha
+	}
+	f.lotes = append(f.lotes, append([]domain.Usuario(nil), usuarios...))
+	return make([]int64, len(usuarios)), nil
+}
+
+// leitorFake entrega as linhas em ordem; uma linha com invalida simula erro de formato.
+type leitorFake struct {
+	linhas []linhaFake
+	pos    int
+}
+
+type linhaFake struct {
+	usuario  domain.Usuario
+	invalida *domain.Error
+	err      error
+}
+
+func (l *leitorFake) Ler() (int, domain.Usuario, *domain.Error, error) {
+	if l.pos == len(l.linhas) {
+		return 0, domain.Usuario{}, nil, io.EOF
+	}
+	l.pos++
+	linha := l.linhas[l.pos-1]
+	return l.pos, linha.usuario, linha.invalida, linha.err
+}
+
+func TestUsuarioService_ImportUsers(t *testing.T) {
+	t.Run("sucesso - grava as linhas válidas e reporta as inválidas", func(t *testing.T) {
+		repo := &importRepoFake{}
+		s := NewUsuarioService(repo)
+		leitor := &leitorFake{linhas: []linhaFake{
+			{usuario: domain.Usuario{Nome: "  Ana   Souza ", Email: "ana@email.com"}},
+			{usuario: domain.Usuario{Nome: "B", Email: "bia@email.com"}},
+			{invalida: domain.ErrRequisicaoInvalida.WithDetail("número de colunas diferente do cabeçalho")},
+			{usuario: domain.Usuario{Nome: "Caio", Email: "caio@email.com"}},
+		}}
+
+		relatorio, err := s.ImportUsers(context.Background(), leitor)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, relatorio.Total)
+		assert.Equal(t, 2, relatorio.Importados)
+		assert.Equal(t, 2, relatorio.Rejeitados)
+		assert.Equal(t, []domain.ErroLinha{
+			{Linha: 2, Erros: []domain.FieldError{{Field: "nome", Code: "muito_curto", Message: "deve ter ao menos 2 caracteres"}}},
+			{Linha: 3, Detail: "número de colunas diferente do cabeçalho"},
+		}, relatorio.Erros)
+		assert.Equal(t, [][]domain.Usuario{{
+			{Nome: "Ana Souza", Email: "ana@email.com"},
+			{Nome: "Caio", Email: "caio@email.com"},
+		}}, repo.lotes)
+	})
+
+	t.Run("sucesso - rejeita e-mails já cadastrados ou repetidos no arquivo", func(t *testing.T) {
+		repo := &importRepoFake{existentes: map[string]bool{"ana@email.com": true}}
+		s := NewUsuarioService(repo)
+		leitor := &leitorFake{linhas: []linhaFake{
+			{usuario: domain.Usuario{Nome: "Ana", Email: "ANA@email.com"}},
+			{usuario: domain.Usuario{Nome: "Bia", Email: "Bia@Email.com"}},
+			{usuario: domain.Usuario{Nome: "Bia", Email: "bia@email.com "}},
+		}}
+
+		relatorio, err := s.ImportUsers(context.Background(), leitor)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, relatorio.Importados)
+		assert.Equal(t, 2, relatorio.Rejeitados)
+		emUso := []domain.FieldError{{Field: "email", Code: "email_em_uso", Message: "o e-mail já é usado por outro usuário"}}
+		assert.Equal(t, []domain.ErroLinha{{Linha: 1, Erros: emUso}, {Linha: 3, Erros: emUso}}, relatorio.Erros)
+		assert.Equal(t, [][]domain.Usuario{{{Nome: "Bia", Email: "bia@email.com"}}}, repo.lotes)
+	})
+
+	t.Run("sucesso - grava em lotes do tamanho configurado", func(t *testing.T) {
+		repo := &importRepoFake{}
+		s := NewUsuarioService(repo)
+		leitor := &leitorFake{}
+		for i := 0; i < tamanhoLoteImportacao+1; i++ {
+			leitor.linhas = append(leitor.linhas, linhaFake{usuario: domain.Usuario{Nome: "Ana", Email: fmt.Sprintf("ana%d@email.com", i)}})
+		}
+
+		relatorio, err := s.ImportUsers(context.Background(), leitor)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tamanhoLoteImportacao+1, relatorio.Importados)
+		assert.Len(t, repo.lotes, 2)
+		assert.Len(t, repo.lotes[1], 1)
+	})
+
+	t.Run("erro - falha de leitura interrompe e informa o que já foi importado", func(t *testing.T) {
+		s := NewUsuarioService(&importRepoFake{})
+		leitor := &leitorFake{linhas: []linhaFake{
+			{usuario: domain.Usuario{Nome: "Ana", Email: "ana@email.com"}},
+			{err: domain.ErrRequisicaoInvalida.WithDetail("CSV malformado")},
+		}}
+
+		_, err := s.ImportUsers(context.Background(), leitor)
+
+		var de *domain.Error
+		assert.True(t, errors.As(err, &de))
+		assert.Equal(t, domain.ErrRequisicaoInvalida.Code, de.Code)
+		assert.Equal(t, map[string]any{"importados": 0, "linhas_lidas": 1}, de.Details)
+	})
+
+	t.Run("erro - falha no banco vira erro interno", func(t *testing.T) {
+		s := NewUsuarioService(&importRepoFake{falha: errors.New("disk I/O error")})
+		leitor := &leitorFake{linhas: []linhaFake{{usuario: domain.Usuario{Nome: "Ana", Email: "ana@email.com"}}}}
+
+		_, err := s.ImportUsers(context.Background(), leitor)
+
+		assert.True(t, errors.Is(err, domain.ErrInterno))
+	})
+}
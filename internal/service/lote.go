@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// maxOperacoesLote limita a quantidade de operações de um lote.
+const maxOperacoesLote = 1000
+
+var (
+	ErrLoteInvalido = &domain.Error{Code: "lote_invalido", Status: http.StatusBadRequest, Title: "lote de operações inválido"}
+	// ErrOperacaoNaoAplicada marca, no modo atômico, as operações desfeitas por causa da falha de outra.
+	ErrOperacaoNaoAplicada = &domain.Error{Code: "operacao_nao_aplicada", Status: http.StatusFailedDependency, Title: "operação não aplicada porque outra operação do lote falhou"}
+)
+
+// errAbortarLote interrompe a transação de um lote atômico após a primeira falha.
+var errAbortarLote = errors.New("lote abortado")
+
+// BatchUsers executa um lote de criações, atualizações e remoções em uma única transação.
+//
+// No modo atômico, qualquer falha (inclusive de validação) desfaz o lote inteiro: a operação
+// que falhou traz o seu erro e as demais são marcadas com ErrOperacaoNaoAplicada. No modo
+// parcial, cada operação roda em um savepoint e as que falham não impedem as outras.
+// As regras de cada operação são as mesmas de CreateUser, UpdateUser e DeleteUser, inclusive
+// a revogação das sessões dos usuários removidos, feita depois que o lote é gravado.
+func (s *UsuarioService) BatchUsers(ctx context.Context, modo string, operacoes []domain.OperacaoLote) (_ *domain.ResultadoLote, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.BatchUsers", trace.WithAttributes(
+		attribute.String("batch.mode", modo),
+		attribute.Int("batch.size", len(operacoes)),
+	))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if modo == "" {
+		modo = domain.ModoAtomico
+	}
+	if modo != domain.ModoAtomico && modo != domain.ModoParcial {
+		return nil, ErrLoteInvalido.WithFields(domain.FieldError{Field: "modo", Code: "modo_invalido", Message: "use atomico ou parcial"})
+	}
+	if len(operacoes) == 0 {
+		return nil, ErrLoteInvalido.WithFields(domain.FieldError{Field: "operacoes", Code: "obrigatorio", Message: "informe ao menos uma operação"})
+	}
+	if len(operacoes) > maxOperacoesLote {
+		return nil, ErrLoteInvalido.WithFields(domain.FieldError{Field: "operacoes", Code: "muitas_operacoes", Message: fmt.Sprintf("no máximo %d operações por lote", maxOperacoesLote)})
+	}
+
+	resultado := &domain.ResultadoLote{Modo: modo, Resultados: make([]domain.ResultadoOperacao, len(operacoes))}
+	atomico := modo == domain.ModoAtomico
+
+	// A validação vem antes da transação: no modo atômico, um lote com dados inválidos
+	// é recusado sem tocar no banco.
+	validas := make([]bool, len(operacoes))
+	algumaInvalida := false
+	for i := range operacoes {
+		if err := prepararOperacao(&operacoes[i]); err != nil {
+			resultado.Resultados[i] = falhaOperacao(err)
+			algumaInvalida = true
+			continue
+		}
+		validas[i] = true
+	}
+	if atomico && algumaInvalida {
+		marcarNaoAplicadas(resultado)
+		return resultado, nil
+	}
+
+	criados := 0
+	err = s.repo.ExecutarLote(ctx, func(lote repository.Lote) error {
+		criados = 0
+		for i, op := range operacoes {
+			if !validas[i] {
+				continue
+			}
+			var r domain.ResultadoOperacao
+			err := lote.Isolar(ctx, func() error {
+				var err error
+				r, err = executarOperacao(ctx, lote, op)
+				return err
+			})
+			if err != nil {
+				resultado.Resultados[i] = falhaOperacao(traduzirErroLote(err))
+				if atomico {
+					return errAbortarLote
+				}
+				continue
+			}
+			resultado.Resultados[i] = r
+			if op.Op == domain.OpCriar {
+				criados++
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errAbortarLote) {
+		marcarNaoAplicadas(resultado)
+		return resultado, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resultado.Aplicado = true
+	metrics.UserSignups.Add(float64(criados))
+	for i, op := range operacoes {
+		if op.Op == domain.OpRemover && validas[i] && resultado.Resultados[i].Err == nil {
+			s.revogarSessoes(ctx, op.ID)
+		}
+	}
+	return resultado, nil
+}
+
+// prepararOperacao confere os campos exigidos pelo tipo da operação e normaliza e valida os dados do usuário.
+func prepararOperacao(op *domain.OperacaoLote) error {
+	var erros []domain.FieldError
+	switch op.Op {
+	case domain.OpCriar:
+		if op.ID != 0 || op.Versao != 0 {
+			erros = append(erros, domain.FieldError{Field: "id", Code: "nao_permitido", Message: "create não aceita id nem versao"})
+		}
+	case domain.OpAtualizar, domain.OpRemover:
+		if op.ID <= 0 {
+			erros = append(erros, domain.FieldError{Field: "id", Code: "obrigatorio", Message: "informe o id do usuário"})
+		}
+	default:
+		return ErrLoteInvalido.WithFields(domain.FieldError{Field: "op", Code: "operacao_invalida", Message: "use create, update ou delete"})
+	}
+
+	precisaUsuario := op.Op != domain.OpRemover
+	if precisaUsuario && op.Usuario == nil {
+		erros = append(erros, domain.FieldError{Field: "usuario", Code: "obrigatorio", Message: "informe os dados do usuário"})
+	}
+	if !precisaUsuario && op.Usuario != nil {
+		erros = append(erros, domain.FieldError{Field: "usuario", Code: "nao_permitido", Message: "delete não aceita dados do usuário"})
+	}
+	if len(erros) > 0 {
+		return ErrLoteInvalido.WithFields(erros...)
+	}
+
+	if precisaUsuario {
+		normalizarUsuario(op.Usuario)
+		return validarUsuario(*op.Usuario)
+	}
+	return nil
+}
+
+// executarOperacao aplica uma operação já validada dentro da transação do lote.
+func executarOperacao(ctx context.Context, lote repository.Lote, op domain.OperacaoLote) (domain.ResultadoOperacao, error) {
+	switch op.Op {
+	case domain.OpCriar:
+		id, err := lote.Create(ctx, *op.Usuario)
+		return domain.ResultadoOperacao{Status: http.StatusCreated, ID: id, Versao: 1}, err
+	case domain.OpAtualizar:
+		usuario := *op.Usuario
+		usuario.Version = op.Versao
+		versao, err := lote.Update(ctx, op.ID, usuario)
+		return domain.ResultadoOperacao{Status: http.StatusOK, ID: op.ID, Versao: versao}, err
+	default:
+		err := lote.Delete(ctx, op.ID, op.Versao)
+		return domain.ResultadoOperacao{Status: http.StatusNoContent, ID: op.ID}, err
+	}
+}
+
+// traduzirErroLote converte os erros do repositório nos erros de domínio das operações individuais.
+func traduzirErroLote(err error) error {
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return ErrUsuarioNaoEncontrado.Wrap(err)
+	}
+	return traduzirErroEscrita(err)
+}
+
+// falhaOperacao monta o resultado de uma operação que falhou. Erros que não são de domínio
+// viram erro interno, com o mesmo status que a operação teria se fosse feita isoladamente.
+func falhaOperacao(err error) domain.ResultadoOperacao {
+	var de *domain.Error
+	if !errors.As(err, &de) {
+		de = domain.ErrInterno.Wrap(err)
+	}
+	return domain.ResultadoOperacao{Status: de.Status, Err: err}
+}
+
+// marcarNaoAplicadas ajusta o resultado de um lote atômico desfeito: as operações que
+// haviam sido aplicadas (ou nem chegaram a ser executadas) passam a ErrOperacaoNaoAplicada.
+func marcarNaoAplicadas(resultado *domain.ResultadoLote) {
+	resultado.Aplicado = false
+	for i, r := range resultado.Resultados {
+		if r.Err == nil {
+			resultado.Resultados[i] = domain.ResultadoOperacao{Status: ErrOperacaoNaoAplicada.Status, Err: ErrOperacaoNaoAplicada}
+		}
+	}
+}
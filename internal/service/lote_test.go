@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// loteRepoFake simula a transação do lote: as escritas só valem se fn terminar sem erro.
+type loteRepoFake struct {
+	repository.UsuarioRepository
+	versoes   map[int64]int64 // id -> versão atual
+	donos     map[int64]bool  // ids que o trigger do banco não deixa remover
+	proximoID int64
+	commits   int
+}
+
+func (f *loteRepoFake) ExecutarLote(ctx context.Context, fn func(repository.Lote) error) error {
+	lote := &loteFake{repo: f, versoes: make(map[int64]int64)}
+	for id, v := range f.versoes {
+		lote.versoes[id] = v
+	}
+	if err := fn(lote); err != nil {
+		return err
+	}
+	f.versoes = lote.versoes
+	f.commits++
+	return nil
+}
+
+type loteFake struct {
+	repo    *loteRepoFake
+	versoes map[int64]int64
+}
+
+func (l *loteFake) Create(ctx context.Context, usuario domain.Usuario) (int64, error) {
+	l.repo.proximoID++
+	l.versoes[l.repo.proximoID] = 1
+	return l.repo.proximoID, nil
+}
+
+func (l *loteFake) Update(ctx context.Context, id int64, usuario domain.Usuario) (int64, error) {
+	atual, ok := l.versoes[id]
+	if !ok {
+		return 0, repository.ErrNaoEncontrado
+	}
+	if usuario.Version != 0 && usuario.Version != atual {
+		return 0, repository.ErrVersaoDesatualizada
+	}
+	l.versoes[id] = atual + 1
+	return atual + 1, nil
+}
+
+func (l *loteFake) Delete(ctx context.Context, id int64, version int64) error {
+	if l.repo.donos[id] {
+		return repository.ErrDonoDeOrganizacao
+	}
+	if _, ok := l.versoes[id]; !ok {
+		return repository.ErrNaoEncontrado
+	}
+	delete(l.versoes, id)
+	return nil
+}
+
+func (l *loteFake) Isolar(ctx context.Context, fn func() error) error { return fn() }
+
+func TestUsuarioService_BatchUsers(t *testing.T) {
+	ana := func() *domain.Usuario { return &domain.Usuario{Nome: "Ana Souza", Email: "ana@email.com"} }
+	novoRepo := func() *loteRepoFake {
+		return &loteRepoFake{versoes: map[int64]int64{1: 3}, proximoID: 1}
+	}
+
+	t.Run("sucesso - modo atômico aplica todas as operações", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		resultado, err := s.BatchUsers(context.Background(), "", []domain.OperacaoLote{
+			{Op: domain.OpCriar, Usuario: ana()},
+			{Op: domain.OpAtualizar, ID: 1, Versao: 3, Usuario: ana()},
+			{Op: domain.OpRemover, ID: 2},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, resultado.Aplicado)
+		assert.Equal(t, domain.ModoAtomico, resultado.Modo)
+		assert.Equal(t, []domain.ResultadoOperacao{
+			{Status: http.StatusCreated, ID: 2, Versao: 1},
+			{Status: http.StatusOK, ID: 1, Versao: 4},
+			{Status: http.StatusNoContent, ID: 2},
+		}, resultado.Resultados)
+		assert.Equal(t, map[int64]int64{1: 4}, repo.versoes)
+	})
+
+	t.Run("sucesso - modo atômico desfaz tudo na primeira falha", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		resultado, err := s.BatchUsers(context.Background(), domain.ModoAtomico, []domain.OperacaoLote{
+			{Op: domain.OpCriar, Usuario: ana()},
+			{Op: domain.OpAtualizar, ID: 1, Versao: 2, Usuario: ana()},
+			{Op: domain.OpRemover, ID: 1},
+		})
+
+		assert.NoError(t, err)
+		assert.False(t, resultado.Aplicado)
+		assert.Equal(t, 0, repo.commits)
+		assert.True(t, errors.Is(resultado.Resultados[0].Err, ErrOperacaoNaoAplicada))
+		assert.True(t, errors.Is(resultado.Resultados[1].Err, ErrVersaoDesatualizada))
+		assert.Equal(t, http.StatusPreconditionFailed, resultado.Resultados[1].Status)
+		assert.Equal(t, http.StatusFailedDependency, resultado.Resultados[2].Status)
+		assert.Equal(t, map[int64]int64{1: 3}, repo.versoes)
+	})
+
+	t.Run("sucesso - modo atômico com dados inválidos não abre transação", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		resultado, err := s.BatchUsers(context.Background(), domain.ModoAtomico, []domain.OperacaoLote{
+			{Op: domain.OpCriar, Usuario: ana()},
+			{Op: domain.OpCriar, Usuario: &domain.Usuario{Nome: "Ana", Email: "invalido"}},
+		})
+
+		assert.NoError(t, err)
+		assert.False(t, resultado.Aplicado)
+		assert.Equal(t, int64(1), repo.proximoID, "nenhuma escrita deve ter sido tentada")
+		assert.True(t, errors.Is(resultado.Resultados[1].Err, ErrDadosInvalidos))
+	})
+
+	t.Run("sucesso - modo parcial aplica as operações válidas", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		resultado, err := s.BatchUsers(context.Background(), domain.ModoParcial, []domain.OperacaoLote{
+			{Op: domain.OpRemover, ID: 99},
+			{Op: "upsert"},
+			{Op: domain.OpAtualizar, ID: 1, Usuario: ana()},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, resultado.Aplicado)
+		assert.Equal(t, http.StatusNotFound, resultado.Resultados[0].Status)
+		assert.True(t, errors.Is(resultado.Resultados[0].Err, ErrUsuarioNaoEncontrado))
+		assert.Equal(t, http.StatusBadRequest, resultado.Resultados[1].Status)
+		assert.Equal(t, domain.ResultadoOperacao{Status: http.StatusOK, ID: 1, Versao: 4}, resultado.Resultados[2])
+	})
+
+	t.Run("sucesso - as remoções aplicadas revogam as sessões dos usuários", func(t *testing.T) {
+		repo := novoRepo()
+		repo.versoes[2] = 1
+		sessoes := novoSessaoRepoFake()
+		for id := int64(1); id <= 3; id++ {
+			sessoes.sessoes[id] = &domain.Sessao{ID: id, UsuarioID: id}
+		}
+		s := NewUsuarioService(repo).WithSessoes(sessoes)
+
+		resultado, err := s.BatchUsers(context.Background(), domain.ModoParcial, []domain.OperacaoLote{
+			{Op: domain.OpRemover, ID: 1},
+			{Op: domain.OpRemover, ID: 3}, // Não existe: a sessão fica.
+			{Op: domain.OpRemover, ID: 2},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, resultado.Aplicado)
+		assert.Equal(t, domain.MotivoUsuarioRemovido, sessoes.sessoes[1].MotivoRevogacao)
+		assert.Equal(t, domain.MotivoUsuarioRemovido, sessoes.sessoes[2].MotivoRevogacao)
+		assert.Nil(t, sessoes.sessoes[3].RevokedAt)
+	})
+
+	t.Run("sucesso - modo parcial recusa remover o dono de uma organização", func(t *testing.T) {
+		repo := novoRepo()
+		repo.donos = map[int64]bool{1: true}
+		s := NewUsuarioService(repo)
+
+		resultado, err := s.BatchUsers(context.Background(), domain.ModoParcial, []domain.OperacaoLote{
+			{Op: domain.OpRemover, ID: 1},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resultado.Resultados[0].Status)
+		assert.True(t, errors.Is(resultado.Resultados[0].Err, ErrDonoDeOrganizacao))
+		assert.Equal(t, map[int64]int64{1: 3}, repo.versoes)
+	})
+
+	t.Run("sucesso - lote atômico desfeito não revoga sessões", func(t *testing.T) {
+		sessoes := novoSessaoRepoFake()
+		sessoes.sessoes[1] = &domain.Sessao{ID: 1, UsuarioID: 1}
+		s := NewUsuarioService(novoRepo()).WithSessoes(sessoes)
+
+		resultado, err := s.BatchUsers(context.Background(), domain.ModoAtomico, []domain.OperacaoLote{
+			{Op: domain.OpRemover, ID: 1},
+			{Op: domain.OpRemover, ID: 99},
+		})
+
+		assert.NoError(t, err)
+		assert.False(t, resultado.Aplicado)
+		assert.Nil(t, sessoes.sessoes[1].RevokedAt)
+	})
+
+	t.Run("erro - modo desconhecido e lote vazio", func(t *testing.T) {
+		s := NewUsuarioService(novoRepo())
+
+		_, err := s.BatchUsers(context.Background(), "tudo", []domain.OperacaoLote{{Op: domain.OpRemover, ID: 1}})
+		assert.True(t, errors.Is(err, ErrLoteInvalido))
+
+		_, err = s.BatchUsers(context.Background(), domain.ModoParcial, nil)
+		assert.True(t, errors.Is(err, ErrLoteInvalido))
+	})
+}
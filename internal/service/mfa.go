@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+	"github.com/willjrcristo/go-sqlite-db/internal/tokens"
+	"github.com/willjrcristo/go-sqlite-db/internal/totp"
+)
+
+// Erros do login por senha e do segundo fator.
+var (
+	ErrCredenciaisInvalidas = &domain.Error{Code: "credenciais_invalidas", Status: http.StatusUnauthorized, Title: "e-mail ou senha incorretos"}
+	ErrDesafioMFAInvalido   = &domain.Error{Code: "desafio_mfa_invalido", Status: http.StatusUnauthorized, Title: "login expirado; entre de novo com e-mail e senha"}
+	ErrCodigoMFAInvalido    = &domain.Error{Code: "codigo_mfa_invalido", Status: http.StatusBadRequest, Title: "código de verificação inválido"}
+	ErrMFAJaAtivo           = &domain.Error{Code: "mfa_ja_ativo", Status: http.StatusConflict, Title: "a autenticação em dois fatores já está ativa"}
+	ErrMFANaoCadastrado     = &domain.Error{Code: "mfa_nao_cadastrado", Status: http.StatusConflict, Title: "a autenticação em dois fatores não está cadastrada"}
+	ErrMFAObrigatorio       = &domain.Error{Code: "mfa_obrigatorio", Status: http.StatusForbidden, Title: "o papel do usuário exige autenticação em dois fatores"}
+)
+
+const (
+	// finalidadeDesafioMFA é a do token devolvido pelo login com senha quando falta o segundo
+	// fator. Ele só serve para POST /auth/login/mfa.
+	finalidadeDesafioMFA = "desafio_mfa"
+	validadeDesafioMFA   = 5 * time.Minute
+
+	// Códigos de recuperação: 10 códigos de 10 caracteres em base32 (50 bits cada), exibidos
+	// como xxxxx-xxxxx.
+	totalCodigosRecuperacao  = 10
+	tamanhoCodigoRecuperacao = 10
+)
+
+// hashSenhaFalsa é verificada quando o e-mail não existe ou não tem senha, para que o tempo
+// de resposta do login não revele quais e-mails estão cadastrados.
+var hashSenhaFalsa = sync.OnceValue(func() string {
+	hash, _ := hashSenha("senha-de-quem-nao-existe")
+	return hash
+})
+
+// WithMFA habilita o segundo fator (TOTP). emissor é o nome exibido no aplicativo
+// autenticador; papeisObrigatorios são os papéis que só recebem os escopos do papel (veja
+// AuthenticateUser) depois de um login com o segundo fator. Sem ele, o login nunca pede o
+// segundo fator e as rotas de /auth/mfa não podem ser usadas.
+func (s *AuthService) WithMFA(repo repository.MFARepository, emissor string, papeisObrigatorios []string) *AuthService {
+	s.mfa = repo
+	s.emissorMFA = emissor
+	s.papeisComMFA = papeisObrigatorios
+	return s
+}
+
+// WithAuditoria registra na trilha de auditoria as ações sobre o segundo fator e o papel dos
+// usuários. Sem ela, as ações são apenas logadas.
+func (s *AuthService) WithAuditoria(repo repository.AuditoriaRepository) *AuthService {
+	s.auditoria = repo
+	return s
+}
+
+// Login autentica o usuário por e-mail e senha. Com MFA ativo, devolve o desafio a ser
+// concluído em LoginMFA no lugar do token de acesso.
+func (s *AuthService) Login(ctx context.Context, email, senha string, origem domain.OrigemSessao) (_ *domain.Login, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	usuario, err := s.repo.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return nil, err
+	}
+	hash := ""
+	if usuario != nil {
+		if hash, err = s.repo.GetPasswordHash(ctx, usuario.ID); err != nil {
+			return nil, err
+		}
+	}
+	if hash == "" {
+		verificarSenha(senha, hashSenhaFalsa())
+		return nil, ErrCredenciaisInvalidas
+	}
+	if !verificarSenha(senha, hash) {
+		return nil, ErrCredenciaisInvalidas
+	}
+	return s.concluirLogin(ctx, *usuario, origem)
+}
+
+// concluirLogin emite o token de acesso (e abre a sessão) do usuário já autenticado pelo
+// primeiro fator (senha ou provedor OIDC) ou, se ele tiver MFA ativo, o desafio do segundo fator.
+func (s *AuthService) concluirLogin(ctx context.Context, usuario domain.Usuario, origem domain.OrigemSessao) (*domain.Login, error) {
+	mfa, err := s.buscarMFA(ctx, usuario.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Ativo() {
+		token, err := s.tokens.Gerar(finalidadeDesafioMFA, usuario.ID, tokens.Vinculo(usuario.Email), validadeDesafioMFA)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.Login{UsuarioID: usuario.ID, MFANecessario: true, MFAToken: token, ExpiresIn: int(validadeDesafioMFA.Seconds())}, nil
+	}
+	login, err := s.emitirAcesso(ctx, usuario, false, origem)
+	if err != nil {
+		return nil, err
+	}
+	login.MFACadastroObrigatorio = s.exigeMFA(usuario.Papel)
+	return login, nil
+}
+
+// LoginMFA conclui o login com o token do desafio e um código do aplicativo autenticador ou
+// um código de recuperação. A sessão é aberta aqui, com a origem desta requisição.
+func (s *AuthService) LoginMFA(ctx context.Context, mfaToken, codigo string, origem domain.OrigemSessao) (_ *domain.Login, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.LoginMFA")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	claims, err := s.tokens.Validar(mfaToken, finalidadeDesafioMFA)
+	if err != nil {
+		return nil, ErrDesafioMFAInvalido
+	}
+	usuario, err := s.repo.GetByID(ctx, claims.UsuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil || tokens.Vinculo(usuario.Email) != claims.Vinculo {
+		return nil, ErrDesafioMFAInvalido
+	}
+	mfa, err := s.buscarMFA(ctx, usuario.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Ativo() {
+		return nil, ErrDesafioMFAInvalido
+	}
+	if err := s.verificarSegundoFator(ctx, *usuario, mfa, codigo); err != nil {
+		return nil, err
+	}
+	return s.emitirAcesso(ctx, *usuario, true, origem)
+}
+
+// EnrollMFA inicia o cadastro do segundo fator: gera o segredo e a URI para o QR code. O
+// cadastro só vale depois de ConfirmMFA; até lá, pedir de novo troca o segredo.
+func (s *AuthService) EnrollMFA(ctx context.Context, usuario domain.Usuario) (_ *domain.CadastroMFA, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.EnrollMFA", trace.WithAttributes(attribute.Int64("usuario.id", usuario.ID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	atual, err := s.buscarMFA(ctx, usuario.ID)
+	if err != nil {
+		return nil, err
+	}
+	if atual.Ativo() {
+		return nil, ErrMFAJaAtivo.WithDetail("desative o segundo fator antes de cadastrar outro aparelho")
+	}
+	segredo, err := totp.NovoSegredo()
+	if err != nil {
+		return nil, err
+	}
+	err = s.mfa.SalvarPendente(ctx, domain.MFA{UsuarioID: usuario.ID, Segredo: segredo, CreatedAt: s.agora()})
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return nil, ErrMFAJaAtivo
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain.CadastroMFA{Segredo: segredo, URI: totp.URI(s.emissorMFA, usuario.Email, segredo)}, nil
+}
+
+// ConfirmMFA ativa o segundo fator com o primeiro código gerado pelo aplicativo e devolve os
+// códigos de recuperação. Eles só aparecem aqui: apenas os seus hashes são guardados.
+func (s *AuthService) ConfirmMFA(ctx context.Context, usuario domain.Usuario, codigo string) (_ []string, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ConfirmMFA", trace.WithAttributes(attribute.Int64("usuario.id", usuario.ID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	mfa, err := s.buscarMFA(ctx, usuario.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFANaoCadastrado.WithDetail("comece o cadastro em POST /auth/mfa/enroll")
+	}
+	if mfa.Ativo() {
+		return nil, ErrMFAJaAtivo
+	}
+	if err := s.verificarSegundoFator(ctx, usuario, mfa, codigo); err != nil {
+		return nil, err
+	}
+
+	codigos := make([]string, totalCodigosRecuperacao)
+	hashes := make([]string, totalCodigosRecuperacao)
+	for i := range codigos {
+		codigos[i] = novoCodigoRecuperacao()
+		hashes[i] = hashCodigoRecuperacao(codigos[i])
+	}
+	err = s.mfa.Confirmar(ctx, usuario.ID, hashes)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return nil, ErrMFAJaAtivo
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.auditar(ctx, atorUsuario(usuario.ID), usuario.ID, domain.AcaoMFAAtivado, nil)
+	return codigos, nil
+}
+
+// DisableMFA desativa o segundo fator, o que exige um código válido (do aplicativo ou de
+// recuperação). Para um papel que exige MFA, o usuário perde os escopos do papel até cadastrar
+// de novo.
+func (s *AuthService) DisableMFA(ctx context.Context, usuario domain.Usuario, codigo string) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.DisableMFA", trace.WithAttributes(attribute.Int64("usuario.id", usuario.ID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	mfa, err := s.buscarMFA(ctx, usuario.ID)
+	if err != nil {
+		return err
+	}
+	if !mfa.Ativo() {
+		return ErrMFANaoCadastrado
+	}
+	if err := s.verificarSegundoFator(ctx, usuario, mfa, codigo); err != nil {
+		return err
+	}
+	if err := s.mfa.Remover(ctx, usuario.ID); err != nil {
+		return err
+	}
+	s.auditar(ctx, atorUsuario(usuario.ID), usuario.ID, domain.AcaoMFADesativado, nil)
+	return nil
+}
+
+// AuthenticateUser autentica o token de acesso nas rotas protegidas por escopo. Os papéis que
+// exigem MFA só passam com um token emitido depois do segundo fator, enquanto ele continuar ativo.
+func (s *AuthService) AuthenticateUser(ctx context.Context, token string) (_ *domain.Usuario, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.AuthenticateUser")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	usuario, comMFA, err := s.validarAcesso(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !s.exigeMFA(usuario.Papel) {
+		return usuario, nil
+	}
+	if comMFA {
+		mfa, err := s.buscarMFA(ctx, usuario.ID)
+		if err != nil {
+			return nil, err
+		}
+		if mfa.Ativo() {
+			return usuario, nil
+		}
+	}
+	return nil, ErrMFAObrigatorio.
+		WithDetail("cadastre o segundo fator em /auth/mfa/enroll e faça login de novo informando o código").
+		WithDetails(map[string]any{"papel": usuario.Papel})
+}
+
+// SetRole altera o papel do usuário e registra a alteração, feita por ator, na trilha de auditoria.
+func (s *AuthService) SetRole(ctx context.Context, id int64, papel, ator string) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.SetRole", trace.WithAttributes(attribute.Int64("usuario.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if !slices.Contains(domain.Papeis, papel) {
+		return ErrDadosInvalidos.WithFields(domain.FieldError{
+			Field:   "papel",
+			Code:    "papel_desconhecido",
+			Message: "use um de: " + strings.Join(domain.Papeis, ", "),
+		})
+	}
+	usuario, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if usuario == nil {
+		return ErrUsuarioNaoEncontrado
+	}
+	if usuario.Papel == papel {
+		return nil
+	}
+	if err := s.repo.UpdatePapel(ctx, id, papel); err != nil {
+		return err
+	}
+	s.auditar(ctx, ator, id, domain.AcaoPapelAlterado, map[string]any{"de": usuario.Papel, "para": papel})
+	return nil
+}
+
+// verificarSegundoFator aceita um código do aplicativo, uma única vez, ou, com o cadastro
+// ativo, um código de recuperação ainda não usado.
+func (s *AuthService) verificarSegundoFator(ctx context.Context, usuario domain.Usuario, mfa *domain.MFA, codigo string) error {
+	if passo, ok := totp.Validar(mfa.Segredo, codigo, s.agora()); ok {
+		novo, err := s.mfa.RegistrarPasso(ctx, usuario.ID, passo)
+		if err != nil {
+			return err
+		}
+		if !novo {
+			return ErrCodigoMFAInvalido.WithDetail("o código já foi usado; aguarde o próximo")
+		}
+		return nil
+	}
+	if !mfa.Ativo() {
+		return ErrCodigoMFAInvalido
+	}
+	usado, err := s.mfa.UsarCodigoRecuperacao(ctx, usuario.ID, hashCodigoRecuperacao(codigo))
+	if err != nil {
+		return err
+	}
+	if !usado {
+		return ErrCodigoMFAInvalido
+	}
+	s.auditar(ctx, atorUsuario(usuario.ID), usuario.ID, domain.AcaoCodigoRecuperacao, nil)
+	return nil
+}
+
+// buscarMFA devolve o cadastro do segundo fator do usuário (nil sem WithMFA).
+func (s *AuthService) buscarMFA(ctx context.Context, usuarioID int64) (*domain.MFA, error) {
+	if s.mfa == nil {
+		return nil, nil
+	}
+	return s.mfa.GetMFA(ctx, usuarioID)
+}
+
+func (s *AuthService) exigeMFA(papel string) bool {
+	return slices.Contains(s.papeisComMFA, papel)
+}
+
+// auditar registra o evento na trilha de auditoria. A ação já foi feita, então uma falha
+// aqui não a desfaz: o erro é logado para ser investigado.
+func (s *AuthService) auditar(ctx context.Context, ator string, usuarioID int64, acao string, detalhes map[string]any) {
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "Evento de auditoria", "acao", acao, "ator", ator, "usuario_id", usuarioID)
+	if s.auditoria == nil {
+		return
+	}
+	evento := domain.EventoAuditoria{Ator: ator, Acao: acao, UsuarioID: usuarioID, Detalhes: detalhes, CreatedAt: s.agora()}
+	if _, err := s.auditoria.Registrar(ctx, evento); err != nil {
+		logger.ErrorContext(ctx, "Erro ao registrar evento de auditoria", "acao", acao, "usuario_id", usuarioID, "error", err)
+	}
+}
+
+// atorUsuario identifica o próprio usuário como autor de uma ação na trilha de auditoria.
+func atorUsuario(id int64) string {
+	return "usuario:" + strconv.FormatInt(id, 10)
+}
+
+func novoCodigoRecuperacao() string {
+	codigo := strings.ToLower(rand.Text()[:tamanhoCodigoRecuperacao])
+	return codigo[:tamanhoCodigoRecuperacao/2] + "-" + codigo[tamanhoCodigoRecuperacao/2:]
+}
+
+// hashCodigoRecuperacao ignora maiúsculas, espaços e o hífen, que o usuário pode digitar ou não.
+func hashCodigoRecuperacao(codigo string) string {
+	codigo = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(codigo))
+	return hashAPIKey(codigo)
+}
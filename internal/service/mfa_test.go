@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/tokens"
+	"github.com/willjrcristo/go-sqlite-db/internal/totp"
+)
+
+// mfaRepoFake guarda o cadastro de um único usuário e os hashes dos códigos de recuperação.
+type mfaRepoFake struct {
+	mfa     *domain.MFA
+	codigos map[string]bool // hash -> já usado
+}
+
+func (f *mfaRepoFake) GetMFA(ctx context.Context, usuarioID int64) (*domain.MFA, error) {
+	if f.mfa == nil {
+		return nil, nil
+	}
+	m := *f.mfa
+	return &m, nil
+}
+
+func (f *mfaRepoFake) SalvarPendente(ctx context.Context, mfa domain.MFA) error {
+	if f.mfa.Ativo() {
+		return repository.ErrNaoEncontrado
+	}
+	f.mfa = &mfa
+	return nil
+}
+
+func (f *mfaRepoFake) Confirmar(ctx context.Context, usuarioID int64, hashes []string) error {
+	if f.mfa == nil || f.mfa.Ativo() {
+		return repository.ErrNaoEncontrado
+	}
+	agora := time.Now()
+	f.mfa.ConfirmadoEm = &agora
+	f.codigos = map[string]bool{}
+	for _, h := range hashes {
+		f.codigos[h] = false
+	}
+	return nil
+}
+
+func (f *mfaRepoFake) RegistrarPasso(ctx context.Context, usuarioID, passo int64) (bool, error) {
+	if passo <= f.mfa.UltimoPasso {
+		return false, nil
+	}
+	f.mfa.UltimoPasso = passo
+	return true, nil
+}
+
+func (f *mfaRepoFake) UsarCodigoRecuperacao(ctx context.Context, usuarioID int64, hash string) (bool, error) {
+	usado, ok := f.codigos[hash]
+	if !ok || usado {
+		return false, nil
+	}
+	f.codigos[hash] = true
+	return true, nil
+}
+
+func (f *mfaRepoFake) Remover(ctx context.Context, usuarioID int64) error {
+	f.mfa, f.codigos = nil, nil
+	return nil
+}
+
+func (f *mfaRepoFake) Close() error { return nil }
+
+// auditoriaFake guarda os eventos registrados.
+type auditoriaFake struct {
+	eventos []domain.EventoAuditoria
+}
+
+func (f *auditoriaFake) Close() error { return nil }
+
+func (f *auditoriaFake) Registrar(ctx context.Context, evento domain.EventoAuditoria) (int64, error) {
+	f.eventos = append(f.eventos, evento)
+	return int64(len(f.eventos)), nil
+}
+
+func (f *auditoriaFake) ListByUsuario(ctx context.Context, usuarioID int64) ([]domain.EventoAuditoria, error) {
+	return f.eventos, nil
+}
+
+func (f *authRepoFake) UpdatePapel(ctx context.Context, id int64, papel string) error {
+	f.usuario.Papel = papel
+	return nil
+}
+
+func TestAuthService_MFA(t *testing.T) {
+	ctx := context.Background()
+	iteracoesOriginais := iteracoesSenha
+	iteracoesSenha = 1000
+	t.Cleanup(func() { iteracoesSenha = iteracoesOriginais })
+
+	type cenario struct {
+		s         *AuthService
+		repo      *authRepoFake
+		mfa       *mfaRepoFake
+		auditoria *auditoriaFake
+		agora     time.Time
+	}
+	novo := func(t *testing.T, papel string) *cenario {
+		hash, err := hashSenha("senha-certa")
+		require.NoError(t, err)
+		c := &cenario{
+			repo:      &authRepoFake{usuario: domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com", Papel: papel}, hash: hash},
+			mfa:       &mfaRepoFake{},
+			auditoria: &auditoriaFake{},
+			agora:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		}
+		c.s = NewAuthService(c.repo, &mailerFake{}, tokens.NewAssinador([]byte("segredo")), "").
+			WithMFA(c.mfa, "API de Usuários", []string{domain.PapelAdmin}).
+			WithAuditoria(c.auditoria)
+		c.s.agora = func() time.Time { return c.agora }
+		return c
+	}
+	// codigoAtual avança o relógio para o próximo intervalo e devolve o código dele.
+	codigoAtual := func(t *testing.T, c *cenario) string {
+		t.Helper()
+		c.agora = c.agora.Add(totp.Periodo)
+		codigo, err := totp.Codigo(c.mfa.mfa.Segredo, totp.Passo(c.agora))
+		require.NoError(t, err)
+		return codigo
+	}
+	// ativar cadastra e confirma o MFA, devolvendo os códigos de recuperação.
+	ativar := func(t *testing.T, c *cenario) []string {
+		t.Helper()
+		cadastro, err := c.s.EnrollMFA(ctx, c.repo.usuario)
+		require.NoError(t, err)
+		assert.Contains(t, cadastro.URI, "secret="+cadastro.Segredo)
+		codigos, err := c.s.ConfirmMFA(ctx, c.repo.usuario, codigoAtual(t, c))
+		require.NoError(t, err)
+		return codigos
+	}
+	acoes := func(c *cenario) []string {
+		var a []string
+		for _, e := range c.auditoria.eventos {
+			a = append(a, e.Acao)
+		}
+		return a
+	}
+
+	t.Run("sucesso - sem MFA, o login com senha emite o token de acesso", func(t *testing.T) {
+		c := novo(t, domain.PapelUsuario)
+
+		login, err := c.s.Login(ctx, "ana@email.com", "senha-certa", domain.OrigemSessao{})
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, login.AccessToken)
+		assert.False(t, login.MFANecessario)
+		assert.False(t, login.MFACadastroObrigatorio)
+		usuario, err := c.s.AuthenticateUser(ctx, login.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usuario.ID)
+	})
+
+	t.Run("erro - senha errada ou e-mail desconhecido", func(t *testing.T) {
+		c := novo(t, domain.PapelUsuario)
+
+		_, err := c.s.Login(ctx, "ana@email.com", "senha-errada", domain.OrigemSessao{})
+		assert.ErrorIs(t, err, ErrCredenciaisInvalidas)
+		_, err = c.s.Login(ctx, "outro@email.com", "senha-certa", domain.OrigemSessao{})
+		assert.ErrorIs(t, err, ErrCredenciaisInvalidas)
+	})
+
+	t.Run("erro - admin sem MFA recebe o token, mas a política o barra nas rotas protegidas", func(t *testing.T) {
+		c := novo(t, domain.PapelAdmin)
+
+		login, err := c.s.Login(ctx, "ana@email.com", "senha-certa", domain.OrigemSessao{})
+		require.NoError(t, err)
+		assert.True(t, login.MFACadastroObrigatorio)
+
+		_, err = c.s.AuthenticateUser(ctx, login.AccessToken)
+		assert.ErrorIs(t, err, ErrMFAObrigatorio)
+		_, err = c.s.AuthenticateAccessToken(ctx, login.AccessToken)
+		assert.NoError(t, err, "o token ainda serve para cadastrar o MFA")
+	})
+
+	t.Run("sucesso - com MFA ativo, o login pede o código e libera o admin", func(t *testing.T) {
+		c := novo(t, domain.PapelAdmin)
+		codigos := ativar(t, c)
+		assert.Len(t, codigos, totalCodigosRecuperacao)
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codigos[0])
+		assert.Equal(t, []string{domain.AcaoMFAAtivado}, acoes(c))
+		assert.Equal(t, "usuario:1", c.auditoria.eventos[0].Ator)
+
+		login, err := c.s.Login(ctx, "ana@email.com", "senha-certa", domain.OrigemSessao{})
+		require.NoError(t, err)
+		require.True(t, login.MFANecessario)
+		assert.Empty(t, login.AccessToken)
+
+		codigo := codigoAtual(t, c)
+		final, err := c.s.LoginMFA(ctx, login.MFAToken, codigo, domain.OrigemSessao{})
+		require.NoError(t, err)
+		usuario, err := c.s.AuthenticateUser(ctx, final.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, domain.PapelAdmin, usuario.Papel)
+
+		_, err = c.s.LoginMFA(ctx, login.MFAToken, codigo, domain.OrigemSessao{})
+		assert.ErrorIs(t, err, ErrCodigoMFAInvalido, "o mesmo código não vale duas vezes")
+		_, err = c.s.LoginMFA(ctx, "adulterado", codigoAtual(t, c), domain.OrigemSessao{})
+		assert.ErrorIs(t, err, ErrDesafioMFAInvalido)
+	})
+
+	t.Run("sucesso - código de recuperação vale uma única vez e fica na auditoria", func(t *testing.T) {
+		c := novo(t, domain.PapelAdmin)
+		codigos := ativar(t, c)
+		login, err := c.s.Login(ctx, "ana@email.com", "senha-certa", domain.OrigemSessao{})
+		require.NoError(t, err)
+
+		_, err = c.s.LoginMFA(ctx, login.MFAToken, " "+codigos[3]+" ", domain.OrigemSessao{})
+		require.NoError(t, err)
+		_, err = c.s.LoginMFA(ctx, login.MFAToken, codigos[3], domain.OrigemSessao{})
+		assert.ErrorIs(t, err, ErrCodigoMFAInvalido)
+		assert.Equal(t, []string{domain.AcaoMFAAtivado, domain.AcaoCodigoRecuperacao}, acoes(c))
+	})
+
+	t.Run("sucesso - desativar exige código, registra na auditoria e devolve o admin à política", func(t *testing.T) {
+		c := novo(t, domain.PapelAdmin)
+		ativar(t, c)
+		login, err := c.s.Login(ctx, "ana@email.com", "senha-certa", domain.OrigemSessao{})
+		require.NoError(t, err)
+		final, err := c.s.LoginMFA(ctx, login.MFAToken, codigoAtual(t, c), domain.OrigemSessao{})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, c.s.DisableMFA(ctx, c.repo.usuario, "000000"), ErrCodigoMFAInvalido)
+		require.NoError(t, c.s.DisableMFA(ctx, c.repo.usuario, codigoAtual(t, c)))
+
+		assert.Equal(t, []string{domain.AcaoMFAAtivado, domain.AcaoMFADesativado}, acoes(c))
+		_, err = c.s.AuthenticateUser(ctx, final.AccessToken)
+		assert.ErrorIs(t, err, ErrMFAObrigatorio)
+		assert.ErrorIs(t, c.s.DisableMFA(ctx, c.repo.usuario, "123456"), ErrMFANaoCadastrado)
+	})
+
+	t.Run("erro - confirmação sem cadastro, código errado ou cadastro já ativo", func(t *testing.T) {
+		c := novo(t, domain.PapelUsuario)
+
+		_, err := c.s.ConfirmMFA(ctx, c.repo.usuario, "123456")
+		assert.ErrorIs(t, err, ErrMFANaoCadastrado)
+
+		_, err = c.s.EnrollMFA(ctx, c.repo.usuario)
+		require.NoError(t, err)
+		_, err = c.s.ConfirmMFA(ctx, c.repo.usuario, "000000")
+		assert.ErrorIs(t, err, ErrCodigoMFAInvalido)
+		assert.Empty(t, acoes(c))
+
+		_, err = c.s.ConfirmMFA(ctx, c.repo.usuario, codigoAtual(t, c))
+		require.NoError(t, err)
+		_, err = c.s.EnrollMFA(ctx, c.repo.usuario)
+		assert.ErrorIs(t, err, ErrMFAJaAtivo)
+	})
+
+	t.Run("sucesso - alteração de papel fica na auditoria com o ator", func(t *testing.T) {
+		c := novo(t, domain.PapelUsuario)
+
+		require.NoError(t, c.s.SetRole(ctx, 1, domain.PapelAdmin, "cli"))
+
+		assert.Equal(t, domain.PapelAdmin, c.repo.usuario.Papel)
+		require.Len(t, c.auditoria.eventos, 1)
+		assert.Equal(t, domain.EventoAuditoria{
+			Ator: "cli", Acao: domain.AcaoPapelAlterado, UsuarioID: 1,
+			Detalhes:  map[string]any{"de": domain.PapelUsuario, "para": domain.PapelAdmin},
+			CreatedAt: c.agora,
+		}, c.auditoria.eventos[0])
+
+		assert.ErrorIs(t, c.s.SetRole(ctx, 1, "dono", "cli"), ErrDadosInvalidos)
+		assert.ErrorIs(t, c.s.SetRole(ctx, 9, domain.PapelAdmin, "cli"), ErrUsuarioNaoEncontrado)
+	})
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/oidc"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// Erros do login OIDC.
+var (
+	ErrProvedorOIDCDesconhecido   = &domain.Error{Code: "provedor_oidc_desconhecido", Status: http.StatusNotFound, Title: "provedor de login não configurado"}
+	ErrLoginOIDCInvalido          = &domain.Error{Code: "login_oidc_invalido", Status: http.StatusBadRequest, Title: "login pelo provedor inválido ou expirado"}
+	ErrEmailProvedorNaoVerificado = &domain.Error{Code: "email_provedor_nao_verificado", Status: http.StatusForbidden, Title: "o provedor não confirmou o e-mail da conta"}
+	ErrProvedorOIDC               = &domain.Error{Code: "provedor_oidc_indisponivel", Status: http.StatusBadGateway, Title: "falha na comunicação com o provedor de login"}
+)
+
+// validadeLoginOIDC é o tempo que o usuário tem para se autenticar no provedor.
+const validadeLoginOIDC = 10 * time.Minute
+
+// ProvedorOIDC é o cliente de um provedor OIDC. É satisfeito por *oidc.Provedor.
+type ProvedorOIDC interface {
+	URLAutorizacao(ctx context.Context, state, nonce, verificador string) (string, error)
+	Trocar(ctx context.Context, codigo, verificador, nonce string) (*oidc.Perfil, error)
+}
+
+// OIDCService faz o login pelos provedores OIDC configurados, vinculando cada conta do
+// provedor a um usuário: pela identidade já vinculada, pelo e-mail verificado de um usuário
+// existente ou, na falta dos dois, criando o usuário.
+type OIDCService struct {
+	provedores  map[string]ProvedorOIDC
+	identidades repository.IdentidadeRepository
+	usuarios    repository.UsuarioRepository
+	auth        *AuthService
+	agora       func() time.Time
+}
+
+// NewOIDCService cria uma nova instância do OIDCService. provedores é indexado pelo nome
+// usado nas rotas (ex: "google").
+func NewOIDCService(usuarios repository.UsuarioRepository, identidades repository.IdentidadeRepository, auth *AuthService, provedores map[string]ProvedorOIDC) *OIDCService {
+	return &OIDCService{provedores: provedores, identidades: identidades, usuarios: usuarios, auth: auth, agora: time.Now}
+}
+
+// StartOIDCLogin inicia o login no provedor: guarda o state, o nonce e o verificador PKCE e
+// devolve a URL de autorização e o state, que o handler também amarra ao navegador.
+func (s *OIDCService) StartOIDCLogin(ctx context.Context, provedor string) (_ string, _ string, err error) {
+	ctx, span := tracer.Start(ctx, "OIDCService.StartOIDCLogin", trace.WithAttributes(attribute.String("oidc.provedor", provedor)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	p, ok := s.provedores[provedor]
+	if !ok {
+		return "", "", ErrProvedorOIDCDesconhecido
+	}
+	estado := domain.EstadoLogin{
+		State:       oidc.NovoSegredo(),
+		Provedor:    provedor,
+		Nonce:       oidc.NovoSegredo(),
+		Verificador: oidc.NovoSegredo(),
+		ExpiresAt:   s.agora().Add(validadeLoginOIDC),
+	}
+	url, err := p.URLAutorizacao(ctx, estado.State, estado.Nonce, estado.Verificador)
+	if err != nil {
+		return "", "", ErrProvedorOIDC.Wrap(err)
+	}
+	if err := s.identidades.SalvarEstado(ctx, estado); err != nil {
+		return "", "", err
+	}
+	return url, estado.State, nil
+}
+
+// CompleteOIDCLogin conclui o login com o código devolvido pelo provedor no callback e emite
+// o token de acesso do usuário vinculado ou, se ele tiver MFA ativo, o desafio do segundo fator.
+func (s *OIDCService) CompleteOIDCLogin(ctx context.Context, provedor, state, codigo string, origem domain.OrigemSessao) (_ *domain.Login, err error) {
+	ctx, span := tracer.Start(ctx, "OIDCService.CompleteOIDCLogin", trace.WithAttributes(attribute.String("oidc.provedor", provedor)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	p, ok := s.provedores[provedor]
+	if !ok {
+		return nil, ErrProvedorOIDCDesconhecido
+	}
+	estado, err := s.identidades.ConsumirEstado(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if estado == nil || estado.Provedor != provedor || !s.agora().Before(estado.ExpiresAt) {
+		return nil, ErrLoginOIDCInvalido.WithDetail("o login expirou ou já foi concluído; comece de novo")
+	}
+
+	perfil, err := p.Trocar(ctx, codigo, estado.Verificador, estado.Nonce)
+	if errors.Is(err, oidc.ErrTokenInvalido) {
+		return nil, ErrLoginOIDCInvalido.Wrap(err)
+	}
+	if err != nil {
+		return nil, ErrProvedorOIDC.Wrap(err)
+	}
+
+	usuario, novo, err := s.vincular(ctx, provedor, perfil)
+	if err != nil {
+		return nil, err
+	}
+	login, err := s.auth.concluirLogin(ctx, *usuario, origem)
+	if err != nil {
+		return nil, err
+	}
+	login.NovoUsuario = novo
+	logging.FromContext(ctx).InfoContext(ctx, "Login OIDC", "provedor", provedor, "usuario_id", usuario.ID, "novo_usuario", novo)
+	return login, nil
+}
+
+// vincular encontra ou cria o usuário da conta do provedor.
+func (s *OIDCService) vincular(ctx context.Context, provedor string, perfil *oidc.Perfil) (_ *domain.Usuario, novo bool, _ error) {
+	agora := s.agora()
+
+	identidade, err := s.identidades.GetByProvedor(ctx, provedor, perfil.Subject)
+	if err != nil {
+		return nil, false, err
+	}
+	if identidade != nil {
+		usuario, err := s.usuarios.GetByID(ctx, identidade.UsuarioID)
+		if err != nil {
+			return nil, false, err
+		}
+		if usuario == nil {
+			return nil, false, ErrUsuarioNaoEncontrado
+		}
+		if err := s.identidades.TouchLastLogin(ctx, identidade.ID, agora); err != nil {
+			return nil, false, err
+		}
+		return usuario, false, nil
+	}
+
+	// Sem vínculo, só confiamos no e-mail que o provedor garante pertencer à conta: do
+	// contrário, qualquer um poderia entrar na conta de um usuário informando o e-mail dele.
+	email := strings.TrimSpace(perfil.Email)
+	if email == "" || !perfil.EmailVerificado {
+		return nil, false, ErrEmailProvedorNaoVerificado
+	}
+	usuario, err := s.usuarios.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, false, err
+	}
+	if usuario == nil {
+		usuario, err = s.criarUsuario(ctx, perfil.Nome, email)
+		if err != nil {
+			return nil, false, err
+		}
+		novo = true
+	}
+
+	if usuario.EmailVerifiedAt == nil {
+		if !novo {
+			if err := s.assumirConta(ctx, usuario.ID, provedor); err != nil {
+				return nil, false, err
+			}
+		}
+		if err := s.usuarios.MarkEmailVerified(ctx, usuario.ID, usuario.Email); err != nil {
+			return nil, false, err
+		}
+		usuario.EmailVerifiedAt = &agora
+	}
+
+	_, err = s.identidades.Create(ctx, domain.Identidade{
+		UsuarioID:   usuario.ID,
+		Provedor:    provedor,
+		Subject:     perfil.Subject,
+		Email:       email,
+		CreatedAt:   agora,
+		LastLoginAt: &agora,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return usuario, novo, nil
+}
+
+// assumirConta prepara o cadastro com e-mail não verificado para o dono do e-mail. Como o
+// cadastro pode ter sido criado por outra pessoa, tudo o que ela usaria para voltar a entrar
+// é descartado: a senha, as identidades de outros provedores, as sessões e o MFA.
+func (s *OIDCService) assumirConta(ctx context.Context, usuarioID int64, provedor string) error {
+	if err := s.usuarios.UpdatePassword(ctx, usuarioID, ""); err != nil {
+		return err
+	}
+	identidades, err := s.identidades.RemoverDoUsuario(ctx, usuarioID)
+	if err != nil {
+		return err
+	}
+	var sessoes int64
+	if s.auth.sessoes != nil {
+		if sessoes, err = s.auth.sessoes.RevogarTodas(ctx, usuarioID, domain.MotivoContaAssumida); err != nil {
+			return err
+		}
+	}
+	if s.auth.mfa != nil {
+		if err := s.auth.mfa.Remover(ctx, usuarioID); err != nil {
+			return err
+		}
+	}
+	logging.FromContext(ctx).WarnContext(ctx, "Cadastro com e-mail não verificado assumido pelo login OIDC",
+		"usuario_id", usuarioID, "provedor", provedor, "identidades_removidas", identidades, "sessoes_revogadas", sessoes)
+	s.auth.auditar(ctx, atorSistema, usuarioID, domain.AcaoContaAssumida, map[string]any{
+		"provedor": provedor, "identidades_removidas": identidades, "sessoes_revogadas": sessoes,
+	})
+	return nil
+}
+
+// criarUsuario cadastra o usuário com o nome do provedor ou, se ele não passar na validação
+// (ex: um apelido com números), com a parte local do e-mail.
+func (s *OIDCService) criarUsuario(ctx context.Context, nome, email string) (*domain.Usuario, error) {
+	usuario := domain.Usuario{Nome: nome, Email: email}
+	normalizarUsuario(&usuario)
+	if validarUsuario(usuario) != nil {
+		local, _, _ := strings.Cut(email, "@")
+		usuario.Nome = strings.NewReplacer(".", " ", "_", " ", "-", " ", "+", " ").Replace(local)
+		normalizarUsuario(&usuario)
+	}
+	if err := validarUsuario(usuario); err != nil {
+		return nil, err
+	}
+	id, err := s.usuarios.Create(ctx, usuario)
+	if err != nil {
+		return nil, traduzirErroEscrita(err)
+	}
+	metrics.UserSignups.Inc()
+	usuario.ID = id
+	return &usuario, nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/oidc"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/tokens"
+)
+
+// provedorFake aceita o código "codigo" e devolve o perfil configurado.
+type provedorFake struct {
+	perfil oidc.Perfil
+}
+
+func (p *provedorFake) URLAutorizacao(ctx context.Context, state, nonce, verificador string) (string, error) {
+	return "https://provedor/authorize?state=" + state, nil
+}
+
+func (p *provedorFake) Trocar(ctx context.Context, codigo, verificador, nonce string) (*oidc.Perfil, error) {
+	if codigo != "codigo" {
+		return nil, oidc.ErrTokenInvalido
+	}
+	perfil := p.perfil
+	return &perfil, nil
+}
+
+// identidadeRepoFake guarda identidades e states em memória.
+type identidadeRepoFake struct {
+	repository.IdentidadeRepository
+	identidades []domain.Identidade
+	estados     map[string]domain.EstadoLogin
+}
+
+func (f *identidadeRepoFake) GetByProvedor(ctx context.Context, provedor, subject string) (*domain.Identidade, error) {
+	for _, i := range f.identidades {
+		if i.Provedor == provedor && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *identidadeRepoFake) Create(ctx context.Context, identidade domain.Identidade) (int64, error) {
+	identidade.ID = int64(len(f.identidades) + 1)
+	f.identidades = append(f.identidades, identidade)
+	return identidade.ID, nil
+}
+
+func (f *identidadeRepoFake) TouchLastLogin(ctx context.Context, id int64, quando time.Time) error {
+	return nil
+}
+
+func (f *identidadeRepoFake) RemoverDoUsuario(ctx context.Context, usuarioID int64) (int64, error) {
+	restantes := f.identidades[:0]
+	for _, i := range f.identidades {
+		if i.UsuarioID != usuarioID {
+			restantes = append(restantes, i)
+		}
+	}
+	n := int64(len(f.identidades) - len(restantes))
+	f.identidades = restantes
+	return n, nil
+}
+
+func (f *identidadeRepoFake) SalvarEstado(ctx context.Context, estado domain.EstadoLogin) error {
+	f.estados[estado.State] = estado
+	return nil
+}
+
+func (f *identidadeRepoFake) ConsumirEstado(ctx context.Context, state string) (*domain.EstadoLogin, error) {
+	estado, ok := f.estados[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(f.estados, state)
+	return &estado, nil
+}
+
+// usuariosOIDCFake guarda os usuários (e as senhas) em memória.
+type usuariosOIDCFake struct {
+	repository.UsuarioRepository
+	usuarios []domain.Usuario
+	senhas   map[int64]string
+}
+
+func (f *usuariosOIDCFake) GetByID(ctx context.Context, id int64) (*domain.Usuario, error) {
+	for _, u := range f.usuarios {
+		if u.ID == id {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *usuariosOIDCFake) GetByEmail(ctx context.Context, email string) (*domain.Usuario, error) {
+	for _, u := range f.usuarios {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *usuariosOIDCFake) Create(ctx context.Context, usuario domain.Usuario) (int64, error) {
+	usuario.ID = int64(len(f.usuarios) + 1)
+	f.usuarios = append(f.usuarios, usuario)
+	return usuario.ID, nil
+}
+
+func (f *usuariosOIDCFake) MarkEmailVerified(ctx context.Context, id int64, email string) error {
+	agora := time.Now()
+	f.usuarios[id-1].EmailVerifiedAt = &agora
+	return nil
+}
+
+func (f *usuariosOIDCFake) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	f.senhas[id] = hash
+	return nil
+}
+
+func TestOIDCService(t *testing.T) {
+	ctx := context.Background()
+	type cenario struct {
+		s           *OIDCService
+		provedor    *provedorFake
+		usuarios    *usuariosOIDCFake
+		identidades *identidadeRepoFake
+	}
+	novo := func(usuarios ...domain.Usuario) cenario {
+		c := cenario{
+			provedor:    &provedorFake{perfil: oidc.Perfil{Subject: "sub-1", Email: "ana@email.com", EmailVerificado: true, Nome: "Ana Souza"}},
+			usuarios:    &usuariosOIDCFake{usuarios: usuarios, senhas: map[int64]string{}},
+			identidades: &identidadeRepoFake{estados: map[string]domain.EstadoLogin{}},
+		}
+		auth := NewAuthService(c.usuarios, &mailerFake{}, tokens.NewAssinador([]byte("segredo")), "")
+		c.s = NewOIDCService(c.usuarios, c.identidades, auth, map[string]ProvedorOIDC{"google": c.provedor})
+		return c
+	}
+	login := func(t *testing.T, c cenario) (*domain.Login, error) {
+		t.Helper()
+		_, state, err := c.s.StartOIDCLogin(ctx, "google")
+		require.NoError(t, err)
+		return c.s.CompleteOIDCLogin(ctx, "google", state, "codigo", domain.OrigemSessao{})
+	}
+
+	t.Run("sucesso - primeiro login cria o usuário já verificado e vincula a identidade", func(t *testing.T) {
+		c := novo()
+
+		l, err := login(t, c)
+
+		require.NoError(t, err)
+		assert.True(t, l.NovoUsuario)
+		assert.Equal(t, "Bearer", l.TokenType)
+		require.Len(t, c.usuarios.usuarios, 1)
+		assert.Equal(t, "Ana Souza", c.usuarios.usuarios[0].Nome)
+		assert.NotNil(t, c.usuarios.usuarios[0].EmailVerifiedAt)
+		assert.Equal(t, "sub-1", c.identidades.identidades[0].Subject)
+
+		l, err = login(t, c)
+		require.NoError(t, err)
+		assert.False(t, l.NovoUsuario, "o segundo login usa a identidade vinculada")
+		assert.Len(t, c.usuarios.usuarios, 1)
+		assert.Len(t, c.identidades.identidades, 1)
+	})
+
+	t.Run("sucesso - vincula ao usuário existente pelo e-mail verificado pelo provedor", func(t *testing.T) {
+		verificado := time.Now()
+		c := novo(domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com", EmailVerifiedAt: &verificado})
+
+		l, err := login(t, c)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), l.UsuarioID)
+		assert.False(t, l.NovoUsuario)
+		assert.Empty(t, c.usuarios.senhas, "a senha de uma conta já verificada é mantida")
+	})
+
+	t.Run("sucesso - ao assumir cadastro com e-mail não verificado, descarta a senha", func(t *testing.T) {
+		c := novo(domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com"})
+
+		_, err := login(t, c)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]string{1: ""}, c.usuarios.senhas)
+		assert.NotNil(t, c.usuarios.usuarios[0].EmailVerifiedAt)
+	})
+
+	t.Run("sucesso - ao assumir cadastro com e-mail não verificado, descarta identidades, sessões e MFA", func(t *testing.T) {
+		c := novo(domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com"})
+		c.identidades.identidades = []domain.Identidade{{ID: 1, UsuarioID: 1, Provedor: "github", Subject: "intruso"}}
+		confirmado := time.Now()
+		mfa := &mfaRepoFake{mfa: &domain.MFA{UsuarioID: 1, Segredo: "segredo", ConfirmadoEm: &confirmado}}
+		sessoes := novoSessaoRepoFake()
+		sessoes.sessoes[1] = &domain.Sessao{ID: 1, UsuarioID: 1}
+		auditoria := &auditoriaFake{}
+		c.s.auth.WithMFA(mfa, "API", nil).WithSessoes(sessoes).WithAuditoria(auditoria)
+
+		l, err := login(t, c)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, l.AccessToken, "sem o MFA de quem criou o cadastro, o login não pede o segundo fator")
+		require.Len(t, c.identidades.identidades, 1)
+		assert.Equal(t, "sub-1", c.identidades.identidades[0].Subject)
+		assert.Nil(t, mfa.mfa)
+		assert.NotNil(t, sessoes.sessoes[1].RevokedAt)
+		assert.Equal(t, domain.MotivoContaAssumida, sessoes.sessoes[1].MotivoRevogacao)
+		require.Len(t, auditoria.eventos, 1)
+		assert.Equal(t, domain.AcaoContaAssumida, auditoria.eventos[0].Acao)
+	})
+
+	t.Run("sucesso - vincular a conta já verificada mantém as outras identidades", func(t *testing.T) {
+		verificado := time.Now()
+		c := novo(domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com", EmailVerifiedAt: &verificado})
+		c.identidades.identidades = []domain.Identidade{{ID: 1, UsuarioID: 1, Provedor: "github", Subject: "ana"}}
+
+		_, err := login(t, c)
+
+		require.NoError(t, err)
+		assert.Len(t, c.identidades.identidades, 2)
+	})
+
+	t.Run("erro - e-mail não verificado pelo provedor não vincula", func(t *testing.T) {
+		c := novo(domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com"})
+		c.provedor.perfil.EmailVerificado = false
+
+		_, err := login(t, c)
+
+		assert.ErrorIs(t, err, ErrEmailProvedorNaoVerificado)
+		assert.Empty(t, c.identidades.identidades)
+	})
+
+	t.Run("erro - state desconhecido, de outro provedor ou reutilizado", func(t *testing.T) {
+		c := novo()
+		_, state, err := c.s.StartOIDCLogin(ctx, "google")
+		require.NoError(t, err)
+
+		_, err = c.s.CompleteOIDCLogin(ctx, "google", "inventado", "codigo", domain.OrigemSessao{})
+		assert.ErrorIs(t, err, ErrLoginOIDCInvalido)
+
+		_, err = c.s.CompleteOIDCLogin(ctx, "google", state, "codigo", domain.OrigemSessao{})
+		require.NoError(t, err)
+		_, err = c.s.CompleteOIDCLogin(ctx, "google", state, "codigo", domain.OrigemSessao{})
+		assert.ErrorIs(t, err, ErrLoginOIDCInvalido)
+
+		_, _, err = c.s.StartOIDCLogin(ctx, "github")
+		assert.ErrorIs(t, err, ErrProvedorOIDCDesconhecido)
+	})
+
+	t.Run("sucesso - o token de acesso identifica o usuário", func(t *testing.T) {
+		c := novo()
+		l, err := login(t, c)
+		require.NoError(t, err)
+
+		usuario, err := c.s.auth.AuthenticateAccessToken(ctx, l.AccessToken)
+
+		require.NoError(t, err)
+		assert.Equal(t, l.UsuarioID, usuario.ID)
+		_, err = c.s.auth.AuthenticateAccessToken(ctx, l.AccessToken+"x")
+		assert.ErrorIs(t, err, ErrTokenAcessoInvalido)
+	})
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/mailer"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+	"github.com/willjrcristo/go-sqlite-db/internal/validation"
+)
+
+// Erros das organizações, dos membros e dos convites.
+var (
+	ErrOrganizacaoNaoEncontrada  = &domain.Error{Code: "organizacao_nao_encontrada", Status: http.StatusNotFound, Title: "organização não encontrada"}
+	ErrDadosOrganizacaoInvalidos = &domain.Error{Code: "dados_organizacao_invalidos", Status: http.StatusBadRequest, Title: "dados da organização inválidos"}
+	ErrPapelInsuficiente         = &domain.Error{Code: "papel_insuficiente", Status: http.StatusForbidden, Title: "o seu papel na organização não permite esta operação"}
+	ErrUsuarioNecessario         = &domain.Error{Code: "usuario_necessario", Status: http.StatusForbidden, Title: "esta operação exige o token de acesso de um usuário"}
+	ErrMembroNaoEncontrado       = &domain.Error{Code: "membro_nao_encontrado", Status: http.StatusNotFound, Title: "membro não encontrado"}
+	ErrJaMembro                  = &domain.Error{Code: "ja_membro", Status: http.StatusConflict, Title: "o e-mail já pertence a um membro da organização"}
+	ErrDonoNaoRemovivel          = &domain.Error{Code: "dono_nao_removivel", Status: http.StatusConflict, Title: "o dono não pode ser removido da organização"}
+	ErrSemAssentos               = &domain.Error{Code: "sem_assentos", Status: http.StatusConflict, Title: "a organização não tem assentos livres"}
+	ErrConviteNaoEncontrado      = &domain.Error{Code: "convite_nao_encontrado", Status: http.StatusNotFound, Title: "convite não encontrado"}
+	ErrConviteInvalido           = &domain.Error{Code: "convite_invalido", Status: http.StatusBadRequest, Title: "convite inválido ou expirado"}
+)
+
+const (
+	orgNomeMaxLen   = 100
+	validadeConvite = 7 * 24 * time.Hour
+)
+
+// papeisGerentes podem gerenciar membros, convites e a assinatura da organização.
+var papeisGerentes = []string{domain.PapelOrgDono, domain.PapelOrgAdmin}
+
+// OrganizacaoService cuida das organizações, dos seus membros, dos convites e da assinatura
+// (veja assinatura.go). Toda operação recebe o domain.Solicitante e só enxerga as
+// organizações de que ele é membro; as demais respondem como inexistentes.
+type OrganizacaoService struct {
+	repo     repository.OrganizacaoRepository
+	usuarios repository.UsuarioRepository
+	mailer   mailer.Mailer
+	cupons   repository.CupomRepository
+	cobranca repository.CobrancaRepository
+	plano    PlanoCheckout
+	outbox   repository.OutboxClienteRepository
+	provider billing.Provider
+	politica string
+	urlBase  string
+	agora    func() time.Time
+}
+
+// NewOrganizacaoService cria uma nova instância do OrganizacaoService. Os convites enviados
+// apontam para o frontend em urlBase, que chama POST /organizations/invitations/accept com o token.
+func NewOrganizacaoService(repo repository.OrganizacaoRepository, usuarios repository.UsuarioRepository, m mailer.Mailer, urlBase string) *OrganizacaoService {
+	return &OrganizacaoService{repo: repo, usuarios: usuarios, mailer: m, urlBase: strings.TrimRight(urlBase, "/"), agora: time.Now}
+}
+
+// CreateOrganization cria a organização com o solicitante como dono.
+func (s *OrganizacaoService) CreateOrganization(ctx context.Context, sol domain.Solicitante, nome string) (_ *domain.Organizacao, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.CreateOrganization")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if sol.UsuarioID == 0 {
+		return nil, ErrUsuarioNecessario.WithDetail("a organização precisa de um usuário como dono")
+	}
+	nome = strings.Join(strings.Fields(nome), " ")
+	if erros := validation.Validate(validation.F("nome", nome, validation.Required(), validation.MaxLength(orgNomeMaxLen))); len(erros) > 0 {
+		return nil, ErrDadosOrganizacaoInvalidos.WithFields(erros...)
+	}
+	usuario, err := s.usuarios.GetByID(ctx, sol.UsuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil {
+		return nil, ErrUsuarioNaoEncontrado
+	}
+
+	id, err := s.repo.Criar(ctx, domain.Organizacao{Nome: nome}, sol.UsuarioID)
+	if err != nil {
+		return nil, err
+	}
+	return s.acessar(ctx, sol, id)
+}
+
+// ListOrganizations devolve as organizações de que o solicitante é membro.
+func (s *OrganizacaoService) ListOrganizations(ctx context.Context, sol domain.Solicitante) (_ []domain.Organizacao, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.ListOrganizations")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if sol.UsuarioID == 0 {
+		return nil, ErrUsuarioNecessario.WithDetail("API keys não pertencem a organizações; consulte cada uma pelo ID")
+	}
+	return s.repo.ListDoUsuario(ctx, sol.UsuarioID)
+}
+
+// GetOrganization devolve a organização, com os assentos e o papel do solicitante nela.
+func (s *OrganizacaoService) GetOrganization(ctx context.Context, sol domain.Solicitante, id int64) (_ *domain.Organizacao, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.GetOrganization", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	return s.acessar(ctx, sol, id)
+}
+
+// ListMembers devolve os membros da organização. Qualquer membro pode consultá-los.
+func (s *OrganizacaoService) ListMembers(ctx context.Context, sol domain.Solicitante, id int64) (_ []domain.Membro, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.ListMembers", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if _, err := s.acessar(ctx, sol, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembros(ctx, id)
+}
+
+// RemoveMember tira o usuário da organização, liberando o assento dele. O dono e os admins
+// removem qualquer membro, exceto o dono; os demais só podem sair eles mesmos.
+func (s *OrganizacaoService) RemoveMember(ctx context.Context, sol domain.Solicitante, id, usuarioID int64) (err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.RemoveMember", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	org, err := s.acessar(ctx, sol, id)
+	if err != nil {
+		return err
+	}
+	if usuarioID != sol.UsuarioID && !gerencia(sol, org) {
+		return ErrPapelInsuficiente.WithDetail("só o dono e os admins removem outros membros")
+	}
+	papel, err := s.repo.Papel(ctx, id, usuarioID)
+	if err != nil {
+		return err
+	}
+	switch papel {
+	case "":
+		return ErrMembroNaoEncontrado
+	case domain.PapelOrgDono:
+		return ErrDonoNaoRemovivel
+	}
+	err = s.repo.RemoverMembro(ctx, id, usuarioID)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return ErrMembroNaoEncontrado
+	}
+	return err
+}
+
+// InviteMember convida o e-mail para a organização e envia o link de aceite. O convite ocupa
+// um assento até ser aceito, revogado ou vencer; convidar de novo o mesmo e-mail substitui o
+// convite anterior.
+func (s *OrganizacaoService) InviteMember(ctx context.Context, sol domain.Solicitante, id int64, email, papel string) (_ *domain.Convite, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.InviteMember", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	org, err := s.acessar(ctx, sol, id, papeisGerentes...)
+	if err != nil {
+		return nil, err
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if papel == "" {
+		papel = domain.PapelOrgMembro
+	}
+	erros := validation.Validate(validation.F("email", email, validation.Required(), validation.MaxLength(emailMaxLen), validation.Email()))
+	if !slices.Contains(domain.PapeisConvite, papel) {
+		erros = append(erros, domain.FieldError{Field: "papel", Code: "papel_desconhecido", Message: "use um de: " + strings.Join(domain.PapeisConvite, ", ")})
+	}
+	if len(erros) > 0 {
+		return nil, ErrDadosOrganizacaoInvalidos.WithFields(erros...)
+	}
+	membro, err := s.repo.MembroPorEmail(ctx, id, email)
+	if err != nil {
+		return nil, err
+	}
+	if membro {
+		return nil, ErrJaMembro
+	}
+
+	agora := s.agora().UTC()
+	convite := domain.Convite{
+		OrganizacaoID: id,
+		Email:         email,
+		Papel:         papel,
+		ConvidadoPor:  sol.UsuarioID,
+		CreatedAt:     agora,
+		ExpiresAt:     agora.Add(validadeConvite),
+	}
+	token := rand.Text() // 130 bits aleatórios; só o hash é guardado, como nas API keys.
+	convite.ID, err = s.repo.CriarConvite(ctx, convite, hashAPIKey(token))
+	if errors.Is(err, repository.ErrSemAssentos) {
+		return nil, ErrSemAssentos.
+			WithDetail("aumente a quantidade de assentos da assinatura ou revogue convites pendentes").
+			WithDetails(map[string]any{"assentos": org.Assentos, "assentos_em_uso": org.AssentosEmUso})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// O convite já vale; se o envio falhar, convidar de novo gera outro link.
+	err = s.mailer.Enviar(ctx, mailer.Mensagem{
+		Para:    email,
+		Assunto: "Convite para " + org.Nome,
+		Texto: fmt.Sprintf("Olá!\n\nVocê foi convidado para a organização %s. Para aceitar, entre com este e-mail e acesse o link abaixo em até %d dias:\n\n%s\n\nSe não esperava este convite, ignore esta mensagem.\n",
+			org.Nome, int(validadeConvite.Hours()/24), s.urlBase+"/convites/aceitar?token="+url.QueryEscape(token)),
+	})
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "Falha ao enviar o convite", "organizacao_id", id, "convite_id", convite.ID, "error", err)
+	}
+	return &convite, nil
+}
+
+// ListInvitations devolve os convites pendentes da organização.
+func (s *OrganizacaoService) ListInvitations(ctx context.Context, sol domain.Solicitante, id int64) (_ []domain.Convite, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.ListInvitations", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if _, err := s.acessar(ctx, sol, id, papeisGerentes...); err != nil {
+		return nil, err
+	}
+	return s.repo.ListConvites(ctx, id)
+}
+
+// RevokeInvitation revoga o convite pendente, liberando o assento dele.
+func (s *OrganizacaoService) RevokeInvitation(ctx context.Context, sol domain.Solicitante, id, conviteID int64) (err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.RevokeInvitation", trace.WithAttributes(attribute.Int64("organizacao.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if _, err := s.acessar(ctx, sol, id, papeisGerentes...); err != nil {
+		return err
+	}
+	err = s.repo.RevogarConvite(ctx, id, conviteID)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return ErrConviteNaoEncontrado
+	}
+	return err
+}
+
+// AcceptInvitation torna o solicitante membro da organização do convite. O convite só vale
+// para o usuário com o e-mail convidado, já verificado.
+func (s *OrganizacaoService) AcceptInvitation(ctx context.Context, sol domain.Solicitante, token string) (_ *domain.Organizacao, err error) {
+	ctx, span := tracer.Start(ctx, "OrganizacaoService.AcceptInvitation")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if sol.UsuarioID == 0 {
+		return nil, ErrUsuarioNecessario
+	}
+	convite, err := s.repo.BuscarConvite(ctx, hashAPIKey(strings.TrimSpace(token)))
+	if err != nil {
+		return nil, err
+	}
+	if !convite.Pendente(s.agora()) {
+		return nil, ErrConviteInvalido
+	}
+	usuario, err := s.usuarios.GetByID(ctx, sol.UsuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil {
+		return nil, ErrUsuarioNaoEncontrado
+	}
+	if !strings.EqualFold(usuario.Email, convite.Email) {
+		return nil, ErrConviteInvalido.WithDetail("o convite foi enviado para outro e-mail")
+	}
+	if usuario.EmailVerifiedAt == nil {
+		return nil, ErrEmailNaoVerificado.WithDetail("confirme o e-mail pelo link enviado antes de aceitar o convite")
+	}
+
+	err = s.repo.AceitarConvite(ctx, *convite, sol.UsuarioID)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return nil, ErrConviteInvalido
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.acessar(ctx, sol, convite.OrganizacaoID)
+}
+
+// acessar carrega a organização para o solicitante e confere se o papel dele nela está entre
+// os exigidos (sem papéis, basta ser membro). Para quem não é membro a organização não existe,
+// para não revelar quais IDs estão em uso; operadores acessam qualquer uma.
+func (s *OrganizacaoService) acessar(ctx context.Context, sol domain.Solicitante, id int64, papeis ...string) (*domain.Organizacao, error) {
+	org, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrganizacaoNaoEncontrada
+	}
+	if sol.UsuarioID != 0 {
+		if org.Papel, err = s.repo.Papel(ctx, id, sol.UsuarioID); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case sol.Operador:
+		return org, nil
+	case org.Papel == "":
+		return nil, ErrOrganizacaoNaoEncontrada
+	case len(papeis) > 0 && !slices.Contains(papeis, org.Papel):
+		return nil, ErrPapelInsuficiente.WithDetail("esta operação exige o papel " + strings.Join(papeis, " ou "))
+	}
+	return org, nil
+}
+
+// gerencia informa se o solicitante pode gerenciar a organização carregada por acessar.
+func gerencia(sol domain.Solicitante, org *domain.Organizacao) bool {
+	return sol.Operador || slices.Contains(papeisGerentes, org.Papel)
+}
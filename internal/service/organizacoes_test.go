@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// orgRepoFake guarda as organizações, os papéis dos membros e os convites (por hash) em memória.
+type orgRepoFake struct {
+	repository.OrganizacaoRepository
+	orgs     map[int64]*domain.Organizacao
+	papeis   map[int64]map[int64]string
+	convites map[string]*domain.Convite
+	usuarios *usuariosOIDCFake
+}
+
+func novoOrgRepoFake(usuarios *usuariosOIDCFake) *orgRepoFake {
+	return &orgRepoFake{orgs: map[int64]*domain.Organizacao{}, papeis: map[int64]map[int64]string{}, convites: map[string]*domain.Convite{}, usuarios: usuarios}
+}
+
+func (f *orgRepoFake) Criar(ctx context.Context, org domain.Organizacao, donoID int64) (int64, error) {
+	org.ID = int64(len(f.orgs) + 1)
+	org.SubscriptionStatus = "inactive"
+	f.orgs[org.ID] = &org
+	f.papeis[org.ID] = map[int64]string{donoID: domain.PapelOrgDono}
+	return org.ID, nil
+}
+
+func (f *orgRepoFake) Get(ctx context.Context, id int64) (*domain.Organizacao, error) {
+	org, ok := f.orgs[id]
+	if !ok {
+		return nil, nil
+	}
+	c := *org
+	c.AssentosEmUso = int64(len(f.papeis[id]))
+	for _, convite := range f.convites {
+		if convite.OrganizacaoID == id && convite.Pendente(time.Now()) {
+			c.AssentosEmUso++
+		}
+	}
+	return &c, nil
+}
+
+func (f *orgRepoFake) Papel(ctx context.Context, orgID, usuarioID int64) (string, error) {
+	return f.papeis[orgID][usuarioID], nil
+}
+
+func (f *orgRepoFake) MembroPorEmail(ctx context.Context, orgID int64, email string) (bool, error) {
+	usuario, _ := f.usuarios.GetByEmail(ctx, email)
+	return usuario != nil && f.papeis[orgID][usuario.ID] != "", nil
+}
+
+func (f *orgRepoFake) ListMembros(ctx context.Context, orgID int64) ([]domain.Membro, error) {
+	var membros []domain.Membro
+	for id, papel := range f.papeis[orgID] {
+		membros = append(membros, domain.Membro{UsuarioID: id, Papel: papel})
+	}
+	return membros, nil
+}
+
+func (f *orgRepoFake) RemoverMembro(ctx context.Context, orgID, usuarioID int64) error {
+	if f.papeis[orgID][usuarioID] == "" {
+		return repository.ErrNaoEncontrado
+	}
+	delete(f.papeis[orgID], usuarioID)
+	return nil
+}
+
+func (f *orgRepoFake) CriarConvite(ctx context.Context, convite domain.Convite, hash string) (int64, error) {
+	org, _ := f.Get(ctx, convite.OrganizacaoID)
+	if org.Assentos > 0 && org.AssentosEmUso >= org.Assentos {
+		return 0, repository.ErrSemAssentos
+	}
+	convite.ID = int64(len(f.convites) + 1)
+	f.convites[hash] = &convite
+	return convite.ID, nil
+}
+
+func (f *orgRepoFake) BuscarConvite(ctx context.Context, hash string) (*domain.Convite, error) {
+	convite, ok := f.convites[hash]
+	if !ok {
+		return nil, nil
+	}
+	c := *convite
+	return &c, nil
+}
+
+func (f *orgRepoFake) AceitarConvite(ctx context.Context, convite domain.Convite, usuarioID int64) error {
+	for _, c := range f.convites {
+		if c.ID == convite.ID && c.Pendente(time.Now()) {
+			agora := time.Now()
+			c.AcceptedAt = &agora
+			f.papeis[c.OrganizacaoID][usuarioID] = c.Papel
+			return nil
+		}
+	}
+	return repository.ErrNaoEncontrado
+}
+
+func (f *orgRepoFake) ListDoUsuario(ctx context.Context, usuarioID int64) ([]domain.Organizacao, error) {
+	var orgs []domain.Organizacao
+	for id, papeis := range f.papeis {
+		if papel, ok := papeis[usuarioID]; ok {
+			org := *f.orgs[id]
+			org.Papel = papel
+			orgs = append(orgs, org)
+		}
+	}
+	return orgs, nil
+}
+
+func (f *orgRepoFake) GetByStripeID(ctx context.Context, stripeCustomerID string) (*domain.Organizacao, error) {
+	for id, org := range f.orgs {
+		if org.StripeCustomerID == stripeCustomerID {
+			return f.Get(ctx, id)
+		}
+	}
+	return nil, nil
+}
+
+func (f *orgRepoFake) AtualizarAssinatura(ctx context.Context, id int64, org domain.Organizacao) error {
+	atual, ok := f.orgs[id]
+	if !ok {
+		return repository.ErrNaoEncontrado
+	}
+	atual.StripeCustomerID, atual.StripeSubscriptionID = org.StripeCustomerID, org.StripeSubscriptionID
+	atual.SubscriptionStatus, atual.SubscriptionCurrentPeriodEnd = org.SubscriptionStatus, org.SubscriptionCurrentPeriodEnd
+	atual.Assentos, atual.Cupom, atual.Moeda = org.Assentos, org.Cupom, org.Moeda
+	return nil
+}
+
+func TestOrganizacaoService(t *testing.T) {
+	ctx := context.Background()
+	verificado := time.Now()
+	novo := func() (*OrganizacaoService, *orgRepoFake, *mailerFake) {
+		usuarios := &usuariosOIDCFake{usuarios: []domain.Usuario{
+			{ID: 1, Nome: "Ana", Email: "ana@email.com", EmailVerifiedAt: &verificado},
+			{ID: 2, Nome: "Bia", Email: "bia@email.com", EmailVerifiedAt: &verificado},
+			{ID: 3, Nome: "Caio", Email: "caio@email.com"},
+		}}
+		repo, m := novoOrgRepoFake(usuarios), &mailerFake{}
+		return NewOrganizacaoService(repo, usuarios, m, "http://app.local/"), repo, m
+	}
+	ana, bia, caio := domain.Solicitante{UsuarioID: 1}, domain.Solicitante{UsuarioID: 2}, domain.Solicitante{UsuarioID: 3}
+
+	t.Run("sucesso - cria a organização e convida um membro, que aceita pelo link", func(t *testing.T) {
+		s, repo, m := novo()
+
+		org, err := s.CreateOrganization(ctx, ana, "  Acme   Ltda ")
+		require.NoError(t, err)
+		assert.Equal(t, "Acme Ltda", org.Nome)
+		assert.Equal(t, domain.PapelOrgDono, org.Papel)
+
+		convite, err := s.InviteMember(ctx, ana, org.ID, " bia@email.com ", "")
+		require.NoError(t, err)
+		assert.Equal(t, "bia@email.com", convite.Email)
+		assert.Equal(t, domain.PapelOrgMembro, convite.Papel)
+		require.Len(t, m.enviadas, 1)
+		assert.Equal(t, "Convite para Acme Ltda", m.enviadas[0].Assunto)
+		assert.Contains(t, m.enviadas[0].Texto, "http://app.local/convites/aceitar?token=")
+
+		aceita, err := s.AcceptInvitation(ctx, bia, tokenEnviado(t, m))
+		require.NoError(t, err)
+		assert.Equal(t, domain.PapelOrgMembro, aceita.Papel)
+		assert.Equal(t, int64(2), aceita.AssentosEmUso)
+		assert.Equal(t, domain.PapelOrgMembro, repo.papeis[org.ID][2])
+
+		_, err = s.AcceptInvitation(ctx, bia, tokenEnviado(t, m))
+		assert.ErrorIs(t, err, ErrConviteInvalido, "o convite é de uso único")
+	})
+
+	t.Run("erro - quem não é membro não vê a organização e membros comuns não gerenciam", func(t *testing.T) {
+		s, repo, _ := novo()
+		org, err := s.CreateOrganization(ctx, ana, "Acme")
+		require.NoError(t, err)
+		repo.papeis[org.ID][2] = domain.PapelOrgMembro
+
+		_, err = s.GetOrganization(ctx, caio, org.ID)
+		assert.ErrorIs(t, err, ErrOrganizacaoNaoEncontrada)
+		_, err = s.ListMembers(ctx, caio, org.ID)
+		assert.ErrorIs(t, err, ErrOrganizacaoNaoEncontrada)
+		_, err = s.GetOrganization(ctx, ana, 99)
+		assert.ErrorIs(t, err, ErrOrganizacaoNaoEncontrada)
+
+		_, err = s.InviteMember(ctx, bia, org.ID, "caio@email.com", domain.PapelOrgMembro)
+		assert.ErrorIs(t, err, ErrPapelInsuficiente)
+		assert.ErrorIs(t, s.RemoveMember(ctx, bia, org.ID, 1), ErrPapelInsuficiente)
+		_, err = s.CreateCheckoutSession(ctx, bia, org.ID, domain.PedidoCheckout{})
+		assert.ErrorIs(t, err, ErrPapelInsuficiente)
+
+		operador := domain.Solicitante{Operador: true}
+		vista, err := s.GetOrganization(ctx, operador, org.ID)
+		require.NoError(t, err)
+		assert.Empty(t, vista.Papel)
+		_, err = s.ListOrganizations(ctx, operador)
+		assert.ErrorIs(t, err, ErrUsuarioNecessario)
+	})
+
+	t.Run("erro - convites além dos assentos contratados", func(t *testing.T) {
+		s, repo, _ := novo()
+		org, err := s.CreateOrganization(ctx, ana, "Acme")
+		require.NoError(t, err)
+		repo.orgs[org.ID].Assentos = 2
+		repo.orgs[org.ID].SubscriptionStatus = "active"
+
+		_, err = s.InviteMember(ctx, ana, org.ID, "bia@email.com", domain.PapelOrgAdmin)
+		require.NoError(t, err)
+		_, err = s.InviteMember(ctx, ana, org.ID, "caio@email.com", domain.PapelOrgMembro)
+
+		assert.ErrorIs(t, err, ErrSemAssentos)
+		var derr *domain.Error
+		require.ErrorAs(t, err, &derr)
+		assert.Equal(t, map[string]any{"assentos": int64(2), "assentos_em_uso": int64(2)}, derr.Details)
+
+		_, err = s.UpdateSeats(ctx, ana, org.ID, 1)
+		assert.ErrorIs(t, err, ErrAssentosEmUso)
+	})
+
+	t.Run("erro - dados do convite, membro existente e convite de outro e-mail", func(t *testing.T) {
+		s, _, m := novo()
+		org, err := s.CreateOrganization(ctx, ana, "Acme")
+		require.NoError(t, err)
+
+		_, err = s.InviteMember(ctx, ana, org.ID, "não-é-email", domain.PapelOrgDono)
+		var derr *domain.Error
+		require.ErrorAs(t, err, &derr)
+		assert.Equal(t, "dados_organizacao_invalidos", derr.Code)
+		assert.Len(t, derr.Fields, 2)
+
+		_, err = s.InviteMember(ctx, ana, org.ID, "ana@email.com", "")
+		assert.ErrorIs(t, err, ErrJaMembro)
+
+		_, err = s.InviteMember(ctx, ana, org.ID, "caio@email.com", "")
+		require.NoError(t, err)
+		_, err = s.AcceptInvitation(ctx, bia, tokenEnviado(t, m))
+		assert.ErrorIs(t, err, ErrConviteInvalido)
+		_, err = s.AcceptInvitation(ctx, caio, tokenEnviado(t, m))
+		assert.ErrorIs(t, err, ErrEmailNaoVerificado)
+		_, err = s.AcceptInvitation(ctx, bia, "inventado")
+		assert.ErrorIs(t, err, ErrConviteInvalido)
+	})
+
+	t.Run("sucesso - membros saem sozinhos e o dono não pode ser removido", func(t *testing.T) {
+		s, repo, _ := novo()
+		org, err := s.CreateOrganization(ctx, ana, "Acme")
+		require.NoError(t, err)
+		repo.papeis[org.ID][2] = domain.PapelOrgAdmin
+		repo.papeis[org.ID][3] = domain.PapelOrgMembro
+
+		require.NoError(t, s.RemoveMember(ctx, caio, org.ID, 3))
+		assert.ErrorIs(t, s.RemoveMember(ctx, bia, org.ID, 1), ErrDonoNaoRemovivel)
+		assert.ErrorIs(t, s.RemoveMember(ctx, bia, org.ID, 3), ErrMembroNaoEncontrado)
+		require.NoError(t, s.RemoveMember(ctx, ana, org.ID, 2))
+		assert.Equal(t, map[int64]string{1: domain.PapelOrgDono}, repo.papeis[org.ID])
+	})
+
+	t.Run("erro - checkout exige o e-mail do dono verificado e assinatura inativa", func(t *testing.T) {
+		s, repo, _ := novo()
+		org, err := s.CreateOrganization(ctx, caio, "Acme")
+		require.NoError(t, err)
+
+		_, err = s.CreateCheckoutSession(ctx, caio, org.ID, domain.PedidoCheckout{})
+		assert.ErrorIs(t, err, ErrEmailNaoVerificado)
+
+		repo.orgs[org.ID].SubscriptionStatus = "active"
+		_, err = s.CreateCheckoutSession(ctx, caio, org.ID, domain.PedidoCheckout{})
+		assert.ErrorIs(t, err, ErrAssinaturaJaAtiva)
+
+		repo.orgs[org.ID].SubscriptionStatus = "canceled"
+		_, err = s.UpdateSeats(ctx, caio, org.ID, 5)
+		assert.ErrorIs(t, err, ErrAssinaturaInativa)
+	})
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// Formatos de patch aceitos por PatchUser.
+const (
+	MergePatch = "application/merge-patch+json" // RFC 7396
+	JSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+var (
+	ErrPatchInvalido        = &domain.Error{Code: "patch_invalido", Status: http.StatusBadRequest, Title: "documento de patch inválido"}
+	ErrPatchConflito        = &domain.Error{Code: "patch_conflito", Status: http.StatusConflict, Title: "o patch não se aplica ao estado atual do usuário"}
+	ErrFormatoPatchInvalido = &domain.Error{Code: "formato_patch_nao_suportado", Status: http.StatusUnsupportedMediaType, Title: "formato de patch não suportado"}
+)
+
+// camposEditaveis são os campos do JSON de domain.Usuario que um patch pode alterar.
+// Os demais (id, dados de assinatura) são somente leitura.
+var camposEditaveis = map[string]bool{"nome": true, "email": true}
+
+// PatchUser aplica uma alteração parcial a um usuário. O patch é aplicado sobre a
+// representação JSON atual do usuário; as regras de validação valem para o resultado
+// e apenas as colunas efetivamente alteradas são gravadas.
+//
+// Com versao diferente de zero (vinda do If-Match), o patch só é aplicado sobre essa versão.
+// Mesmo sem ela, a gravação é condicionada à versão lida, para que uma alteração concorrente
+// entre a leitura e a escrita não seja sobrescrita silenciosamente.
+func (s *UsuarioService) PatchUser(ctx context.Context, id int64, formato string, patch []byte, versao int64) (_ *domain.Usuario, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.PatchUser", trace.WithAttributes(
+		attribute.Int64("usuario.id", id),
+		attribute.String("patch.format", formato),
+	))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	atual, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checarVersao(atual, versao); err != nil {
+		return nil, err
+	}
+
+	original, err := json.Marshal(atual)
+	if err != nil {
+		return nil, err
+	}
+	resultado, err := aplicarPatch(formato, original, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	alterado, err := decodificarResultado(original, resultado)
+	if err != nil {
+		return nil, err
+	}
+	normalizarUsuario(&alterado)
+	if err := validarUsuario(alterado); err != nil {
+		return nil, err
+	}
+
+	mudancas := domain.UsuarioPatch{Version: atual.Version}
+	if alterado.Nome != atual.Nome {
+		mudancas.Nome = &alterado.Nome
+		atual.Nome = alterado.Nome
+	}
+	if alterado.Email != atual.Email {
+		mudancas.Email = &alterado.Email
+		atual.Email = alterado.Email
+	}
+	if mudancas.Vazio() {
+		return atual, nil
+	}
+	if err := s.repo.Patch(ctx, id, mudancas); err != nil {
+		return nil, traduzirErroEscrita(err)
+	}
+	// Relemos o usuário para devolver a nova versão e o updated_at gravados pelo repositório.
+	atualizado, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if mudancas.Email != nil {
+		s.enviarVerificacao(ctx, *atualizado)
+	}
+	return atualizado, nil
+}
+
+// aplicarPatch aplica o documento de patch no formato informado sobre o JSON original.
+func aplicarPatch(formato string, original, patch []byte) ([]byte, error) {
+	switch formato {
+	case MergePatch:
+		if !json.Valid(patch) || bytes.TrimSpace(patch)[0] != '{' {
+			return nil, ErrPatchInvalido.WithDetail("o merge patch deve ser um objeto JSON")
+		}
+		resultado, err := jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return nil, ErrPatchInvalido.Wrap(err)
+		}
+		return resultado, nil
+
+	case JSONPatch:
+		operacoes, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, ErrPatchInvalido.Wrap(err)
+		}
+		resultado, err := operacoes.Apply(original)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, ErrPatchConflito.WithDetail(err.Error())
+		}
+		if err != nil {
+			return nil, ErrPatchInvalido.WithDetail(err.Error()).Wrap(err)
+		}
+		return resultado, nil
+
+	default:
+		return nil, ErrFormatoPatchInvalido.WithDetail("use " + MergePatch + " ou " + JSONPatch)
+	}
+}
+
+// decodificarResultado converte o documento resultante em domain.Usuario, rejeitando
+// alterações em campos somente leitura e campos desconhecidos.
+func decodificarResultado(original, resultado []byte) (domain.Usuario, error) {
+	var antes, depois map[string]json.RawMessage
+	if err := json.Unmarshal(original, &antes); err != nil {
+		return domain.Usuario{}, err
+	}
+	if err := json.Unmarshal(resultado, &depois); err != nil {
+		return domain.Usuario{}, ErrPatchInvalido.WithDetail("o resultado do patch deve ser um objeto JSON")
+	}
+
+	var erros []domain.FieldError
+	for campo := range antes {
+		if camposEditaveis[campo] {
+			continue
+		}
+		if valor, ok := depois[campo]; !ok || !jsonpatch.Equal(valor, antes[campo]) {
+			erros = append(erros, domain.FieldError{Field: campo, Code: "somente_leitura", Message: "campo não pode ser alterado"})
+		}
+	}
+	for campo := range depois {
+		if _, ok := antes[campo]; !ok && !camposEditaveis[campo] {
+			erros = append(erros, domain.FieldError{Field: campo, Code: "campo_desconhecido", Message: "campo não reconhecido"})
+		}
+	}
+	if len(erros) > 0 {
+		sort.Slice(erros, func(i, j int) bool { return erros[i].Field < erros[j].Field })
+		return domain.Usuario{}, ErrDadosInvalidos.WithFields(erros...)
+	}
+
+	// Campos editáveis removidos pelo patch (ex: {"nome": null}) ficam vazios e caem na validação.
+	var usuario domain.Usuario
+	if err := json.Unmarshal(resultado, &usuario); err != nil {
+		return domain.Usuario{}, ErrDadosInvalidos.WithDetail("tipo de valor inválido no resultado do patch").Wrap(err)
+	}
+	return usuario, nil
+}
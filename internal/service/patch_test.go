@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// patchRepoFake implementa só o que o PatchUser usa; os demais métodos da
+// interface ficam no repositório embutido (nil) e causariam panic se chamados.
+type patchRepoFake struct {
+	repository.UsuarioRepository
+	usuario domain.Usuario
+	gravado *domain.UsuarioPatch
+	falha   error
+}
+
+func (f *patchRepoFake) GetByID(ctx context.Context, id int64) (*domain.Usuario, error) {
+	u := f.usuario
+	return &u, nil
+}
+
+func (f *patchRepoFake) Patch(ctx context.Context, id int64, patch domain.UsuarioPatch) error {
+	if f.falha != nil {
+		return f.falha
+	}
+	f.gravado = &patch
+	if patch.Nome != nil {
+		f.usuario.Nome = *patch.Nome
+	}
+	if patch.Email != nil {
+		f.usuario.Email = *patch.Email
+	}
+	f.usuario.Version++
+	return nil
+}
+
+func TestUsuarioService_PatchUser(t *testing.T) {
+	novoRepo := func() *patchRepoFake {
+		return &patchRepoFake{usuario: domain.Usuario{ID: 1, Nome: "Ana Souza", Email: "ana@email.com", Papel: domain.PapelUsuario, Version: 3}}
+	}
+
+	t.Run("sucesso - merge patch grava apenas o campo alterado", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		usuario, err := s.PatchUser(context.Background(), 1, MergePatch, []byte(`{"email":"ana.souza@email.com"}`), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ana.souza@email.com", usuario.Email)
+		assert.Equal(t, "Ana Souza", usuario.Nome)
+		assert.Nil(t, repo.gravado.Nome)
+		assert.Equal(t, "ana.souza@email.com", *repo.gravado.Email)
+		assert.Equal(t, int64(3), repo.gravado.Version, "a gravação deve ser condicionada à versão lida")
+		assert.Equal(t, int64(4), usuario.Version)
+	})
+
+	t.Run("sucesso - json patch com test e replace", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		patch := `[{"op":"test","path":"/nome","value":"Ana Souza"},{"op":"replace","path":"/nome","value":"Ana Lima"}]`
+		usuario, err := s.PatchUser(context.Background(), 1, JSONPatch, []byte(patch), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Ana Lima", usuario.Nome)
+		assert.Equal(t, "Ana Lima", *repo.gravado.Nome)
+		assert.Nil(t, repo.gravado.Email)
+	})
+
+	t.Run("sucesso - patch sem mudanças não grava nada", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		_, err := s.PatchUser(context.Background(), 1, MergePatch, []byte(`{"nome":"Ana Souza"}`), 0)
+
+		assert.NoError(t, err)
+		assert.Nil(t, repo.gravado)
+	})
+
+	t.Run("erro - json patch com test divergente retorna conflito", func(t *testing.T) {
+		s := NewUsuarioService(novoRepo())
+
+		_, err := s.PatchUser(context.Background(), 1, JSONPatch, []byte(`[{"op":"test","path":"/nome","value":"Outro"}]`), 0)
+
+		assert.True(t, errors.Is(err, ErrPatchConflito))
+	})
+
+	t.Run("erro - remover campo obrigatório falha na validação", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		_, err := s.PatchUser(context.Background(), 1, MergePatch, []byte(`{"nome":null}`), 0)
+
+		var de *domain.Error
+		assert.True(t, errors.As(err, &de))
+		assert.Equal(t, ErrDadosInvalidos.Code, de.Code)
+		assert.Equal(t, "nome", de.Fields[0].Field)
+		assert.Nil(t, repo.gravado)
+	})
+
+	t.Run("erro - campos somente leitura não podem ser alterados", func(t *testing.T) {
+		s := NewUsuarioService(novoRepo())
+
+		_, err := s.PatchUser(context.Background(), 1, MergePatch, []byte(`{"papel":"admin"}`), 0)
+
+		var de *domain.Error
+		assert.True(t, errors.As(err, &de))
+		assert.Equal(t, []domain.FieldError{{Field: "papel", Code: "somente_leitura", Message: "campo não pode ser alterado"}}, de.Fields)
+	})
+
+	t.Run("erro - versão do If-Match diferente da atual retorna 412", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		_, err := s.PatchUser(context.Background(), 1, MergePatch, []byte(`{"nome":"Ana Lima"}`), 2)
+
+		assert.True(t, errors.Is(err, ErrVersaoDesatualizada))
+		assert.Nil(t, repo.gravado)
+	})
+
+	t.Run("sucesso - o e-mail é gravado em minúsculas", func(t *testing.T) {
+		repo := novoRepo()
+		s := NewUsuarioService(repo)
+
+		usuario, err := s.PatchUser(context.Background(), 1, MergePatch, []byte(`{"email":" Ana.Souza@Email.com "}`), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ana.souza@email.com", usuario.Email)
+	})
+
+	t.Run("erro - e-mail de outro usuário retorna 409", func(t *testing.T) {
+		repo := novoRepo()
+		repo.falha = repository.ErrEmailEmUso
+		s := NewUsuarioService(repo)
+
+		_, err := s.PatchUser(context.Background(), 1, MergePatch, []byte(`{"email":"bia@email.com"}`), 0)
+
+		assert.True(t, errors.Is(err, ErrEmailEmUso))
+	})
+
+	t.Run("erro - formato desconhecido", func(t *testing.T) {
+		s := NewUsuarioService(novoRepo())
+
+		_, err := s.PatchUser(context.Background(), 1, "text/plain", []byte(`nome=Ana`), 0)
+
+		assert.True(t, errors.Is(err, ErrFormatoPatchInvalido))
+	})
+}
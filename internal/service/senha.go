@@ -0,0 +1,60 @@
+package service
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parâmetros do hash de senhas: PBKDF2-HMAC-SHA256 com as iterações recomendadas pela OWASP.
+// As iterações ficam gravadas em cada hash, então aumentá-las não invalida as senhas antigas.
+var iteracoesSenha = 600_000
+
+const (
+	senhaMinLen    = 8
+	senhaMaxLen    = 128
+	senhaSaltBytes = 16
+	senhaHashBytes = 32
+	prefixoHash    = "pbkdf2-sha256"
+)
+
+// hashSenha gera o hash da senha no formato pbkdf2-sha256$<iterações>$<salt>$<hash>.
+func hashSenha(senha string) (string, error) {
+	salt := make([]byte, senhaSaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	chave, err := pbkdf2.Key(sha256.New, senha, salt, iteracoesSenha, senhaHashBytes)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", prefixoHash, iteracoesSenha,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(chave)), nil
+}
+
+// verificarSenha compara a senha com um hash gerado por hashSenha, em tempo constante.
+func verificarSenha(senha, hash string) bool {
+	partes := strings.Split(hash, "$")
+	if len(partes) != 4 || partes[0] != prefixoHash {
+		return false
+	}
+	iteracoes, err := strconv.Atoi(partes[1])
+	if err != nil || iteracoes < 1 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(partes[2])
+	if err != nil {
+		return false
+	}
+	esperada, err := base64.RawStdEncoding.DecodeString(partes[3])
+	if err != nil || len(esperada) == 0 {
+		return false
+	}
+	chave, err := pbkdf2.Key(sha256.New, senha, salt, iteracoes, len(esperada))
+	return err == nil && subtle.ConstantTimeCompare(chave, esperada) == 1
+}
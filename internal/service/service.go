@@ -2,34 +2,39 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"log/slog"
-	"os"
-	"strings"
-	"time"
+	"net/http"
 
 	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
 	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
 
-	"github.com/stripe/stripe-go/v78"
-	"github.com/stripe/stripe-go/v78/checkout/session"
-	"github.com/stripe/stripe-go/v78/customer"
-	"github.com/stripe/stripe-go/v78/subscription"
-	"github.com/stripe/stripe-go/v78/webhook"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Erros de negócio relacionados à assinatura.
+var tracer = otel.Tracer("github.com/willjrcristo/go-sqlite-db/internal/service")
+
+// Erros de negócio relacionados ao usuário.
+// O Code de cada erro é estável e faz parte do contrato da API (campo "code" do problem+json).
 var (
-	ErrUsuarioNaoEncontrado = errors.New("usuário não encontrado")
-	ErrDadosInvalidos       = errors.New("dados do usuário inválidos")
-	ErrAssinaturaJaAtiva    = errors.New("usuário já possui uma assinatura ativa")
-	ErrWebhookStripe        = errors.New("erro ao processar webhook da stripe")
+	ErrUsuarioNaoEncontrado = &domain.Error{Code: "usuario_nao_encontrado", Status: http.StatusNotFound, Title: "usuário não encontrado"}
+	ErrDadosInvalidos       = &domain.Error{Code: "dados_invalidos", Status: http.StatusBadRequest, Title: "dados do usuário inválidos"}
+	ErrVersaoDesatualizada  = &domain.Error{Code: "versao_desatualizada", Status: http.StatusPreconditionFailed, Title: "o usuário foi alterado por outra requisição"}
+	ErrEmailNaoVerificado   = &domain.Error{Code: "email_nao_verificado", Status: http.StatusForbidden, Title: "o e-mail do usuário ainda não foi verificado"}
+	ErrEmailEmUso           = &domain.Error{Code: "email_em_uso", Status: http.StatusConflict, Title: "o e-mail já é usado por outro usuário"}
+	ErrDonoDeOrganizacao    = &domain.Error{Code: "dono_de_organizacao", Status: http.StatusConflict, Title: "o usuário é dono de uma organização e não pode ser removido"}
 )
 
-// UsuarioService encapsula a lógica de negócio para usuários e assinaturas.
+// UsuarioService encapsula a lógica de negócio para usuários. As assinaturas pertencem às
+// organizações (veja OrganizacaoService).
 type UsuarioService struct {
-	repo repository.UsuarioRepository
+	repo        repository.UsuarioRepository
+	verificacao *AuthService
+	sessoes     repository.SessaoRepository
 }
 
 // NewUsuarioService cria uma nova instância do UsuarioService.
@@ -39,19 +44,48 @@ func NewUsuarioService(repo repository.UsuarioRepository) *UsuarioService {
 	}
 }
 
+// WithVerificacaoEmail faz o serviço enviar o link de confirmação quando um usuário é
+// cadastrado ou troca de e-mail. Sem ele, nenhum e-mail é enviado.
+func (s *UsuarioService) WithVerificacaoEmail(a *AuthService) *UsuarioService {
+	s.verificacao = a
+	return s
+}
+
+// enviarVerificacao envia o link de confirmação sem falhar a operação: o usuário pode pedir
+// um novo link em POST /auth/verify/resend.
+func (s *UsuarioService) enviarVerificacao(ctx context.Context, usuario domain.Usuario) {
+	if s.verificacao == nil {
+		return
+	}
+	if err := s.verificacao.SendVerification(ctx, usuario); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "Falha ao enviar o e-mail de verificação", "usuario_id", usuario.ID, "error", err)
+	}
+}
+
 // --- MÉTODOS CRUD EXISTENTES ---
 // (CreateUser, GetUserByID, etc. continuam aqui, sem alterações)
-func (s *UsuarioService) CreateUser(ctx context.Context, usuario domain.Usuario) (int64, error) {
-	if usuario.Nome == "" || usuario.Email == "" {
-		return 0, ErrDadosInvalidos
+func (s *UsuarioService) CreateUser(ctx context.Context, usuario domain.Usuario) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.CreateUser")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	normalizarUsuario(&usuario)
+	if err := validarUsuario(usuario); err != nil {
+		return 0, err
 	}
-	if !strings.Contains(usuario.Email, "@") {
-		return 0, ErrDadosInvalidos
+	id, err := s.repo.Create(ctx, usuario)
+	if err != nil {
+		return 0, traduzirErroEscrita(err)
 	}
-	return s.repo.Create(ctx, usuario)
+	metrics.UserSignups.Inc()
+	usuario.ID = id
+	s.enviarVerificacao(ctx, usuario)
+	return id, nil
 }
 
-func (s *UsuarioService) GetUserByID(ctx context.Context, id int64) (*domain.Usuario, error) {
+func (s *UsuarioService) GetUserByID(ctx context.Context, id int64) (_ *domain.Usuario, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.GetUserByID", trace.WithAttributes(attribute.Int64("usuario.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	usuario, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -62,146 +96,79 @@ func (s *UsuarioService) GetUserByID(ctx context.Context, id int64) (*domain.Usu
 	return usuario, nil
 }
 
-func (s *UsuarioService) GetAllUsers(ctx context.Context) ([]domain.Usuario, error) {
-	return s.repo.GetAll(ctx)
+func (s *UsuarioService) GetAllUsers(ctx context.Context, filtro domain.FiltroUsuarios) (_ []domain.Usuario, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.GetAllUsers")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	return s.repo.GetAll(ctx, filtro)
 }
 
-func (s *UsuarioService) UpdateUser(ctx context.Context, id int64, usuario domain.Usuario) error {
-	if usuario.Nome == "" || usuario.Email == "" {
-		return ErrDadosInvalidos
+func (s *UsuarioService) UpdateUser(ctx context.Context, id int64, usuario domain.Usuario) (err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.UpdateUser", trace.WithAttributes(attribute.Int64("usuario.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	normalizarUsuario(&usuario)
+	if err := validarUsuario(usuario); err != nil {
+		return err
 	}
-	_, err := s.GetUserByID(ctx, id)
+	atual, err := s.GetUserByID(ctx, id)
 	if err != nil {
 		return err
 	}
-	return s.repo.Update(ctx, id, usuario)
-}
-
-func (s *UsuarioService) DeleteUser(ctx context.Context, id int64) error {
-	_, err := s.GetUserByID(ctx, id)
-	if err != nil {
+	if err := checarVersao(atual, usuario.Version); err != nil {
 		return err
 	}
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Update(ctx, id, usuario); err != nil {
+		return traduzirErroEscrita(err)
+	}
+	if usuario.Email != atual.Email {
+		usuario.ID = id
+		s.enviarVerificacao(ctx, usuario)
+	}
+	return nil
 }
 
-// --- NOVOS MÉTODOS PARA STRIPE ---
+// DeleteUser remove o usuário. Com versao diferente de zero (vinda do If-Match),
+// só remove se o usuário não tiver sido alterado desde que essa versão foi lida. O dono de uma
+// organização não é removido (ErrDonoDeOrganizacao). As sessões do usuário são revogadas
+// (veja WithSessoes).
+func (s *UsuarioService) DeleteUser(ctx context.Context, id int64, versao int64) (err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.DeleteUser", trace.WithAttributes(attribute.Int64("usuario.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
 
-// CreateCheckoutSession cria uma sessão de pagamento na Stripe.
-func (s *UsuarioService) CreateCheckoutSession(ctx context.Context, userID int64) (string, error) {
-	// 1. Buscar o usuário no nosso banco
-	user, err := s.repo.GetByID(ctx, userID)
-	if err != nil {
-		return "", err
-	}
-	if user == nil {
-		return "", ErrUsuarioNaoEncontrado
-	}
-
-	// 2. Regra de negócio: não permitir criar uma nova sessão se a assinatura já estiver ativa.
-	if user.SubscriptionStatus == "active" {
-		return "", ErrAssinaturaJaAtiva
-	}
-
-	stripeCustomerID := user.StripeCustomerID
-	// 3. Se o usuário ainda não for um cliente na Stripe, crie um.
-	if stripeCustomerID == "" {
-		params := &stripe.CustomerParams{
-			Name:  stripe.String(user.Nome),
-			Email: stripe.String(user.Email),
-		}
-		c, err := customer.New(params)
-		if err != nil {
-			slog.Error("Falha ao criar cliente na Stripe", "error", err)
-			return "", err
-		}
-		stripeCustomerID = c.ID
-		// Salva o novo ID do cliente no nosso banco
-		user.StripeCustomerID = stripeCustomerID
-		if err := s.repo.UpdateSubscriptionDetails(ctx, user.ID, *user); err != nil {
-			return "", err
-		}
-	}
-
-	// 4. Criar a Sessão de Checkout
-	// IMPORTANTE: Substitua os valores de Price ID e URLs pelos seus.
-	params := &stripe.CheckoutSessionParams{
-		Customer:   stripe.String(stripeCustomerID),
-		Mode:       stripe.String(string(stripe.CheckoutSessionModeSubscription)),
-		SuccessURL: stripe.String("http://localhost:3000/sucesso?session_id={CHECKOUT_SESSION_ID}"), // URL do seu frontend
-		CancelURL:  stripe.String("http://localhost:3000/cancelou"),                                // URL do seu frontend
-		LineItems: []*stripe.CheckoutSessionLineItemParams{
-			{
-				Price:    stripe.String("price_SEU_PRICE_ID_AQUI"), // Crie um produto e preço no Dashboard da Stripe
-				Quantity: stripe.Int64(1),
-			},
-		},
-	}
-
-	sess, err := session.New(params)
+	atual, err := s.GetUserByID(ctx, id)
 	if err != nil {
-		slog.Error("Falha ao criar a sessão de checkout na Stripe", "error", err)
-		return "", err
+		return err
 	}
-
-	return sess.URL, nil
+	if err := checarVersao(atual, versao); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id, versao); err != nil {
+		return traduzirErroEscrita(err)
+	}
+	s.revogarSessoes(ctx, id)
+	return nil
 }
 
-// HandleStripeWebhook processa os eventos recebidos da Stripe.
-func (s *UsuarioService) HandleStripeWebhook(payload []byte, signature string) error {
-	// IMPORTANTE: Obtenha este segredo do Dashboard da Stripe (seção Webhooks)
-	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
-
-	// 1. Verificar a assinatura do evento
-	event, err := webhook.ConstructEvent(payload, signature, webhookSecret)
-	if err != nil {
-		slog.Error("Erro ao verificar a assinatura do webhook", "error", err)
-		return ErrWebhookStripe
-	}
-
-	// 2. Processar o evento com base no seu tipo
-	switch event.Type {
-	case "checkout.session.completed":
-		var session stripe.CheckoutSession
-		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
-			return err
-		}
-
-		// Obtenha a assinatura completa para ter a data de expiração
-		sub, err := subscription.Get(session.Subscription.ID, nil)
-		if err != nil {
-			return err
-		}
-
-		// Encontre nosso usuário pelo ID do cliente Stripe
-		user, err := s.repo.GetByStripeID(context.Background(), session.Customer.ID)
-		if err != nil || user == nil {
-			return err
-		}
-
-		// Atualize os dados da assinatura do usuário
-		user.StripeSubscriptionID = sub.ID
-		user.SubscriptionStatus = string(sub.Status)
-		user.SubscriptionCurrentPeriodEnd = time.Unix(sub.CurrentPeriodEnd, 0)
-
-		return s.repo.UpdateSubscriptionDetails(context.Background(), user.ID, *user)
-
-	case "customer.subscription.updated", "customer.subscription.deleted":
-		var sub stripe.Subscription
-		if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
-			return err
-		}
-		user, err := s.repo.GetByStripeID(context.Background(), sub.Customer.ID)
-		if err != nil || user == nil {
-			return err
-		}
-		user.SubscriptionStatus = string(sub.Status)
-		user.SubscriptionCurrentPeriodEnd = time.Unix(sub.CurrentPeriodEnd, 0)
-		return s.repo.UpdateSubscriptionDetails(context.Background(), user.ID, *user)
-
-	default:
-		slog.Info("Webhook da Stripe recebido, mas não tratado", "event_type", event.Type)
+// checarVersao compara a versão esperada pelo cliente (zero para não verificar) com a atual.
+func checarVersao(atual *domain.Usuario, esperada int64) error {
+	if esperada != 0 && atual.Version != esperada {
+		return ErrVersaoDesatualizada
 	}
-
 	return nil
 }
+
+// traduzirErroEscrita converte os conflitos do repositório nos erros de domínio: versão
+// desatualizada (412), e-mail já usado por outro usuário (409) e remoção do dono de uma
+// organização (409).
+func traduzirErroEscrita(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrVersaoDesatualizada):
+		return ErrVersaoDesatualizada.Wrap(err)
+	case errors.Is(err, repository.ErrEmailEmUso):
+		return ErrEmailEmUso.Wrap(err)
+	case errors.Is(err, repository.ErrDonoDeOrganizacao):
+		return ErrDonoDeOrganizacao.Wrap(err)
+	}
+	return err
+}
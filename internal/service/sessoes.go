@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+// Erros das sessões de login.
+var (
+	ErrRefreshTokenInvalido = &domain.Error{Code: "refresh_token_invalido", Status: http.StatusUnauthorized, Title: "sessão inválida ou expirada; entre de novo"}
+	ErrSessaoNaoEncontrada  = &domain.Error{Code: "sessao_nao_encontrada", Status: http.StatusNotFound, Title: "sessão não encontrada"}
+)
+
+const (
+	// validadeSessao é o prazo de uma sessão a partir do login. As renovações não o estendem:
+	// depois dele, o usuário entra de novo.
+	validadeSessao = 30 * 24 * time.Hour
+
+	// Limites do que é guardado sobre a origem da sessão. O resto é descartado.
+	dispositivoMaxLen = 100
+	userAgentMaxLen   = 512
+)
+
+// atorSistema identifica na trilha de auditoria as ações tomadas pela própria API.
+const atorSistema = "sistema"
+
+// WithSessoes faz cada login abrir uma sessão, renovável com refresh tokens de uso único em
+// Refresh. O token de acesso fica amarrado à sessão e deixa de valer quando ela é revogada.
+// Sem ele, o login só emite o token de acesso, que vale até expirar.
+func (s *AuthService) WithSessoes(repo repository.SessaoRepository) *AuthService {
+	s.sessoes = repo
+	return s
+}
+
+// abrirSessao grava a sessão do login e devolve o seu ID e o primeiro refresh token.
+func (s *AuthService) abrirSessao(ctx context.Context, usuarioID int64, comMFA bool, origem domain.OrigemSessao) (int64, string, error) {
+	origem = normalizarOrigem(origem)
+	agora := s.agora()
+	refresh := novoRefreshToken()
+	id, err := s.sessoes.CriarSessao(ctx, domain.Sessao{
+		UsuarioID:   usuarioID,
+		Dispositivo: origem.Dispositivo,
+		IP:          origem.IP,
+		UserAgent:   origem.UserAgent,
+		ComMFA:      comMFA,
+		CreatedAt:   agora,
+		LastUsedAt:  agora,
+		ExpiresAt:   agora.Add(validadeSessao),
+	}, hashAPIKey(refresh))
+	if err != nil {
+		return 0, "", err
+	}
+	return id, refresh, nil
+}
+
+// Refresh troca o refresh token por um novo token de acesso e um novo refresh token da mesma
+// sessão. Cada refresh token vale uma única vez: reapresentar um já trocado indica que ele
+// vazou, e a sessão inteira é revogada, derrubando também quem o usou primeiro.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, origem domain.OrigemSessao) (_ *domain.Login, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Refresh")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if s.sessoes == nil || refreshToken == "" {
+		return nil, ErrRefreshTokenInvalido
+	}
+	hash := hashAPIKey(refreshToken)
+	sessao, usado, err := s.buscarSessaoDoRefresh(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !sessao.Ativa(s.agora()) {
+		return nil, ErrRefreshTokenInvalido
+	}
+	span.SetAttributes(attribute.Int64("sessao.id", sessao.ID), attribute.Int64("usuario.id", sessao.UsuarioID))
+	if usado {
+		s.revogarPorReuso(ctx, sessao)
+		return nil, ErrRefreshTokenInvalido
+	}
+	usuario, err := s.repo.GetByID(ctx, sessao.UsuarioID)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil {
+		return nil, ErrRefreshTokenInvalido
+	}
+
+	novo := novoRefreshToken()
+	ok, err := s.sessoes.Rotacionar(ctx, sessao.ID, hash, hashAPIKey(novo), normalizarOrigem(origem))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Outra requisição trocou o mesmo token entre a busca e a rotação.
+		s.revogarPorReuso(ctx, sessao)
+		return nil, ErrRefreshTokenInvalido
+	}
+	login, err := s.tokenDeAcesso(*usuario, sessao.ComMFA, sessao.ID)
+	if err != nil {
+		return nil, err
+	}
+	login.RefreshToken = novo
+	return login, nil
+}
+
+// Logout revoga a sessão do refresh token. Um token desconhecido ou de uma sessão já
+// encerrada não é erro: o resultado é o mesmo.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) (err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Logout")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if s.sessoes == nil || refreshToken == "" {
+		return nil
+	}
+	sessao, _, err := s.buscarSessaoDoRefresh(ctx, hashAPIKey(refreshToken))
+	if err != nil || sessao == nil {
+		return err
+	}
+	err = s.sessoes.Revogar(ctx, sessao.UsuarioID, sessao.ID, domain.MotivoLogout)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return nil
+	}
+	return err
+}
+
+// buscarSessaoDoRefresh devolve a sessão do refresh token (nil se ele não existir) e se o
+// token já foi trocado.
+func (s *AuthService) buscarSessaoDoRefresh(ctx context.Context, hash string) (*domain.Sessao, bool, error) {
+	sessaoID, usado, err := s.sessoes.BuscarRefreshToken(ctx, hash)
+	if err != nil || sessaoID == 0 {
+		return nil, false, err
+	}
+	sessao, err := s.sessoes.GetSessao(ctx, sessaoID)
+	return sessao, usado, err
+}
+
+// sessaoAtiva confere se a sessão do token de acesso continua ativa e é do usuário do token.
+func (s *AuthService) sessaoAtiva(ctx context.Context, usuarioID, sessaoID int64) (bool, error) {
+	if s.sessoes == nil {
+		return true, nil
+	}
+	sessao, err := s.sessoes.GetSessao(ctx, sessaoID)
+	if err != nil {
+		return false, err
+	}
+	return sessao.Ativa(s.agora()) && sessao.UsuarioID == usuarioID, nil
+}
+
+// revogarPorReuso revoga a sessão cujo refresh token foi reapresentado e registra o evento na
+// trilha de auditoria. Como em auditar, uma falha é apenas logada: a renovação já foi recusada.
+func (s *AuthService) revogarPorReuso(ctx context.Context, sessao *domain.Sessao) {
+	logger := logging.FromContext(ctx)
+	logger.WarnContext(ctx, "Refresh token reutilizado; revogando a sessão", "sessao_id", sessao.ID, "usuario_id", sessao.UsuarioID)
+	err := s.sessoes.Revogar(ctx, sessao.UsuarioID, sessao.ID, domain.MotivoReusoDetectado)
+	if err != nil && !errors.Is(err, repository.ErrNaoEncontrado) {
+		logger.ErrorContext(ctx, "Erro ao revogar a sessão", "sessao_id", sessao.ID, "error", err)
+	}
+	s.auditar(ctx, atorSistema, sessao.UsuarioID, domain.AcaoReusoRefreshToken, map[string]any{"sessao_id": sessao.ID})
+}
+
+// WithSessoes permite listar e revogar as sessões dos usuários e faz DeleteUser (e as remoções
+// de BatchUsers) revogar as sessões do usuário removido. Sem ele, não há sessões a listar.
+func (s *UsuarioService) WithSessoes(repo repository.SessaoRepository) *UsuarioService {
+	s.sessoes = repo
+	return s
+}
+
+// ListSessions devolve as sessões ativas do usuário, da usada mais recentemente à mais antiga.
+func (s *UsuarioService) ListSessions(ctx context.Context, id int64) (_ []domain.Sessao, err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.ListSessions", trace.WithAttributes(attribute.Int64("usuario.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if _, err := s.GetUserByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if s.sessoes == nil {
+		return []domain.Sessao{}, nil
+	}
+	return s.sessoes.ListAtivas(ctx, id)
+}
+
+// RevokeSessions revoga todas as sessões do usuário. Os tokens de acesso delas deixam de
+// valer imediatamente.
+func (s *UsuarioService) RevokeSessions(ctx context.Context, id int64) (err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.RevokeSessions", trace.WithAttributes(attribute.Int64("usuario.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if _, err := s.GetUserByID(ctx, id); err != nil {
+		return err
+	}
+	if s.sessoes == nil {
+		return nil
+	}
+	n, err := s.sessoes.RevogarTodas(ctx, id, domain.MotivoRevogada)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).InfoContext(ctx, "Sessões revogadas", "usuario_id", id, "sessoes", n)
+	return nil
+}
+
+// RevokeSession revoga uma sessão do usuário. Revogar uma sessão já revogada não é erro.
+func (s *UsuarioService) RevokeSession(ctx context.Context, id, sessaoID int64) (err error) {
+	ctx, span := tracer.Start(ctx, "UsuarioService.RevokeSession",
+		trace.WithAttributes(attribute.Int64("usuario.id", id), attribute.Int64("sessao.id", sessaoID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if _, err := s.GetUserByID(ctx, id); err != nil {
+		return err
+	}
+	if s.sessoes == nil {
+		return ErrSessaoNaoEncontrada
+	}
+	sessao, err := s.sessoes.GetSessao(ctx, sessaoID)
+	if err != nil {
+		return err
+	}
+	if sessao == nil || sessao.UsuarioID != id {
+		return ErrSessaoNaoEncontrada
+	}
+	err = s.sessoes.Revogar(ctx, id, sessaoID, domain.MotivoRevogada)
+	if errors.Is(err, repository.ErrNaoEncontrado) {
+		return nil // Já revogada.
+	}
+	return err
+}
+
+// revogarSessoes revoga as sessões do usuário removido sem falhar a remoção, que já foi
+// feita: sem o usuário, as sessões não são mais renovadas de qualquer forma.
+func (s *UsuarioService) revogarSessoes(ctx context.Context, id int64) {
+	if s.sessoes == nil {
+		return
+	}
+	if _, err := s.sessoes.RevogarTodas(ctx, id, domain.MotivoUsuarioRemovido); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "Erro ao revogar as sessões do usuário removido", "usuario_id", id, "error", err)
+	}
+}
+
+// normalizarOrigem limpa os dados de origem informados pelo cliente e corta os longos demais.
+func normalizarOrigem(o domain.OrigemSessao) domain.OrigemSessao {
+	o.Dispositivo = cortar(strings.Join(strings.Fields(o.Dispositivo), " "), dispositivoMaxLen)
+	o.UserAgent = cortar(strings.TrimSpace(o.UserAgent), userAgentMaxLen)
+	return o
+}
+
+// cortar limita s a max caracteres, sem quebrar um caractere UTF-8 ao meio.
+func cortar(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// novoRefreshToken gera um refresh token de 26 caracteres aleatórios (130 bits).
+func novoRefreshToken() string {
+	return rand.Text()
+}
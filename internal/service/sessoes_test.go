@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/tokens"
+)
+
+// sessaoRepoFake guarda as sessões e, por hash, a sessão de cada refresh token e se ele já foi usado.
+type sessaoRepoFake struct {
+	sessoes map[int64]*domain.Sessao
+	tokens  map[string]int64
+	usados  map[string]bool
+}
+
+func (f *sessaoRepoFake) Close() error { return nil }
+
+func novoSessaoRepoFake() *sessaoRepoFake {
+	return &sessaoRepoFake{sessoes: map[int64]*domain.Sessao{}, tokens: map[string]int64{}, usados: map[string]bool{}}
+}
+
+func (f *sessaoRepoFake) CriarSessao(ctx context.Context, sessao domain.Sessao, hashRefresh string) (int64, error) {
+	sessao.ID = int64(len(f.sessoes) + 1)
+	f.sessoes[sessao.ID] = &sessao
+	f.tokens[hashRefresh] = sessao.ID
+	return sessao.ID, nil
+}
+
+func (f *sessaoRepoFake) GetSessao(ctx context.Context, id int64) (*domain.Sessao, error) {
+	s, ok := f.sessoes[id]
+	if !ok {
+		return nil, nil
+	}
+	c := *s
+	return &c, nil
+}
+
+func (f *sessaoRepoFake) BuscarRefreshToken(ctx context.Context, hash string) (int64, bool, error) {
+	return f.tokens[hash], f.usados[hash], nil
+}
+
+func (f *sessaoRepoFake) Rotacionar(ctx context.Context, sessaoID int64, hashAtual, hashNovo string, origem domain.OrigemSessao) (bool, error) {
+	if f.usados[hashAtual] {
+		return false, nil
+	}
+	f.usados[hashAtual] = true
+	f.tokens[hashNovo] = sessaoID
+	f.sessoes[sessaoID].IP = origem.IP
+	return true, nil
+}
+
+func (f *sessaoRepoFake) ListAtivas(ctx context.Context, usuarioID int64) ([]domain.Sessao, error) {
+	var ativas []domain.Sessao
+	for _, s := range f.sessoes {
+		if s.UsuarioID == usuarioID && s.RevokedAt == nil {
+			ativas = append(ativas, *s)
+		}
+	}
+	return ativas, nil
+}
+
+func (f *sessaoRepoFake) Revogar(ctx context.Context, usuarioID, sessaoID int64, motivo string) error {
+	s, ok := f.sessoes[sessaoID]
+	if !ok || s.UsuarioID != usuarioID || s.RevokedAt != nil {
+		return repository.ErrNaoEncontrado
+	}
+	agora := time.Now()
+	s.RevokedAt, s.MotivoRevogacao = &agora, motivo
+	return nil
+}
+
+func (f *sessaoRepoFake) RevogarTodas(ctx context.Context, usuarioID int64, motivo string) (int64, error) {
+	var n int64
+	for id, s := range f.sessoes {
+		if s.UsuarioID == usuarioID && f.Revogar(ctx, usuarioID, id, motivo) == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *authRepoFake) Delete(ctx context.Context, id int64, versao int64) error {
+	if f.falhaRemocao != nil {
+		return f.falhaRemocao
+	}
+	if id != f.usuario.ID {
+		return repository.ErrNaoEncontrado
+	}
+	f.usuario = domain.Usuario{}
+	return nil
+}
+
+func TestAuthService_Sessoes(t *testing.T) {
+	ctx := context.Background()
+	iteracoesOriginais := iteracoesSenha
+	iteracoesSenha = 1000
+	t.Cleanup(func() { iteracoesSenha = iteracoesOriginais })
+
+	type cenario struct {
+		s         *AuthService
+		repo      *authRepoFake
+		sessoes   *sessaoRepoFake
+		auditoria *auditoriaFake
+		mailer    *mailerFake
+		agora     time.Time
+	}
+	novo := func(t *testing.T) *cenario {
+		hash, err := hashSenha("senha-certa")
+		require.NoError(t, err)
+		c := &cenario{
+			repo:      &authRepoFake{usuario: domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com"}, hash: hash},
+			sessoes:   novoSessaoRepoFake(),
+			auditoria: &auditoriaFake{},
+			mailer:    &mailerFake{},
+			agora:     time.Now(),
+		}
+		c.s = NewAuthService(c.repo, c.mailer, tokens.NewAssinador([]byte("segredo")), "").
+			WithSessoes(c.sessoes).
+			WithAuditoria(c.auditoria)
+		c.s.agora = func() time.Time { return c.agora }
+		return c
+	}
+	origem := domain.OrigemSessao{Dispositivo: "  iPhone   da Ana ", IP: "203.0.113.7", UserAgent: "app/1.0"}
+	login := func(t *testing.T, c *cenario) *domain.Login {
+		t.Helper()
+		l, err := c.s.Login(ctx, "ana@email.com", "senha-certa", origem)
+		require.NoError(t, err)
+		return l
+	}
+
+	t.Run("sucesso - o login abre a sessão com a origem e o refresh token", func(t *testing.T) {
+		c := novo(t)
+
+		l := login(t, c)
+
+		assert.NotEmpty(t, l.RefreshToken)
+		require.Equal(t, int64(1), l.SessaoID)
+		sessao := c.sessoes.sessoes[1]
+		assert.Equal(t, "iPhone da Ana", sessao.Dispositivo)
+		assert.Equal(t, "203.0.113.7", sessao.IP)
+		assert.Equal(t, c.agora.Add(validadeSessao), sessao.ExpiresAt)
+		usuario, err := c.s.AuthenticateAccessToken(ctx, l.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usuario.ID)
+	})
+
+	t.Run("sucesso - a renovação troca os dois tokens e mantém a sessão", func(t *testing.T) {
+		c := novo(t)
+		l := login(t, c)
+
+		renovado, err := c.s.Refresh(ctx, l.RefreshToken, domain.OrigemSessao{IP: "198.51.100.1"})
+
+		require.NoError(t, err)
+		assert.Equal(t, l.SessaoID, renovado.SessaoID)
+		assert.NotEqual(t, l.RefreshToken, renovado.RefreshToken)
+		assert.Equal(t, "198.51.100.1", c.sessoes.sessoes[1].IP)
+		_, err = c.s.AuthenticateAccessToken(ctx, renovado.AccessToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("erro - reusar um refresh token revoga a família inteira", func(t *testing.T) {
+		c := novo(t)
+		l := login(t, c)
+		renovado, err := c.s.Refresh(ctx, l.RefreshToken, origem)
+		require.NoError(t, err)
+
+		_, err = c.s.Refresh(ctx, l.RefreshToken, origem)
+
+		assert.ErrorIs(t, err, ErrRefreshTokenInvalido)
+		assert.Equal(t, domain.MotivoReusoDetectado, c.sessoes.sessoes[1].MotivoRevogacao)
+		_, err = c.s.Refresh(ctx, renovado.RefreshToken, origem)
+		assert.ErrorIs(t, err, ErrRefreshTokenInvalido, "o token legítimo também deixa de valer")
+		_, err = c.s.AuthenticateAccessToken(ctx, renovado.AccessToken)
+		assert.ErrorIs(t, err, ErrTokenAcessoInvalido)
+		require.Len(t, c.auditoria.eventos, 1)
+		assert.Equal(t, domain.EventoAuditoria{
+			Ator: atorSistema, Acao: domain.AcaoReusoRefreshToken, UsuarioID: 1,
+			Detalhes: map[string]any{"sessao_id": int64(1)}, CreatedAt: c.agora,
+		}, c.auditoria.eventos[0])
+	})
+
+	t.Run("erro - token desconhecido, sessão expirada ou usuário removido", func(t *testing.T) {
+		c := novo(t)
+		_, err := c.s.Refresh(ctx, "inventado", origem)
+		assert.ErrorIs(t, err, ErrRefreshTokenInvalido)
+
+		l := login(t, c)
+		c.agora = c.agora.Add(validadeSessao)
+		_, err = c.s.Refresh(ctx, l.RefreshToken, origem)
+		assert.ErrorIs(t, err, ErrRefreshTokenInvalido)
+
+		c = novo(t)
+		l = login(t, c)
+		c.repo.usuario = domain.Usuario{}
+		_, err = c.s.Refresh(ctx, l.RefreshToken, origem)
+		assert.ErrorIs(t, err, ErrRefreshTokenInvalido)
+	})
+
+	t.Run("sucesso - o logout revoga a sessão e o token de acesso dela", func(t *testing.T) {
+		c := novo(t)
+		l := login(t, c)
+
+		require.NoError(t, c.s.Logout(ctx, l.RefreshToken))
+		require.NoError(t, c.s.Logout(ctx, l.RefreshToken), "logout repetido não é erro")
+		require.NoError(t, c.s.Logout(ctx, "inventado"))
+
+		assert.Equal(t, domain.MotivoLogout, c.sessoes.sessoes[1].MotivoRevogacao)
+		_, err := c.s.AuthenticateAccessToken(ctx, l.AccessToken)
+		assert.ErrorIs(t, err, ErrTokenAcessoInvalido)
+		_, err = c.s.Refresh(ctx, l.RefreshToken, origem)
+		assert.ErrorIs(t, err, ErrRefreshTokenInvalido)
+	})
+
+	t.Run("sucesso - redefinir a senha revoga as sessões abertas antes", func(t *testing.T) {
+		c := novo(t)
+		l := login(t, c)
+		require.NoError(t, c.s.RequestPasswordReset(ctx, "ana@email.com"))
+
+		require.NoError(t, c.s.ResetPassword(ctx, tokenEnviado(t, c.mailer), "nova-senha-123"))
+
+		assert.Equal(t, domain.MotivoSenhaRedefinida, c.sessoes.sessoes[1].MotivoRevogacao)
+		_, err := c.s.Refresh(ctx, l.RefreshToken, origem)
+		assert.ErrorIs(t, err, ErrRefreshTokenInvalido)
+		_, err = c.s.AuthenticateAccessToken(ctx, l.AccessToken)
+		assert.ErrorIs(t, err, ErrTokenAcessoInvalido)
+		require.Len(t, c.auditoria.eventos, 1)
+		assert.Equal(t, domain.EventoAuditoria{
+			Ator: "usuario:1", Acao: domain.AcaoSenhaRedefinida, UsuarioID: 1,
+			Detalhes: map[string]any{"sessoes_revogadas": int64(1)}, CreatedAt: c.agora,
+		}, c.auditoria.eventos[0])
+	})
+
+	t.Run("sucesso - sem WithSessoes o login só emite o token de acesso", func(t *testing.T) {
+		c := novo(t)
+		c.s.sessoes = nil
+
+		l := login(t, c)
+
+		assert.Empty(t, l.RefreshToken)
+		assert.Zero(t, l.SessaoID)
+		_, err := c.s.AuthenticateAccessToken(ctx, l.AccessToken)
+		assert.NoError(t, err)
+	})
+}
+
+func TestUsuarioService_Sessions(t *testing.T) {
+	ctx := context.Background()
+	novo := func() (*UsuarioService, *sessaoRepoFake) {
+		repo := &authRepoFake{usuario: domain.Usuario{ID: 1, Nome: "Ana", Email: "ana@email.com"}}
+		sessoes := novoSessaoRepoFake()
+		agora := time.Now()
+		for i, usuarioID := range []int64{1, 1, 2} {
+			sessoes.CriarSessao(ctx, domain.Sessao{UsuarioID: usuarioID, ExpiresAt: agora.Add(time.Hour)}, "h"+strconv.Itoa(i))
+		}
+		return NewUsuarioService(repo).WithSessoes(sessoes), sessoes
+	}
+
+	t.Run("sucesso - lista e revoga as sessões do usuário", func(t *testing.T) {
+		s, sessoes := novo()
+
+		lista, err := s.ListSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Len(t, lista, 2)
+
+		require.NoError(t, s.RevokeSession(ctx, 1, 1))
+		require.NoError(t, s.RevokeSession(ctx, 1, 1), "revogar de novo não é erro")
+		assert.Equal(t, domain.MotivoRevogada, sessoes.sessoes[1].MotivoRevogacao)
+
+		require.NoError(t, s.RevokeSessions(ctx, 1))
+		lista, err = s.ListSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Empty(t, lista)
+		assert.Nil(t, sessoes.sessoes[3].RevokedAt, "as de outro usuário ficam intactas")
+	})
+
+	t.Run("erro - sessão de outro usuário ou usuário inexistente", func(t *testing.T) {
+		s, _ := novo()
+
+		assert.ErrorIs(t, s.RevokeSession(ctx, 1, 3), ErrSessaoNaoEncontrada)
+		assert.ErrorIs(t, s.RevokeSession(ctx, 1, 99), ErrSessaoNaoEncontrada)
+		_, err := s.ListSessions(ctx, 9)
+		assert.ErrorIs(t, err, ErrUsuarioNaoEncontrado)
+		assert.ErrorIs(t, s.RevokeSessions(ctx, 9), ErrUsuarioNaoEncontrado)
+	})
+
+	t.Run("sucesso - remover o usuário revoga as sessões dele", func(t *testing.T) {
+		s, sessoes := novo()
+
+		require.NoError(t, s.DeleteUser(ctx, 1, 0))
+
+		assert.Equal(t, domain.MotivoUsuarioRemovido, sessoes.sessoes[1].MotivoRevogacao)
+		assert.Equal(t, domain.MotivoUsuarioRemovido, sessoes.sessoes[2].MotivoRevogacao)
+		assert.Nil(t, sessoes.sessoes[3].RevokedAt)
+	})
+
+	t.Run("erro - o dono de uma organização não é removido e mantém as sessões", func(t *testing.T) {
+		repo := &authRepoFake{usuario: domain.Usuario{ID: 1, Nome: "Ana"}, falhaRemocao: repository.ErrDonoDeOrganizacao}
+		sessoes := novoSessaoRepoFake()
+		sessoes.sessoes[1] = &domain.Sessao{ID: 1, UsuarioID: 1}
+		s := NewUsuarioService(repo).WithSessoes(sessoes)
+
+		err := s.DeleteUser(ctx, 1, 0)
+
+		var de *domain.Error
+		require.True(t, errors.As(err, &de))
+		assert.Equal(t, ErrDonoDeOrganizacao.Code, de.Code)
+		assert.Equal(t, http.StatusConflict, de.Status)
+		assert.Nil(t, sessoes.sessoes[1].RevokedAt)
+	})
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/logging"
+	"github.com/willjrcristo/go-sqlite-db/internal/metrics"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+	"github.com/willjrcristo/go-sqlite-db/internal/telemetry"
+)
+
+const (
+	// janelaReporteUso é a idade máxima de um evento reportado: a Stripe só aceita meter
+	// events dos últimos 35 dias.
+	janelaReporteUso = 35 * 24 * time.Hour
+	// loteEventosUso e loteRelatoriosUso limitam o trabalho de cada rodada de ReportUsage.
+	loteEventosUso    = 1000
+	loteRelatoriosUso = 100
+	// maxTentativasUso é quantas vezes um relatório é enviado antes de ficar só registrado
+	// (com o último erro) em usage_reports.
+	maxTentativasUso = 10
+)
+
+// UsoService mede o consumo dos usuários e o reporta ao provedor de cobrança. O consumo é
+// cobrado da organização de assinatura ativa do usuário (veja UsoRepository.OrganizacaoCobrada).
+type UsoService struct {
+	repo     repository.UsoRepository
+	orgs     repository.OrganizacaoRepository
+	usuarios repository.UsuarioRepository
+	provider billing.Provider
+	agora    func() time.Time
+}
+
+// NewUsoService cria uma nova instância do UsoService.
+func NewUsoService(repo repository.UsoRepository, orgs repository.OrganizacaoRepository, usuarios repository.UsuarioRepository, p billing.Provider) *UsoService {
+	return &UsoService{repo: repo, orgs: orgs, usuarios: usuarios, provider: p, agora: time.Now}
+}
+
+// RegistrarUso grava um consumo do usuário. A chave, gerada pelo servidor para cada evento,
+// evita que o mesmo evento seja contado duas vezes.
+func (s *UsoService) RegistrarUso(ctx context.Context, usuarioID int64, metrica string, quantidade int64, chave string) (err error) {
+	ctx, span := tracer.Start(ctx, "UsoService.RegistrarUso", trace.WithAttributes(attribute.Int64("usuario.id", usuarioID)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	evento := domain.EventoUso{UsuarioID: usuarioID, Metrica: metrica, Quantidade: quantidade, Chave: chave, OcorridoEm: s.agora()}
+	if err := s.repo.Registrar(ctx, evento); err != nil {
+		return err
+	}
+	metrics.UsageEvents.WithLabelValues(metrica).Add(float64(quantidade))
+	return nil
+}
+
+// GetUsage devolve o consumo do usuário no período de cobrança atual.
+func (s *UsoService) GetUsage(ctx context.Context, id int64) (_ *domain.Uso, err error) {
+	ctx, span := tracer.Start(ctx, "UsoService.GetUsage", trace.WithAttributes(attribute.Int64("usuario.id", id)))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	usuario, err := s.usuarios.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil {
+		return nil, ErrUsuarioNaoEncontrado
+	}
+	org, err := s.repo.OrganizacaoCobrada(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	uso := &domain.Uso{UsuarioID: id}
+	var fimAtual *time.Time
+	if org != nil {
+		uso.OrganizacaoID, fimAtual = org.ID, org.SubscriptionCurrentPeriodEnd
+	}
+	uso.PeriodoInicio, uso.PeriodoFim = domain.PeriodoCobranca(fimAtual, s.agora())
+	if uso.Metricas, err = s.repo.Consumo(ctx, id, uso.PeriodoInicio, uso.PeriodoFim); err != nil {
+		return nil, err
+	}
+	return uso, nil
+}
+
+// ReportUsage faz uma rodada de reporte: agrega os eventos pendentes em relatórios por
+// organização, métrica e período de cobrança e envia ao provedor, em um lote, os relatórios
+// ainda não aceitos. Um lote recusado é reenviado inteiro na rodada seguinte; o identificador
+// de cada relatório impede a cobrança em dobro. Devolve quantos relatórios foram aceitos.
+func (s *UsoService) ReportUsage(ctx context.Context) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "UsoService.ReportUsage")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if err := s.agregar(ctx); err != nil {
+		return 0, fmt.Errorf("agregando o consumo: %w", err)
+	}
+
+	relatorios, err := s.repo.RelatoriosPendentes(ctx, maxTentativasUso, loteRelatoriosUso)
+	if err != nil || len(relatorios) == 0 {
+		return 0, err
+	}
+	lote := make([]billing.Consumo, len(relatorios))
+	ids := make([]int64, len(relatorios))
+	for i, rel := range relatorios {
+		lote[i] = billing.Consumo{Identificador: rel.Identificador, ClienteID: rel.StripeCustomerID, Metrica: rel.Metrica, Quantidade: rel.Quantidade, Momento: rel.Momento}
+		ids[i] = rel.ID
+	}
+
+	if err := s.provider.ReportarUso(ctx, lote); err != nil {
+		metrics.UsageReports.WithLabelValues("error").Add(float64(len(lote)))
+		if errFalha := s.repo.MarcarFalha(ctx, ids, err.Error()); errFalha != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "Falha ao registrar o erro do reporte de consumo", "error", errFalha)
+		}
+		return 0, fmt.Errorf("reportando %d relatórios de consumo: %w", len(lote), err)
+	}
+	metrics.UsageReports.WithLabelValues("success").Add(float64(len(lote)))
+	return len(lote), s.repo.MarcarReportados(ctx, ids)
+}
+
+// agregar transforma os eventos pendentes em relatórios, um por organização, métrica e
+// período de cobrança.
+func (s *UsoService) agregar(ctx context.Context) error {
+	eventos, err := s.repo.EventosPendentes(ctx, s.agora().Add(-janelaReporteUso), loteEventosUso)
+	if err != nil || len(eventos) == 0 {
+		return err
+	}
+
+	type grupo struct {
+		organizacaoID int64
+		metrica       string
+		inicio        time.Time
+	}
+	orgs := make(map[int64]*domain.Organizacao)
+	grupos := make(map[grupo]int)
+	var relatorios []domain.RelatorioUso
+	for _, e := range eventos {
+		org, ok := orgs[e.OrganizacaoID]
+		if !ok {
+			if org, err = s.orgs.Get(ctx, e.OrganizacaoID); err != nil {
+				return err
+			}
+			if org == nil {
+				continue // Removida desde a consulta; o evento fica para a próxima rodada.
+			}
+			orgs[e.OrganizacaoID] = org
+		}
+		inicio, _ := domain.PeriodoCobranca(org.SubscriptionCurrentPeriodEnd, e.OcorridoEm)
+		g := grupo{e.OrganizacaoID, e.Metrica, inicio}
+		i, ok := grupos[g]
+		if !ok {
+			i = len(relatorios)
+			grupos[g] = i
+			relatorios = append(relatorios, domain.RelatorioUso{OrganizacaoID: e.OrganizacaoID, Metrica: e.Metrica, PeriodoInicio: inicio, Identificador: "uso_" + rand.Text()})
+		}
+		rel := &relatorios[i]
+		rel.Quantidade += e.Quantidade
+		rel.EventoIDs = append(rel.EventoIDs, e.ID)
+		if e.OcorridoEm.After(rel.Momento) {
+			rel.Momento = e.OcorridoEm
+		}
+	}
+
+	err = s.repo.CriarRelatorios(ctx, relatorios)
+	if errors.Is(err, repository.ErrEventosJaAgregados) {
+		// Outra instância agregou ao mesmo tempo: os eventos restantes ficam para a próxima rodada.
+		logging.FromContext(ctx).InfoContext(ctx, "Eventos de consumo agregados por outra instância")
+		return nil
+	}
+	return err
+}
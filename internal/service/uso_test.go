@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/willjrcristo/go-sqlite-db/internal/billing"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/repository"
+)
+
+// usoRepoFake guarda eventos e relatórios em memória. cobradas indica a organização cobrada
+// de cada usuário.
+type usoRepoFake struct {
+	repository.UsoRepository
+	orgs       *orgRepoFake
+	cobradas   map[int64]int64
+	eventos    []domain.EventoUso
+	agregados  map[int64]int64 // evento -> relatório
+	relatorios []domain.RelatorioUso
+	aceitos    map[int64]bool
+}
+
+func (f *usoRepoFake) Registrar(ctx context.Context, evento domain.EventoUso) error {
+	for _, e := range f.eventos {
+		if evento.Chave != "" && e.Chave == evento.Chave {
+			return nil
+		}
+	}
+	evento.ID = int64(len(f.eventos) + 1)
+	f.eventos = append(f.eventos, evento)
+	return nil
+}
+
+func (f *usoRepoFake) Consumo(ctx context.Context, usuarioID int64, inicio, fim time.Time) ([]domain.ConsumoMetrica, error) {
+	consumo := []domain.ConsumoMetrica{}
+	for _, e := range f.eventos {
+		if e.UsuarioID != usuarioID || e.OcorridoEm.Before(inicio) || !e.OcorridoEm.Before(fim) {
+			continue
+		}
+		if len(consumo) == 0 {
+			consumo = append(consumo, domain.ConsumoMetrica{Metrica: e.Metrica})
+		}
+		consumo[0].Quantidade += e.Quantidade
+		if relID, ok := f.agregados[e.ID]; ok && f.aceitos[relID] {
+			consumo[0].Reportado += e.Quantidade
+		}
+	}
+	return consumo, nil
+}
+
+func (f *usoRepoFake) OrganizacaoCobrada(ctx context.Context, usuarioID int64) (*domain.Organizacao, error) {
+	id, ok := f.cobradas[usuarioID]
+	if !ok {
+		return nil, nil
+	}
+	return f.orgs.Get(ctx, id)
+}
+
+func (f *usoRepoFake) EventosPendentes(ctx context.Context, desde time.Time, limite int) ([]domain.EventoUso, error) {
+	var pendentes []domain.EventoUso
+	for _, e := range f.eventos {
+		orgID, ok := f.cobradas[e.UsuarioID]
+		if _, agregado := f.agregados[e.ID]; ok && !agregado && !e.OcorridoEm.Before(desde) {
+			e.OrganizacaoID = orgID
+			pendentes = append(pendentes, e)
+		}
+	}
+	return pendentes, nil
+}
+
+func (f *usoRepoFake) CriarRelatorios(ctx context.Context, relatorios []domain.RelatorioUso) error {
+	for _, rel := range relatorios {
+		rel.ID = int64(len(f.relatorios) + 1)
+		rel.StripeCustomerID = f.orgs.orgs[rel.OrganizacaoID].StripeCustomerID
+		for _, id := range rel.EventoIDs {
+			f.agregados[id] = rel.ID
+		}
+		f.relatorios = append(f.relatorios, rel)
+	}
+	return nil
+}
+
+func (f *usoRepoFake) RelatoriosPendentes(ctx context.Context, maxTentativas, limite int) ([]domain.RelatorioUso, error) {
+	var pendentes []domain.RelatorioUso
+	for _, rel := range f.relatorios {
+		if !f.aceitos[rel.ID] && rel.Tentativas < maxTentativas {
+			pendentes = append(pendentes, rel)
+		}
+	}
+	return pendentes, nil
+}
+
+func (f *usoRepoFake) MarcarReportados(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		f.aceitos[id] = true
+	}
+	return nil
+}
+
+func (f *usoRepoFake) MarcarFalha(ctx context.Context, ids []int64, erro string) error {
+	for _, id := range ids {
+		f.relatorios[id-1].Tentativas++
+	}
+	return nil
+}
+
+func TestUsoService(t *testing.T) {
+	ctx := context.Background()
+	agora := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+	fimPeriodo := time.Date(2026, 4, 5, 0, 0, 0, 0, time.UTC)
+	novo := func() (*UsoService, *usoRepoFake, *billing.StubProvider) {
+		usuarios := &usuariosOIDCFake{usuarios: []domain.Usuario{{ID: 1, Nome: "Ana", Email: "ana@email.com"}, {ID: 2, Nome: "Bia", Email: "bia@email.com"}}}
+		orgs := novoOrgRepoFake(usuarios)
+		orgs.orgs[1] = &domain.Organizacao{ID: 1, Nome: "Acme", StripeCustomerID: "cus_acme", SubscriptionStatus: "active", SubscriptionCurrentPeriodEnd: &fimPeriodo}
+		repo := &usoRepoFake{orgs: orgs, cobradas: map[int64]int64{1: 1}, agregados: map[int64]int64{}, aceitos: map[int64]bool{}}
+		provider := &billing.StubProvider{}
+		s := NewUsoService(repo, orgs, usuarios, provider)
+		s.agora = func() time.Time { return agora }
+		return s, repo, provider
+	}
+
+	t.Run("sucesso - agrega por período de cobrança e reporta uma única vez", func(t *testing.T) {
+		s, repo, provider := novo()
+		require.NoError(t, s.RegistrarUso(ctx, 1, domain.MetricaChamadasAPI, 1, "req-1"))
+		require.NoError(t, s.RegistrarUso(ctx, 1, domain.MetricaChamadasAPI, 1, "req-1"))
+		require.NoError(t, s.RegistrarUso(ctx, 1, domain.MetricaChamadasAPI, 2, "req-2"))
+		require.NoError(t, s.RegistrarUso(ctx, 2, domain.MetricaChamadasAPI, 5, "req-3"))
+		// Um evento do período anterior (antes de 5/3) vira um relatório separado.
+		repo.eventos = append(repo.eventos, domain.EventoUso{ID: 4, UsuarioID: 1, Metrica: domain.MetricaChamadasAPI, Quantidade: 4, OcorridoEm: agora.AddDate(0, 0, -16)})
+
+		n, err := s.ReportUsage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		require.Len(t, provider.Lotes, 1)
+		assert.Equal(t, int64(7), provider.Total("cus_acme", domain.MetricaChamadasAPI), "Bia não tem organização cobrada")
+		assert.Equal(t, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), repo.relatorios[0].PeriodoInicio)
+		assert.Equal(t, time.Date(2026, 2, 5, 0, 0, 0, 0, time.UTC), repo.relatorios[1].PeriodoInicio)
+
+		n, err = s.ReportUsage(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		assert.Len(t, provider.Lotes, 1)
+	})
+
+	t.Run("erro - lote recusado é reenviado com os mesmos identificadores", func(t *testing.T) {
+		s, repo, provider := novo()
+		require.NoError(t, s.RegistrarUso(ctx, 1, domain.MetricaChamadasAPI, 3, "req-1"))
+		provider.Err = errors.New("stripe indisponível")
+
+		_, err := s.ReportUsage(ctx)
+		assert.ErrorIs(t, err, provider.Err)
+		assert.Equal(t, 1, repo.relatorios[0].Tentativas)
+
+		provider.Err = nil
+		n, err := s.ReportUsage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, repo.relatorios[0].Identificador, provider.Lotes[0][0].Identificador)
+		assert.Equal(t, int64(3), provider.Total("cus_acme", domain.MetricaChamadasAPI))
+	})
+
+	t.Run("sucesso - consumo do período atual da assinatura", func(t *testing.T) {
+		s, _, _ := novo()
+		require.NoError(t, s.RegistrarUso(ctx, 1, domain.MetricaChamadasAPI, 2, "req-1"))
+		_, err := s.ReportUsage(ctx)
+		require.NoError(t, err)
+		require.NoError(t, s.RegistrarUso(ctx, 1, domain.MetricaChamadasAPI, 1, "req-2"))
+
+		uso, err := s.GetUsage(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), uso.OrganizacaoID)
+		assert.Equal(t, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), uso.PeriodoInicio)
+		assert.Equal(t, fimPeriodo, uso.PeriodoFim)
+		assert.Equal(t, []domain.ConsumoMetrica{{Metrica: domain.MetricaChamadasAPI, Quantidade: 3, Reportado: 2}}, uso.Metricas)
+
+		uso, err = s.GetUsage(ctx, 2)
+		require.NoError(t, err)
+		assert.Zero(t, uso.OrganizacaoID)
+		assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), uso.PeriodoInicio, "sem assinatura, o período é o mês corrente")
+
+		_, err = s.GetUsage(ctx, 99)
+		assert.ErrorIs(t, err, ErrUsuarioNaoEncontrado)
+	})
+}
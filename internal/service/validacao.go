@@ -0,0 +1,48 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+	"github.com/willjrcristo/go-sqlite-db/internal/validation"
+)
+
+// nomeValido aceita letras (inclusive acentuadas), espaços, apóstrofo, hífen e ponto,
+// começando sempre por uma letra. Ex: "Maria d'Ávila", "João Jr.", "Ana-Luísa".
+var nomeValido = regexp.MustCompile(`^\p{L}[\p{L}\p{M} '.-]*$`)
+
+// Limites dos campos de domain.Usuario.
+const (
+	nomeMinLen  = 2
+	nomeMaxLen  = 100
+	emailMaxLen = 254 // Limite prático de um endereço de e-mail (RFC 5321).
+)
+
+// normalizarUsuario remove espaços supérfluos dos campos informados pelo cliente. O e-mail
+// é gravado em minúsculas: ele é único sem diferenciar maiúsculas.
+func normalizarUsuario(usuario *domain.Usuario) {
+	usuario.Nome = strings.Join(strings.Fields(usuario.Nome), " ")
+	usuario.Email = strings.ToLower(strings.TrimSpace(usuario.Email))
+}
+
+// validarUsuario aplica as regras de nome e e-mail e devolve ErrDadosInvalidos com os erros por campo.
+func validarUsuario(usuario domain.Usuario) error {
+	erros := validation.Validate(
+		validation.F("nome", usuario.Nome,
+			validation.Required(),
+			validation.Length(nomeMinLen, nomeMaxLen),
+			validation.Matches(nomeValido, "deve conter apenas letras, espaços, apóstrofos, hífens e pontos"),
+		),
+		validation.F("email", usuario.Email,
+			validation.Required(),
+			validation.MaxLength(emailMaxLen),
+			validation.Email(),
+			validation.NotDisposableEmail(),
+		),
+	)
+	if len(erros) > 0 {
+		return ErrDadosInvalidos.WithFields(erros...)
+	}
+	return nil
+}
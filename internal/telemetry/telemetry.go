@@ -0,0 +1,152 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NomeServico é o nome padrão com que a API aparece nos traces.
+// Pode ser sobrescrito pela variável de ambiente OTEL_SERVICE_NAME.
+const NomeServico = "api-usuarios"
+
+// Setup configura o TracerProvider global de acordo com OTEL_TRACES_EXPORTER:
+//   - "otlp": exporta via OTLP/HTTP (endpoint definido por OTEL_EXPORTER_OTLP_ENDPOINT)
+//   - "stdout" ou "console": imprime os spans no stdout (útil em desenvolvimento)
+//   - vazio ou "none": não exporta, mas continua propagando o contexto de trace
+//
+// A função retornada deve ser chamada no encerramento para descarregar os spans pendentes.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch tipo := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")); tipo {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout", "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("exporter de traces desconhecido: %q", tipo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(NomeServico)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// EndSpan registra o erro (se houver) no span e o finaliza.
+// Pensado para ser usado com retornos nomeados: defer func() { telemetry.EndSpan(span, err) }().
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Middleware cria um span de servidor para cada requisição HTTP.
+// O nome do span usa o padrão da rota do chi (ex: "GET /usuarios/{id}"), que só é
+// conhecido depois que o roteamento termina, por isso o renomeamos ao final.
+func Middleware(next http.Handler) http.Handler {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil || rctx.RoutePattern() == "" {
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + rctx.RoutePattern())
+		span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+	})
+	return otelhttp.NewHandler(inner, "http.server")
+}
+
+// NewHTTPClient retorna um cliente HTTP que cria spans de cliente para cada chamada, com o
+// nome do serviço externo no nome do span (ex: "stripe POST /v1/customers"). É usado nas
+// chamadas à Stripe e aos provedores OIDC, para que apareçam dentro do trace da requisição.
+func NewHTTPClient(servico string) *http.Client {
+	return &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport,
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return servico + " " + r.Method + " " + r.URL.Path
+			}),
+		),
+	}
+}
+
+// logHandler adiciona trace_id e span_id em cada registro de log que tenha um span ativo no contexto.
+type logHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler embrulha um slog.Handler para correlacionar os logs com os traces.
+// Só funciona para chamadas que recebem o contexto (slog.InfoContext, slog.ErrorContext, ...).
+func NewLogHandler(h slog.Handler) slog.Handler {
+	return &logHandler{Handler: h}
+}
+
+func (h *logHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		rec.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// DBAttributes retorna os atributos padrão de um span de consulta ao SQLite.
+func DBAttributes(operacao, query string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.DBSystemNameSQLite,
+		semconv.DBOperationName(operacao),
+		semconv.DBQueryText(query),
+	}
+}
@@ -0,0 +1,107 @@
+// Package tokens gera e valida tokens assinados (HMAC-SHA256) com prazo de validade, usados
+// nos links enviados por e-mail. Não há estado no servidor: o token carrega a finalidade, o
+// usuário, um vínculo e a expiração, e a assinatura garante que nada disso foi alterado.
+//
+// O vínculo amarra o token a um estado do usuário (ex: um hash do e-mail ou da senha atual).
+// Quando esse estado muda, o token deixa de valer, o que o torna de uso único na prática.
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalido indica um token malformado, com assinatura inválida ou de outra finalidade.
+	ErrInvalido = errors.New("token inválido")
+	// ErrExpirado indica um token autêntico cujo prazo já passou.
+	ErrExpirado = errors.New("token expirado")
+)
+
+// Claims é o conteúdo de um token.
+type Claims struct {
+	Finalidade string `json:"f"`
+	UsuarioID  int64  `json:"u"`
+	Vinculo    string `json:"v"`
+	Sessao     int64  `json:"s,omitempty"` // Sessão de login do token, se houver (veja GerarNaSessao).
+	Expira     int64  `json:"exp"`         // Unix, em segundos.
+}
+
+// Assinador gera e valida tokens com um segredo compartilhado por todas as instâncias da API.
+type Assinador struct {
+	segredo []byte
+	agora   func() time.Time
+}
+
+// NewAssinador cria um Assinador. O segredo deve ter ao menos 32 bytes aleatórios.
+func NewAssinador(segredo []byte) *Assinador {
+	return &Assinador{segredo: segredo, agora: time.Now}
+}
+
+var base64Token = base64.RawURLEncoding
+
+// Gerar cria um token para a finalidade, válido por ttl.
+func (a *Assinador) Gerar(finalidade string, usuarioID int64, vinculo string, ttl time.Duration) (string, error) {
+	return a.GerarNaSessao(finalidade, usuarioID, vinculo, 0, ttl)
+}
+
+// GerarNaSessao cria um token como Gerar, amarrado também a uma sessão de login. Cabe a quem
+// valida conferir se a sessão continua ativa.
+func (a *Assinador) GerarNaSessao(finalidade string, usuarioID int64, vinculo string, sessaoID int64, ttl time.Duration) (string, error) {
+	payload, err := json.Marshal(Claims{
+		Finalidade: finalidade,
+		UsuarioID:  usuarioID,
+		Vinculo:    vinculo,
+		Sessao:     sessaoID,
+		Expira:     a.agora().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	corpo := base64Token.EncodeToString(payload)
+	return corpo + "." + base64Token.EncodeToString(a.assinar(corpo)), nil
+}
+
+// Validar confere a assinatura, a finalidade e a validade do token e devolve o seu conteúdo.
+// O vínculo fica a cargo de quem chama, que conhece o estado atual do usuário.
+func (a *Assinador) Validar(token, finalidade string) (Claims, error) {
+	corpo, assinatura, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalido
+	}
+	recebida, err := base64Token.DecodeString(assinatura)
+	if err != nil || !hmac.Equal(recebida, a.assinar(corpo)) {
+		return Claims{}, ErrInvalido
+	}
+	payload, err := base64Token.DecodeString(corpo)
+	if err != nil {
+		return Claims{}, ErrInvalido
+	}
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Finalidade != finalidade {
+		return Claims{}, ErrInvalido
+	}
+	if a.agora().Unix() >= c.Expira {
+		return Claims{}, ErrExpirado
+	}
+	return c, nil
+}
+
+func (a *Assinador) assinar(corpo string) []byte {
+	mac := hmac.New(sha256.New, a.segredo)
+	mac.Write([]byte(corpo))
+	return mac.Sum(nil)
+}
+
+// Vinculo resume um valor (ex: e-mail ou hash da senha) para uso como vínculo do token,
+// sem expô-lo: o conteúdo do token é apenas codificado, não cifrado.
+func Vinculo(valor string) string {
+	h := sha256.Sum256([]byte(valor))
+	return hex.EncodeToString(h[:8])
+}
@@ -0,0 +1,64 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssinador(t *testing.T) {
+	a := NewAssinador([]byte("segredo-de-teste-com-32-bytes!!!"))
+	agora := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.agora = func() time.Time { return agora }
+
+	token, err := a.Gerar("verificar_email", 42, Vinculo("ana@email.com"), time.Hour)
+	require.NoError(t, err)
+
+	t.Run("sucesso - valida e devolve o conteúdo", func(t *testing.T) {
+		c, err := a.Validar(token, "verificar_email")
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), c.UsuarioID)
+		assert.Equal(t, Vinculo("ana@email.com"), c.Vinculo)
+		assert.NotContains(t, token, "ana")
+	})
+
+	t.Run("erro - outra finalidade, assinatura alterada ou outro segredo", func(t *testing.T) {
+		_, err := a.Validar(token, "redefinir_senha")
+		assert.ErrorIs(t, err, ErrInvalido)
+
+		corpo, assinatura, _ := strings.Cut(token, ".")
+		_, err = a.Validar(corpo+"x."+assinatura, "verificar_email")
+		assert.ErrorIs(t, err, ErrInvalido)
+
+		_, err = NewAssinador([]byte("outro-segredo")).Validar(token, "verificar_email")
+		assert.ErrorIs(t, err, ErrInvalido)
+
+		_, err = a.Validar("lixo", "verificar_email")
+		assert.ErrorIs(t, err, ErrInvalido)
+	})
+
+	t.Run("erro - token expirado", func(t *testing.T) {
+		b := NewAssinador([]byte("segredo-de-teste-com-32-bytes!!!"))
+		b.agora = func() time.Time { return agora.Add(time.Hour) }
+
+		_, err := b.Validar(token, "verificar_email")
+
+		assert.ErrorIs(t, err, ErrExpirado)
+	})
+
+	t.Run("sucesso - token amarrado a uma sessão", func(t *testing.T) {
+		naSessao, err := a.GerarNaSessao("acesso", 42, "v", 7, time.Hour)
+		require.NoError(t, err)
+
+		c, err := a.Validar(naSessao, "acesso")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), c.Sessao)
+		c, _ = a.Validar(token, "verificar_email")
+		assert.Zero(t, c.Sessao)
+	})
+}
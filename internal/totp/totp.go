@@ -0,0 +1,120 @@
+// Package totp implementa os códigos de uso único baseados em tempo (TOTP, RFC 6238) usados
+// como segundo fator de login. Os parâmetros são os aceitos por todos os aplicativos
+// autenticadores (Google Authenticator, Authy, 1Password etc.): HMAC-SHA1, 6 dígitos e
+// intervalos de 30 segundos.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Periodo é a duração de cada código.
+	Periodo = 30 * time.Second
+	// Digitos é o tamanho dos códigos.
+	Digitos = 6
+	modulo  = 1_000_000 // 10^Digitos
+	// janela é quantos intervalos antes e depois do atual são aceitos, para tolerar a
+	// diferença entre o relógio do celular e o do servidor.
+	janela = 1
+	// bytesSegredo segue o tamanho recomendado pela RFC 4226 para HMAC-SHA1.
+	bytesSegredo = 20
+)
+
+// ErrSegredoInvalido indica um segredo que não está em base32.
+var ErrSegredoInvalido = errors.New("segredo TOTP inválido")
+
+var base32Segredo = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NovoSegredo gera um segredo aleatório, em base32 como os aplicativos esperam.
+func NovoSegredo() (string, error) {
+	b := make([]byte, bytesSegredo)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32Segredo.EncodeToString(b), nil
+}
+
+// Passo devolve o número do intervalo de 30 segundos do instante.
+func Passo(t time.Time) int64 {
+	return t.Unix() / int64(Periodo/time.Second)
+}
+
+// Codigo calcula o código do segredo no intervalo passo.
+func Codigo(segredo string, passo int64) (string, error) {
+	chave, err := decodificar(segredo)
+	if err != nil {
+		return "", err
+	}
+	return codigo(chave, passo), nil
+}
+
+// Validar confere o código no instante agora, aceitando um intervalo de diferença para cada
+// lado. Devolve o intervalo do código aceito, que quem chama deve registrar para recusar o
+// mesmo código (ou um anterior) depois.
+func Validar(segredo, codigoInformado string, agora time.Time) (passo int64, ok bool) {
+	codigoInformado = strings.ReplaceAll(strings.TrimSpace(codigoInformado), " ", "")
+	if len(codigoInformado) != Digitos {
+		return 0, false
+	}
+	chave, err := decodificar(segredo)
+	if err != nil {
+		return 0, false
+	}
+	atual := Passo(agora)
+	for p := atual - janela; p <= atual+janela; p++ {
+		if subtle.ConstantTimeCompare([]byte(codigo(chave, p)), []byte(codigoInformado)) == 1 {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// URI monta a URI otpauth:// de cadastro, exibida como QR code para o aplicativo
+// autenticador. emissor identifica a aplicação e conta, o usuário (ex: o e-mail).
+func URI(emissor, conta, segredo string) string {
+	v := url.Values{}
+	v.Set("secret", segredo)
+	v.Set("issuer", emissor)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digitos))
+	v.Set("period", fmt.Sprint(int(Periodo/time.Second)))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + emissor + ":" + conta,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
+
+func decodificar(segredo string) ([]byte, error) {
+	chave, err := base32Segredo.DecodeString(strings.ToUpper(strings.TrimRight(segredo, "=")))
+	if err != nil || len(chave) == 0 {
+		return nil, ErrSegredoInvalido
+	}
+	return chave, nil
+}
+
+// codigo é o HOTP (RFC 4226) da chave no contador passo, truncado em Digitos.
+func codigo(chave []byte, passo int64) string {
+	var contador [8]byte
+	binary.BigEndian.PutUint64(contador[:], uint64(passo))
+	mac := hmac.New(sha1.New, chave)
+	mac.Write(contador[:])
+	soma := mac.Sum(nil)
+
+	deslocamento := soma[len(soma)-1] & 0x0f
+	valor := binary.BigEndian.Uint32(soma[deslocamento:deslocamento+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digitos, valor%modulo)
+}
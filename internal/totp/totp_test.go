@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodigo(t *testing.T) {
+	// Vetores de teste da RFC 6238 (SHA1), truncados nos 6 dígitos finais.
+	segredo := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vetores := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, esperado := range vetores {
+		codigo, err := Codigo(segredo, Passo(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, esperado, codigo, "instante %d", unix)
+	}
+
+	_, err := Codigo("não é base32!", 1)
+	assert.ErrorIs(t, err, ErrSegredoInvalido)
+}
+
+func TestValidar(t *testing.T) {
+	segredo, err := NovoSegredo()
+	require.NoError(t, err)
+	agora := time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC)
+	codigoEm := func(t *testing.T, instante time.Time) string {
+		t.Helper()
+		c, err := Codigo(segredo, Passo(instante))
+		require.NoError(t, err)
+		return c
+	}
+
+	t.Run("sucesso - aceita o código atual e os vizinhos, devolvendo o intervalo", func(t *testing.T) {
+		passo, ok := Validar(segredo, codigoEm(t, agora), agora)
+		assert.True(t, ok)
+		assert.Equal(t, Passo(agora), passo)
+
+		passo, ok = Validar(segredo, codigoEm(t, agora.Add(-Periodo)), agora)
+		assert.True(t, ok)
+		assert.Equal(t, Passo(agora)-1, passo)
+
+		_, ok = Validar(segredo, " "+codigoEm(t, agora.Add(Periodo))+" ", agora)
+		assert.True(t, ok)
+	})
+
+	t.Run("erro - código fora da janela, malformado ou segredo inválido", func(t *testing.T) {
+		_, ok := Validar(segredo, codigoEm(t, agora.Add(-2*Periodo)), agora)
+		assert.False(t, ok)
+		_, ok = Validar(segredo, "12345", agora)
+		assert.False(t, ok)
+		_, ok = Validar("!!", "123456", agora)
+		assert.False(t, ok)
+	})
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("API de Usuários", "ana@email.com", "JBSWY3DPEHPK3PXP")
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/API de Usuários:ana@email.com", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "API de Usuários", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+}
@@ -0,0 +1,180 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+// Rule é uma regra de validação aplicada ao valor de um campo.
+// Devolve nil quando o valor é válido, ou o erro (sem o nome do campo, preenchido por Validate).
+type Rule func(value string) *domain.FieldError
+
+// Field associa um campo do payload às regras que ele deve respeitar.
+type Field struct {
+	Name  string
+	Value string
+	Rules []Rule
+}
+
+// F é um atalho para declarar um Field.
+func F(name, value string, rules ...Rule) Field {
+	return Field{Name: name, Value: value, Rules: rules}
+}
+
+// Validate aplica as regras de cada campo e devolve os erros encontrados.
+// As regras de um campo são avaliadas em ordem e param no primeiro erro, para não
+// reportar, por exemplo, "formato inválido" de um campo que está vazio.
+func Validate(fields ...Field) []domain.FieldError {
+	var erros []domain.FieldError
+	for _, f := range fields {
+		for _, rule := range f.Rules {
+			if fe := rule(f.Value); fe != nil {
+				fe.Field = f.Name
+				erros = append(erros, *fe)
+				break
+			}
+		}
+	}
+	return erros
+}
+
+func fail(code, format string, args ...any) *domain.FieldError {
+	return &domain.FieldError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Required exige um valor não vazio (espaços em branco não contam).
+func Required() Rule {
+	return func(value string) *domain.FieldError {
+		if strings.TrimSpace(value) == "" {
+			return fail("obrigatorio", "campo obrigatório")
+		}
+		return nil
+	}
+}
+
+// Length exige que o valor tenha entre min e max caracteres (runas, não bytes).
+func Length(min, max int) Rule {
+	return func(value string) *domain.FieldError {
+		n := utf8.RuneCountInString(value)
+		if n < min {
+			return fail("muito_curto", "deve ter ao menos %d caracteres", min)
+		}
+		if n > max {
+			return fail("muito_longo", "deve ter no máximo %d caracteres", max)
+		}
+		return nil
+	}
+}
+
+// MaxLength exige que o valor tenha no máximo max caracteres.
+func MaxLength(max int) Rule {
+	return Length(0, max)
+}
+
+// Matches exige que o valor case com a expressão regular; message explica o formato esperado.
+func Matches(re *regexp.Regexp, message string) Rule {
+	return func(value string) *domain.FieldError {
+		if !re.MatchString(value) {
+			return fail("formato_invalido", "%s", message)
+		}
+		return nil
+	}
+}
+
+// Email exige um endereço de e-mail simples (sem nome de exibição) com domínio qualificado.
+func Email() Rule {
+	return func(value string) *domain.FieldError {
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return fail("email_invalido", "e-mail em formato inválido")
+		}
+		dominio := value[strings.LastIndex(value, "@")+1:]
+		if !strings.Contains(dominio, ".") || strings.HasPrefix(dominio, ".") || strings.HasSuffix(dominio, ".") {
+			return fail("email_invalido", "e-mail em formato inválido")
+		}
+		return nil
+	}
+}
+
+// NotDisposableEmail rejeita e-mails de provedores descartáveis (ver disposable_domains.txt).
+func NotDisposableEmail() Rule {
+	return func(value string) *domain.FieldError {
+		dominio := strings.ToLower(value[strings.LastIndex(value, "@")+1:])
+		for d := dominio; d != ""; {
+			if _, ok := dominiosDescartaveis[d]; ok {
+				return fail("email_descartavel", "e-mails descartáveis não são aceitos")
+			}
+			i := strings.Index(d, ".")
+			if i < 0 {
+				break
+			}
+			d = d[i+1:]
+		}
+		return nil
+	}
+}
+
+// CPFouCNPJ exige um CPF (11 dígitos) ou CNPJ (14 dígitos), só com números e com os dígitos
+// verificadores corretos. Sequências de um só dígito repetido são rejeitadas.
+func CPFouCNPJ() Rule {
+	return func(value string) *domain.FieldError {
+		if strings.Trim(value, "0123456789") != "" || (len(value) != 11 && len(value) != 14) {
+			return fail("formato_invalido", "informe o CPF (11 dígitos) ou o CNPJ (14 dígitos), só os números")
+		}
+		if strings.Count(value, value[:1]) == len(value) || !digitosVerificadores(value) {
+			return fail("documento_invalido", "CPF ou CNPJ inválido")
+		}
+		return nil
+	}
+}
+
+// digitosVerificadores confere os dois últimos dígitos do CPF ou CNPJ (módulo 11). No CPF os
+// pesos vão de 2 a 10 (e 11) da direita para a esquerda; no CNPJ, de 2 a 9, recomeçando.
+func digitosVerificadores(doc string) bool {
+	pesoMax := 11
+	if len(doc) == 14 {
+		pesoMax = 9
+	}
+	for n := len(doc) - 2; n < len(doc); n++ {
+		soma, peso := 0, 2
+		for i := n - 1; i >= 0; i-- {
+			soma += int(doc[i]-'0') * peso
+			if peso++; peso > pesoMax {
+				peso = 2
+			}
+		}
+		dv := 11 - soma%11
+		if dv >= 10 {
+			dv = 0
+		}
+		if int(doc[n]-'0') != dv {
+			return false
+		}
+	}
+	return true
+}
+
+//go:embed disposable_domains.txt
+var disposableDomainsFile string
+
+var dominiosDescartaveis = carregarDominios(disposableDomainsFile)
+
+func carregarDominios(conteudo string) map[string]struct{} {
+	dominios := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(conteudo))
+	for scanner.Scan() {
+		linha := strings.TrimSpace(scanner.Text())
+		if linha == "" || strings.HasPrefix(linha, "#") {
+			continue
+		}
+		dominios[strings.ToLower(linha)] = struct{}{}
+	}
+	return dominios
+}
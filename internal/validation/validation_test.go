@@ -0,0 +1,89 @@
+package validation
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willjrcristo/go-sqlite-db/internal/domain"
+)
+
+func TestValidate(t *testing.T) {
+	nome := regexp.MustCompile(`^\p{L}[\p{L} ]*$`)
+
+	casos := []struct {
+		nome     string
+		campos   []Field
+		esperado []domain.FieldError
+	}{
+		{
+			nome: "sucesso - valores válidos não geram erros",
+			campos: []Field{
+				F("nome", "João Ávila", Required(), Length(2, 100), Matches(nome, "apenas letras")),
+				F("email", "joao@exemplo.com.br", Required(), Email(), NotDisposableEmail()),
+			},
+		},
+		{
+			nome:     "erro - para na primeira regra violada do campo",
+			campos:   []Field{F("nome", "", Required(), Length(2, 100))},
+			esperado: []domain.FieldError{{Field: "nome", Code: "obrigatorio", Message: "campo obrigatório"}},
+		},
+		{
+			nome:     "erro - tamanho é contado em caracteres, não em bytes",
+			campos:   []Field{F("nome", "Éé", Length(3, 10))},
+			esperado: []domain.FieldError{{Field: "nome", Code: "muito_curto", Message: "deve ter ao menos 3 caracteres"}},
+		},
+		{
+			nome:     "erro - e-mail com nome de exibição é rejeitado",
+			campos:   []Field{F("email", "João <joao@exemplo.com>", Email())},
+			esperado: []domain.FieldError{{Field: "email", Code: "email_invalido", Message: "e-mail em formato inválido"}},
+		},
+		{
+			nome:     "erro - e-mail sem domínio qualificado é rejeitado",
+			campos:   []Field{F("email", "joao@localhost", Email())},
+			esperado: []domain.FieldError{{Field: "email", Code: "email_invalido", Message: "e-mail em formato inválido"}},
+		},
+		{
+			nome:     "erro - subdomínio de provedor descartável é rejeitado",
+			campos:   []Field{F("email", "x@abc.Mailinator.com", NotDisposableEmail())},
+			esperado: []domain.FieldError{{Field: "email", Code: "email_descartavel", Message: "e-mails descartáveis não são aceitos"}},
+		},
+		{
+			nome: "sucesso - CPF e CNPJ com dígitos verificadores corretos",
+			campos: []Field{
+				F("documento", "11144477735", CPFouCNPJ()),
+				F("documento", "11222333000181", CPFouCNPJ()),
+			},
+		},
+		{
+			nome: "erro - CPF com dígito errado, repetido ou formatado",
+			campos: []Field{
+				F("documento", "11144477734", CPFouCNPJ()),
+				F("documento", "00000000000", CPFouCNPJ()),
+				F("documento", "111.444.777-35", CPFouCNPJ()),
+			},
+			esperado: []domain.FieldError{
+				{Field: "documento", Code: "documento_invalido", Message: "CPF ou CNPJ inválido"},
+				{Field: "documento", Code: "documento_invalido", Message: "CPF ou CNPJ inválido"},
+				{Field: "documento", Code: "formato_invalido", Message: "informe o CPF (11 dígitos) ou o CNPJ (14 dígitos), só os números"},
+			},
+		},
+		{
+			nome: "erro - reporta um erro para cada campo inválido",
+			campos: []Field{
+				F("nome", "J0ão", Matches(nome, "apenas letras")),
+				F("email", "invalido", Email()),
+			},
+			esperado: []domain.FieldError{
+				{Field: "nome", Code: "formato_invalido", Message: "apenas letras"},
+				{Field: "email", Code: "email_invalido", Message: "e-mail em formato inválido"},
+			},
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			assert.Equal(t, c.esperado, Validate(c.campos...))
+		})
+	}
+}
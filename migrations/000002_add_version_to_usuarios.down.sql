@@ -0,0 +1 @@
+ALTER TABLE usuarios DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX idx_usuarios_updated_at;
+DROP INDEX idx_usuarios_created_at;
+ALTER TABLE usuarios DROP COLUMN updated_at;
+ALTER TABLE usuarios DROP COLUMN created_at;
\ No newline at end of file
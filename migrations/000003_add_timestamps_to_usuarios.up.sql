@@ -0,0 +1,14 @@
+-- O SQLite não aceita DEFAULT não constante (ex: CURRENT_TIMESTAMP) em ADD COLUMN, então as
+-- colunas entram nulas e são preenchidas pelo repositório a partir daqui.
+-- Backfill: a data real de cadastro dos registros existentes é desconhecida, então eles recebem
+-- o instante da migration, no mesmo formato que o driver grava (UTC, "AAAA-MM-DD HH:MM:SS.SSS+00:00").
+ALTER TABLE usuarios ADD COLUMN created_at DATETIME;
+ALTER TABLE usuarios ADD COLUMN updated_at DATETIME;
+
+UPDATE usuarios
+SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE created_at IS NULL;
+
+CREATE INDEX idx_usuarios_created_at ON usuarios(created_at);
+CREATE INDEX idx_usuarios_updated_at ON usuarios(updated_at);
\ No newline at end of file
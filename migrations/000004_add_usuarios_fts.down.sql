@@ -0,0 +1,5 @@
+-- migrations/000004_add_usuarios_fts.down.sql
+DROP TRIGGER IF EXISTS usuarios_fts_au;
+DROP TRIGGER IF EXISTS usuarios_fts_ad;
+DROP TRIGGER IF EXISTS usuarios_fts_ai;
+DROP TABLE IF EXISTS usuarios_fts;
@@ -0,0 +1,30 @@
+-- migrations/000004_add_usuarios_fts.up.sql
+-- Índice de busca textual (FTS5) sobre nome e e-mail, sem duplicar os dados: a tabela
+-- virtual lê o conteúdo de usuarios e é mantida em sincronia pelos triggers abaixo.
+-- unicode61 com remove_diacritics 2 ignora acentos ("joao" encontra "João");
+-- os índices de prefixo aceleram as buscas por início de palavra ("jo*").
+-- Requer o SQLite compilado com FTS5 (go build -tags sqlite_fts5).
+CREATE VIRTUAL TABLE usuarios_fts USING fts5(
+    nome,
+    email,
+    content = 'usuarios',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3 4'
+);
+
+-- Indexa os usuários já existentes.
+INSERT INTO usuarios_fts(usuarios_fts) VALUES ('rebuild');
+
+CREATE TRIGGER usuarios_fts_ai AFTER INSERT ON usuarios BEGIN
+    INSERT INTO usuarios_fts(rowid, nome, email) VALUES (new.id, new.nome, new.email);
+END;
+
+CREATE TRIGGER usuarios_fts_ad AFTER DELETE ON usuarios BEGIN
+    INSERT INTO usuarios_fts(usuarios_fts, rowid, nome, email) VALUES ('delete', old.id, old.nome, old.email);
+END;
+
+CREATE TRIGGER usuarios_fts_au AFTER UPDATE OF nome, email ON usuarios BEGIN
+    INSERT INTO usuarios_fts(usuarios_fts, rowid, nome, email) VALUES ('delete', old.id, old.nome, old.email);
+    INSERT INTO usuarios_fts(rowid, nome, email) VALUES (new.id, new.nome, new.email);
+END;
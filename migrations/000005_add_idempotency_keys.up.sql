@@ -0,0 +1,13 @@
+-- Respostas guardadas por Idempotency-Key. Enquanto a requisição original está em andamento,
+-- status fica nulo; depois, status, headers (JSON) e corpo permitem repetir a resposta.
+CREATE TABLE idempotency_keys (
+    chave TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    status INTEGER,
+    headers TEXT,
+    corpo BLOB,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,12 @@
+-- API keys dos clientes máquina a máquina. Só o hash (SHA-256) da chave é guardado; o
+-- prefixo, que é parte da chave, serve para encontrá-la. Os escopos ficam separados por espaço.
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nome TEXT NOT NULL,
+    prefixo TEXT NOT NULL UNIQUE,
+    hash TEXT NOT NULL,
+    escopos TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    revoked_at DATETIME
+);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS usuarios_email_alterado;
+DROP INDEX IF EXISTS idx_usuarios_email;
+ALTER TABLE usuarios DROP COLUMN password_hash;
+ALTER TABLE usuarios DROP COLUMN email_verified_at;
@@ -0,0 +1,13 @@
+-- Verificação de e-mail e senha dos usuários. Os registros existentes ficam não verificados.
+ALTER TABLE usuarios ADD COLUMN email_verified_at DATETIME;
+ALTER TABLE usuarios ADD COLUMN password_hash TEXT;
+
+-- Busca por e-mail nos pedidos de reenvio de verificação e de redefinição de senha.
+CREATE INDEX idx_usuarios_email ON usuarios(email);
+
+-- Um novo e-mail precisa ser verificado de novo, qualquer que seja o caminho da alteração
+-- (PUT, PATCH ou lote).
+CREATE TRIGGER usuarios_email_alterado AFTER UPDATE OF email ON usuarios
+WHEN old.email IS NOT new.email BEGIN
+    UPDATE usuarios SET email_verified_at = NULL WHERE id = new.id;
+END;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS oidc_states;
+DROP TRIGGER IF EXISTS usuarios_identities_removidas;
+DROP TABLE IF EXISTS identities;
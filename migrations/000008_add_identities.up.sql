@@ -0,0 +1,31 @@
+-- Contas de provedores OIDC (Google, servidor local de testes etc.) vinculadas aos usuários.
+-- O par provedor/subject identifica a conta no provedor; o e-mail é o informado no vínculo.
+CREATE TABLE identities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    usuario_id INTEGER NOT NULL REFERENCES usuarios(id),
+    provedor TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_login_at DATETIME,
+    UNIQUE (provedor, subject)
+);
+
+CREATE INDEX idx_identities_usuario_id ON identities(usuario_id);
+
+-- O SQLite só aplica ON DELETE CASCADE com foreign_keys ligado, então a remoção fica no trigger.
+CREATE TRIGGER usuarios_identities_removidas AFTER DELETE ON usuarios BEGIN
+    DELETE FROM identities WHERE usuario_id = old.id;
+END;
+
+-- Logins OIDC em andamento: o state enviado ao provedor guarda o nonce e o verificador PKCE
+-- até o callback, que o consome uma única vez.
+CREATE TABLE oidc_states (
+    state TEXT PRIMARY KEY,
+    provedor TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    verificador TEXT NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_oidc_states_expires_at ON oidc_states(expires_at);
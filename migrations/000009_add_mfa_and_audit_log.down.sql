@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_audit_log_usuario_id;
+DROP TABLE IF EXISTS audit_log;
+DROP TRIGGER IF EXISTS usuarios_mfa_removido;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS mfa;
+ALTER TABLE usuarios DROP COLUMN papel;
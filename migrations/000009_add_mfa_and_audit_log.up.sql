@@ -0,0 +1,41 @@
+-- Papel do usuário. O papel admin dá acesso às rotas de /usuarios com o token de acesso do
+-- usuário, e a política de MFA_REQUIRED_ROLES exige dois fatores para ele.
+ALTER TABLE usuarios ADD COLUMN papel TEXT NOT NULL DEFAULT 'usuario';
+
+-- Segundo fator (TOTP) dos usuários. O cadastro fica pendente (confirmado_em nulo) até o
+-- usuário informar o primeiro código; ultimo_passo guarda o intervalo de 30s do último
+-- código aceito, para que o mesmo código não seja usado duas vezes.
+CREATE TABLE mfa (
+    usuario_id INTEGER PRIMARY KEY REFERENCES usuarios(id),
+    segredo TEXT NOT NULL,
+    confirmado_em DATETIME,
+    ultimo_passo INTEGER,
+    created_at DATETIME NOT NULL
+);
+
+-- Códigos de recuperação, de uso único. Só o hash é guardado.
+CREATE TABLE mfa_recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    usuario_id INTEGER NOT NULL REFERENCES usuarios(id),
+    hash TEXT NOT NULL,
+    used_at DATETIME,
+    UNIQUE (usuario_id, hash)
+);
+
+CREATE TRIGGER usuarios_mfa_removido AFTER DELETE ON usuarios BEGIN
+    DELETE FROM mfa WHERE usuario_id = old.id;
+    DELETE FROM mfa_recovery_codes WHERE usuario_id = old.id;
+END;
+
+-- Trilha de auditoria das ações sensíveis (MFA, papel). Os eventos são mantidos mesmo
+-- depois que o usuário é removido.
+CREATE TABLE audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ator TEXT NOT NULL,
+    acao TEXT NOT NULL,
+    usuario_id INTEGER NOT NULL,
+    detalhes TEXT,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_audit_log_usuario_id ON audit_log(usuario_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_sessao_id;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP INDEX IF EXISTS idx_sessoes_usuario_id;
+DROP TABLE IF EXISTS sessoes;
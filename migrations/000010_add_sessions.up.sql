@@ -0,0 +1,30 @@
+-- Sessões de login. Cada sessão é uma família de refresh tokens: a cada renovação o token
+-- apresentado é marcado como usado e um novo é emitido. Reapresentar um token já usado
+-- indica que ele vazou, e a sessão inteira é revogada. As sessões revogadas são mantidas
+-- (com o motivo) mesmo depois que o usuário é removido, como a trilha de auditoria.
+CREATE TABLE sessoes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    usuario_id INTEGER NOT NULL,
+    dispositivo TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    com_mfa INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    motivo_revogacao TEXT
+);
+
+CREATE INDEX idx_sessoes_usuario_id ON sessoes(usuario_id);
+
+-- Refresh tokens emitidos para cada sessão. Só o hash é guardado; used_at marca os já trocados.
+CREATE TABLE refresh_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sessao_id INTEGER NOT NULL REFERENCES sessoes(id),
+    hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    used_at DATETIME
+);
+
+CREATE INDEX idx_refresh_tokens_sessao_id ON refresh_tokens(sessao_id);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS usuarios_memberships_removidas;
+DROP INDEX IF EXISTS idx_invitations_organizacao_id;
+DROP TABLE IF EXISTS invitations;
+DROP INDEX IF EXISTS idx_memberships_usuario_id;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS organizations;
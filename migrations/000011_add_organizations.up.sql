@@ -0,0 +1,58 @@
+-- Organizações (tenants). A assinatura da Stripe passa a pertencer à organização, e não mais
+-- ao usuário: assentos é a quantidade contratada na assinatura, que limita membros e convites
+-- pendentes somados. Zero indica que a organização nunca assinou, e então não há limite.
+CREATE TABLE organizations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nome TEXT NOT NULL,
+    stripe_customer_id TEXT UNIQUE,
+    stripe_subscription_id TEXT,
+    subscription_status TEXT NOT NULL DEFAULT 'inactive',
+    subscription_current_period_end DATETIME,
+    assentos INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL
+);
+
+-- Membros de cada organização, com o papel deles nela (dono, admin ou membro).
+CREATE TABLE memberships (
+    organizacao_id INTEGER NOT NULL REFERENCES organizations(id),
+    usuario_id INTEGER NOT NULL REFERENCES usuarios(id),
+    papel TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (organizacao_id, usuario_id)
+);
+
+CREATE INDEX idx_memberships_usuario_id ON memberships(usuario_id);
+
+-- Convites por e-mail. Só o hash do token é guardado; o convite ocupa um assento enquanto
+-- estiver pendente (nem aceito, nem revogado, nem expirado).
+CREATE TABLE invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    organizacao_id INTEGER NOT NULL REFERENCES organizations(id),
+    email TEXT NOT NULL,
+    papel TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    convidado_por INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE INDEX idx_invitations_organizacao_id ON invitations(organizacao_id);
+
+CREATE TRIGGER usuarios_memberships_removidas AFTER DELETE ON usuarios BEGIN
+    DELETE FROM memberships WHERE usuario_id = old.id;
+END;
+
+-- Os clientes da Stripe existentes viram organizações com o usuário como dono e, se ele já
+-- assinou, um assento.
+INSERT INTO organizations (nome, stripe_customer_id, stripe_subscription_id, subscription_status, subscription_current_period_end, assentos, created_at)
+SELECT nome, stripe_customer_id, stripe_subscription_id, COALESCE(subscription_status, 'inactive'),
+       subscription_current_period_end,
+       CASE WHEN COALESCE(stripe_subscription_id, '') <> '' THEN 1 ELSE 0 END, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+FROM usuarios
+WHERE stripe_customer_id IS NOT NULL AND stripe_customer_id <> '';
+
+INSERT INTO memberships (organizacao_id, usuario_id, papel, created_at)
+SELECT o.id, u.id, 'dono', o.created_at
+FROM organizations o JOIN usuarios u ON u.stripe_customer_id = o.stripe_customer_id;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_usage_reports_pendentes;
+DROP TABLE IF EXISTS usage_reports;
+DROP INDEX IF EXISTS idx_usage_events_pendentes;
+DROP INDEX IF EXISTS idx_usage_events_usuario_id;
+DROP TABLE IF EXISTS usage_events;
@@ -0,0 +1,34 @@
+-- Eventos de consumo medidos por usuário. chave identifica o evento na origem (gerada pelo
+-- servidor) e impede que ele seja contado duas vezes. report_id aponta o relatório em que o
+-- evento foi agregado; enquanto for nulo, o evento está pendente.
+CREATE TABLE usage_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    usuario_id INTEGER NOT NULL,
+    metrica TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    chave TEXT UNIQUE,
+    ocorrido_em DATETIME NOT NULL,
+    report_id INTEGER
+);
+
+CREATE INDEX idx_usage_events_usuario_id ON usage_events(usuario_id, ocorrido_em);
+CREATE INDEX idx_usage_events_pendentes ON usage_events(ocorrido_em) WHERE report_id IS NULL;
+
+-- Consumo agregado por organização, métrica e período de cobrança, reportado ao provedor
+-- (Stripe) em lotes. identificador é a chave de idempotência junto ao provedor, então um
+-- relatório pode ser reenviado depois de uma falha sem duplicar a cobrança.
+CREATE TABLE usage_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    organizacao_id INTEGER NOT NULL REFERENCES organizations(id),
+    metrica TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    periodo_inicio DATETIME NOT NULL,
+    momento DATETIME NOT NULL,
+    identificador TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    reported_at DATETIME,
+    tentativas INTEGER NOT NULL DEFAULT 0,
+    ultimo_erro TEXT
+);
+
+CREATE INDEX idx_usage_reports_pendentes ON usage_reports(id) WHERE reported_at IS NULL;
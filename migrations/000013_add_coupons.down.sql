@@ -0,0 +1,4 @@
+ALTER TABLE organizations DROP COLUMN cupom;
+DROP INDEX IF EXISTS idx_coupons_stripe_coupon_id;
+DROP INDEX IF EXISTS idx_coupons_codigo;
+DROP TABLE IF EXISTS coupons;
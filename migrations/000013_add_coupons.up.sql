@@ -0,0 +1,26 @@
+-- Códigos promocionais espelhados da Stripe (promotion codes, cada um de um cupom), mantidos
+-- pelos webhooks promotion_code.* e coupon.deleted e pelo comando sincronizar-cupons. O
+-- checkout valida o código informado contra esta tabela antes de chamar a Stripe. resgates
+-- vem da Stripe e é incrementado localmente a cada assinatura que passa a usar o código.
+CREATE TABLE coupons (
+    stripe_promotion_code_id TEXT PRIMARY KEY,
+    codigo TEXT NOT NULL COLLATE NOCASE,
+    stripe_coupon_id TEXT NOT NULL,
+    nome TEXT NOT NULL DEFAULT '',
+    percent_off REAL NOT NULL DEFAULT 0,
+    amount_off INTEGER NOT NULL DEFAULT 0,
+    moeda TEXT NOT NULL DEFAULT '',
+    duracao TEXT NOT NULL,
+    duracao_meses INTEGER NOT NULL DEFAULT 0,
+    ativo BOOLEAN NOT NULL,
+    expires_at DATETIME,
+    max_resgates INTEGER NOT NULL DEFAULT 0,
+    resgates INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_coupons_codigo ON coupons(codigo);
+CREATE INDEX idx_coupons_stripe_coupon_id ON coupons(stripe_coupon_id);
+
+-- Código promocional do desconto em vigor na assinatura da organização.
+ALTER TABLE organizations ADD COLUMN cupom TEXT;
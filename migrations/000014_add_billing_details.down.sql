@@ -0,0 +1,3 @@
+ALTER TABLE organizations DROP COLUMN moeda;
+DROP TRIGGER IF EXISTS usuarios_billing_details_removidos;
+DROP TABLE IF EXISTS billing_details;
@@ -0,0 +1,22 @@
+-- Endereço de cobrança e documento fiscal (CPF ou CNPJ, só dígitos) de cada usuário. Vão
+-- para o cliente da Stripe das organizações de que ele é dono e definem a moeda e os
+-- impostos do checkout.
+CREATE TABLE billing_details (
+    usuario_id INTEGER PRIMARY KEY REFERENCES usuarios(id),
+    linha1 TEXT NOT NULL,
+    linha2 TEXT NOT NULL DEFAULT '',
+    cidade TEXT NOT NULL,
+    estado TEXT NOT NULL DEFAULT '',
+    cep TEXT NOT NULL,
+    pais TEXT NOT NULL,
+    documento TEXT NOT NULL DEFAULT '',
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TRIGGER usuarios_billing_details_removidos AFTER DELETE ON usuarios BEGIN
+    DELETE FROM billing_details WHERE usuario_id = old.id;
+END;
+
+-- Moeda da assinatura da organização. Um cliente da Stripe não muda de moeda depois de
+-- assinar, então os próximos checkouts usam o preço desta moeda.
+ALTER TABLE organizations ADD COLUMN moeda TEXT;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS usuarios_customer_sync_removido;
+DROP TRIGGER IF EXISTS usuarios_perfil_alterado;
+DROP TABLE IF EXISTS customer_sync_outbox;
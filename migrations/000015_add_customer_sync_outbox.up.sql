@@ -0,0 +1,27 @@
+-- Caixa de saída (outbox) das alterações de perfil que precisam chegar ao cliente da Stripe
+-- das organizações de que o usuário é dono. O trigger grava a pendência na mesma transação
+-- da alteração, qualquer que seja o caminho (PUT, PATCH, lote ou webhook); o envio é feito
+-- em segundo plano com os valores atuais do usuário e repetido depois de uma falha.
+CREATE TABLE customer_sync_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    usuario_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    sent_at DATETIME,
+    tentativas INTEGER NOT NULL DEFAULT 0,
+    ultimo_erro TEXT
+);
+
+CREATE INDEX idx_customer_sync_outbox_pendentes ON customer_sync_outbox(id) WHERE sent_at IS NULL;
+
+-- Só os donos de organizações que já são clientes da Stripe têm o que sincronizar.
+CREATE TRIGGER usuarios_perfil_alterado AFTER UPDATE OF nome, email ON usuarios
+WHEN (old.nome IS NOT new.nome OR old.email IS NOT new.email) AND EXISTS (
+    SELECT 1 FROM memberships m JOIN organizations o ON o.id = m.organizacao_id
+    WHERE m.usuario_id = new.id AND m.papel = 'dono' AND o.stripe_customer_id IS NOT NULL
+) BEGIN
+    INSERT INTO customer_sync_outbox (usuario_id, created_at) VALUES (new.id, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'));
+END;
+
+CREATE TRIGGER usuarios_customer_sync_removido AFTER DELETE ON usuarios BEGIN
+    DELETE FROM customer_sync_outbox WHERE usuario_id = old.id;
+END;
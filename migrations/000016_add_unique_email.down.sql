@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_usuarios_email_unico;
+CREATE INDEX idx_usuarios_email ON usuarios(email);
@@ -0,0 +1,34 @@
+-- O e-mail identifica o usuário no login, na verificação e nos convites, então passa a ser
+-- único sem diferenciar maiúsculas. A aplicação grava os e-mails em minúsculas.
+
+-- Cadastros que só diferem nas maiúsculas são pessoas (ou tentativas) distintas, e escolher
+-- qual manter cabe a quem opera a base. Com algum deles, a migração é abortada; para listá-los:
+--   SELECT lower(trim(email)), group_concat(id) FROM usuarios
+--   GROUP BY lower(trim(email)) HAVING count(*) > 1;
+-- Fora de um trigger o SQLite não aceita RAISE, então o aborto vem da restrição abaixo.
+CREATE TEMP TABLE checagem_email_unico (
+    duplicados INTEGER CONSTRAINT "há usuários com o mesmo e-mail ignorando maiúsculas; resolva-os antes de migrar" CHECK (duplicados = 0)
+);
+INSERT INTO checagem_email_unico (duplicados)
+SELECT count(*) FROM (SELECT 1 FROM usuarios GROUP BY lower(trim(email)) HAVING count(*) > 1);
+DROP TABLE checagem_email_unico;
+
+-- Passar o e-mail para minúsculas não é uma troca de e-mail: a verificação é mantida e
+-- nada é enviado à Stripe.
+DROP TRIGGER usuarios_email_alterado;
+DROP TRIGGER usuarios_perfil_alterado;
+UPDATE usuarios SET email = lower(trim(email)) WHERE email IS NOT lower(trim(email));
+CREATE TRIGGER usuarios_email_alterado AFTER UPDATE OF email ON usuarios
+WHEN old.email IS NOT new.email BEGIN
+    UPDATE usuarios SET email_verified_at = NULL WHERE id = new.id;
+END;
+CREATE TRIGGER usuarios_perfil_alterado AFTER UPDATE OF nome, email ON usuarios
+WHEN (old.nome IS NOT new.nome OR old.email IS NOT new.email) AND EXISTS (
+    SELECT 1 FROM memberships m JOIN organizations o ON o.id = m.organizacao_id
+    WHERE m.usuario_id = new.id AND m.papel = 'dono' AND o.stripe_customer_id IS NOT NULL
+) BEGIN
+    INSERT INTO customer_sync_outbox (usuario_id, created_at) VALUES (new.id, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'));
+END;
+
+DROP INDEX idx_usuarios_email;
+CREATE UNIQUE INDEX idx_usuarios_email_unico ON usuarios(lower(email));
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS usuarios_dono_nao_removido;
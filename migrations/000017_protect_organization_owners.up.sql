@@ -0,0 +1,8 @@
+-- O dono de uma organização não pode ser removido: a organização ficaria sem ninguém para
+-- administrá-la e sem o e-mail que recebe as faturas. A remoção falha enquanto ele for dono
+-- de alguma organização, qualquer que seja o caminho (DELETE, lote ou migração).
+CREATE TRIGGER usuarios_dono_nao_removido BEFORE DELETE ON usuarios
+WHEN EXISTS (SELECT 1 FROM memberships WHERE usuario_id = old.id AND papel = 'dono')
+BEGIN
+    SELECT RAISE(ABORT, 'dono_de_organizacao');
+END;